@@ -2,9 +2,11 @@ package assembler
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/silaspace/aria/device"
 	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/object"
 	"github.com/silaspace/aria/parser"
 )
 
@@ -14,30 +16,56 @@ type Reader interface {
 	Close()
 }
 
+/*
+The file and line each line of the source was read from, when
+the source has been preprocessed
+*/
+type LineMap interface {
+	Origin(line uint64) (string, uint64)
+}
+
 type Writer interface {
 	Write([]byte) error
 	Close()
 }
 
 type Assembler struct {
-	Device  device.Device
-	Line    uint64
-	Parser  parser.Parser
-	PC      uint64
-	Reader  Reader
-	Symbols map[string]uint64
-	Writer  Writer
-}
-
-func (a *Assembler) AddSymbol(symbol string, value uint64) error {
-	_, exists := a.Symbols[symbol]
-
-	if exists {
-		return a.error("duplicate label %v", symbol)
-	}
-
-	a.Symbols[symbol] = value
-	return nil
+	Conditions  []Condition
+	Counters    map[string]uint64
+	Defines     []Define
+	Depth       int
+	Device      device.Device
+	Expansion   parser.Line
+	Exports     map[string]Location
+	File        string
+	Forwards    []Forward
+	Line        uint64
+	Lines       LineMap
+	Listing     Listing
+	Locals      map[string]uint64
+	Macros      map[string]*Macro
+	Odd         []byte
+	Outcomes    []bool
+	Parser      parser.Parser
+	Pass        int
+	PC          uint64
+	Pending     []parser.Line
+	Reader      Reader
+	Recording   *Macro
+	Relax       bool
+	Relaxation  Relaxation
+	Relocatable bool
+	Relocations []object.Relocation
+	Scope       string
+	Section     string
+	Sections    []string
+	Segment     language.Segment
+	Symbols     map[string]uint64
+	Syntax      language.Syntax
+	Table       map[string]*Symbol
+	Target      string
+	Uses        map[string][]Location /* Lines naming each symbol, defined yet or not */
+	Writer      Writer
 }
 
 func (a *Assembler) Close() {
@@ -46,19 +74,61 @@ func (a *Assembler) Close() {
 }
 
 func (a *Assembler) HardReset() error {
+	a.Symbols = map[string]uint64{}
+	a.Table = map[string]*Symbol{}
+	a.Device = *device.DefaultDevice()
+	a.Outcomes = []bool{}
+	a.Macros = map[string]*Macro{}
+	a.Exports = map[string]Location{}
+	a.Forwards = []Forward{}
+	a.Uses = map[string][]Location{}
+
 	err := a.SoftReset()
 
 	if err != nil {
 		return err
 	}
 
-	a.Symbols = map[string]uint64{}
-	a.Device = *device.DefaultDevice()
+	// Select the device and seed the symbols given on the command line
+	if a.Target != "" {
+		err := a.SetDevice(a.Target)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, def := range a.Defines {
+		err := a.AddDefine(def)
+
+		if err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+/*
+Get the next line of source, taking lines from any pending
+macro expansion first. Expanded lines keep the line number
+of the invocation.
+*/
 func (a *Assembler) GetNextLine() parser.Line {
+	for len(a.Pending) > 0 {
+		line := a.Pending[0]
+		a.Pending = a.Pending[1:]
+
+		if _, ok := line.(*endExpansion); ok {
+			a.Depth--
+			continue
+		}
+
+		a.Expansion = line
+		return line
+	}
+
+	a.Expansion = nil
 	line := a.Parser.Next()
 	a.Line = line.Number()
 	return line
@@ -66,15 +136,71 @@ func (a *Assembler) GetNextLine() parser.Line {
 
 func (a *Assembler) Run() error {
 	/*
-		PASS 1 - Record lables and directives
+		PASS 1 - Record lables and directives, repeated until the
+		sizes of any relaxed jumps and branches settle
 	*/
 
-	err := a.HardReset()
+	a.Relaxation = NewRelaxation()
+
+	for i := 1; ; i++ {
+		err := a.Size()
+
+		if err != nil {
+			return err
+		}
+
+		if !a.Unsettled() {
+			break
+		}
+
+		if i == MAX_RELAX_PASSES {
+			return fmt.Errorf("sizes of jumps and branches did not settle after %v passes", MAX_RELAX_PASSES)
+		}
+	}
+
+	err := a.CheckMacros()
+
+	if err != nil {
+		return err
+	}
+
+	err = a.CheckConditions()
+
+	if err != nil {
+		return err
+	}
+
+	err = a.CheckExports()
+
+	if err != nil {
+		return err
+	}
+
+	// Check the size of each section before encoding any instructions
+	err = a.SetSection(a.Section)
+
+	if err != nil {
+		return err
+	}
+
+	for _, name := range a.Sections {
+		if err := a.CheckSize(SectionSegment(name), a.Counters[name]); err != nil {
+			return err
+		}
+	}
+
+	/*
+		PASS 2 - Generate code
+	*/
+
+	err = a.SoftReset()
 
 	if err != nil {
 		return err
 	}
 
+	a.Pass = 2
+
 	for {
 		line := a.GetNextLine()
 
@@ -82,38 +208,61 @@ func (a *Assembler) Run() error {
 			break
 		}
 
+		if a.Recording != nil {
+			if err := a.Record(line); err != nil {
+				return a.wrap(err)
+			}
+
+			continue
+		}
+
+		if a.Skip(line) {
+			continue
+		}
+
+		line, err := a.Localise(line)
+
+		if err != nil {
+			return a.wrap(err)
+		}
+
 		switch line := line.(type) {
-		case *parser.Comment:
+		case *parser.Comment, *parser.Label:
 			continue
 
 		case *parser.Directive:
-			dir, err := language.GetDir(line.Mnemonic)
+			err := a.ExecuteDir(line)
 
 			if err != nil {
 				return a.wrap(err)
 			}
 
-			val := EvalDirVal(line.Value, a.Symbols)
-			dir.Execute(a, val)
-
-		case *parser.Label:
-			err := a.AddSymbol(line.Value, a.PC)
+		case *parser.MacroCall:
+			err := a.Expand(line)
 
 			if err != nil {
 				return a.wrap(err)
 			}
 
 		case *parser.Instruction:
-			instr, err := language.GetInstr(line.Mnemonic, &a.Device)
+			if err := a.Flush(); err != nil {
+				return a.wrap(err)
+			}
+
+			lines, err := a.Relaxed(line)
 
 			if err != nil {
 				return a.wrap(err)
 			}
 
-			if instr.IsLong() {
-				a.PC += 2
-			} else {
-				a.PC += 1
+			for _, line := range lines {
+				if err := a.Encode(line); err != nil {
+					return a.wrap(err)
+				}
+			}
+
+			if len(lines) > 1 {
+				a.MarkRelaxed()
 			}
 
 		case *parser.Error:
@@ -124,16 +273,88 @@ func (a *Assembler) Run() error {
 		}
 	}
 
-	/*
-		PASS 2 - Generate code
-	*/
+	return a.Flush()
+}
 
-	err = a.SoftReset()
+/*
+Encode an instruction at the current address and write it out
+*/
+func (a *Assembler) Encode(line *parser.Instruction) error {
+	instr, err := language.GetInstr(line.Mnemonic, &a.Device)
+
+	if err != nil {
+		return err
+	}
+
+	relative := instr.IsRelative()
+
+	op1, err := a.EvalOperand(line.Op1, line, relative)
+
+	if err != nil {
+		return err
+	}
+
+	op2, err := a.EvalOperand(line.Op2, line, relative)
 
 	if err != nil {
 		return err
 	}
 
+	// Check arguments against one another for undefined behaviour
+	if err := op1.Augment(op2); err != nil {
+		return err
+	}
+
+	if err := op2.Augment(op1); err != nil {
+		return err
+	}
+
+	if err := instr.Apply1(op1); err != nil {
+		return err
+	}
+
+	if err := instr.Apply2(op2); err != nil {
+		return err
+	}
+
+	address := a.PC
+
+	if instr.IsLong() {
+		a.PC += 2
+	} else {
+		a.PC += 1
+	}
+
+	// Check every emitted address against the size of flash
+	if err := a.CheckSize(language.CSEG, a.PC); err != nil {
+		return err
+	}
+
+	a.AddInstruction(address, instr)
+	err = a.Writer.Write(instr.Encode())
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+/*
+Record the labels and directives of the source, and the size
+of each instruction
+*/
+func (a *Assembler) Size() error {
+	a.Pass = 1
+	err := a.HardReset()
+
+	if err != nil {
+		return err
+	}
+
+	// Symbols defined before any error keep the lines using them
+	defer a.AttachReferences()
+
 	for {
 		line := a.GetNextLine()
 
@@ -141,52 +362,68 @@ func (a *Assembler) Run() error {
 			break
 		}
 
+		if a.Recording != nil {
+			if err := a.Record(line); err != nil {
+				return a.wrap(err)
+			}
+
+			continue
+		}
+
+		if a.Skip(line) {
+			continue
+		}
+
+		line, err := a.Localise(line)
+
+		if err != nil {
+			return a.wrap(err)
+		}
+
+		a.AddReferences(line)
+
 		switch line := line.(type) {
-		case *parser.Comment, *parser.Directive, *parser.Label:
+		case *parser.Comment:
 			continue
 
-		case *parser.Instruction:
-			instr, err := language.GetInstr(line.Mnemonic, &a.Device)
+		case *parser.Directive:
+			err := a.ExecuteDir(line)
 
 			if err != nil {
 				return a.wrap(err)
 			}
 
-			relative := instr.IsRelative()
-
-			op1 := EvalArg(line.Op1, a.Symbols, relative, a.PC)
-			op2 := EvalArg(line.Op2, a.Symbols, relative, a.PC)
+		case *parser.Label:
+			err := a.AddLabel(line.Value)
 
-			// Check arguments against one another for undefined behaviour
-			if err := op1.Augment(op2); err != nil {
+			if err != nil {
 				return a.wrap(err)
 			}
 
-			if err := op2.Augment(op1); err != nil {
-				return a.wrap(err)
-			}
+		case *parser.MacroCall:
+			err := a.Expand(line)
 
-			if err := instr.Apply1(op1); err != nil {
+			if err != nil {
 				return a.wrap(err)
 			}
 
-			if err := instr.Apply2(op2); err != nil {
+		case *parser.Instruction:
+			if err := a.Flush(); err != nil {
 				return a.wrap(err)
 			}
 
-			if instr.IsLong() {
-				a.PC += 2
-			} else {
-				a.PC += 1
-			}
-
-			bytes := instr.Encode()
-			err = a.Writer.Write(bytes)
+			size, err := a.InstrSize(line)
 
 			if err != nil {
 				return a.wrap(err)
 			}
 
+			if a.Segment != language.CSEG {
+				return a.error("instructions are only allowed in the code segment")
+			}
+
+			a.PC += size
+
 		case *parser.Error:
 			return a.error(line.Value)
 
@@ -195,20 +432,153 @@ func (a *Assembler) Run() error {
 		}
 	}
 
+	if err := a.Flush(); err != nil {
+		return err
+	}
+
+	return a.ResolveForwards()
+}
+
+func (a *Assembler) ExecuteDir(line *parser.Directive) error {
+	if a.IsForward(line) {
+		return a.Postpone(line)
+	}
+
+	if err := a.CheckForward(line); err != nil {
+		return err
+	}
+
+	if err := a.CheckData(line); err != nil {
+		return err
+	}
+
+	dir, err := language.GetDir(line.Mnemonic, a.Syntax)
+
+	if err != nil {
+		return err
+	}
+
+	val := EvalDirVal(line.Value, a.Symbols)
+	return dir.Execute(a, val)
+}
+
+func (a *Assembler) Reserve(size uint64) error {
+	if a.Segment == language.CSEG {
+		return fmt.Errorf("cannot reserve bytes in the code segment")
+	}
+
+	err := a.CheckSize(a.Segment, a.PC+size)
+
+	if err != nil {
+		return err
+	}
+
+	a.AddEntry(a.PC, nil, size)
+	a.PC += size
 	return nil
 }
 
 func (a *Assembler) SetDevice(name string) error {
+	// The device given on the command line takes priority
+	if a.Target != "" && name != a.Target {
+		return fmt.Errorf("device '%v' does not match '%v' given on the command line", name, a.Target)
+	}
+
+	if a.Device.Name == device.DeviceType(name) {
+		return nil
+	}
+
+	if a.Device.Name != device.DEFAULT {
+		return fmt.Errorf("device already set to '%v'", a.Device.Name)
+	}
+
 	dev, err := device.NewDevice(name)
 
 	if err != nil {
 		return err
 	}
 
+	// Move the data segment to the start of the new device's SRAM
+	if !a.Relocatable && a.Counters[".data"] == uint64(a.Device.RAMStart) {
+		a.Counters[".data"] = uint64(dev.RAMStart)
+
+		if a.Segment == language.DSEG {
+			a.PC = uint64(dev.RAMStart)
+		}
+	}
+
 	a.Device = *dev
+
+	// Predefine the device's registers, vectors and memory limits
+	loc := Location{
+		File: DEVICE_FILE,
+	}
+
+	for _, sym := range dev.Symbols() {
+		name := strings.ToLower(sym.Name)
+
+		// Symbols the source has defined already are kept
+		if _, exists := a.Table[name]; exists {
+			continue
+		}
+
+		err := a.declare(name, sym.Value, EQU, loc)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+Continue assembling into the named section, which belongs to
+the segment given by the start of its name. Sections other
+than the default section of each segment are placed by the
+linker, so can only be used when assembling an object. In GNU
+syntax they are instead merged into the default section.
+*/
+func (a *Assembler) SetSection(name string) error {
+	seg := SectionSegment(name)
+
+	if !a.Relocatable && a.Syntax == language.SYNTAX_GNU {
+		name = SectionName(seg)
+	}
+
+	if !a.Relocatable && name != SectionName(seg) {
+		return fmt.Errorf("section %v can only be used when assembling an object", name)
+	}
+
+	if err := a.Flush(); err != nil {
+		return err
+	}
+
+	a.Counters[a.Section] = a.PC
+	a.Section = name
+	a.Segment = seg
+	a.PC = a.Counters[name]
+
+	for _, sec := range a.Sections {
+		if sec == name {
+			return nil
+		}
+	}
+
+	a.Sections = append(a.Sections, name)
 	return nil
 }
 
+func (a *Assembler) SetSegment(seg language.Segment) error {
+	switch seg {
+	case language.CSEG, language.DSEG, language.ESEG:
+		return a.SetSection(SectionName(seg))
+
+	default:
+		return fmt.Errorf("unknown segment %v", seg)
+	}
+}
+
 func (a *Assembler) SoftReset() error {
 	err := a.Reader.Reset()
 
@@ -218,13 +588,32 @@ func (a *Assembler) SoftReset() error {
 
 	a.Parser.Reset()
 	a.Parser.Lexer.Reset()
+	a.Conditions = []Condition{}
+	a.Depth = 0
+	a.Expansion = nil
+	a.Listing = NewListing()
+	a.Locals = map[string]uint64{}
+	a.Odd = nil
+	a.Pending = []parser.Line{}
+	a.Recording = nil
+	a.Relaxation.Index = 0
 	a.PC = 0
+	a.Scope = ""
+	a.Section = SectionName(language.CSEG)
+	a.Sections = []string{a.Section}
+	a.Segment = language.CSEG
+	a.Counters = map[string]uint64{
+		SectionName(language.CSEG): 0,
+		SectionName(language.DSEG): a.DataOrigin(),
+		SectionName(language.ESEG): 0,
+	}
+
 	return nil
 }
 
 func (a *Assembler) error(fstr string, args ...interface{}) error {
 	msg := fmt.Sprintf(fstr, args...)
-	err := fmt.Errorf("%v on line %v", msg, a.Line)
+	err := fmt.Errorf("%v on %v", msg, a.at(a.Location()))
 	return err
 }
 
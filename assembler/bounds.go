@@ -0,0 +1,51 @@
+package assembler
+
+import (
+	"fmt"
+
+	"github.com/silaspace/aria/language"
+)
+
+/*
+Check that a segment ending at the given address fits in the
+memory of the selected device. Addresses in the code segment
+are in words, whereas the data and eeprom segments are in bytes.
+*/
+func (a *Assembler) CheckSize(seg language.Segment, end uint64) error {
+	switch seg {
+	case language.CSEG:
+		limit := uint64(a.Device.FlashSize)
+
+		if end > limit {
+			return fmt.Errorf(
+				"program exceeds flash size of %v bytes by %v bytes",
+				limit*2,
+				(end-limit)*2,
+			)
+		}
+
+	case language.DSEG:
+		limit := a.DataOrigin() + uint64(a.Device.RAMSize)
+
+		if end > limit {
+			return fmt.Errorf(
+				"data segment exceeds sram size of %v bytes by %v bytes",
+				a.Device.RAMSize,
+				end-limit,
+			)
+		}
+
+	case language.ESEG:
+		limit := uint64(a.Device.EEPROMSize)
+
+		if end > limit {
+			return fmt.Errorf(
+				"eeprom segment exceeds eeprom size of %v bytes by %v bytes",
+				limit,
+				end-limit,
+			)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package assembler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/parser"
+)
+
+type Condition struct {
+	Line   uint64
+	Parent bool /* Enclosing block is being assembled */
+	Taken  bool /* A branch of this block has been assembled */
+	Active bool /* The current branch is being assembled */
+}
+
+func (a *Assembler) Assembling() bool {
+	depth := len(a.Conditions)
+	return depth == 0 || a.Conditions[depth-1].Active
+}
+
+func (a *Assembler) Defined(symbol string) bool {
+	_, exists := a.Table[symbol]
+	return exists
+}
+
+func (a *Assembler) If(v language.Value) error {
+	parent := a.Assembling()
+	active := false
+
+	if parent {
+		cond, err := a.evalCondition(v)
+
+		if err != nil {
+			return err
+		}
+
+		active = cond
+	}
+
+	a.Conditions = append(a.Conditions, Condition{
+		Line:   a.Line,
+		Parent: parent,
+		Taken:  active,
+		Active: active,
+	})
+
+	return nil
+}
+
+func (a *Assembler) ElseIf(v language.Value) error {
+	top, err := a.topCondition(language.DIR_ELIF)
+
+	if err != nil {
+		return err
+	}
+
+	if !top.Parent || top.Taken {
+		top.Active = false
+		return nil
+	}
+
+	cond, err := a.evalCondition(v)
+
+	if err != nil {
+		return err
+	}
+
+	top.Active = cond
+	top.Taken = cond
+	return nil
+}
+
+func (a *Assembler) Else() error {
+	top, err := a.topCondition(language.DIR_ELSE)
+
+	if err != nil {
+		return err
+	}
+
+	top.Active = top.Parent && !top.Taken
+	top.Taken = true
+	return nil
+}
+
+func (a *Assembler) EndIf() error {
+	_, err := a.topCondition(language.DIR_ENDIF)
+
+	if err != nil {
+		return err
+	}
+
+	a.Conditions = a.Conditions[:len(a.Conditions)-1]
+	return nil
+}
+
+/*
+Lines inside a conditional block that is not being assembled
+are skipped, apart from the conditional directives themselves
+which are needed to find the end of the block.
+*/
+func (a *Assembler) Skip(line parser.Line) bool {
+	if a.Assembling() {
+		return false
+	}
+
+	if dir, ok := line.(*parser.Directive); ok {
+		return !language.IsConditional(dir.Mnemonic)
+	}
+
+	return true
+}
+
+/*
+Check every conditional block has been closed by the end of
+the source
+*/
+func (a *Assembler) CheckConditions() error {
+	if len(a.Conditions) > 0 {
+		top := a.Conditions[len(a.Conditions)-1]
+		return fmt.Errorf("missing .%v for conditional block on %v", language.DIR_ENDIF, a.at(a.Origin(top.Line)))
+	}
+
+	return nil
+}
+
+/*
+Conditions are evaluated in pass 1 and replayed in pass 2, so
+that both passes assemble the same lines even though pass 2
+already knows every symbol.
+*/
+func (a *Assembler) evalCondition(v language.Value) (bool, error) {
+	if a.Pass != 1 {
+		if len(a.Outcomes) == 0 {
+			return false, errors.New("conditional block differs between passes")
+		}
+
+		cond := a.Outcomes[0]
+		a.Outcomes = a.Outcomes[1:]
+		return cond, nil
+	}
+
+	switch v := v.(type) {
+	case *language.Int:
+		cond := v.Value != 0
+		a.Outcomes = append(a.Outcomes, cond)
+		return cond, nil
+
+	case *language.Error:
+		return false, errors.New(v.Value)
+
+	default:
+		return false, fmt.Errorf("expected condition, got '%v'", v.Fmt())
+	}
+}
+
+func (a *Assembler) topCondition(mn language.Mnemonic) (*Condition, error) {
+	depth := len(a.Conditions)
+
+	if depth == 0 {
+		return nil, fmt.Errorf(".%v without .%v", mn, language.DIR_IF)
+	}
+
+	return &a.Conditions[depth-1], nil
+}
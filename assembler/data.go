@@ -0,0 +1,131 @@
+package assembler
+
+import (
+	"fmt"
+
+	"github.com/silaspace/aria/language"
+)
+
+/*
+Place values of the given size in bytes, least significant
+byte first. Negative values are stored in two's complement.
+*/
+func (a *Assembler) Data(values []uint64, size uint64) error {
+	limit := int64(1) << (size*8 - 1)
+	bytes := []byte{}
+
+	for _, val := range values {
+		signed := int64(val)
+
+		if signed < -limit || (signed >= 0 && val >= uint64(limit)*2) {
+			return fmt.Errorf("value 0x%X does not fit in %v bytes", val, size)
+		}
+
+		for i := uint64(0); i < size; i++ {
+			bytes = append(bytes, byte(val>>(8*i)))
+		}
+	}
+
+	return a.Emit(bytes)
+}
+
+/*
+Place bytes at the current address. Program memory is filled a
+word at a time, so an odd byte at the end is held back until
+more bytes follow or it is flushed.
+*/
+func (a *Assembler) Emit(bytes []byte) error {
+	if len(bytes) == 0 {
+		return nil
+	}
+
+	if a.Segment != language.CSEG {
+		err := a.CheckSize(a.Segment, a.PC+uint64(len(bytes)))
+
+		if err != nil {
+			return err
+		}
+
+		a.AddEntry(a.PC, bytes, uint64(len(bytes)))
+		a.PC += uint64(len(bytes))
+		return nil
+	}
+
+	words := append(append([]byte{}, a.Odd...), bytes...)
+	a.Odd = nil
+
+	if len(words)%2 != 0 {
+		a.Odd = words[len(words)-1:]
+		words = words[:len(words)-1]
+	}
+
+	if len(words) == 0 {
+		return nil
+	}
+
+	address := a.PC
+	a.PC += uint64(len(words)) / 2
+
+	if err := a.CheckSize(language.CSEG, a.PC); err != nil {
+		return err
+	}
+
+	a.AddEntry(address, words, 0)
+
+	if a.Pass == 2 {
+		return a.Writer.Write(words)
+	}
+
+	return nil
+}
+
+/*
+Pad a byte held back in program memory to a whole word, before
+an instruction or when leaving the section
+*/
+func (a *Assembler) Flush() error {
+	if len(a.Odd) == 0 {
+		return nil
+	}
+
+	return a.Emit([]byte{0})
+}
+
+/*
+Pad with zero bytes up to a multiple of the given number of
+bytes
+*/
+func (a *Assembler) Align(n uint64) error {
+	if n == 0 || n&(n-1) != 0 {
+		return fmt.Errorf("alignment of %v bytes is not a power of two", n)
+	}
+
+	address := a.PC
+
+	if a.Segment == language.CSEG {
+		address = a.PC*2 + uint64(len(a.Odd))
+	}
+
+	return a.Fill((n - address%n) % n)
+}
+
+/*
+Place the given number of zero bytes in program memory, or
+reserve them in data memory and eeprom
+*/
+func (a *Assembler) Fill(size uint64) error {
+	if size == 0 {
+		return nil
+	}
+
+	if a.Segment != language.CSEG {
+		return a.Reserve(size)
+	}
+
+	// Check the size before making the padding
+	if err := a.CheckSize(language.CSEG, a.PC+size/2); err != nil {
+		return err
+	}
+
+	return a.Emit(make([]byte, size))
+}
@@ -0,0 +1,62 @@
+package assembler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/lexer"
+	"github.com/silaspace/aria/parser"
+)
+
+/*
+A symbol defined outside of the source, such as on the
+command line with -D F_CPU=16000000. A definition without
+a value is given the value 1.
+*/
+type Define struct {
+	Name  string
+	Value string
+}
+
+func (a *Assembler) AddDefine(def Define) error {
+	value := def.Value
+
+	if value == "" {
+		value = "1"
+	}
+
+	val, err := a.Eval(value)
+
+	if err != nil {
+		return fmt.Errorf("%v in definition of %v", err, def.Name)
+	}
+
+	loc := Location{
+		File: COMMAND_LINE,
+	}
+
+	return a.declare(strings.ToLower(def.Name), val, EQU, loc)
+}
+
+/*
+Evaluate an expression given as text, with the symbols defined
+so far
+*/
+func (a *Assembler) Eval(text string) (uint64, error) {
+	reader := handler.NewWebReader()
+	reader.Write([]byte(text))
+
+	l := lexer.NewLexer(reader)
+	l.Syntax = a.Syntax
+
+	p := parser.NewParser(l)
+	p.GetNextToken()
+	expr := parser.ParseExpr(p, 0)
+
+	if token := p.GetCurrentToken(); !token.IsEOF() {
+		return 0, fmt.Errorf("unexpected token %v", token.Print())
+	}
+
+	return EvalExpr(expr, a.Symbols, false, 0)
+}
@@ -1,8 +1,10 @@
 package assembler
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/silaspace/aria/language"
 	"github.com/silaspace/aria/parser"
@@ -16,6 +18,11 @@ func EvalArg(arg parser.Arg, symbolTable map[string]uint64, relativeInstr bool,
 	case *parser.ArgReg:
 		return EvalReg(arg.Value)
 
+	case *parser.ArgError:
+		return &language.Error{
+			Value: arg.Value,
+		}
+
 	case *parser.ArgExpr:
 		val, err := EvalExpr(arg.Value, symbolTable, relativeInstr, pc)
 
@@ -73,9 +80,7 @@ func EvalDirVal(dirval parser.DirVal, symbolTable map[string]uint64) language.Va
 		}
 
 	case *parser.ExprListDirVal:
-		return &language.Error{
-			Value: "List of expressions in a directive is not implemeneted",
-		}
+		return EvalExprList(dirval.Value, symbolTable)
 
 	case *parser.AssignDirVal:
 		val, err := EvalExpr(dirval.Value, symbolTable, false, 0)
@@ -91,6 +96,20 @@ func EvalDirVal(dirval parser.DirVal, symbolTable map[string]uint64) language.Va
 			Value:  val,
 		}
 
+	case *parser.DefDirVal:
+		reg, ok := EvalReg(dirval.Value).(*language.Reg)
+
+		if !ok {
+			return &language.Error{
+				Value: fmt.Sprintf("'%v' is not a register", dirval.Value.Fmt()),
+			}
+		}
+
+		return &language.Assignment{
+			Symbol: dirval.Symbol,
+			Value:  reg.Value,
+		}
+
 	case *parser.ErrorDirVal:
 		return &language.Error{
 			Value: dirval.Value,
@@ -103,11 +122,52 @@ func EvalDirVal(dirval parser.DirVal, symbolTable map[string]uint64) language.Va
 	}
 }
 
+/*
+Evaluate a list of expressions, giving text if every one is a
+string, as in .ascii, or otherwise a list of values
+*/
+func EvalExprList(exprs []parser.Expr, symbolTable map[string]uint64) language.Value {
+	strs := []string{}
+
+	for _, expr := range exprs {
+		if str, ok := expr.(*parser.String); ok {
+			strs = append(strs, str.Value)
+		}
+	}
+
+	if len(strs) == len(exprs) {
+		return &language.Text{
+			Value: strs,
+		}
+	}
+
+	values := []uint64{}
+
+	for _, expr := range exprs {
+		val, err := EvalExpr(expr, symbolTable, false, 0)
+
+		if err != nil {
+			return &language.Error{
+				Value: err.Error(),
+			}
+		}
+
+		values = append(values, val)
+	}
+
+	return &language.List{
+		Value: values,
+	}
+}
+
 func EvalExpr(expr parser.Expr, symbolTable map[string]uint64, relativeInstr bool, pc uint64) (uint64, error) {
 	switch expr := expr.(type) {
 	case *parser.Ident:
-		// Return the value of pc if used in an expression
-		if expr.Value == string(language.PC) {
+		// Return the value of pc if used in an expression, which is
+		// relative to the next instruction in jumps and branches
+		if expr.Value == string(language.PC) && relativeInstr {
+			return pc - pc - 1, nil
+		} else if expr.Value == string(language.PC) {
 			return pc, nil
 		}
 
@@ -122,6 +182,10 @@ func EvalExpr(expr parser.Expr, symbolTable map[string]uint64, relativeInstr boo
 		}
 
 	case *parser.Literal:
+		if strings.Contains(expr.Value, ".") {
+			return 0, fmt.Errorf("fractional number %v must be converted with int, frac, q7 or q15", expr.Value)
+		}
+
 		val, err := strconv.ParseUint(expr.Value, expr.Base, 64)
 
 		if err != nil {
@@ -137,6 +201,11 @@ func EvalExpr(expr parser.Expr, symbolTable map[string]uint64, relativeInstr boo
 			return 0, err
 		}
 
+		// Negation subtracts from zero
+		if expr.Symbol == string(language.OP_SUB) {
+			return expr.Op.Apply(0, e1), nil
+		}
+
 		return expr.Op.Apply(e1, 0), nil
 
 	case *parser.BinopExpr:
@@ -146,7 +215,7 @@ func EvalExpr(expr parser.Expr, symbolTable map[string]uint64, relativeInstr boo
 			return 0, err
 		}
 
-		e2, err := EvalExpr(expr.E1, symbolTable, relativeInstr, pc)
+		e2, err := EvalExpr(expr.E2, symbolTable, relativeInstr, pc)
 
 		if err != nil {
 			return 0, err
@@ -155,17 +224,147 @@ func EvalExpr(expr parser.Expr, symbolTable map[string]uint64, relativeInstr boo
 		return expr.Op.Apply(e1, e2), nil
 
 	case *parser.FuncExpr:
-		e1, err := EvalExpr(expr.E1, symbolTable, relativeInstr, pc)
+		return EvalFunc(expr, symbolTable, relativeInstr, pc)
+
+	case *parser.String:
+		return 0, fmt.Errorf("string %v cannot be used as a number", expr.Fmt())
+
+	case *parser.ErrorExpr:
+		return 0, errors.New(expr.Value)
+
+	default:
+		return 0, fmt.Errorf("unkown expr type")
+	}
+}
+
+func EvalFunc(expr *parser.FuncExpr, symbolTable map[string]uint64, relativeInstr bool, pc uint64) (uint64, error) {
+	// Fractional results are truncated
+	if expr.Func.Real != nil {
+		val, err := EvalReal(expr, symbolTable, pc)
+		return uint64(int64(val)), err
+	}
+
+	args := []uint64{}
+
+	for i, arg := range expr.Args {
+		switch expr.Func.Params[i] {
+		case language.PARAM_SYMBOL:
+			ident, ok := arg.(*parser.Ident)
+
+			if !ok {
+				return 0, fmt.Errorf("%v expects a symbol, got '%v'", expr.Symbol, arg.Fmt())
+			}
+
+			_, exists := symbolTable[ident.Value]
+			args = append(args, boolToInt(exists))
+
+		case language.PARAM_STRING:
+			str, ok := arg.(*parser.String)
+
+			if !ok {
+				return 0, fmt.Errorf("%v expects a string, got '%v'", expr.Symbol, arg.Fmt())
+			}
+
+			args = append(args, uint64(len(str.Value)))
+
+		default:
+			val, err := EvalExpr(arg, symbolTable, relativeInstr, pc)
+
+			if err != nil {
+				return 0, err
+			}
+
+			args = append(args, val)
+		}
+	}
+
+	return expr.Func.Apply(args)
+}
+
+/*
+Evaluate the argument of a function of fractional numbers.
+Integers are signed, and arithmetic keeps the fractional part,
+so q7(1/3) is a third.
+*/
+func EvalReal(expr parser.Expr, symbolTable map[string]uint64, pc uint64) (float64, error) {
+	switch expr := expr.(type) {
+	case *parser.Literal:
+		if expr.Base != 10 {
+			break
+		}
+
+		return strconv.ParseFloat(expr.Value, 64)
+
+	case *parser.MonopExpr:
+		if expr.Symbol != string(language.OP_SUB) {
+			break
+		}
+
+		e1, err := EvalReal(expr.E1, symbolTable, pc)
+		return -e1, err
+
+	case *parser.BinopExpr:
+		e1, err := EvalReal(expr.E1, symbolTable, pc)
 
 		if err != nil {
 			return 0, err
 		}
 
-		return expr.Func.Apply(e1), nil
+		e2, err := EvalReal(expr.E2, symbolTable, pc)
 
-	default:
-		return 0, fmt.Errorf("unkown expr type")
+		if err != nil {
+			return 0, err
+		}
+
+		switch language.Mnemonic(expr.Symbol) {
+		case language.OP_ADD:
+			return e1 + e2, nil
+
+		case language.OP_SUB:
+			return e1 - e2, nil
+
+		case language.OP_MUL:
+			return e1 * e2, nil
+
+		case language.OP_DIV:
+			if e2 == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+
+			return e1 / e2, nil
+		}
+
+	case *parser.FuncExpr:
+		if expr.Func.Real == nil {
+			break
+		}
+
+		args := []float64{}
+
+		for _, arg := range expr.Args {
+			val, err := EvalReal(arg, symbolTable, pc)
+
+			if err != nil {
+				return 0, err
+			}
+
+			args = append(args, val)
+		}
+
+		return expr.Func.Real(args)
+	}
+
+	// Anything else is an integer
+	val, err := EvalExpr(expr, symbolTable, false, pc)
+	return float64(int64(val)), err
+}
+
+func boolToInt(b bool) uint64 {
+	if b {
+		return 1
 	}
+
+	return 0
 }
 
 func EvalReg(reg parser.Reg) language.Value {
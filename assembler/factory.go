@@ -17,6 +17,7 @@ func NewAssembler(reader Reader, writer Writer) *Assembler {
 		PC:      0,
 		Reader:  reader,
 		Symbols: map[string]uint64{},
+		Table:   map[string]*Symbol{},
 		Writer:  writer,
 	}
 }
@@ -0,0 +1,154 @@
+package assembler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/parser"
+)
+
+/*
+A constant from .equ whose value depends on symbols defined
+later in the source, evaluated at the end of pass 1
+*/
+type Forward struct {
+	Defined Location
+	Symbol  string
+	Value   parser.Expr
+}
+
+/*
+Whether a directive is an .equ referring to a symbol that has
+not been given a value yet
+*/
+func (a *Assembler) IsForward(line *parser.Directive) bool {
+	if a.Pass != 1 || line.Mnemonic != string(language.DIR_EQU) {
+		return false
+	}
+
+	assign, ok := line.Value.(*parser.AssignDirVal)
+
+	if !ok {
+		return false
+	}
+
+	for _, name := range exprIdentifiers(assign.Value) {
+		if _, exists := a.Symbols[name]; !exists {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Fail when a directive in pass 1 needs the value of a constant
+that is only known once the source has been read, such as the
+size of a reservation, which the later symbols depend on
+*/
+func (a *Assembler) CheckForward(line *parser.Directive) error {
+	if a.Pass != 1 {
+		return nil
+	}
+
+	for _, name := range Identifiers(line) {
+		for _, fwd := range a.Forwards {
+			if fwd.Symbol == name {
+				return fmt.Errorf("%v depends on symbols defined after it and cannot be used here", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+Declare a constant now, so it cannot be defined twice, but
+leave it without a value until every symbol is known
+*/
+func (a *Assembler) Postpone(line *parser.Directive) error {
+	assign := line.Value.(*parser.AssignDirVal)
+	err := a.declare(assign.Symbol, 0, EQU, a.Location())
+
+	if err != nil {
+		return err
+	}
+
+	delete(a.Symbols, assign.Symbol)
+
+	a.Forwards = append(a.Forwards, Forward{
+		Defined: a.Location(),
+		Symbol:  assign.Symbol,
+		Value:   assign.Value,
+	})
+
+	return nil
+}
+
+/*
+Evaluate the postponed constants, each after the constants it
+depends on, failing if any depend on themselves
+*/
+func (a *Assembler) ResolveForwards() error {
+	pending := map[string]*Forward{}
+
+	for i := range a.Forwards {
+		pending[a.Forwards[i].Symbol] = &a.Forwards[i]
+	}
+
+	resolved := map[string]bool{}
+	path := []string{}
+
+	var resolve func(fwd *Forward) error
+
+	resolve = func(fwd *Forward) error {
+		if resolved[fwd.Symbol] {
+			return nil
+		}
+
+		for i, name := range path {
+			if name == fwd.Symbol {
+				cycle := append(path[i:], fwd.Symbol)
+
+				return fmt.Errorf(
+					"circular definition of %v (%v) on %v",
+					fwd.Symbol,
+					strings.Join(cycle, " -> "),
+					a.at(fwd.Defined),
+				)
+			}
+		}
+
+		path = append(path, fwd.Symbol)
+
+		for _, name := range exprIdentifiers(fwd.Value) {
+			if dep, exists := pending[name]; exists {
+				if err := resolve(dep); err != nil {
+					return err
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+
+		val, err := EvalExpr(fwd.Value, a.Symbols, false, 0)
+
+		if err != nil {
+			return fmt.Errorf("%v on %v", err, a.at(fwd.Defined))
+		}
+
+		a.Symbols[fwd.Symbol] = val
+		a.Table[fwd.Symbol].Value = val
+		resolved[fwd.Symbol] = true
+		return nil
+	}
+
+	for i := range a.Forwards {
+		if err := resolve(&a.Forwards[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package assembler
+
+import (
+	"fmt"
+
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/object"
+	"github.com/silaspace/aria/parser"
+)
+
+/*
+Functions of GNU syntax selecting a byte of an address, and
+the function of AVRASM2 each is relocated as. Those of pm give
+a byte of the word address of a location in program memory.
+*/
+var GnuParts = map[language.Mnemonic]language.Mnemonic{
+	language.FUNC_LO8:    language.FUNC_LOW,
+	language.FUNC_HI8:    language.FUNC_HIGH,
+	language.FUNC_HH8:    language.FUNC_BYTE3,
+	language.FUNC_HLO8:   language.FUNC_BYTE3,
+	language.FUNC_PM_LO8: language.FUNC_LOW,
+	language.FUNC_PM_HI8: language.FUNC_HIGH,
+	language.FUNC_PM_HH8: language.FUNC_BYTE3,
+}
+
+/*
+Read the source in the given dialect
+*/
+func (a *Assembler) SetSyntax(syntax language.Syntax) {
+	a.Syntax = syntax
+	a.Parser.Syntax = syntax
+	a.Parser.Lexer.Syntax = syntax
+}
+
+/*
+Value of a new label. In GNU syntax locations in program memory
+are byte addresses, so labels in the code segment are given in
+bytes rather than in words.
+*/
+func (a *Assembler) LabelValue() uint64 {
+	if a.Syntax == language.SYNTAX_GNU && a.Segment == language.CSEG {
+		return a.PC*2 + uint64(len(a.Odd))
+	}
+
+	return a.PC
+}
+
+/*
+Address of a label in the address space of the GNU tools
+*/
+func (a *Assembler) LabelAddress(sym *Symbol) uint64 {
+	if a.Syntax == language.SYNTAX_GNU && sym.Segment == language.CSEG {
+		return a.SectionAddress(sym.Segment, 0) + sym.Value
+	}
+
+	return a.SectionAddress(sym.Segment, sym.Value)
+}
+
+/*
+Evaluate the target of a jump, branch or call, as a word
+address or as an offset from the following instruction if
+relative. Targets are byte addresses in GNU syntax, so must be
+converted to words.
+*/
+func (a *Assembler) EvalTarget(expr parser.Expr, symbols map[string]uint64, relative bool) (uint64, error) {
+	if a.Syntax != language.SYNTAX_GNU {
+		return EvalExpr(expr, symbols, relative, a.PC)
+	}
+
+	address, err := EvalExpr(expr, symbols, false, a.PC)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if address%2 != 0 {
+		return 0, fmt.Errorf("target 0x%X is not word aligned", address)
+	}
+
+	if relative {
+		return address/2 - a.PC - 1, nil
+	}
+
+	return address / 2, nil
+}
+
+/*
+Whether an operand of an instruction is the location in
+program memory it jumps to, branches to or calls
+*/
+func isTarget(line *parser.Instruction, arg parser.Arg) bool {
+	switch language.GetRelocation(line.Mnemonic) {
+	case language.RELOC_BRANCH, language.RELOC_JUMP, language.RELOC_CALL:
+		return arg == target(line)
+
+	default:
+		return false
+	}
+}
+
+/*
+Convert the addend of a reference in GNU syntax to aria's
+units. Byte addresses in program memory become word addresses
+when jumped to or taken with pm, while the byte address of a
+label in program memory is not known to the linker.
+*/
+func (a *Assembler) gnuReference(ref *Reference, typ object.RelocType) error {
+	switch {
+	case ref.Program || typ == object.R_AVR_7_PCREL || typ == object.R_AVR_13_PCREL || typ == object.R_AVR_CALL:
+		if ref.Addend%2 != 0 {
+			return fmt.Errorf("offset %v from %v is not word aligned", ref.Addend, ref.Symbol)
+		}
+
+		ref.Addend /= 2
+		return nil
+
+	case a.inProgram(ref.Symbol):
+		return fmt.Errorf("byte address of %v in program memory cannot be relocated, use pm_lo8 or pm_hi8", ref.Symbol)
+
+	default:
+		return nil
+	}
+}
+
+/*
+Give the location counter, written as a dot in GNU syntax, its
+value on the line. Within an instruction it is the address of
+the following instruction, as in rjmp .-2 which loops forever.
+Elsewhere it is the current address, in bytes in every segment.
+*/
+func (a *Assembler) Here(line parser.Line) parser.Line {
+	switch line := line.(type) {
+	case *parser.Instruction:
+		address := a.PC
+
+		if len(a.Odd) > 0 {
+			address++
+		}
+
+		if instr, err := language.GetInstr(line.Mnemonic, &a.Device); err == nil && instr.IsLong() {
+			address += 2
+		} else {
+			address += 1
+		}
+
+		return &parser.Instruction{
+			Mnemonic: line.Mnemonic,
+			Op1:      hereArg(line.Op1, address*2),
+			Op2:      hereArg(line.Op2, address*2),
+			Line:     line.Line,
+		}
+
+	case *parser.Directive:
+		return &parser.Directive{
+			Mnemonic: line.Mnemonic,
+			Value:    hereDirVal(line.Value, a.LabelValue()),
+			Line:     line.Line,
+		}
+
+	default:
+		return line
+	}
+}
+
+func hereArg(arg parser.Arg, here uint64) parser.Arg {
+	switch arg := arg.(type) {
+	case *parser.ArgExpr:
+		return &parser.ArgExpr{
+			Value: hereExpr(arg.Value, here),
+		}
+
+	default:
+		return arg
+	}
+}
+
+func hereDirVal(dirval parser.DirVal, here uint64) parser.DirVal {
+	switch dirval := dirval.(type) {
+	case *parser.ExprDirVal:
+		return &parser.ExprDirVal{
+			Value: hereExpr(dirval.Value, here),
+		}
+
+	case *parser.AssignDirVal:
+		return &parser.AssignDirVal{
+			Symbol: dirval.Symbol,
+			Value:  hereExpr(dirval.Value, here),
+		}
+
+	case *parser.ExprListDirVal:
+		exprs := []parser.Expr{}
+
+		for _, expr := range dirval.Value {
+			exprs = append(exprs, hereExpr(expr, here))
+		}
+
+		return &parser.ExprListDirVal{
+			Value: exprs,
+		}
+
+	default:
+		return dirval
+	}
+}
+
+func hereExpr(expr parser.Expr, here uint64) parser.Expr {
+	switch expr := expr.(type) {
+	case *parser.Ident:
+		if expr.Value != "." {
+			return expr
+		}
+
+		return &parser.Literal{
+			Base:  10,
+			Value: fmt.Sprint(here),
+		}
+
+	case *parser.MonopExpr:
+		return &parser.MonopExpr{
+			E1:     hereExpr(expr.E1, here),
+			Symbol: expr.Symbol,
+			Op:     expr.Op,
+		}
+
+	case *parser.BinopExpr:
+		return &parser.BinopExpr{
+			E1:     hereExpr(expr.E1, here),
+			E2:     hereExpr(expr.E2, here),
+			Symbol: expr.Symbol,
+			Op:     expr.Op,
+		}
+
+	case *parser.FuncExpr:
+		args := []parser.Expr{}
+
+		for _, arg := range expr.Args {
+			args = append(args, hereExpr(arg, here))
+		}
+
+		return &parser.FuncExpr{
+			Args:   args,
+			Symbol: expr.Symbol,
+			Func:   expr.Func,
+		}
+
+	default:
+		return expr
+	}
+}
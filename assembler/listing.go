@@ -0,0 +1,477 @@
+package assembler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/silaspace/aria/language"
+)
+
+const (
+	LIST_WORDS  int = 3
+	LIST_CYCLES int = 7 /* Width of the cycles column */
+)
+
+type Listing struct {
+	Enabled bool
+	Entries map[uint64][]Entry
+	Macros  bool
+	Toggles map[uint64]bool
+}
+
+/*
+Bytes emitted or reserved by a single line of pass 2. Lines
+expanded from a macro are recorded against the line of the
+invocation, with Source giving the line in the macro body.
+Relaxed marks a branch inverted to skip over the jump after it.
+*/
+type Entry struct {
+	Address      uint64
+	Bytes        []byte
+	Expanded     bool
+	File         string
+	Instructions []language.Instruction
+	Relaxed      bool
+	Section      string
+	Segment      language.Segment
+	Size         uint64
+	Source       uint64
+}
+
+type Usage struct {
+	Begin uint64
+	End   uint64
+	Code  uint64
+	Data  uint64
+}
+
+func NewListing() Listing {
+	return Listing{
+		Enabled: true,
+		Entries: map[uint64][]Entry{},
+		Macros:  false,
+		Toggles: map[uint64]bool{},
+	}
+}
+
+func (a *Assembler) SetList(on bool) error {
+	a.Listing.Enabled = on
+
+	if a.Pass == 2 && a.Expansion == nil {
+		a.Listing.Toggles[a.Line] = on
+	}
+
+	return nil
+}
+
+func (a *Assembler) SetListMacros(on bool) error {
+	a.Listing.Macros = on
+	return nil
+}
+
+/*
+Record the bytes emitted, or the number of bytes reserved,
+by the current line
+*/
+func (a *Assembler) AddEntry(address uint64, bytes []byte, size uint64) {
+	if a.Pass != 2 {
+		return
+	}
+
+	entry := Entry{
+		Address: address,
+		Bytes:   bytes,
+		File:    a.File,
+		Section: a.Section,
+		Segment: a.Segment,
+		Size:    size,
+		Source:  a.Line,
+	}
+
+	if a.Expansion != nil {
+		entry.Expanded = a.Listing.Macros
+		entry.Source = a.Expansion.Number()
+	}
+
+	a.Listing.Entries[a.Line] = append(a.Listing.Entries[a.Line], entry)
+}
+
+/*
+Record an instruction encoded by the current line, whose
+cycles are listed beside it
+*/
+func (a *Assembler) AddInstruction(address uint64, instr language.Instruction) {
+	a.AddEntry(address, instr.Encode(), 0)
+
+	if a.Pass != 2 {
+		return
+	}
+
+	entries := a.Listing.Entries[a.Line]
+	entries[len(entries)-1].Instructions = []language.Instruction{instr}
+}
+
+/*
+Mark the entry before the last of the current line as a branch
+relaxed to skip over the jump encoded after it
+*/
+func (a *Assembler) MarkRelaxed() {
+	if a.Pass != 2 {
+		return
+	}
+
+	entries := a.Listing.Entries[a.Line]
+
+	if len(entries) > 1 {
+		entries[len(entries)-2].Relaxed = true
+	}
+}
+
+/*
+Bytes of code and data in each segment, and the range of
+addresses they occupy
+*/
+func (a *Assembler) Usage() map[language.Segment]*Usage {
+	usage := map[language.Segment]*Usage{}
+
+	for _, seg := range []language.Segment{language.CSEG, language.DSEG, language.ESEG} {
+		start := uint64(0)
+
+		if seg == language.DSEG {
+			start = a.DataOrigin()
+		}
+
+		usage[seg] = &Usage{
+			Begin: start,
+			End:   start,
+		}
+	}
+
+	first := map[language.Segment]bool{}
+
+	for _, entries := range a.Listing.Entries {
+		for _, entry := range entries {
+			usage[entry.Segment].add(entry, !first[entry.Segment])
+			first[entry.Segment] = true
+		}
+	}
+
+	return usage
+}
+
+/*
+Usage of each section, with the sections of a relocatable
+object each starting from zero
+*/
+func (a *Assembler) SectionUsage() map[string]*Usage {
+	usage := map[string]*Usage{}
+
+	for _, name := range a.Sections {
+		start := uint64(0)
+
+		if name == SectionName(language.DSEG) {
+			start = a.DataOrigin()
+		}
+
+		usage[name] = &Usage{
+			Begin: start,
+			End:   start,
+		}
+	}
+
+	first := map[string]bool{}
+
+	for _, entries := range a.Listing.Entries {
+		for _, entry := range entries {
+			usage[entry.Section].add(entry, !first[entry.Section])
+			first[entry.Section] = true
+		}
+	}
+
+	return usage
+}
+
+func (u *Usage) add(entry Entry, first bool) {
+	end := entry.Address + entry.Size
+
+	if entry.Segment == language.CSEG {
+		u.Code += uint64(len(entry.Bytes))
+		end = entry.Address + uint64(len(entry.Bytes))/2
+	} else {
+		u.Data += entry.Size
+	}
+
+	if first || entry.Address < u.Begin {
+		u.Begin = entry.Address
+	}
+
+	if first || end > u.End {
+		u.End = end
+	}
+}
+
+/*
+Size of each segment of the device in bytes
+*/
+func (a *Assembler) SegmentSize(seg language.Segment) uint64 {
+	switch seg {
+	case language.CSEG:
+		return uint64(a.Device.FlashSize) * 2
+	case language.DSEG:
+		return uint64(a.Device.RAMSize)
+	default:
+		return uint64(a.Device.EEPROMSize)
+	}
+}
+
+/*
+Write an AVRASM2 style listing of the source assembled in
+pass 2, followed by the symbol table and segment usage
+*/
+func (a *Assembler) WriteListing(w io.Writer, name string, source []string) error {
+	fmt.Fprintf(w, "aria listing of %v\n", name)
+	fmt.Fprintf(w, "device: %v\n\n", a.Device.Name)
+
+	listed := true
+
+	for i, text := range source {
+		line := uint64(i + 1)
+
+		if on, ok := a.Listing.Toggles[line]; ok {
+			listed = on
+		}
+
+		if !listed {
+			continue
+		}
+
+		entries := a.Listing.Entries[line]
+
+		// Code from macros without .listmac belongs to the invocation
+		folded := []Entry{}
+		expanded := []Entry{}
+
+		for _, entry := range entries {
+			if entry.Expanded {
+				expanded = append(expanded, entry)
+			} else {
+				folded = append(folded, entry)
+			}
+		}
+
+		writeEntry(w, merge(folded), LineCycles(folded), " ", text)
+		indent := indentation(text)
+
+		for _, entry := range expanded {
+			body := ""
+
+			if entry.Source > 0 && entry.Source <= uint64(len(source)) {
+				body = strings.TrimSpace(source[entry.Source-1])
+			}
+
+			writeEntry(w, []Entry{entry}, LineCycles([]Entry{entry}), "+", indent+body)
+		}
+	}
+
+	a.writeSymbols(w)
+	a.writeUsage(w)
+	return nil
+}
+
+/*
+Spaces as wide as the indentation of a line, with tabs stopping
+every 8 columns, so that lines expanded from a macro start in
+the same column as the invocation
+*/
+func indentation(text string) string {
+	width := 0
+
+	for _, c := range text {
+		switch c {
+		case ' ':
+			width++
+
+		case '\t':
+			width += 8 - width%8
+
+		default:
+			return strings.Repeat(" ", width)
+		}
+	}
+
+	return strings.Repeat(" ", width)
+}
+
+/*
+Join the entries of a line into one, when they are contiguous
+*/
+func merge(entries []Entry) []Entry {
+	if len(entries) < 2 {
+		return entries
+	}
+
+	merged := []Entry{entries[0]}
+
+	for _, entry := range entries[1:] {
+		last := &merged[len(merged)-1]
+
+		if entry.Segment == language.CSEG && last.Segment == language.CSEG &&
+			last.Address+uint64(len(last.Bytes))/2 == entry.Address {
+			last.Bytes = append(append([]byte{}, last.Bytes...), entry.Bytes...)
+			last.Instructions = append(append([]language.Instruction{}, last.Instructions...), entry.Instructions...)
+			continue
+		}
+
+		merged = append(merged, entry)
+	}
+
+	return merged
+}
+
+func writeEntry(w io.Writer, entries []Entry, cycles string, marker string, text string) {
+	blank := strings.Repeat(" ", 9+5*LIST_WORDS+LIST_CYCLES)
+
+	if len(entries) == 0 {
+		fmt.Fprintf(w, "%v%v%v\n", blank, marker, text)
+		return
+	}
+
+	for n, entry := range entries {
+		words := Words(entry.Bytes)
+
+		// Addresses outside the code segment are in bytes
+		step := uint64(LIST_WORDS)
+
+		if entry.Segment != language.CSEG {
+			step *= 2
+		}
+
+		// The source text follows the first row only
+		for row := 0; row == 0 || row*LIST_WORDS < len(words); row++ {
+			end := min((row+1)*LIST_WORDS, len(words))
+			cols := ""
+
+			if row*LIST_WORDS < len(words) {
+				cols = strings.Join(words[row*LIST_WORDS:end], " ")
+			}
+
+			rowAddress := fmt.Sprintf("%v:%06X ", segmentLetter(entry.Segment), entry.Address+uint64(row)*step)
+
+			if row == 0 && n == 0 {
+				fmt.Fprintf(w, "%v%-*v%-*v%v%v\n", rowAddress, 5*LIST_WORDS, cols, LIST_CYCLES, cycles, marker, text)
+			} else {
+				fmt.Fprintf(w, "%v%v\n", rowAddress, cols)
+			}
+		}
+	}
+}
+
+/*
+Bytes as the little endian words of a listing, with any byte
+left over on its own
+*/
+func Words(bytes []byte) []string {
+	words := []string{}
+
+	for i := 0; i+1 < len(bytes); i += 2 {
+		words = append(words, fmt.Sprintf("%04X", binary.LittleEndian.Uint16(bytes[i:])))
+	}
+
+	// Data may end with a single byte
+	if len(bytes)%2 != 0 {
+		words = append(words, fmt.Sprintf("%02X", bytes[len(bytes)-1]))
+	}
+
+	return words
+}
+
+/*
+Cycles taken by the instructions of a line, or the fewest and
+most they can take when there are several. A relaxed branch is
+timed as the branch it replaces, taking one path or the other.
+*/
+func LineCycles(entries []Entry) string {
+	least, most := uint64(0), uint64(0)
+	count, only := 0, ""
+
+	for i := 0; i < len(entries); i++ {
+		instrs := entries[i].Instructions
+
+		if entries[i].Relaxed && len(instrs) == 1 && i+1 < len(entries) && len(entries[i+1].Instructions) == 1 {
+			// Taking the inverted branch skips the jump, so the original is not taken
+			skip := instrs[0].Cycles.Taken
+			jump := instrs[0].Cycles.Cycles + entries[i+1].Instructions[0].MinCycles()
+
+			least += min(skip, jump)
+			most += max(skip, jump)
+			count, only = count+1, fmt.Sprintf("%v/%v", skip, jump)
+			i++
+			continue
+		}
+
+		for _, instr := range instrs {
+			least += instr.MinCycles()
+			most += instr.MaxCycles()
+			count, only = count+1, instr.FmtCycles()
+		}
+	}
+
+	switch {
+	case count == 0:
+		return ""
+
+	case count == 1:
+		return only
+
+	case least == most:
+		return fmt.Sprint(least)
+
+	default:
+		return fmt.Sprintf("%v-%v", least, most)
+	}
+}
+
+func segmentLetter(seg language.Segment) string {
+	switch seg {
+	case language.CSEG:
+		return "C"
+	case language.DSEG:
+		return "D"
+	default:
+		return "E"
+	}
+}
+
+func (a *Assembler) writeSymbols(w io.Writer) {
+	fmt.Fprintf(w, "\nSymbol table\n")
+
+	for _, sym := range a.SortedSymbols() {
+		if sym.IsPredefined() || sym.IsNumeric() || sym.Kind == MACRO {
+			continue
+		}
+
+		fmt.Fprintf(w, "  %-24v %-6v %v\n", sym.Name, sym.Kind, sym.FmtValue())
+	}
+}
+
+func (a *Assembler) writeUsage(w io.Writer) {
+	usage := a.Usage()
+
+	fmt.Fprintf(w, "\nSegment   Begin    End        Code   Data   Used    Size   Use%%\n")
+	fmt.Fprintf(w, "%v\n", strings.Repeat("-", 66))
+
+	for _, seg := range []language.Segment{language.CSEG, language.DSEG, language.ESEG} {
+		u := usage[seg]
+		used := u.Code + u.Data
+		size := a.SegmentSize(seg)
+		percent := 0.0
+
+		if size > 0 {
+			percent = float64(used) * 100 / float64(size)
+		}
+
+		fmt.Fprintf(w, "[.%v]   0x%06X 0x%06X %6v %6v %6v %7v %5.1f%%\n", seg, u.Begin, u.End, u.Code, u.Data, used, size, percent)
+	}
+}
@@ -0,0 +1,238 @@
+package assembler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/parser"
+)
+
+/*
+Give local labels the names they are stored under. A label
+starting with a dot belongs to the last label without one, so
+.loop after func: is func.loop. A numeric label may be defined
+any number of times, and Nb and Nf refer to the nearest
+definition of N before and after the line. Each definition is
+stored as N$k, which cannot be written in source.
+*/
+func (a *Assembler) Localise(line parser.Line) (parser.Line, error) {
+	if a.Syntax == language.SYNTAX_GNU {
+		line = a.Here(line)
+	}
+
+	switch line := line.(type) {
+	case *parser.Label:
+		name, err := a.localLabel(line.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &parser.Label{
+			Value: name,
+			Line:  line.Line,
+		}, nil
+
+	case *parser.Instruction:
+		return &parser.Instruction{
+			Mnemonic: line.Mnemonic,
+			Op1:      a.LocaliseArg(line.Op1),
+			Op2:      a.LocaliseArg(line.Op2),
+			Line:     line.Line,
+		}, nil
+
+	case *parser.Directive:
+		return &parser.Directive{
+			Mnemonic: line.Mnemonic,
+			Value:    a.LocaliseDirVal(line.Value),
+			Line:     line.Line,
+		}, nil
+
+	case *parser.MacroCall:
+		args := []parser.Arg{}
+
+		for _, arg := range line.Args {
+			args = append(args, a.LocaliseArg(arg))
+		}
+
+		return &parser.MacroCall{
+			Name: line.Name,
+			Args: args,
+			Line: line.Line,
+		}, nil
+
+	default:
+		return line, nil
+	}
+}
+
+/*
+Name of a label being defined, updating the scope of dotted
+labels or the count of numeric labels
+*/
+func (a *Assembler) localLabel(label string) (string, error) {
+	if strings.HasPrefix(label, ".") {
+		if a.Scope == "" {
+			return "", fmt.Errorf("local label %v has no label before it", label)
+		}
+
+		return a.Scope + label, nil
+	}
+
+	if isNumeric(label) {
+		a.Locals[label]++
+		return numbered(label, a.Locals[label]), nil
+	}
+
+	a.Scope = label
+	return label, nil
+}
+
+func (a *Assembler) LocaliseArg(arg parser.Arg) parser.Arg {
+	switch arg := arg.(type) {
+	case *parser.ArgExpr:
+		return &parser.ArgExpr{
+			Value: a.LocaliseExpr(arg.Value),
+		}
+
+	default:
+		return arg
+	}
+}
+
+func (a *Assembler) LocaliseDirVal(dirval parser.DirVal) parser.DirVal {
+	switch dirval := dirval.(type) {
+	case *parser.ExprDirVal:
+		return &parser.ExprDirVal{
+			Value: a.LocaliseExpr(dirval.Value),
+		}
+
+	case *parser.AssignDirVal:
+		return &parser.AssignDirVal{
+			Symbol: dirval.Symbol,
+			Value:  a.LocaliseExpr(dirval.Value),
+		}
+
+	case *parser.ExprListDirVal:
+		exprs := []parser.Expr{}
+
+		for _, expr := range dirval.Value {
+			exprs = append(exprs, a.LocaliseExpr(expr))
+		}
+
+		return &parser.ExprListDirVal{
+			Value: exprs,
+		}
+
+	default:
+		return dirval
+	}
+}
+
+func (a *Assembler) LocaliseExpr(expr parser.Expr) parser.Expr {
+	switch expr := expr.(type) {
+	case *parser.Ident:
+		name, err := a.localReference(expr.Value)
+
+		if err != nil {
+			return &parser.ErrorExpr{
+				Value: err.Error(),
+			}
+		}
+
+		return &parser.Ident{
+			Value: name,
+		}
+
+	case *parser.MonopExpr:
+		return &parser.MonopExpr{
+			E1:     a.LocaliseExpr(expr.E1),
+			Symbol: expr.Symbol,
+			Op:     expr.Op,
+		}
+
+	case *parser.BinopExpr:
+		return &parser.BinopExpr{
+			E1:     a.LocaliseExpr(expr.E1),
+			E2:     a.LocaliseExpr(expr.E2),
+			Symbol: expr.Symbol,
+			Op:     expr.Op,
+		}
+
+	case *parser.FuncExpr:
+		args := []parser.Expr{}
+
+		for _, arg := range expr.Args {
+			args = append(args, a.LocaliseExpr(arg))
+		}
+
+		return &parser.FuncExpr{
+			Args:   args,
+			Symbol: expr.Symbol,
+			Func:   expr.Func,
+		}
+
+	default:
+		return expr
+	}
+}
+
+/*
+Name of the label an identifier refers to
+*/
+func (a *Assembler) localReference(ident string) (string, error) {
+	if strings.HasPrefix(ident, ".") {
+		if a.Scope == "" {
+			return "", fmt.Errorf("local label %v has no label before it", ident)
+		}
+
+		return a.Scope + ident, nil
+	}
+
+	number, direction := ident[:len(ident)-1], ident[len(ident)-1]
+
+	if !isNumeric(number) {
+		return ident, nil
+	}
+
+	switch direction {
+	case 'b':
+		if a.Locals[number] == 0 {
+			return "", fmt.Errorf("no local label %v before %v", number, ident)
+		}
+
+		return numbered(number, a.Locals[number]), nil
+
+	case 'f':
+		name := numbered(number, a.Locals[number]+1)
+
+		// Every label is known by pass 2
+		if _, exists := a.Table[name]; !exists && a.Pass == 2 {
+			return "", fmt.Errorf("no local label %v after %v", number, ident)
+		}
+
+		return name, nil
+
+	default:
+		return ident, nil
+	}
+}
+
+/*
+Whether a label is a numeric local label, which like a decimal
+number cannot start with zero
+*/
+func isNumeric(label string) bool {
+	if label == "" || label[0] == '0' {
+		return false
+	}
+
+	_, err := strconv.ParseUint(label, 10, 64)
+	return err == nil
+}
+
+func numbered(label string, n uint64) string {
+	return fmt.Sprintf("%v$%v", label, n)
+}
@@ -0,0 +1,295 @@
+package assembler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/parser"
+)
+
+const MAX_MACRO_DEPTH int = 32
+
+type Macro struct {
+	Name string
+	Line uint64
+	Body []parser.Line
+}
+
+/*
+Begin recording the body of a macro. The lines up to the
+matching .endm are stored rather than assembled.
+*/
+func (a *Assembler) Macro(name string) error {
+	if a.Recording != nil {
+		return fmt.Errorf("macro '%v' defined inside macro '%v'", name, a.Recording.Name)
+	}
+
+	a.Recording = &Macro{
+		Name: name,
+		Line: a.Line,
+		Body: []parser.Line{},
+	}
+
+	return nil
+}
+
+func (a *Assembler) EndMacro() error {
+	if a.Recording == nil {
+		return fmt.Errorf(".%v without .%v", language.DIR_ENDM, language.DIR_MACRO)
+	}
+
+	macro := a.Recording
+	a.Recording = nil
+
+	// Macros are recorded in pass 1 only
+	if a.Pass != 1 {
+		return nil
+	}
+
+	if _, exists := a.Macros[macro.Name]; exists {
+		return fmt.Errorf("duplicate macro %v", macro.Name)
+	}
+
+	loc := a.Origin(macro.Line)
+	a.Macros[macro.Name] = macro
+	return a.declare(macro.Name, 0, MACRO, loc)
+}
+
+/*
+Add a line to the body of the macro being recorded, ending
+the recording at .endm
+*/
+func (a *Assembler) Record(line parser.Line) error {
+	if dir, ok := line.(*parser.Directive); ok && language.IsEndMacro(dir.Mnemonic) {
+		return a.ExecuteDir(dir)
+	}
+
+	a.Recording.Body = append(a.Recording.Body, line)
+	return nil
+}
+
+/*
+Check every macro has been closed by the end of the source
+*/
+func (a *Assembler) CheckMacros() error {
+	if a.Recording != nil {
+		return fmt.Errorf("missing .%v for macro '%v' on %v", language.DIR_ENDM, a.Recording.Name, a.at(a.Origin(a.Recording.Line)))
+	}
+
+	return nil
+}
+
+/*
+Expand a macro call by queueing the body of the macro, with
+the parameters @0 to @9 replaced by the call's arguments, to
+be assembled before the next line of source.
+*/
+func (a *Assembler) Expand(call *parser.MacroCall) error {
+	macro, exists := a.Macros[call.Name]
+
+	if !exists {
+		return fmt.Errorf("'%v' is not an instruction or macro", call.Name)
+	}
+
+	if a.Depth >= MAX_MACRO_DEPTH {
+		return fmt.Errorf("macro '%v' nested too deeply", call.Name)
+	}
+
+	lines := []parser.Line{}
+
+	for _, line := range macro.Body {
+		lines = append(lines, SubstituteLine(line, call.Args))
+	}
+
+	// Mark the end of the expansion so the nesting depth can be tracked
+	lines = append(lines, &endExpansion{})
+
+	a.Depth++
+	a.Pending = append(lines, a.Pending...)
+	return nil
+}
+
+/*
+Marker queued after the body of an expanded macro
+*/
+type endExpansion struct {
+	Line uint64
+}
+
+func (e *endExpansion) Fmt() string {
+	return ""
+}
+
+func (e *endExpansion) Number() uint64 {
+	return e.Line
+}
+
+func (e *endExpansion) Type() parser.LineType {
+	return parser.ComType
+}
+
+func SubstituteLine(line parser.Line, args []parser.Arg) parser.Line {
+	switch line := line.(type) {
+	case *parser.Instruction:
+		return &parser.Instruction{
+			Mnemonic: line.Mnemonic,
+			Op1:      SubstituteArg(line.Op1, args),
+			Op2:      SubstituteArg(line.Op2, args),
+			Line:     line.Line,
+		}
+
+	case *parser.Directive:
+		return &parser.Directive{
+			Mnemonic: line.Mnemonic,
+			Value:    SubstituteDirVal(line.Value, args),
+			Line:     line.Line,
+		}
+
+	case *parser.MacroCall:
+		nested := []parser.Arg{}
+
+		for _, arg := range line.Args {
+			nested = append(nested, SubstituteArg(arg, args))
+		}
+
+		return &parser.MacroCall{
+			Name: line.Name,
+			Args: nested,
+			Line: line.Line,
+		}
+
+	default:
+		return line
+	}
+}
+
+func SubstituteArg(arg parser.Arg, args []parser.Arg) parser.Arg {
+	switch arg := arg.(type) {
+	case *parser.ArgExpr:
+		// A parameter used as a whole operand may be a register
+		if ident, ok := arg.Value.(*parser.Ident); ok && isParameter(ident.Value) {
+			sub, err := parameter(ident.Value, args)
+
+			if err != nil {
+				return &parser.ArgError{
+					Value: err.Error(),
+				}
+			}
+
+			return sub
+		}
+
+		return &parser.ArgExpr{
+			Value: SubstituteExpr(arg.Value, args),
+		}
+
+	default:
+		return arg
+	}
+}
+
+func SubstituteDirVal(dirval parser.DirVal, args []parser.Arg) parser.DirVal {
+	switch dirval := dirval.(type) {
+	case *parser.ExprDirVal:
+		return &parser.ExprDirVal{
+			Value: SubstituteExpr(dirval.Value, args),
+		}
+
+	case *parser.AssignDirVal:
+		return &parser.AssignDirVal{
+			Symbol: dirval.Symbol,
+			Value:  SubstituteExpr(dirval.Value, args),
+		}
+
+	case *parser.ExprListDirVal:
+		exprs := []parser.Expr{}
+
+		for _, expr := range dirval.Value {
+			exprs = append(exprs, SubstituteExpr(expr, args))
+		}
+
+		return &parser.ExprListDirVal{
+			Value: exprs,
+		}
+
+	default:
+		return dirval
+	}
+}
+
+func SubstituteExpr(expr parser.Expr, args []parser.Arg) parser.Expr {
+	switch expr := expr.(type) {
+	case *parser.Ident:
+		if !isParameter(expr.Value) {
+			return expr
+		}
+
+		sub, err := parameter(expr.Value, args)
+
+		if err != nil {
+			return &parser.ErrorExpr{
+				Value: err.Error(),
+			}
+		}
+
+		if sub, ok := sub.(*parser.ArgExpr); ok {
+			return sub.Value
+		}
+
+		return &parser.ErrorExpr{
+			Value: fmt.Sprintf("macro parameter %v is not an expression", expr.Value),
+		}
+
+	case *parser.MonopExpr:
+		return &parser.MonopExpr{
+			E1:     SubstituteExpr(expr.E1, args),
+			Symbol: expr.Symbol,
+			Op:     expr.Op,
+		}
+
+	case *parser.BinopExpr:
+		return &parser.BinopExpr{
+			E1:     SubstituteExpr(expr.E1, args),
+			E2:     SubstituteExpr(expr.E2, args),
+			Symbol: expr.Symbol,
+			Op:     expr.Op,
+		}
+
+	case *parser.FuncExpr:
+		subs := []parser.Expr{}
+
+		for _, arg := range expr.Args {
+			subs = append(subs, SubstituteExpr(arg, args))
+		}
+
+		return &parser.FuncExpr{
+			Args:   subs,
+			Symbol: expr.Symbol,
+			Func:   expr.Func,
+		}
+
+	default:
+		return expr
+	}
+}
+
+func isParameter(ident string) bool {
+	return strings.HasPrefix(ident, "@")
+}
+
+func parameter(ident string, args []parser.Arg) (parser.Arg, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(ident, "@"))
+
+	if err != nil || n < 0 || n > 9 {
+		return nil, fmt.Errorf("invalid macro parameter %v", ident)
+	}
+
+	if n >= len(args) {
+		return nil, errors.New("missing argument for macro parameter " + ident)
+	}
+
+	return args[n], nil
+}
@@ -0,0 +1,67 @@
+package assembler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+/*
+Write the symbol table with each symbol's kind, value, and
+where it was defined and referenced, followed by the memory
+used in each segment. Symbols predefined for the device are
+only included if the source uses them.
+*/
+func (a *Assembler) WriteMap(w io.Writer, name string) error {
+	fmt.Fprintf(w, "aria map of %v\n", name)
+	fmt.Fprintf(w, "device: %v\n\n", a.Device.Name)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tKIND\tSEGMENT\tVALUE\tDEFINED\tREFERENCES")
+
+	for _, sym := range a.SortedSymbols() {
+		if sym.IsPredefined() && len(sym.References) == 0 || sym.IsNumeric() {
+			continue
+		}
+
+		segment := "-"
+		refs := []string{}
+
+		if sym.Kind == LABEL {
+			segment = "." + sym.Segment.String()
+		}
+
+		if sym.Kind == LABEL && sym.Section != SectionName(sym.Segment) {
+			segment += " " + sym.Section
+		}
+
+		for _, ref := range sym.References {
+			refs = append(refs, ref.String())
+		}
+
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\n", sym.Name, sym.Kind, segment, sym.FmtValue(), sym.Defined, strings.Join(refs, " "))
+	}
+
+	tw.Flush()
+	a.writeUsage(w)
+	return nil
+}
+
+/*
+Symbols of the source, sorted by name
+*/
+func (a *Assembler) SortedSymbols() []*Symbol {
+	symbols := []*Symbol{}
+
+	for _, sym := range a.Table {
+		symbols = append(symbols, sym)
+	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbols[i].Name < symbols[j].Name
+	})
+
+	return symbols
+}
@@ -0,0 +1,221 @@
+package assembler
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/object"
+)
+
+/*
+Sections of the assembled program and the symbols defined by
+the source, with addresses in the unified address space used
+by the GNU tools. Code addresses are in bytes rather than in
+words. Relocatable objects give addresses as offsets from the
+start of each section instead, to be placed by the linker.
+*/
+func (a *Assembler) Object() *object.File {
+	file := &object.File{
+		Arch:        a.Device.Arch,
+		Device:      string(a.Device.Name),
+		Entry:       0,
+		Relocatable: a.Relocatable,
+		Relocations: a.Relocations,
+		Sections:    []object.Section{},
+		Symbols:     []object.Symbol{},
+	}
+
+	usage := a.SectionUsage()
+
+	for _, name := range a.Sections {
+		seg := SectionSegment(name)
+		u := usage[name]
+		sec := object.Section{
+			Name:    name,
+			Kind:    SectionKind(seg),
+			Address: a.SectionAddress(seg, u.Begin),
+			Size:    u.End - u.Begin,
+		}
+
+		if seg == language.CSEG {
+			sec.Size *= 2
+		}
+
+		// The code section is always present, others only if used
+		if name != SectionName(language.CSEG) && sec.Size == 0 {
+			continue
+		}
+
+		for _, entries := range a.Listing.Entries {
+			for _, entry := range entries {
+				if entry.Section != name || entry.Bytes == nil {
+					continue
+				}
+
+				if sec.Data == nil {
+					sec.Data = make([]byte, sec.Size)
+				}
+
+				start := a.SectionAddress(seg, entry.Address) - sec.Address
+				copy(sec.Data[start:], entry.Bytes)
+			}
+		}
+
+		file.Sections = append(file.Sections, sec)
+	}
+
+	// Numeric labels are only kept where a relocation refers to them
+	relocated := map[string]bool{}
+
+	for _, reloc := range a.Relocations {
+		relocated[reloc.Symbol] = true
+	}
+
+	for _, sym := range a.SortedSymbols() {
+		if sym.IsPredefined() || sym.IsNumeric() && !relocated[sym.Name] {
+			continue
+		}
+
+		switch sym.Kind {
+		case LABEL:
+			kind := object.Object
+
+			if sym.Segment == language.CSEG {
+				kind = object.Func
+			}
+
+			file.Symbols = append(file.Symbols, object.Symbol{
+				Name:    sym.Name,
+				Section: sym.Section,
+				Kind:    kind,
+				Value:   a.LabelAddress(sym),
+				Global:  sym.Global,
+			})
+
+		case EQU, SET:
+			file.Symbols = append(file.Symbols, object.Symbol{
+				Name:   sym.Name,
+				Kind:   object.NoType,
+				Value:  sym.Value,
+				Global: sym.Global,
+			})
+
+		case EXTERN:
+			file.Symbols = append(file.Symbols, object.Symbol{
+				Name:   sym.Name,
+				Kind:   object.NoType,
+				Extern: true,
+			})
+		}
+	}
+
+	return file
+}
+
+func SectionKind(seg language.Segment) object.SectionKind {
+	switch seg {
+	case language.CSEG:
+		return object.Code
+	case language.DSEG:
+		return object.Data
+	default:
+		return object.EEPROM
+	}
+}
+
+/*
+Address of a location in a segment in the object being built,
+relative to the start of its section if it is relocatable
+*/
+func (a *Assembler) SectionAddress(seg language.Segment, address uint64) uint64 {
+	if a.Relocatable {
+		return SectionAddress(seg, address) - SectionAddress(seg, 0)
+	}
+
+	return SectionAddress(seg, address)
+}
+
+/*
+Segment of a section, given by the start of its name. Sections
+not named for data or eeprom hold code.
+*/
+func SectionSegment(name string) language.Segment {
+	for _, prefix := range []string{".data", ".bss", ".noinit"} {
+		if name == prefix || strings.HasPrefix(name, prefix+".") {
+			return language.DSEG
+		}
+	}
+
+	if name == ".eeprom" || strings.HasPrefix(name, ".eeprom.") {
+		return language.ESEG
+	}
+
+	return language.CSEG
+}
+
+func SectionName(seg language.Segment) string {
+	switch seg {
+	case language.CSEG:
+		return ".text"
+	case language.DSEG:
+		return ".data"
+	default:
+		return ".eeprom"
+	}
+}
+
+/*
+Address of a location in a segment in the address space of
+the GNU tools
+*/
+func SectionAddress(seg language.Segment, address uint64) uint64 {
+	switch seg {
+	case language.CSEG:
+		return object.TEXT_ADDRESS + address*2
+	case language.DSEG:
+		return object.DATA_ADDRESS + address
+	default:
+		return object.EEPROM_ADDRESS + address
+	}
+}
+
+/*
+Line number information mapping each instruction to the line
+it was assembled from. Instructions expanded from a macro are
+mapped to the line in the body of the macro. Only the .text
+section is covered.
+*/
+func (a *Assembler) DebugInfo() *object.Debug {
+	debug := &object.Debug{
+		Name:     a.File,
+		Producer: "aria",
+		Rows:     []object.LineRow{},
+	}
+
+	for _, entries := range a.Listing.Entries {
+		for _, entry := range entries {
+			if entry.Section != SectionName(language.CSEG) || entry.Bytes == nil {
+				continue
+			}
+
+			loc := a.Origin(entry.Source)
+
+			debug.Rows = append(debug.Rows, object.LineRow{
+				Address: a.SectionAddress(language.CSEG, entry.Address),
+				File:    loc.File,
+				Line:    loc.Line,
+			})
+		}
+	}
+
+	sort.Slice(debug.Rows, func(i, j int) bool {
+		return debug.Rows[i].Address < debug.Rows[j].Address
+	})
+
+	usage := a.SectionUsage()[SectionName(language.CSEG)]
+	debug.LowPC = a.SectionAddress(language.CSEG, usage.Begin)
+	debug.HighPC = a.SectionAddress(language.CSEG, usage.End)
+
+	return debug
+}
@@ -0,0 +1,223 @@
+package assembler
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/parser"
+)
+
+const MAX_RELAX_PASSES int = 16
+
+/*
+State kept between the passes sizing a program with relaxed
+jumps and branches. Each jump or branch is numbered in the
+order it is assembled, and only ever grows from one pass to
+the next, so the sizes are certain to converge. Targets are
+found from the symbols of the previous pass.
+*/
+type Relaxation struct {
+	Index   uint64
+	Resized bool
+	Sizes   map[uint64]uint64
+	Symbols map[string]uint64
+	Table   map[string]*Symbol
+}
+
+func NewRelaxation() Relaxation {
+	return Relaxation{
+		Index:   0,
+		Resized: false,
+		Sizes:   map[uint64]uint64{},
+		Symbols: map[string]uint64{},
+		Table:   map[string]*Symbol{},
+	}
+}
+
+/*
+Whether another sizing pass is needed, recording the symbols
+of this pass for the next
+*/
+func (a *Assembler) Unsettled() bool {
+	if !a.Relax {
+		return false
+	}
+
+	unsettled := a.Relaxation.Resized || !maps.Equal(a.Symbols, a.Relaxation.Symbols)
+
+	a.Relaxation.Resized = false
+	a.Relaxation.Symbols = maps.Clone(a.Symbols)
+	a.Relaxation.Table = maps.Clone(a.Table)
+
+	return unsettled
+}
+
+/*
+Size of an instruction in words. In relaxed mode, jumps and
+calls take the short form when their target is in range, and
+branches are lengthened to skip over a jump when it is not.
+*/
+func (a *Assembler) InstrSize(line *parser.Instruction) (uint64, error) {
+	if !a.Relax || !(language.IsJump(line.Mnemonic) || language.IsBranch(line.Mnemonic)) {
+		instr, err := language.GetInstr(line.Mnemonic, &a.Device)
+
+		if err != nil {
+			return 0, err
+		}
+
+		if instr.IsLong() {
+			return 2, nil
+		}
+
+		return 1, nil
+	}
+
+	index := a.Relaxation.Index
+	a.Relaxation.Index++
+
+	size := max(a.Relaxation.Sizes[index], a.needed(line))
+
+	if size != a.Relaxation.Sizes[index] {
+		a.Relaxation.Sizes[index] = size
+		a.Relaxation.Resized = true
+	}
+
+	return size, nil
+}
+
+/*
+Words needed to reach the target of a jump or branch from the
+current address, or the most that may be needed if the target
+is only known once the program is linked
+*/
+func (a *Assembler) needed(line *parser.Instruction) uint64 {
+	long := a.Device.Flags&device.JMP_CALL == device.JMP_CALL
+	far := true
+	distance := int64(0)
+
+	if arg, ok := target(line).(*parser.ArgExpr); ok && !a.isExternal(arg.Value) {
+		d, err := a.EvalTarget(arg.Value, a.Relaxation.Symbols, true)
+
+		// Targets not yet defined are assumed to be near
+		distance = int64(d)
+		far = false
+
+		if err != nil {
+			distance = 0
+		}
+	}
+
+	if language.IsJump(line.Mnemonic) {
+		if long && (far || distance < -2048 || distance > 2047) {
+			return 2
+		}
+
+		return 1
+	}
+
+	if !far && distance >= -64 && distance <= 63 {
+		return 1
+	}
+
+	// The jump follows the inverted branch
+	if long && (far || distance-1 < -2048 || distance-1 > 2047) {
+		return 3
+	}
+
+	return 2
+}
+
+/*
+Whether an expression refers to a symbol defined by another
+object or in another section, whose distance is unknown
+*/
+func (a *Assembler) isExternal(expr parser.Expr) bool {
+	if !a.Relocatable {
+		return false
+	}
+
+	for _, name := range exprIdentifiers(expr) {
+		sym, exists := a.Relaxation.Table[name]
+
+		if exists && (sym.Kind == EXTERN || (sym.Kind == LABEL && sym.Section != a.Section)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func target(line *parser.Instruction) parser.Arg {
+	if line.Mnemonic == string(language.BRBC) || line.Mnemonic == string(language.BRBS) {
+		return line.Op2
+	}
+
+	return line.Op1
+}
+
+/*
+The instructions a jump or branch is assembled as in pass 2,
+given the size chosen for it in pass 1
+*/
+func (a *Assembler) Relaxed(line *parser.Instruction) ([]*parser.Instruction, error) {
+	if !a.Relax || !(language.IsJump(line.Mnemonic) || language.IsBranch(line.Mnemonic)) {
+		return []*parser.Instruction{line}, nil
+	}
+
+	index := a.Relaxation.Index
+	a.Relaxation.Index++
+
+	size, exists := a.Relaxation.Sizes[index]
+
+	if !exists {
+		return nil, fmt.Errorf("jump or branch was not sized in pass 1")
+	}
+
+	if forms, ok := language.Jumps[language.Mnemonic(line.Mnemonic)]; ok {
+		relaxed := *line
+		relaxed.Mnemonic = string(forms[size-1])
+		return []*parser.Instruction{&relaxed}, nil
+	}
+
+	if size == 1 {
+		return []*parser.Instruction{line}, nil
+	}
+
+	// Branch on the opposite condition over a jump to the target
+	offset := size - 1
+
+	// Targets are byte addresses in GNU syntax
+	if a.Syntax == language.SYNTAX_GNU {
+		offset = (a.PC + size) * 2
+	}
+
+	skip := &parser.ArgExpr{
+		Value: &parser.Literal{
+			Base:  10,
+			Value: fmt.Sprint(offset),
+		},
+	}
+
+	inverse := &parser.Instruction{
+		Mnemonic: string(language.Branches[language.Mnemonic(line.Mnemonic)]),
+		Op1:      skip,
+		Op2:      &parser.Nil{},
+		Line:     line.Line,
+	}
+
+	if line.Mnemonic == string(language.BRBC) || line.Mnemonic == string(language.BRBS) {
+		inverse.Op1 = line.Op1
+		inverse.Op2 = skip
+	}
+
+	jump := &parser.Instruction{
+		Mnemonic: string(language.Jumps[language.JMP][size-2]),
+		Op1:      target(line),
+		Op2:      &parser.Nil{},
+		Line:     line.Line,
+	}
+
+	return []*parser.Instruction{inverse, jump}, nil
+}
@@ -0,0 +1,464 @@
+package assembler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/object"
+	"github.com/silaspace/aria/parser"
+)
+
+/*
+A use of a relocatable symbol, optionally through one of the
+functions selecting a byte of its address
+*/
+type Reference struct {
+	Symbol  string
+	Addend  int64
+	Part    language.Mnemonic
+	Program bool
+}
+
+/*
+Import a symbol defined by another object
+*/
+func (a *Assembler) Extern(symbol string) error {
+	if !a.Relocatable {
+		return fmt.Errorf("external symbol %v can only be used when assembling an object", symbol)
+	}
+
+	return a.declare(symbol, 0, EXTERN, a.Location())
+}
+
+/*
+Export a symbol to other objects. The symbol may be defined
+before or after the directive.
+*/
+func (a *Assembler) Global(symbol string) error {
+	if a.Pass == 1 {
+		a.Exports[symbol] = a.Location()
+	}
+
+	return nil
+}
+
+/*
+Mark each exported symbol as global once every symbol in the
+source has been defined
+*/
+func (a *Assembler) CheckExports() error {
+	for name, loc := range a.Exports {
+		sym, exists := a.Table[name]
+
+		if !exists {
+			return fmt.Errorf("global symbol %v is not defined, exported on %v", name, loc)
+		}
+
+		switch sym.Kind {
+		case LABEL, EQU, SET:
+			sym.Global = true
+
+		default:
+			return fmt.Errorf("%v %v cannot be exported", sym.Kind, name)
+		}
+	}
+
+	return nil
+}
+
+/*
+The origin of the data segment, which starts at the beginning
+of SRAM unless it is to be placed by the linker
+*/
+func (a *Assembler) DataOrigin() uint64 {
+	if a.Relocatable {
+		return 0
+	}
+
+	return uint64(a.Device.RAMStart)
+}
+
+/*
+Evaluate an instruction operand, recording a relocation in
+place of its value if it refers to a symbol only known once
+the object is linked
+*/
+func (a *Assembler) EvalOperand(arg parser.Arg, line *parser.Instruction, relative bool) (language.Value, error) {
+	mnemonic := line.Mnemonic
+	targets := isTarget(line, arg)
+	arg = a.Resolve(arg)
+	expr, ok := arg.(*parser.ArgExpr)
+
+	if !ok || !a.Relocatable {
+		return a.wrapAround(mnemonic, a.evalArg(arg, targets, relative))
+	}
+
+	ref, err := a.Reference(expr.Value, relative)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if ref == nil {
+		return a.wrapAround(mnemonic, a.evalArg(arg, targets, relative))
+	}
+
+	program := ref.Program || a.inProgram(ref.Symbol)
+	typ, err := RelocationType(language.GetRelocation(mnemonic), ref.Part, program)
+
+	if err != nil {
+		return nil, fmt.Errorf("%v cannot refer to %v here, %v", mnemonic, ref.Symbol, err)
+	}
+
+	if a.Syntax == language.SYNTAX_GNU {
+		if err := a.gnuReference(ref, typ); err != nil {
+			return nil, err
+		}
+	}
+
+	reloc := object.Relocation{
+		Section: a.Section,
+		Offset:  a.SectionAddress(a.Segment, a.PC),
+		Symbol:  ref.Symbol,
+		Type:    typ,
+		Addend:  ref.Addend,
+	}
+
+	// Data addresses are held in the second word of the instruction
+	if typ == object.R_AVR_16 {
+		reloc.Offset += 2
+	}
+
+	a.Relocations = append(a.Relocations, reloc)
+
+	return &language.Int{
+		Value: 0,
+	}, nil
+}
+
+/*
+Fail when the values placed by a directive refer to a symbol
+only known once the object is linked, since data is never
+relocated
+*/
+func (a *Assembler) CheckData(line *parser.Directive) error {
+	list, ok := line.Value.(*parser.ExprListDirVal)
+
+	if !ok || !a.Relocatable {
+		return nil
+	}
+
+	for _, expr := range list.Value {
+		for _, name := range exprIdentifiers(expr) {
+			if a.isRelocatable(name) {
+				return fmt.Errorf("values of .%v cannot refer to %v, which is not known until the program is linked", line.Mnemonic, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+Offset of an rjmp or rcall beyond its reach, taken the other way
+around flash. On devices of up to 4K words every address can be
+reached this way, as AVRASM2 allows, while larger devices need
+jmp and call.
+*/
+func (a *Assembler) wrapAround(mnemonic string, val language.Value) (language.Value, error) {
+	offset, ok := val.(*language.Int)
+
+	if !ok || (mnemonic != string(language.RJMP) && mnemonic != string(language.RCALL)) {
+		return val, nil
+	}
+
+	distance := int64(offset.Value)
+
+	if distance >= -2048 && distance <= 2047 {
+		return val, nil
+	}
+
+	size := int64(a.Device.FlashSize)
+
+	if size > 4096 {
+		if a.Device.Flags&device.JMP_CALL == 0 {
+			return nil, fmt.Errorf("target of %v is out of reach, and the %v has no jmp or call", mnemonic, a.Device.Name)
+		}
+
+		return val, nil
+	}
+
+	distance = (distance%size + size) % size
+
+	if distance > 2047 {
+		distance -= size
+	}
+
+	return &language.Int{
+		Value: uint64(distance),
+	}, nil
+}
+
+/*
+Evaluate an operand whose value is already known
+*/
+func (a *Assembler) evalArg(arg parser.Arg, targets bool, relative bool) language.Value {
+	expr, ok := arg.(*parser.ArgExpr)
+
+	if !ok || !targets {
+		return EvalArg(arg, a.Symbols, relative, a.PC)
+	}
+
+	val, err := a.EvalTarget(expr.Value, a.Symbols, relative)
+
+	if err != nil {
+		return &language.Error{
+			Value: err.Error(),
+		}
+	}
+
+	return &language.Int{
+		Value: val,
+	}
+}
+
+/*
+Relocation type for an operand. Bytes of an address in program
+memory are taken from its word address.
+*/
+func RelocationType(reloc language.Relocation, part language.Mnemonic, program bool) (object.RelocType, error) {
+	switch {
+	case reloc == language.RELOC_BRANCH && part == "":
+		return object.R_AVR_7_PCREL, nil
+
+	case reloc == language.RELOC_JUMP && part == "":
+		return object.R_AVR_13_PCREL, nil
+
+	case reloc == language.RELOC_CALL && part == "":
+		return object.R_AVR_CALL, nil
+
+	case reloc == language.RELOC_ADDRESS && part == "":
+		return object.R_AVR_16, nil
+
+	case reloc == language.RELOC_ADIW && part == "":
+		return object.R_AVR_6_ADIW, nil
+
+	case reloc == language.RELOC_IMMEDIATE && program:
+		switch part {
+		case "":
+			return object.R_AVR_LDI, nil
+		case language.FUNC_LOW:
+			return object.R_AVR_LO8_LDI_PM, nil
+		case language.FUNC_HIGH, language.FUNC_BYTE2:
+			return object.R_AVR_HI8_LDI_PM, nil
+		case language.FUNC_BYTE3:
+			return object.R_AVR_HH8_LDI_PM, nil
+		}
+
+	case reloc == language.RELOC_IMMEDIATE:
+		switch part {
+		case "":
+			return object.R_AVR_LDI, nil
+		case language.FUNC_LOW:
+			return object.R_AVR_LO8_LDI, nil
+		case language.FUNC_HIGH, language.FUNC_BYTE2:
+			return object.R_AVR_HI8_LDI, nil
+		case language.FUNC_BYTE3:
+			return object.R_AVR_HH8_LDI, nil
+		}
+	}
+
+	return 0, fmt.Errorf("the address is not known until the program is linked")
+}
+
+/*
+Find the symbol an expression needs to be relocated against,
+or nil if its value is already known. Labels in the same
+section as the instruction are known relative to one another,
+so differences between them and relative jumps to them need
+no relocation.
+*/
+func (a *Assembler) Reference(expr parser.Expr, relative bool) (*Reference, error) {
+	if !a.refersToRelocatable(expr) {
+		return nil, nil
+	}
+
+	ref := &Reference{}
+
+	if fn, ok := expr.(*parser.FuncExpr); ok {
+		mn := language.Mnemonic(fn.Symbol)
+
+		if part, exists := GnuParts[mn]; exists && a.Syntax == language.SYNTAX_GNU {
+			ref.Program = strings.HasPrefix(fn.Symbol, "pm_")
+			mn = part
+		}
+
+		switch mn {
+		case language.FUNC_LOW, language.FUNC_HIGH, language.FUNC_BYTE2, language.FUNC_BYTE3:
+			ref.Part = mn
+			expr = fn.Args[0]
+		}
+	}
+
+	constant, terms, err := a.linearise(expr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Sum the symbols from each segment or object
+	bases := map[string]int64{}
+
+	for name, coef := range terms {
+		bases[a.base(name)] += coef
+	}
+
+	target := ""
+
+	for base, coef := range bases {
+		switch {
+		case coef == 0:
+			continue
+
+		case coef == 1 && target == "":
+			target = base
+
+		default:
+			return nil, fmt.Errorf("expression cannot be relocated")
+		}
+	}
+
+	// Symbols cancelling out contribute only their values
+	for name, coef := range terms {
+		if a.base(name) != target || ref.Symbol != "" || coef != 1 {
+			constant += coef * int64(a.Table[name].Value)
+			continue
+		}
+
+		ref.Symbol = name
+	}
+
+	if target == "" || (relative && ref.Part == "" && target == a.base("")) {
+		return nil, nil
+	}
+
+	ref.Addend = constant
+	return ref, nil
+}
+
+/*
+Symbols are relocated against the section defining them, or
+the object exporting them. The empty name gives the section
+being assembled.
+*/
+func (a *Assembler) base(name string) string {
+	sym, exists := a.Table[name]
+
+	if !exists {
+		return "section " + a.Section
+	}
+
+	if sym.Kind == EXTERN {
+		return "extern " + name
+	}
+
+	return "section " + sym.Section
+}
+
+/*
+Labels in the code segment, whose addresses are in words
+*/
+func (a *Assembler) inProgram(name string) bool {
+	sym, exists := a.Table[name]
+	return exists && sym.Kind == LABEL && sym.Segment == language.CSEG
+}
+
+func (a *Assembler) isRelocatable(name string) bool {
+	sym, exists := a.Table[name]
+	return exists && (sym.Kind == LABEL || sym.Kind == EXTERN)
+}
+
+func (a *Assembler) refersToRelocatable(expr parser.Expr) bool {
+	for _, name := range exprIdentifiers(expr) {
+		if a.isRelocatable(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Split an expression into a constant and a sum of multiples of
+relocatable symbols
+*/
+func (a *Assembler) linearise(expr parser.Expr) (int64, map[string]int64, error) {
+	terms := map[string]int64{}
+
+	if !a.refersToRelocatable(expr) {
+		val, err := EvalExpr(expr, a.Symbols, false, a.PC)
+		return int64(val), terms, err
+	}
+
+	switch expr := expr.(type) {
+	case *parser.Ident:
+		terms[expr.Value] = 1
+		return 0, terms, nil
+
+	case *parser.MonopExpr:
+		if expr.Symbol == "-" {
+			c, t, err := a.linearise(expr.E1)
+			return -c, scale(t, -1), err
+		}
+
+	case *parser.BinopExpr:
+		c1, t1, err := a.linearise(expr.E1)
+
+		if err != nil {
+			return 0, nil, err
+		}
+
+		c2, t2, err := a.linearise(expr.E2)
+
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch expr.Symbol {
+		case "+":
+			return c1 + c2, add(t1, t2), nil
+
+		case "-":
+			return c1 - c2, add(t1, scale(t2, -1)), nil
+
+		case "*":
+			if len(t1) == 0 {
+				return c1 * c2, scale(t2, c1), nil
+			}
+
+			if len(t2) == 0 {
+				return c1 * c2, scale(t1, c2), nil
+			}
+		}
+	}
+
+	return 0, nil, fmt.Errorf("expression cannot be relocated")
+}
+
+func add(t1 map[string]int64, t2 map[string]int64) map[string]int64 {
+	for name, coef := range t2 {
+		t1[name] += coef
+	}
+
+	return t1
+}
+
+func scale(t map[string]int64, k int64) map[string]int64 {
+	for name := range t {
+		t[name] *= k
+	}
+
+	return t
+}
@@ -0,0 +1,365 @@
+package assembler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/parser"
+)
+
+type SymbolKind int
+
+const (
+	LABEL  SymbolKind = 0
+	EQU    SymbolKind = 1
+	SET    SymbolKind = 2
+	DEF    SymbolKind = 3
+	MACRO  SymbolKind = 4
+	EXTERN SymbolKind = 5
+)
+
+/* Files of symbols not defined in the source */
+const (
+	COMMAND_LINE string = "<command line>"
+	DEVICE_FILE  string = "<device>"
+)
+
+type Location struct {
+	File string
+	Line uint64
+}
+
+/*
+Everything known about a symbol once the source has been
+assembled. Labels are given the segment they were defined
+in, along with the section within it, and aliases from .def
+the number of their register.
+*/
+type Symbol struct {
+	Defined    Location
+	Global     bool
+	Kind       SymbolKind
+	Name       string
+	References []Location
+	Section    string
+	Segment    language.Segment
+	Value      uint64
+}
+
+func (k SymbolKind) String() string {
+	switch k {
+	case LABEL:
+		return "label"
+	case EQU:
+		return "equ"
+	case SET:
+		return "set"
+	case DEF:
+		return "def"
+	case MACRO:
+		return "macro"
+	case EXTERN:
+		return "extern"
+	default:
+		return fmt.Sprintf("kind %d", int(k))
+	}
+}
+
+func (l Location) String() string {
+	if l.Line == 0 {
+		return l.File
+	}
+
+	if l.File == "" {
+		return fmt.Sprintf("line %v", l.Line)
+	}
+
+	return fmt.Sprintf("%v:%v", l.File, l.Line)
+}
+
+/*
+Value of the symbol as written in listings and map files
+*/
+func (s *Symbol) FmtValue() string {
+	switch s.Kind {
+	case DEF:
+		return fmt.Sprintf("r%v", s.Value)
+	case MACRO, EXTERN:
+		return "-"
+	default:
+		return fmt.Sprintf("0x%06X", s.Value)
+	}
+}
+
+func (s *Symbol) IsPredefined() bool {
+	return s.Defined.File == COMMAND_LINE || s.Defined.File == DEVICE_FILE
+}
+
+/*
+Whether the symbol is a definition of a numeric local label,
+stored as N$k, a name which cannot be written in source
+*/
+func (s *Symbol) IsNumeric() bool {
+	return strings.Contains(s.Name, "$")
+}
+
+/*
+Location of the line being assembled
+*/
+func (a *Assembler) Location() Location {
+	return a.Origin(a.Line)
+}
+
+/*
+File and line a line of the source was read from
+*/
+func (a *Assembler) Origin(line uint64) Location {
+	if a.Lines == nil {
+		return Location{
+			File: a.File,
+			Line: line,
+		}
+	}
+
+	file, number := a.Lines.Origin(line)
+
+	return Location{
+		File: file,
+		Line: number,
+	}
+}
+
+/*
+A location as given in messages, naming the file only if it
+is not the file being assembled
+*/
+func (a *Assembler) at(loc Location) string {
+	if loc.File == a.File {
+		return fmt.Sprintf("line %v", loc.Line)
+	}
+
+	return fmt.Sprintf("line %v of %v", loc.Line, loc.File)
+}
+
+func (a *Assembler) AddSymbol(symbol string, value uint64) error {
+	return a.declare(symbol, value, EQU, a.Location())
+}
+
+func (a *Assembler) AddLabel(label string) error {
+	return a.declare(label, a.LabelValue(), LABEL, a.Location())
+}
+
+/*
+Give a register another name with .def. Unlike other symbols
+aliases may be redefined, so they are updated in both passes.
+*/
+func (a *Assembler) AddAlias(alias string, reg uint64) error {
+	if reg > 31 {
+		return fmt.Errorf("r%v is not a register", reg)
+	}
+
+	return a.redefine(alias, reg, DEF)
+}
+
+/*
+Assign a symbol with .set, which unlike .equ may be assigned
+again later in the source
+*/
+func (a *Assembler) SetSymbol(symbol string, value uint64) error {
+	err := a.redefine(symbol, value, SET)
+
+	if err != nil {
+		return err
+	}
+
+	a.Symbols[symbol] = value
+	return nil
+}
+
+func (a *Assembler) declare(name string, value uint64, kind SymbolKind, loc Location) error {
+	// Symbols are recorded in pass 1 only
+	if a.Pass != 1 {
+		return nil
+	}
+
+	if sym, exists := a.Table[name]; exists {
+		// Symbols predefined for the device may be defined again, as by a definition file
+		if sym.Defined.File != DEVICE_FILE {
+			return fmt.Errorf("duplicate %v %v", kind, name)
+		}
+
+		delete(a.Symbols, name)
+	}
+
+	a.Table[name] = &Symbol{
+		Defined:    loc,
+		Kind:       kind,
+		Name:       name,
+		References: []Location{},
+		Section:    a.Section,
+		Segment:    a.Segment,
+		Value:      value,
+	}
+
+	// Aliases, macros and external symbols have no value to evaluate
+	if kind != DEF && kind != MACRO && kind != EXTERN {
+		a.Symbols[name] = value
+	}
+
+	return nil
+}
+
+func (a *Assembler) redefine(name string, value uint64, kind SymbolKind) error {
+	sym, exists := a.Table[name]
+
+	if !exists || sym.Defined.File == DEVICE_FILE {
+		return a.declare(name, value, kind, a.Location())
+	}
+
+	if sym.Kind != kind {
+		return fmt.Errorf("cannot redefine %v %v with .%v", sym.Kind, name, kind)
+	}
+
+	sym.Value = value
+	return nil
+}
+
+/*
+Replace an operand naming a register alias with the register
+*/
+func (a *Assembler) Resolve(arg parser.Arg) parser.Arg {
+	expr, ok := arg.(*parser.ArgExpr)
+
+	if !ok {
+		return arg
+	}
+
+	ident, ok := expr.Value.(*parser.Ident)
+
+	if !ok {
+		return arg
+	}
+
+	if sym, exists := a.Table[ident.Value]; exists && sym.Kind == DEF {
+		return &parser.ArgReg{
+			Value: &parser.Register{
+				Value: strconv.FormatUint(sym.Value, 10),
+			},
+		}
+	}
+
+	return arg
+}
+
+/*
+Record the current line against each name it uses. Uses are
+recorded in pass 1, so that source which stops at an error
+still has those found before it.
+*/
+func (a *Assembler) AddReferences(line parser.Line) {
+	if a.Pass != 1 {
+		return
+	}
+
+	loc := a.Location()
+
+	for _, name := range Identifiers(line) {
+		// Lines naming a symbol more than once are recorded once
+		refs := a.Uses[name]
+
+		if len(refs) > 0 && refs[len(refs)-1] == loc {
+			continue
+		}
+
+		a.Uses[name] = append(refs, loc)
+	}
+}
+
+/*
+Give each symbol defined the lines using it, including those
+before its definition
+*/
+func (a *Assembler) AttachReferences() {
+	for name, sym := range a.Table {
+		sym.References = append([]Location{}, a.Uses[name]...)
+	}
+}
+
+/*
+Names used by a line, excluding those it defines
+*/
+func Identifiers(line parser.Line) []string {
+	names := []string{}
+
+	switch line := line.(type) {
+	case *parser.Instruction:
+		names = append(names, argIdentifiers(line.Op1)...)
+		names = append(names, argIdentifiers(line.Op2)...)
+
+	case *parser.MacroCall:
+		names = append(names, line.Name)
+
+		for _, arg := range line.Args {
+			names = append(names, argIdentifiers(arg)...)
+		}
+
+	case *parser.Directive:
+		switch value := line.Value.(type) {
+		case *parser.ExprDirVal:
+			names = append(names, exprIdentifiers(value.Value)...)
+
+		case *parser.AssignDirVal:
+			names = append(names, exprIdentifiers(value.Value)...)
+
+		case *parser.ExprListDirVal:
+			for _, expr := range value.Value {
+				names = append(names, exprIdentifiers(expr)...)
+			}
+
+		case *parser.IdentDirVal:
+			if language.IsConditional(line.Mnemonic) {
+				names = append(names, value.Value)
+			}
+		}
+	}
+
+	return names
+}
+
+func argIdentifiers(arg parser.Arg) []string {
+	if arg, ok := arg.(*parser.ArgExpr); ok {
+		return exprIdentifiers(arg.Value)
+	}
+
+	return []string{}
+}
+
+func exprIdentifiers(expr parser.Expr) []string {
+	switch expr := expr.(type) {
+	case *parser.Ident:
+		return []string{expr.Value}
+
+	case *parser.MonopExpr:
+		return exprIdentifiers(expr.E1)
+
+	case *parser.BinopExpr:
+		return append(exprIdentifiers(expr.E1), exprIdentifiers(expr.E2)...)
+
+	case *parser.FuncExpr:
+		names := []string{}
+
+		// Asking whether a symbol is defined does not use its value
+		for i, arg := range expr.Args {
+			if expr.Func.Params[i] != language.PARAM_SYMBOL {
+				names = append(names, exprIdentifiers(arg)...)
+			}
+		}
+
+		return names
+
+	default:
+		return []string{}
+	}
+}
@@ -1,26 +1,74 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/silaspace/aria/assembler"
 	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/language"
 )
 
 type BuildCommand struct {
-	input   string
-	output  string
-	verbose bool
+	debug    bool
+	defines  []assembler.Define
+	device   string
+	format   string
+	includes []string
+	input    string
+	inputs   []string
+	listing  string
+	mapfile  string
+	output   string
+	relax    bool
+	syntax   language.Syntax
+	verbose  bool
 }
 
 func NewBuildCommand(rawArgs []string) *BuildCommand {
 	// Parse command line arguments
 	flags := NewFlags("build")
+	flags.Multiple = true
 	err := flags.Parse(rawArgs)
 
 	if err != nil {
 		exit(err)
 	}
 
-	err = flags.SetOutput(HexExt)
+	switch flags.Format {
+	case "bin":
+		err = flags.SetOutput(HexExt)
+
+	case "elf":
+		err = flags.SetOutput(ElfExt)
+
+	case "obj":
+		err = flags.SetOutput(ObjExt)
+
+	default:
+		err = fmt.Errorf("unknown output format '%v'", flags.Format)
+	}
+
+	if flags.Debug && flags.Format == "bin" {
+		err = fmt.Errorf("debug information requires --format elf or obj")
+	}
+
+	// Each of several sources is assembled to an object of its own
+	if len(flags.Inputs) > 1 {
+		switch {
+		case flags.Format != "obj":
+			err = fmt.Errorf("several input files can only be assembled with --format obj")
+
+		case flags.Output != "" && flags.Output != ReplaceExt(filepath.Base(flags.Input), ObjExt):
+			err = fmt.Errorf("cannot set the output file of several input files")
+
+		case flags.Listing != "" || flags.Map != "":
+			err = fmt.Errorf("listing and map files need a single input file")
+		}
+	}
 
 	if err != nil {
 		exit(err)
@@ -28,31 +76,142 @@ func NewBuildCommand(rawArgs []string) *BuildCommand {
 
 	// Return command
 	return &BuildCommand{
-		input:   flags.Input,
-		output:  flags.Output,
-		verbose: flags.Verbose,
+		debug:    flags.Debug,
+		defines:  flags.Defines,
+		device:   strings.ToLower(flags.Device),
+		format:   flags.Format,
+		includes: flags.Includes,
+		input:    flags.Input,
+		inputs:   flags.Inputs,
+		listing:  flags.Listing,
+		mapfile:  flags.Map,
+		output:   flags.Output,
+		relax:    flags.Relax,
+		syntax:   language.Syntax(flags.Syntax),
+		verbose:  flags.Verbose,
 	}
 }
 
 func (bc *BuildCommand) Run() {
-	reader, err := handler.NewFileReader(bc.input)
+	if len(bc.inputs) > 1 {
+		for _, input := range bc.inputs {
+			bc.input = input
+			bc.output = ReplaceExt(filepath.Base(input), ObjExt)
+			bc.assemble()
+		}
+
+		return
+	}
+
+	bc.assemble()
+}
+
+func (bc *BuildCommand) assemble() {
+	pp, err := preprocess(bc.input, bc.syntax, bc.defines, bc.includes)
+
+	if err != nil && len(bc.inputs) > 1 {
+		exit(fmt.Errorf("%v: %v", bc.input, err))
+	}
 
 	if err != nil {
 		exit(err)
 	}
 
-	writer, err := handler.NewFileWriter(bc.output)
+	var writer assembler.Writer
+
+	// The code is collected in memory to be wrapped in an object file
+	if bc.format == "bin" {
+		writer, err = handler.NewFileWriter(bc.output)
+	} else {
+		writer = handler.NewWebWriter()
+	}
 
 	if err != nil {
 		exit(err)
 	}
 
-	asm := assembler.NewAssembler(reader, writer)
+	asm := assembler.NewAssembler(pp, writer)
+	asm.Defines = bc.defines
+	asm.File = bc.input
+	asm.Lines = pp
+	asm.Relax = bc.relax
+	asm.Relocatable = bc.format == "obj"
+	asm.Target = bc.device
+	asm.SetSyntax(bc.syntax)
+
 	err = asm.Run()
 
+	if err != nil && len(bc.inputs) > 1 {
+		exit(fmt.Errorf("%v: %v", bc.input, err))
+	}
+
 	if err != nil {
 		exit(err)
 	}
 
 	asm.Close()
+
+	if bc.format != "bin" {
+		obj := asm.Object()
+
+		if bc.debug {
+			obj.Debug = asm.DebugInfo()
+			obj.Debug.Dir, _ = os.Getwd()
+		}
+
+		var buf bytes.Buffer
+		err = obj.WriteELF(&buf)
+
+		if err != nil {
+			exit(err)
+		}
+
+		writeFile(bc.output, buf.Bytes())
+	}
+
+	if bc.listing != "" {
+		bc.writeListing(asm, pp.Source())
+	}
+
+	if bc.mapfile != "" {
+		var buf bytes.Buffer
+		err = asm.WriteMap(&buf, bc.input)
+
+		if err != nil {
+			exit(err)
+		}
+
+		writeFile(bc.mapfile, buf.Bytes())
+	}
+}
+
+/*
+Write the listing of the source, with included files in place
+of the lines including them
+*/
+func (bc *BuildCommand) writeListing(asm *assembler.Assembler, lines []string) {
+	var buf bytes.Buffer
+	err := asm.WriteListing(&buf, bc.input, lines)
+
+	if err != nil {
+		exit(err)
+	}
+
+	writeFile(bc.listing, buf.Bytes())
+}
+
+func writeFile(name string, data []byte) {
+	writer, err := handler.NewFileWriter(name)
+
+	if err != nil {
+		exit(err)
+	}
+
+	err = writer.Write(data)
+
+	if err != nil {
+		exit(err)
+	}
+
+	writer.Close()
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/silaspace/aria/assembler"
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/disassembler"
+	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/object"
+	"github.com/silaspace/aria/preprocessor"
+)
+
+type CyclesCommand struct {
+	defines  Defines
+	device   string
+	from     string
+	includes Includes
+	input    string
+	syntax   Syntax
+	to       string
+}
+
+func NewCyclesCommand(rawArgs []string) *CyclesCommand {
+	cc := &CyclesCommand{}
+
+	// Parse command line arguments
+	fs := flag.NewFlagSet("cycles", flag.ContinueOnError)
+
+	fs.StringVar(&cc.device, "device", "", "target device, checked against .device")
+	fs.StringVar(&cc.device, "m", "", "target device, checked against .device (shorthand)")
+
+	fs.Var(&cc.syntax, "syntax", "dialect of the source, avrasm or gnu")
+
+	fs.Var(&cc.defines, "D", "define a symbol as NAME or NAME=expr (repeatable)")
+
+	fs.Var(&cc.includes, "I", "search a directory for #include files (repeatable)")
+
+	args, err := ParseArgs(fs, rawArgs)
+
+	if err != nil {
+		exit(err)
+	}
+
+	switch {
+	case len(args) < 2:
+		exit(fmt.Errorf("expected an input file and labels as from..to"))
+
+	case len(args) > 2:
+		exit(fmt.Errorf("unexpected arguments %+v", args[2:]))
+	}
+
+	from, to, ok := strings.Cut(args[1], "..")
+
+	if !ok || from == "" || to == "" {
+		exit(fmt.Errorf("expected labels as from..to, got '%v'", args[1]))
+	}
+
+	// Return command
+	cc.device = strings.ToLower(cc.device)
+	cc.from = from
+	cc.input = args[0]
+	cc.to = to
+	return cc
+}
+
+func (cc *CyclesCommand) Run() {
+	program, dev, err := cc.program()
+
+	if err != nil {
+		exit(err)
+	}
+
+	dis := disassembler.NewDisassembler(dev)
+	addLabels(dis, program)
+	dis.Disassemble(program.Image(object.Code, 0xFF))
+
+	from, err := findLabel(dis, cc.from)
+
+	if err != nil {
+		exit(err)
+	}
+
+	to, err := findLabel(dis, cc.to)
+
+	if err != nil {
+		exit(err)
+	}
+
+	cycles, err := dis.Cycles(from, to)
+
+	if err != nil {
+		exit(err)
+	}
+
+	fmt.Printf("%v (0x%04X) to %v (0x%04X)\n", cc.from, from, cc.to, to)
+	fmt.Printf("  min  %v cycles\n", cycles.Min)
+
+	if cycles.Bounded {
+		fmt.Printf("  max  %v cycles\n", cycles.Max)
+	} else {
+		fmt.Printf("  max  unbounded, as the code between them can loop\n")
+	}
+}
+
+/*
+Assemble the input if it is source, or read it if it is an
+assembled program, along with the device it is for
+*/
+func (cc *CyclesCommand) program() (*object.File, *device.Device, error) {
+	switch filepath.Ext(cc.input) {
+	case "", string(AsmExt):
+		break
+
+	default:
+		data, err := os.ReadFile(cc.input)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		program, err := readProgram(cc.input, data)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		dev, err := programDevice(cc.input, cc.device, program)
+		return program, dev, err
+	}
+
+	pp, err := preprocess(cc.input, language.Syntax(cc.syntax), cc.defines, cc.includes)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	asm, err := assembleSource(cc.input, pp, cc.syntax, cc.defines, cc.device)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Source is assembled for the default device unless it names one
+	return asm.Object(), &asm.Device, nil
+}
+
+/*
+Assemble preprocessed source in memory, for the commands that
+go on to analyse or run it
+*/
+func assembleSource(input string, pp *preprocessor.Preprocessor, syntax Syntax, defines Defines, target string) (*assembler.Assembler, error) {
+	asm := newSourceAssembler(input, pp, syntax, defines, target)
+	err := asm.Run()
+
+	if err != nil {
+		return nil, err
+	}
+
+	asm.Close()
+	return asm, nil
+}
+
+/*
+An assembler of preprocessed source, writing to memory
+*/
+func newSourceAssembler(input string, pp *preprocessor.Preprocessor, syntax Syntax, defines Defines, target string) *assembler.Assembler {
+	asm := assembler.NewAssembler(pp, handler.NewWebWriter())
+	asm.Defines = defines
+	asm.File = input
+	asm.Lines = pp
+	asm.Target = target
+	asm.SetSyntax(language.Syntax(syntax))
+	return asm
+}
+
+/*
+Word address of a label of the program
+*/
+func findLabel(dis *disassembler.Disassembler, name string) (uint64, error) {
+	for addr, label := range dis.Labels {
+		if strings.EqualFold(label, name) {
+			return addr, nil
+		}
+	}
+
+	return 0, fmt.Errorf("label '%v' not found in code", name)
+}
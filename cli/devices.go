@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/language"
+)
+
+type DevicesCommand struct {
+	name string
+	json bool
+}
+
+type DeviceSummary struct {
+	Name   string `json:"name"`
+	Core   string `json:"core"`
+	Flash  uint64 `json:"flash"`
+	SRAM   uint64 `json:"sram"`
+	EEPROM uint64 `json:"eeprom"`
+}
+
+type DeviceDetails struct {
+	DeviceSummary
+	SRAMStart    uint64          `json:"sramStart"`
+	Features     []string        `json:"features"`
+	Vectors      []device.Vector `json:"vectors"`
+	Instructions []string        `json:"instructions"`
+	Symbols      []device.Symbol `json:"symbols"`
+}
+
+func NewDevicesCommand(rawArgs []string) *DevicesCommand {
+	dc := &DevicesCommand{}
+
+	// Parse command line arguments
+	fs := flag.NewFlagSet("devices", flag.ContinueOnError)
+	fs.BoolVar(&dc.json, "json", false, "output the devices as json")
+
+	args, err := ParseArgs(fs, rawArgs)
+
+	if err != nil {
+		exit(err)
+	}
+
+	switch len(args) {
+	case 0:
+		break
+
+	case 1:
+		dc.name = strings.ToLower(args[0])
+
+	default:
+		exit(fmt.Errorf("unexpected arguments %+v", args))
+	}
+
+	// Return command
+	return dc
+}
+
+func (dc *DevicesCommand) Run() {
+	if dc.name == "" {
+		dc.list()
+		return
+	}
+
+	dev, err := device.NewDevice(dc.name)
+
+	if err != nil || dev.Name == device.DEFAULT {
+		exit(fmt.Errorf("unrecognised device '%v' - try 'aria devices' for a list", dc.name))
+	}
+
+	dc.describe(dev)
+}
+
+func (dc *DevicesCommand) list() {
+	summaries := []DeviceSummary{}
+
+	for _, dev := range device.Devices() {
+		summaries = append(summaries, Summarise(&dev))
+	}
+
+	if dc.json {
+		printJSON(summaries)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCORE\tFLASH\tSRAM\tEEPROM")
+
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", s.Name, s.Core, s.Flash, s.SRAM, s.EEPROM)
+	}
+
+	w.Flush()
+}
+
+func (dc *DevicesCommand) describe(dev *device.Device) {
+	details := DeviceDetails{
+		DeviceSummary: Summarise(dev),
+		SRAMStart:     uint64(dev.RAMStart),
+		Features:      dev.Flags.Features(),
+		Vectors:       dev.Vectors,
+		Instructions:  []string{},
+		Symbols:       dev.Symbols(),
+	}
+
+	for _, mn := range language.InstructionSet(dev) {
+		details.Instructions = append(details.Instructions, string(mn))
+	}
+
+	if dc.json {
+		printJSON(details)
+		return
+	}
+
+	fmt.Printf("%v (%v core)\n\n", dev.Name, dev.DeviceCore)
+
+	// Memory map
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "Memory map")
+	fmt.Fprintf(w, "  flash\t0x%04X - 0x%04X\t%v bytes\n", 0, details.Flash-1, details.Flash)
+	fmt.Fprintf(w, "  registers\t0x%04X - 0x%04X\t%v bytes\n", 0x00, 0x1F, 32)
+	fmt.Fprintf(w, "  i/o\t0x%04X - 0x%04X\t%v bytes\n", 0x20, details.SRAMStart-1, details.SRAMStart-0x20)
+	fmt.Fprintf(w, "  sram\t0x%04X - 0x%04X\t%v bytes\n", details.SRAMStart, details.SRAMStart+details.SRAM-1, details.SRAM)
+	fmt.Fprintf(w, "  eeprom\t0x%04X - 0x%04X\t%v bytes\n", 0, details.EEPROM-1, details.EEPROM)
+	w.Flush()
+
+	// Features
+	fmt.Println("\nFeatures")
+
+	if len(details.Features) == 0 {
+		fmt.Println("  none")
+	}
+
+	for _, feature := range details.Features {
+		fmt.Printf("  %v\n", feature)
+	}
+
+	// Vector table
+	fmt.Println("\nInterrupt vectors")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+
+	for _, vec := range details.Vectors {
+		fmt.Fprintf(w, "  0x%04X\t%v\n", vec.Address, vec.Name)
+	}
+
+	w.Flush()
+
+	// Instruction set, wrapped to a fixed width
+	fmt.Println("\nInstruction set")
+	line := " "
+
+	for _, mn := range details.Instructions {
+		if len(line)+len(mn)+1 > 72 {
+			fmt.Println(line)
+			line = " "
+		}
+
+		line += " " + mn
+	}
+
+	fmt.Println(line)
+
+	// Predefined symbols
+	fmt.Println("\nPredefined symbols")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+
+	for _, sym := range details.Symbols {
+		fmt.Fprintf(w, "  %v\t0x%04X\n", sym.Name, sym.Value)
+	}
+
+	w.Flush()
+}
+
+func Summarise(dev *device.Device) DeviceSummary {
+	return DeviceSummary{
+		Name:   string(dev.Name),
+		Core:   dev.DeviceCore.String(),
+		Flash:  uint64(dev.FlashSize) * 2,
+		SRAM:   uint64(dev.RAMSize),
+		EEPROM: uint64(dev.EEPROMSize),
+	}
+}
+
+func printJSON(v interface{}) {
+	out, err := json.MarshalIndent(v, "", "  ")
+
+	if err != nil {
+		exit(err)
+	}
+
+	fmt.Println(string(out))
+}
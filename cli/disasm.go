@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/disassembler"
+	"github.com/silaspace/aria/object"
+)
+
+type DisasmCommand struct {
+	device string
+	input  string
+	labels bool
+	output string
+}
+
+func NewDisasmCommand(rawArgs []string) *DisasmCommand {
+	dc := &DisasmCommand{}
+
+	// Parse command line arguments
+	fs := flag.NewFlagSet("disasm", flag.ContinueOnError)
+
+	fs.StringVar(&dc.output, "output", "", "output filename, or the terminal if not given")
+	fs.StringVar(&dc.output, "o", "", "output filename, or the terminal if not given (shorthand)")
+
+	fs.StringVar(&dc.device, "device", "", "device whose instruction set is decoded")
+	fs.StringVar(&dc.device, "m", "", "device whose instruction set is decoded (shorthand)")
+
+	fs.BoolVar(&dc.labels, "labels", false, "label the targets of jumps, calls and branches")
+
+	args, err := ParseArgs(fs, rawArgs)
+
+	if err != nil {
+		exit(err)
+	}
+
+	switch {
+	case len(args) == 0:
+		exit(fmt.Errorf("missing input file"))
+
+	case len(args) > 1:
+		exit(fmt.Errorf("unexpected arguments %+v", args[1:]))
+	}
+
+	// Return command
+	dc.device = strings.ToLower(dc.device)
+	dc.input = args[0]
+	return dc
+}
+
+func (dc *DisasmCommand) Run() {
+	data, err := os.ReadFile(dc.input)
+
+	if err != nil {
+		exit(err)
+	}
+
+	program, err := readProgram(dc.input, data)
+
+	if err != nil {
+		exit(fmt.Errorf("%v: %v", dc.input, err))
+	}
+
+	dev, err := programDevice(dc.input, dc.device, program)
+
+	if err != nil {
+		exit(err)
+	}
+
+	dis := disassembler.NewDisassembler(dev)
+	dis.Named = dc.labels
+	addLabels(dis, program)
+	dis.Disassemble(program.Image(object.Code, 0xFF))
+
+	var buf bytes.Buffer
+	err = dis.Write(&buf, filepath.Base(dc.input))
+
+	if err != nil {
+		exit(err)
+	}
+
+	if dc.output == "" {
+		fmt.Print(buf.String())
+		return
+	}
+
+	writeFile(dc.output, buf.Bytes())
+}
+
+/*
+Name the addresses in code of the symbols of a program
+*/
+func addLabels(dis *disassembler.Disassembler, program *object.File) {
+	for _, sym := range program.Symbols {
+		index := program.SectionIndex(sym.Section)
+
+		if index == -1 || program.Sections[index].Kind != object.Code || sym.Kind == object.Object {
+			continue
+		}
+
+		address := sym.Value
+
+		if program.Relocatable {
+			address += program.Sections[index].Address
+		}
+
+		if address%2 == 0 {
+			dis.AddLabel(address/2, sym.Name)
+		}
+	}
+}
+
+/*
+The device given on the command line, or else the one an ELF
+file records. Intel HEX and binary images record no device, so
+one must be given for them.
+*/
+func programDevice(input string, target string, program *object.File) (*device.Device, error) {
+	name := target
+
+	if name == "" {
+		name = program.Device
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("%v does not record a device, give one with -m", input)
+	}
+
+	return device.NewDevice(name)
+}
+
+/*
+Read a program from an ELF file, Intel HEX, or a binary image
+of flash such as aria builds by default
+*/
+func readProgram(name string, data []byte) (*object.File, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x7fELF")):
+		return object.ReadProgram(bytes.NewReader(data))
+
+	case bytes.HasPrefix(data, []byte(":")) && filepath.Ext(name) != ".bin":
+		return object.ReadHex(bytes.NewReader(data))
+
+	default:
+		return object.ReadBinary(data), nil
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+
+	"github.com/silaspace/aria/assembler"
+	"github.com/silaspace/aria/language"
 )
 
 type FileExt string
@@ -13,14 +16,116 @@ const (
 	NoExt  FileExt = ""
 	AsmExt FileExt = ".s"
 	HexExt FileExt = ".hex"
+	ElfExt FileExt = ".elf"
+	ObjExt FileExt = ".o"
 	TxtExt FileExt = ".txt"
 )
 
 type Flags struct {
-	FlagSet flag.FlagSet
-	Input   string
-	Output  string
-	Verbose bool
+	FlagSet  *flag.FlagSet
+	Debug    bool
+	Defines  Defines
+	Device   string
+	Format   string
+	Includes Includes
+	Input    string
+	Inputs   []string
+	Listing  string
+	Map      string
+	Multiple bool /* Accept more than one input file */
+	Output   string
+	Relax    bool
+	Syntax   Syntax
+	Verbose  bool
+}
+
+/*
+Repeatable -D NAME[=expr] symbol definitions
+*/
+type Defines []assembler.Define
+
+func (d *Defines) String() string {
+	defs := []string{}
+
+	for _, def := range *d {
+		defs = append(defs, def.Name+"="+def.Value)
+	}
+
+	return strings.Join(defs, " ")
+}
+
+func (d *Defines) Set(value string) error {
+	name, expr, _ := strings.Cut(value, "=")
+
+	if name == "" {
+		return fmt.Errorf("missing symbol name in definition '%v'", value)
+	}
+
+	*d = append(*d, assembler.Define{
+		Name:  name,
+		Value: expr,
+	})
+
+	return nil
+}
+
+/*
+Repeatable -I dir directories searched for included files
+*/
+type Includes []string
+
+func (i *Includes) String() string {
+	return strings.Join(*i, " ")
+}
+
+func (i *Includes) Set(value string) error {
+	*i = append(*i, value)
+	return nil
+}
+
+/*
+Names of I/O registers, given as a comma separated list that
+may be repeated
+*/
+type Registers []string
+
+func (r *Registers) String() string {
+	return strings.Join(*r, ",")
+}
+
+func (r *Registers) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+
+		if name == "" {
+			return fmt.Errorf("missing register name in '%v'", value)
+		}
+
+		*r = append(*r, name)
+	}
+
+	return nil
+}
+
+/*
+The dialect of the source given by --syntax, avrasm or gnu
+*/
+type Syntax language.Syntax
+
+func (s *Syntax) String() string {
+	for name, syntax := range language.Syntaxes {
+		if syntax == language.Syntax(*s) {
+			return name
+		}
+	}
+
+	return ""
+}
+
+func (s *Syntax) Set(value string) error {
+	syntax, err := language.GetSyntax(value)
+	*s = Syntax(syntax)
+	return err
 }
 
 func NewFlags(name string) *Flags {
@@ -28,12 +133,35 @@ func NewFlags(name string) *Flags {
 	fs := flag.NewFlagSet(name, flag.ContinueOnError)
 
 	flags := &Flags{
-		FlagSet: *fs,
+		FlagSet: fs,
 	}
 
 	fs.StringVar(&flags.Output, "output", "", "output filename")
 	fs.StringVar(&flags.Output, "o", "", "output filename (shorthand)")
 
+	fs.StringVar(&flags.Format, "format", "bin", "output format, bin, elf or obj")
+	fs.StringVar(&flags.Format, "f", "bin", "output format, bin, elf or obj (shorthand)")
+
+	fs.BoolVar(&flags.Debug, "debug", false, "include debug information in elf output")
+	fs.BoolVar(&flags.Debug, "g", false, "include debug information in elf output (shorthand)")
+
+	fs.StringVar(&flags.Listing, "listing", "", "write a listing file")
+	fs.StringVar(&flags.Listing, "l", "", "write a listing file (shorthand)")
+
+	fs.StringVar(&flags.Map, "map", "", "write a map file")
+	fs.StringVar(&flags.Map, "M", "", "write a map file (shorthand)")
+
+	fs.StringVar(&flags.Device, "device", "", "target device, checked against .device")
+	fs.StringVar(&flags.Device, "m", "", "target device, checked against .device (shorthand)")
+
+	fs.BoolVar(&flags.Relax, "relax", false, "choose the shortest jumps, calls and branches that reach their targets")
+
+	fs.Var(&flags.Syntax, "syntax", "dialect of the source, avrasm or gnu")
+
+	fs.Var(&flags.Defines, "D", "define a symbol as NAME or NAME=expr (repeatable)")
+
+	fs.Var(&flags.Includes, "I", "search a directory for #include files (repeatable)")
+
 	fs.BoolVar(&flags.Verbose, "verbose", false, "verbosity of the assembler")
 	fs.BoolVar(&flags.Verbose, "v", false, "verbosity of the assembler (shorthand)")
 
@@ -41,32 +169,35 @@ func NewFlags(name string) *Flags {
 }
 
 func (f *Flags) Parse(rawArgs []string) error {
-	// Parse flags
-	err := f.FlagSet.Parse(rawArgs)
+	// Parse flags and arguments
+	args, err := ParseArgs(f.FlagSet, rawArgs)
 
 	if err != nil {
 		return err
 	}
 
-	// Parse arguents
-	args := f.FlagSet.Args()
+	if len(args) == 0 {
+		return fmt.Errorf("missing input file")
+	}
 
-	if len(args) != 1 {
+	if len(args) > 1 && !f.Multiple {
 		return fmt.Errorf("unexpected arguments %+v", args)
 	}
 
-	// Test inputfile
-	input := args[0]
-	ext := filepath.Ext(input)
+	// Test inputfiles
+	for _, input := range args {
+		ext := filepath.Ext(input)
 
-	switch ext {
-	case "", ".s":
-		f.Input = input
+		switch ext {
+		case "", ".s":
+			f.Inputs = append(f.Inputs, input)
 
-	default:
-		return fmt.Errorf("unknown file extension %v", ext)
+		default:
+			return fmt.Errorf("unknown file extension %v", ext)
+		}
 	}
 
+	f.Input = f.Inputs[0]
 	return nil
 }
 
@@ -77,6 +208,35 @@ func (f *Flags) SetOutput(ext FileExt) error {
 		output = filepath.Base(f.Input)
 	}
 
-	f.Output = strings.Split(output, ".")[0] + string(ext)
+	f.Output = ReplaceExt(output, ext)
 	return nil
 }
+
+func ReplaceExt(name string, ext FileExt) string {
+	return strings.Split(name, ".")[0] + string(ext)
+}
+
+/*
+Parse flags and arguments in any order, since the flag
+package stops parsing at the first non-flag argument
+*/
+func ParseArgs(fs *flag.FlagSet, rawArgs []string) ([]string, error) {
+	args := []string{}
+
+	for {
+		err := fs.Parse(rawArgs)
+
+		if err != nil {
+			return args, err
+		}
+
+		rest := fs.Args()
+
+		if len(rest) == 0 {
+			return args, nil
+		}
+
+		args = append(args, rest[0])
+		rawArgs = rest[1:]
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/silaspace/aria/formatter"
+	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/language"
+)
+
+type FmtCommand struct {
+	check    bool
+	defines  Defines
+	device   string
+	includes Includes
+	inputs   []string
+	syntax   Syntax
+	write    bool
+}
+
+func NewFmtCommand(rawArgs []string) *FmtCommand {
+	fc := &FmtCommand{}
+
+	// Parse command line arguments
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+
+	fs.BoolVar(&fc.write, "w", false, "write the formatted source back to each file")
+
+	fs.BoolVar(&fc.check, "check", false, "list the files which are not formatted, failing if there are any")
+
+	fs.StringVar(&fc.device, "device", "", "target device, checked against .device")
+	fs.StringVar(&fc.device, "m", "", "target device, checked against .device (shorthand)")
+
+	fs.Var(&fc.syntax, "syntax", "dialect of the source, avrasm or gnu")
+
+	fs.Var(&fc.defines, "D", "define a symbol as NAME or NAME=expr (repeatable)")
+
+	fs.Var(&fc.includes, "I", "search a directory for #include files (repeatable)")
+
+	args, err := ParseArgs(fs, rawArgs)
+
+	if err != nil {
+		exit(err)
+	}
+
+	if len(args) == 0 {
+		exit(fmt.Errorf("no input files"))
+	}
+
+	if fc.write && fc.check {
+		exit(fmt.Errorf("-w and --check cannot be used together"))
+	}
+
+	// Return command
+	fc.device = strings.ToLower(fc.device)
+	fc.inputs = args
+	return fc
+}
+
+func (fc *FmtCommand) Run() {
+	files, err := fc.files()
+
+	if err != nil {
+		exit(err)
+	}
+
+	unformatted := false
+
+	for _, file := range files {
+		source, formatted, err := fc.format(file)
+
+		if err != nil {
+			exit(err)
+		}
+
+		switch {
+		case fc.check && formatted != source:
+			fmt.Println(file)
+			unformatted = true
+
+		case fc.write && formatted != source:
+			err = os.WriteFile(file, []byte(formatted), 0644)
+
+			if err != nil {
+				exit(err)
+			}
+
+		case !fc.check && !fc.write:
+			fmt.Print(formatted)
+		}
+	}
+
+	if unformatted {
+		exit(fmt.Errorf("files are not formatted"))
+	}
+}
+
+/*
+Files given, and source files in the directories given
+*/
+func (fc *FmtCommand) files() ([]string, error) {
+	files := []string{}
+
+	for _, input := range fc.inputs {
+		info, err := os.Stat(input)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, input)
+			continue
+		}
+
+		err = filepath.Walk(input, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && filepath.Ext(path) == string(AsmExt) {
+				files = append(files, path)
+			}
+
+			return err
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+/*
+The source of a file and the source formatted. A file which
+assembles must assemble to the same program once formatted.
+*/
+func (fc *FmtCommand) format(file string) (string, string, error) {
+	data, err := os.ReadFile(file)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	source := string(data)
+	formatted := formatter.NewFormatter(language.Syntax(fc.syntax)).Format(source)
+
+	if formatted == source {
+		return source, formatted, nil
+	}
+
+	before, err := fc.assemble(file, source)
+
+	// Source which does not assemble has no program to keep the same
+	if err != nil {
+		return source, formatted, nil
+	}
+
+	after, err := fc.assemble(file, formatted)
+
+	if err != nil || !bytes.Equal(before, after) {
+		return "", "", fmt.Errorf("formatting %v would change the program it assembles to", file)
+	}
+
+	return source, formatted, nil
+}
+
+/*
+The program assembled from the source of a file, as it would
+be written to an object file
+*/
+func (fc *FmtCommand) assemble(file string, source string) ([]byte, error) {
+	reader := handler.NewWebReader()
+	reader.Write([]byte(source))
+
+	pp, err := preprocessFrom(file, reader, language.Syntax(fc.syntax), fc.defines, fc.includes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	asm, err := assembleSource(file, pp, fc.syntax, fc.defines, fc.device)
+
+	if err != nil {
+		return nil, err
+	}
+
+	program := &bytes.Buffer{}
+	err = asm.Object().WriteELF(program)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return program.Bytes(), nil
+}
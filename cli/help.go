@@ -6,15 +6,126 @@ import (
 
 const helptext = `
 
-usage: aria command [options] inputfile
+usage: aria command [options] inputfile...
 	command:
 		lex		Output the tokenised input in a text file
 		parse	Output the parsed syntax trees in a text file
-		build	Fully assemble the input into a hex file
+		build	Fully assemble the input into a hex file, or each
+			input into a relocatable object with --format obj
 		help	Print this help menu
 	options:
 		-o, --output	Set the output file manually
+		-f, --format	Set the output format of build, bin, elf or obj
+		-g, --debug	Include line number debug information in elf output
+		-l, --listing	Write a listing of the assembled source to a file
+		-M, --map	Write the symbols and memory usage to a map file
 		-v, --verbose	Increase the verbosity of the terminal output
+		-m, --device	Set the target device, checked against .device
+		-D NAME[=expr]	Define a symbol before assembling, may be repeated
+		-I dir		Search a directory for files given to #include,
+				may be repeated
+		--relax		Use rjmp or jmp and rcall or call, whichever reaches,
+				and invert branches over a jump when out of range
+		--syntax	Read the source as avrasm, the default, or as gnu
+				for the syntax of avr-as
+
+usage: aria link [options] object...
+	Link relocatable objects into a single program
+	options:
+		-o, --output	Set the output file manually
+		-f, --format	Set the output format, bin or elf
+		-m, --device	Set the target device, checked against the objects
+		-T, --layout	Place sections in memory as given by a layout file
+
+usage: aria disasm [options] program
+	Disassemble a program in elf, Intel HEX or binary into source
+	that can be assembled again
+	options:
+		-o, --output	Write the source to a file rather than the terminal
+		-m, --device	Decode the instruction set of a device
+		--labels	Label the targets of jumps, calls and branches
+
+usage: aria sim [options] program
+	Run a program in elf, Intel HEX or binary on a simulated device
+	until it reaches break or sleep, then show its registers.
+	GPIO ports, timers and interrupts are simulated, and the
+	USART sends to stdout and receives from stdin
+	options:
+		-m, --device	Simulate a device other than the one in the program
+		--cycles	Stop after a number of cycles (default 1000000)
+		--gdb		Serve the gdb remote protocol at an address such as
+				:1234, for avr-gdb to connect with target remote
+		--vcd		Write a value change dump of the PC, SREG flags
+				and port pins, for viewers such as GTKWave
+		--vcd-io	Add I/O registers to the dump, as in PORTB,TCNT0
+		--fcpu		Clock frequency in Hz for the dump (default 1000000)
+
+usage: aria cycles [options] input from..to
+	Report the fewest and most cycles taken from one label to
+	another, following calls, in source or an elf program
+	options:
+		-m, --device	Set the target device, checked against .device
+		-D NAME[=expr]	Define a symbol before assembling, may be repeated
+		-I dir		Search a directory for files given to #include
+		--syntax	Read the source as avrasm or gnu
+
+usage: aria test [options] [file or directory...]
+	Run the tests of assembly routines, in the files given or in
+	files ending _test.s in the directories given, by default the
+	current one. Each .test names a routine to call, which is run
+	with the state set up by the .given lines after it and checked
+	by the .expect lines, as in
+
+		.test add8 "carry out"
+		.given r24 = 0xFF
+		.given [buf] = "abc", 0
+		.expect r25:r24 == 0x0100
+		.expect SREG.C == 1
+		.expect cycles <= 10
+
+	Registers, pairs such as r25:r24 or X, SREG, its flags as in
+	SREG.Z, SP and memory as in [buf] may be given and expected,
+	and cycles expected. Values are expressions of the source.
+	options:
+		-m, --device	Set the target device, checked against .device
+		-D NAME[=expr]	Define a symbol before assembling, may be repeated
+		-I dir		Search a directory for files given to #include
+		--syntax	Read the source as avrasm or gnu
+		--cycles	Fail a routine running this many cycles (default 1000000)
+		--json		Output the results as json
+
+usage: aria lsp [options]
+	Serve the Language Server Protocol over stdin and stdout, for
+	editors to show errors as source is written, go to where
+	labels, .equ and .def symbols are defined and find where they
+	are used, describe instructions, symbols and the code of each
+	line on hover, and complete mnemonics, directives, registers
+	and the I/O registers of the device
+	options:
+		-m, --device	Set the target device, checked against .device
+		-D NAME[=expr]	Define a symbol before assembling, may be repeated
+		-I dir		Search a directory for files given to #include
+		--syntax	Read the source as avrasm or gnu
+
+usage: aria fmt [options] file or directory...
+	Format assembly source, or the .s files in the directories
+	given, writing it to stdout. Labels are put at the margin,
+	mnemonics, operands and trailing comments in columns, and
+	mnemonics written in lowercase and hex digits in uppercase.
+	Lines that cannot be parsed are left as they are. Source which
+	assembles must assemble to the same program once formatted.
+	options:
+		-w		Write the formatted source back to each file
+		--check		List files which are not formatted, failing if any
+		-m, --device	Set the target device, checked against .device
+		-D NAME[=expr]	Define a symbol before assembling, may be repeated
+		-I dir		Search a directory for files given to #include
+		--syntax	Read the source as avrasm or gnu
+
+usage: aria devices [options] [device]
+	List the supported devices, or describe a single device
+	options:
+		--json		Output the devices as json
 
 `
 
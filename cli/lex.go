@@ -1,14 +1,19 @@
 package main
 
 import (
+	"github.com/silaspace/aria/assembler"
 	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/language"
 	"github.com/silaspace/aria/lexer"
 )
 
 type LexCommand struct {
-	input   string
-	output  string
-	verbose bool
+	defines  []assembler.Define
+	includes []string
+	input    string
+	output   string
+	syntax   language.Syntax
+	verbose  bool
 }
 
 func NewLexCommand(rawArgs []string) *LexCommand {
@@ -28,14 +33,17 @@ func NewLexCommand(rawArgs []string) *LexCommand {
 
 	// Return command
 	return &LexCommand{
-		input:   flags.Input,
-		output:  flags.Output,
-		verbose: flags.Verbose,
+		defines:  flags.Defines,
+		includes: flags.Includes,
+		input:    flags.Input,
+		output:   flags.Output,
+		syntax:   language.Syntax(flags.Syntax),
+		verbose:  flags.Verbose,
 	}
 }
 
 func (lc *LexCommand) Run() {
-	reader, err := handler.NewFileReader(lc.input)
+	reader, err := preprocess(lc.input, lc.syntax, lc.defines, lc.includes)
 
 	if err != nil {
 		exit(err)
@@ -48,6 +56,7 @@ func (lc *LexCommand) Run() {
 	}
 
 	lex := lexer.NewLexer(reader)
+	lex.Syntax = lc.syntax
 
 	for {
 		nextToken := lex.Next()
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/silaspace/aria/linker"
+	"github.com/silaspace/aria/object"
+)
+
+type LinkCommand struct {
+	device  string
+	format  string
+	inputs  []string
+	layout  string
+	output  string
+	verbose bool
+}
+
+func NewLinkCommand(rawArgs []string) *LinkCommand {
+	lc := &LinkCommand{}
+
+	// Parse command line arguments
+	fs := flag.NewFlagSet("link", flag.ContinueOnError)
+
+	fs.StringVar(&lc.output, "output", "", "output filename")
+	fs.StringVar(&lc.output, "o", "", "output filename (shorthand)")
+
+	fs.StringVar(&lc.format, "format", "bin", "output format, bin or elf")
+	fs.StringVar(&lc.format, "f", "bin", "output format, bin or elf (shorthand)")
+
+	fs.StringVar(&lc.device, "device", "", "target device, checked against the objects")
+	fs.StringVar(&lc.device, "m", "", "target device, checked against the objects (shorthand)")
+
+	fs.StringVar(&lc.layout, "layout", "", "layout file placing sections in memory")
+	fs.StringVar(&lc.layout, "T", "", "layout file placing sections in memory (shorthand)")
+
+	fs.BoolVar(&lc.verbose, "verbose", false, "verbosity of the linker")
+	fs.BoolVar(&lc.verbose, "v", false, "verbosity of the linker (shorthand)")
+
+	args, err := ParseArgs(fs, rawArgs)
+
+	if err != nil {
+		exit(err)
+	}
+
+	if len(args) == 0 {
+		exit(fmt.Errorf("missing input file"))
+	}
+
+	for _, input := range args {
+		if ext := filepath.Ext(input); ext != string(ObjExt) {
+			exit(fmt.Errorf("unknown file extension %v", ext))
+		}
+	}
+
+	ext := HexExt
+
+	switch lc.format {
+	case "bin":
+		break
+
+	case "elf":
+		ext = ElfExt
+
+	default:
+		exit(fmt.Errorf("unknown output format '%v'", lc.format))
+	}
+
+	if lc.output == "" {
+		lc.output = ReplaceExt(filepath.Base(args[0]), ext)
+	}
+
+	// Return command
+	lc.device = strings.ToLower(lc.device)
+	lc.inputs = args
+	return lc
+}
+
+func (lc *LinkCommand) Run() {
+	ld := linker.NewLinker(lc.device)
+
+	if lc.layout != "" {
+		script, err := os.ReadFile(lc.layout)
+
+		if err != nil {
+			exit(err)
+		}
+
+		ld.Script = string(script)
+	}
+
+	for _, input := range lc.inputs {
+		f, err := os.Open(input)
+
+		if err != nil {
+			exit(err)
+		}
+
+		obj, err := object.ReadELF(f)
+		f.Close()
+
+		if err != nil {
+			exit(fmt.Errorf("%v: %v", input, err))
+		}
+
+		err = ld.Add(input, obj)
+
+		if err != nil {
+			exit(err)
+		}
+	}
+
+	out, err := ld.Link()
+
+	if err != nil {
+		exit(err)
+	}
+
+	// Gaps between sections are left as erased flash
+	if lc.format == "bin" {
+		writeFile(lc.output, out.Image(object.Code, 0xFF))
+		return
+	}
+
+	var buf bytes.Buffer
+	err = out.WriteELF(&buf)
+
+	if err != nil {
+		exit(err)
+	}
+
+	writeFile(lc.output, buf.Bytes())
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/silaspace/aria/assembler"
+	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/lsp"
+)
+
+type LspCommand struct {
+	defines  Defines
+	device   string
+	includes Includes
+	syntax   Syntax
+}
+
+func NewLspCommand(rawArgs []string) *LspCommand {
+	lc := &LspCommand{}
+
+	// Parse command line arguments
+	fs := flag.NewFlagSet("lsp", flag.ContinueOnError)
+
+	fs.StringVar(&lc.device, "device", "", "target device, checked against .device")
+	fs.StringVar(&lc.device, "m", "", "target device, checked against .device (shorthand)")
+
+	fs.Var(&lc.syntax, "syntax", "dialect of the source, avrasm or gnu")
+
+	fs.Var(&lc.defines, "D", "define a symbol as NAME or NAME=expr (repeatable)")
+
+	fs.Var(&lc.includes, "I", "search a directory for #include files (repeatable)")
+
+	args, err := ParseArgs(fs, rawArgs)
+
+	if err != nil {
+		exit(err)
+	}
+
+	if len(args) > 0 {
+		exit(fmt.Errorf("unexpected arguments %+v", args))
+	}
+
+	// Return command
+	lc.device = strings.ToLower(lc.device)
+	return lc
+}
+
+func (lc *LspCommand) Run() {
+	server := lsp.NewServer(os.Stdin, os.Stdout, lc.analyse)
+	exit(server.Run())
+}
+
+/*
+Assemble the text of a document in the editor, which may differ
+from the file as it is saved
+*/
+func (lc *LspCommand) analyse(file string, source string) (*assembler.Assembler, error) {
+	reader := handler.NewWebReader()
+	reader.Write([]byte(source))
+
+	pp, err := preprocessFrom(file, reader, language.Syntax(lc.syntax), lc.defines, lc.includes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	asm := newSourceAssembler(file, pp, lc.syntax, lc.defines, lc.device)
+	return asm, asm.Run()
+}
@@ -38,6 +38,38 @@ func main() {
 		bc := NewBuildCommand(os.Args[2:])
 		bc.Run()
 
+	case "link":
+		lc := NewLinkCommand(os.Args[2:])
+		lc.Run()
+
+	case "disasm":
+		dc := NewDisasmCommand(os.Args[2:])
+		dc.Run()
+
+	case "sim":
+		sc := NewSimCommand(os.Args[2:])
+		sc.Run()
+
+	case "cycles":
+		cc := NewCyclesCommand(os.Args[2:])
+		cc.Run()
+
+	case "test":
+		tc := NewTestCommand(os.Args[2:])
+		tc.Run()
+
+	case "lsp":
+		lc := NewLspCommand(os.Args[2:])
+		lc.Run()
+
+	case "fmt":
+		fc := NewFmtCommand(os.Args[2:])
+		fc.Run()
+
+	case "devices":
+		dc := NewDevicesCommand(os.Args[2:])
+		dc.Run()
+
 	default:
 		fmt.Printf("Invalid subcommand '%v' - try 'aria help' for more information\n", subcommand)
 	}
@@ -1,15 +1,20 @@
 package main
 
 import (
+	"github.com/silaspace/aria/assembler"
 	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/language"
 	"github.com/silaspace/aria/lexer"
 	"github.com/silaspace/aria/parser"
 )
 
 type ParseCommand struct {
-	input   string
-	output  string
-	verbose bool
+	defines  []assembler.Define
+	includes []string
+	input    string
+	output   string
+	syntax   language.Syntax
+	verbose  bool
 }
 
 func NewParseCommand(rawArgs []string) *ParseCommand {
@@ -29,14 +34,17 @@ func NewParseCommand(rawArgs []string) *ParseCommand {
 
 	// Return command
 	return &ParseCommand{
-		input:   flags.Input,
-		output:  flags.Output,
-		verbose: flags.Verbose,
+		defines:  flags.Defines,
+		includes: flags.Includes,
+		input:    flags.Input,
+		output:   flags.Output,
+		syntax:   language.Syntax(flags.Syntax),
+		verbose:  flags.Verbose,
 	}
 }
 
 func (pc *ParseCommand) Run() {
-	reader, err := handler.NewFileReader(pc.input)
+	reader, err := preprocess(pc.input, pc.syntax, pc.defines, pc.includes)
 
 	if err != nil {
 		exit(err)
@@ -49,6 +57,7 @@ func (pc *ParseCommand) Run() {
 	}
 
 	lex := lexer.NewLexer(reader)
+	lex.Syntax = pc.syntax
 	parse := parser.NewParser(lex)
 
 	for {
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/silaspace/aria/assembler"
+	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/preprocessor"
+)
+
+/*
+Run the preprocessor over a source file. Symbols defined with
+-D can be tested by #ifdef and #if, and included files are
+searched for in the directories given with -I.
+*/
+func preprocess(input string, syntax language.Syntax, defines []assembler.Define, includes []string) (*preprocessor.Preprocessor, error) {
+	reader, err := handler.NewFileReader(input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return preprocessFrom(input, reader, syntax, defines, includes)
+}
+
+/*
+Run the preprocessor over source read from a reader, as though
+it were the named file
+*/
+func preprocessFrom(input string, reader preprocessor.Reader, syntax language.Syntax, defines []assembler.Define, includes []string) (*preprocessor.Preprocessor, error) {
+	pp := preprocessor.NewPreprocessor(openFile)
+	pp.Include = includes
+	pp.Syntax = syntax
+
+	for _, def := range defines {
+		pp.Defined[def.Name] = def.Value
+	}
+
+	err := pp.Process(input, reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pp, nil
+}
+
+func openFile(path string) (preprocessor.Reader, error) {
+	reader, err := handler.NewFileReader(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return reader, nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/silaspace/aria/simulator"
+)
+
+const DEFAULT_CYCLE_LIMIT uint64 = 1000000
+
+/* Clock of a new AVR, its internal 8 MHz oscillator divided by 8 */
+const DEFAULT_F_CPU uint64 = 1000000
+
+type SimCommand struct {
+	cycles    uint64
+	device    string
+	fcpu      uint64
+	gdb       string
+	input     string
+	registers Registers
+	vcd       string
+}
+
+func NewSimCommand(rawArgs []string) *SimCommand {
+	sc := &SimCommand{}
+
+	// Parse command line arguments
+	fs := flag.NewFlagSet("sim", flag.ContinueOnError)
+
+	fs.StringVar(&sc.device, "device", "", "device to simulate")
+	fs.StringVar(&sc.device, "m", "", "device to simulate (shorthand)")
+
+	fs.Uint64Var(&sc.cycles, "cycles", DEFAULT_CYCLE_LIMIT, "number of cycles to stop after")
+
+	fs.StringVar(&sc.gdb, "gdb", "", "serve the gdb remote protocol at a local address such as :1234")
+
+	fs.StringVar(&sc.vcd, "vcd", "", "write a trace of the program to a value change dump")
+
+	fs.Var(&sc.registers, "vcd-io", "trace I/O registers, as a comma separated list (repeatable)")
+
+	fs.Uint64Var(&sc.fcpu, "fcpu", DEFAULT_F_CPU, "clock frequency in Hz, for the times of a trace")
+
+	args, err := ParseArgs(fs, rawArgs)
+
+	if err != nil {
+		exit(err)
+	}
+
+	switch {
+	case len(args) == 0:
+		exit(fmt.Errorf("missing input file"))
+
+	case len(args) > 1:
+		exit(fmt.Errorf("unexpected arguments %+v", args[1:]))
+	}
+
+	// Return command
+	sc.device = strings.ToLower(sc.device)
+	sc.input = args[0]
+	return sc
+}
+
+func (sc *SimCommand) Run() {
+	data, err := os.ReadFile(sc.input)
+
+	if err != nil {
+		exit(err)
+	}
+
+	program, err := readProgram(sc.input, data)
+
+	if err != nil {
+		exit(fmt.Errorf("%v: %v", sc.input, err))
+	}
+
+	dev, err := programDevice(sc.input, sc.device, program)
+
+	if err != nil {
+		exit(err)
+	}
+
+	sim := simulator.NewSimulator(dev)
+	err = sim.LoadFile(program)
+
+	if err != nil {
+		exit(err)
+	}
+
+	sim.Connect(os.Stdin, os.Stdout)
+
+	var trace *simulator.Trace
+
+	if sc.vcd != "" {
+		trace, err = sc.trace(sim)
+
+		if err != nil {
+			exit(err)
+		}
+	}
+
+	if sc.gdb != "" {
+		err = sc.debug(sim)
+	} else {
+		err = sim.Run(sc.cycles)
+		sim.Write(os.Stdout)
+
+		if err != nil {
+			fmt.Println()
+		}
+	}
+
+	// The trace is written out even if the program failed
+	if trace != nil {
+		if cerr := trace.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	if err != nil {
+		exit(err)
+	}
+}
+
+/*
+Start tracing the program to the file given by --vcd
+*/
+func (sc *SimCommand) trace(sim *simulator.Simulator) (*simulator.Trace, error) {
+	file, err := os.Create(sc.vcd)
+
+	if err != nil {
+		return nil, err
+	}
+
+	trace, err := simulator.NewTrace(sim, file, sc.fcpu, sc.registers)
+
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	sim.Attach(trace)
+	trace.Start()
+	return trace, nil
+}
+
+/*
+Wait for avr-gdb to connect, then let it run the program. An
+address without a host is only served on this machine.
+*/
+func (sc *SimCommand) debug(sim *simulator.Simulator) error {
+	addr := sc.gdb
+
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+
+	listener, err := net.Listen("tcp", addr)
+
+	if err != nil {
+		return err
+	}
+
+	defer listener.Close()
+	fmt.Printf("Waiting for gdb at %v\n", listener.Addr())
+
+	conn, err := listener.Accept()
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+	return simulator.NewStub(sim, conn).Serve()
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/tester"
+)
+
+/* Files searched for tests when a directory is given */
+const TEST_SUFFIX string = "_test.s"
+
+type TestCommand struct {
+	cycles   uint64
+	defines  Defines
+	device   string
+	includes Includes
+	inputs   []string
+	json     bool
+	syntax   Syntax
+}
+
+/*
+Results of every test, as written by --json
+*/
+type TestReport struct {
+	Failed int             `json:"failed"`
+	Passed int             `json:"passed"`
+	Tests  []tester.Result `json:"tests"`
+}
+
+func NewTestCommand(rawArgs []string) *TestCommand {
+	tc := &TestCommand{}
+
+	// Parse command line arguments
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+
+	fs.StringVar(&tc.device, "device", "", "target device, checked against .device")
+	fs.StringVar(&tc.device, "m", "", "target device, checked against .device (shorthand)")
+
+	fs.Var(&tc.syntax, "syntax", "dialect of the source, avrasm or gnu")
+
+	fs.Var(&tc.defines, "D", "define a symbol as NAME or NAME=expr (repeatable)")
+
+	fs.Var(&tc.includes, "I", "search a directory for #include files (repeatable)")
+
+	fs.Uint64Var(&tc.cycles, "cycles", tester.DEFAULT_LIMIT, "number of cycles a routine may run for")
+
+	fs.BoolVar(&tc.json, "json", false, "output the results as json")
+
+	args, err := ParseArgs(fs, rawArgs)
+
+	if err != nil {
+		exit(err)
+	}
+
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	// Return command
+	tc.device = strings.ToLower(tc.device)
+	tc.inputs = args
+	return tc
+}
+
+func (tc *TestCommand) Run() {
+	files, err := tc.files()
+
+	if err != nil {
+		exit(err)
+	}
+
+	report := TestReport{
+		Tests: []tester.Result{},
+	}
+
+	for _, file := range files {
+		report.Tests = append(report.Tests, tc.runFile(file)...)
+	}
+
+	if len(report.Tests) == 0 {
+		exit(fmt.Errorf("no tests found"))
+	}
+
+	for _, result := range report.Tests {
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+	}
+
+	if tc.json {
+		printJSON(report)
+	} else {
+		writeResults(report)
+	}
+
+	// Exit without a message, so that the json is all that is written
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+/*
+Files given, and test files in the directories given
+*/
+func (tc *TestCommand) files() ([]string, error) {
+	files := []string{}
+
+	for _, input := range tc.inputs {
+		info, err := os.Stat(input)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, input)
+			continue
+		}
+
+		err = filepath.Walk(input, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && strings.HasSuffix(path, TEST_SUFFIX) {
+				files = append(files, path)
+			}
+
+			return err
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+/*
+Assemble a file without its test directives, and run each of
+its tests. A file that cannot be assembled fails as a whole.
+*/
+func (tc *TestCommand) runFile(file string) []tester.Result {
+	results, err := tc.tryFile(file)
+
+	if err != nil {
+		return []tester.Result{{
+			Failures: []string{err.Error()},
+			File:     file,
+			Name:     filepath.Base(file),
+		}}
+	}
+
+	return results
+}
+
+func (tc *TestCommand) tryFile(file string) ([]tester.Result, error) {
+	data, err := os.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tests, source, err := tester.Parse(string(data))
+
+	if err != nil || len(tests) == 0 {
+		return nil, err
+	}
+
+	reader := handler.NewWebReader()
+	reader.Write([]byte(source))
+
+	pp, err := preprocessFrom(file, reader, language.Syntax(tc.syntax), tc.defines, tc.includes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	asm, err := assembleSource(file, pp, tc.syntax, tc.defines, tc.device)
+
+	if err != nil {
+		return nil, err
+	}
+
+	runner := tester.NewRunner(&asm.Device, asm.Object(), asm.Eval)
+	runner.ByteAddresses = asm.Syntax == language.SYNTAX_GNU
+	runner.File = file
+	runner.Limit = tc.cycles
+
+	results := []tester.Result{}
+
+	for _, test := range tests {
+		results = append(results, runner.Run(test))
+	}
+
+	return results, nil
+}
+
+func writeResults(report TestReport) {
+	for _, result := range report.Tests {
+		status := "PASS"
+
+		if !result.Passed {
+			status = "FAIL"
+		}
+
+		name := result.Name
+
+		if result.Routine != "" && result.Routine != result.Name {
+			name = fmt.Sprintf("%v: %v", result.Routine, result.Name)
+		}
+
+		where := result.File
+
+		if result.Line != 0 {
+			where = fmt.Sprintf("%v:%v", result.File, result.Line)
+		}
+
+		if result.Passed {
+			fmt.Printf("%v  %v  %v (%v cycles)\n", status, where, name, result.Cycles)
+		} else {
+			fmt.Printf("%v  %v  %v\n", status, where, name)
+		}
+
+		for _, failure := range result.Failures {
+			fmt.Printf("      %v\n", failure)
+		}
+	}
+
+	fmt.Printf("\n%v passed, %v failed\n", report.Passed, report.Failed)
+}
@@ -1,16 +1,40 @@
 package device
 
+import (
+	"fmt"
+	"strings"
+)
+
 type DeviceType string
 type Flag int
 type Core int
 
 type Device struct {
+	Name       DeviceType
 	DeviceCore Core
+	Arch       uint32 /* avr-gcc architecture, e.g. 5 for avr5 */
 	RAMStart   uint32
 	RAMSize    uint32 /* Bytes */
 	EEPROMSize uint32 /* Bytes */
 	FlashSize  uint32 /* Words */
 	Flags      Flag
+	IO         []IORegister
+	Vectors    []Vector
+}
+
+type IORegister struct {
+	Name    string `json:"name"`
+	Address uint32 `json:"address"` /* Data space */
+}
+
+type Vector struct {
+	Name    string `json:"name"`
+	Address uint32 `json:"address"` /* Words */
+}
+
+type Symbol struct {
+	Name  string `json:"name"`
+	Value uint64 `json:"value"`
 }
 
 /* Device cores */
@@ -20,37 +44,212 @@ const (
 	AVRe Core = 2
 )
 
+/*
+Device features
+
+Devices sharing a core do not necessarily implement every
+instruction of that core. Instructions that depend on one of
+these features are only available if the device has its flag.
+*/
+const (
+	JMP_CALL Flag = 1      /* Absolute jmp and call */
+	MUL      Flag = 1 << 1 /* Hardware multiplier */
+	BREAK    Flag = 1 << 2 /* On-chip debugging */
+)
+
 /* Device Names */
 const (
 	DEFAULT    DeviceType = "" /* Based on the attiny25 */
 	AT90USB82  DeviceType = "at90usb82"
 	AT90USB162 DeviceType = "at90usb162"
+	ATMEGA8    DeviceType = "atmega8"
+	ATMEGA328P DeviceType = "atmega328p"
+	ATTINY25   DeviceType = "attiny25"
+	ATTINY85   DeviceType = "attiny85"
 )
 
 /* Devices */
 var DeviceMap = map[DeviceType]Device{
 	DEFAULT: {
-		Nil,
-		0x060,
-		128,
-		128,
-		2048,
-		0,
+		Name:       DEFAULT,
+		DeviceCore: Nil,
+		Arch:       2,
+		RAMStart:   0x060,
+		RAMSize:    128,
+		EEPROMSize: 128,
+		FlashSize:  2048,
+		Flags:      0,
+		IO:         nil,
+		Vectors:    defaultVectors,
 	},
 	AT90USB82: {
-		AVRe,
-		0x100,
-		512,
-		512,
-		4096,
-		0,
+		Name:       AT90USB82,
+		DeviceCore: AVRe,
+		Arch:       35,
+		RAMStart:   0x100,
+		RAMSize:    512,
+		EEPROMSize: 512,
+		FlashSize:  4096,
+		Flags:      JMP_CALL | BREAK,
+		IO:         at90usbX2IO,
+		Vectors:    at90usbX2Vectors,
 	},
 	AT90USB162: {
-		AVRe,
-		0x100,
-		512,
-		512,
-		8192,
-		0,
+		Name:       AT90USB162,
+		DeviceCore: AVRe,
+		Arch:       35,
+		RAMStart:   0x100,
+		RAMSize:    512,
+		EEPROMSize: 512,
+		FlashSize:  8192,
+		Flags:      JMP_CALL | BREAK,
+		IO:         at90usbX2IO,
+		Vectors:    at90usbX2Vectors,
+	},
+	ATMEGA8: {
+		Name:       ATMEGA8,
+		DeviceCore: AVRe,
+		Arch:       4,
+		RAMStart:   0x060,
+		RAMSize:    1024,
+		EEPROMSize: 512,
+		FlashSize:  4096,
+		Flags:      MUL,
+		IO:         atmega8IO,
+		Vectors:    atmega8Vectors,
+	},
+	ATMEGA328P: {
+		Name:       ATMEGA328P,
+		DeviceCore: AVRe,
+		Arch:       5,
+		RAMStart:   0x100,
+		RAMSize:    2048,
+		EEPROMSize: 1024,
+		FlashSize:  16384,
+		Flags:      JMP_CALL | MUL | BREAK,
+		IO:         atmega328pIO,
+		Vectors:    atmega328pVectors,
 	},
+	ATTINY25: {
+		Name:       ATTINY25,
+		DeviceCore: AVR,
+		Arch:       25,
+		RAMStart:   0x060,
+		RAMSize:    128,
+		EEPROMSize: 128,
+		FlashSize:  1024,
+		Flags:      BREAK,
+		IO:         attinyX5IO,
+		Vectors:    attinyX5Vectors,
+	},
+	ATTINY85: {
+		Name:       ATTINY85,
+		DeviceCore: AVR,
+		Arch:       25,
+		RAMStart:   0x060,
+		RAMSize:    512,
+		EEPROMSize: 512,
+		FlashSize:  4096,
+		Flags:      BREAK,
+		IO:         attinyX5IO,
+		Vectors:    attinyX5Vectors,
+	},
+}
+
+func (f Flag) Features() []string {
+	features := []string{}
+
+	if f&JMP_CALL == JMP_CALL {
+		features = append(features, "absolute jmp/call")
+	}
+
+	if f&MUL == MUL {
+		features = append(features, "hardware multiplier")
+	}
+
+	if f&BREAK == BREAK {
+		features = append(features, "on-chip debugging")
+	}
+
+	return features
+}
+
+func (f Flag) String() string {
+	return strings.Join(f.Features(), ", ")
+}
+
+func (c Core) String() string {
+	switch c {
+	case Nil:
+		return "none"
+	case AVR:
+		return "AVR"
+	case AVRe:
+		return "AVRe"
+	default:
+		return fmt.Sprintf("core %d", int(c))
+	}
+}
+
+func (t DeviceType) String() string {
+	if t == DEFAULT {
+		return "default device"
+	}
+
+	return string(t)
+}
+
+/*
+Symbols predefined by the assembler once the device is
+selected, in the style of the AVRASM2 device definition
+files, and listed by aria devices. I/O registers below 0x60
+in the data space take their I/O address so they can be
+used with in and out.
+*/
+func (d *Device) Symbols() []Symbol {
+	symbols := []Symbol{}
+
+	for _, reg := range d.IO {
+		value := uint64(reg.Address)
+
+		if value < 0x60 {
+			value -= 0x20
+		}
+
+		symbols = append(symbols, Symbol{reg.Name, value})
+	}
+
+	for _, vec := range d.Vectors {
+		symbols = append(symbols, Symbol{vec.Name + "addr", uint64(vec.Address)})
+	}
+
+	ramEnd := uint64(d.RAMStart) + uint64(d.RAMSize) - 1
+
+	symbols = append(symbols,
+		Symbol{"SRAM_START", uint64(d.RAMStart)},
+		Symbol{"SRAM_SIZE", uint64(d.RAMSize)},
+		Symbol{"RAMEND", ramEnd},
+		Symbol{"FLASHEND", uint64(d.FlashSize) - 1},
+		Symbol{"E2END", uint64(d.EEPROMSize) - 1},
+		Symbol{"INT_VECTORS_SIZE", d.VectorsSize()},
+	)
+
+	return symbols
+}
+
+/*
+Size of the interrupt vector table in words
+*/
+func (d *Device) VectorsSize() uint64 {
+	if len(d.Vectors) == 0 {
+		return 0
+	}
+
+	last := d.Vectors[len(d.Vectors)-1]
+
+	if d.Flags&JMP_CALL == JMP_CALL {
+		return uint64(last.Address) + 2
+	}
+
+	return uint64(last.Address) + 1
 }
@@ -2,6 +2,7 @@ package device
 
 import (
 	"fmt"
+	"sort"
 )
 
 func NewDevice(name string) (*Device, error) {
@@ -19,3 +20,25 @@ func DefaultDevice() *Device {
 	device := DeviceMap[DEFAULT]
 	return &device
 }
+
+/*
+All devices in the registry sorted by name, excluding the
+default device
+*/
+func Devices() []Device {
+	devices := []Device{}
+
+	for dt, device := range DeviceMap {
+		if dt == DEFAULT {
+			continue
+		}
+
+		devices = append(devices, device)
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Name < devices[j].Name
+	})
+
+	return devices
+}
@@ -0,0 +1,405 @@
+package device
+
+/*
+I/O registers
+
+Addresses are in the data space, so the first 64 registers
+sit at 0x20 to 0x5F and can also be reached with in and out
+using their I/O address (data address - 0x20).
+*/
+
+var atmega328pIO = []IORegister{
+	{"PINB", 0x23},
+	{"DDRB", 0x24},
+	{"PORTB", 0x25},
+	{"PINC", 0x26},
+	{"DDRC", 0x27},
+	{"PORTC", 0x28},
+	{"PIND", 0x29},
+	{"DDRD", 0x2A},
+	{"PORTD", 0x2B},
+	{"TIFR0", 0x35},
+	{"TIFR1", 0x36},
+	{"TIFR2", 0x37},
+	{"PCIFR", 0x3B},
+	{"EIFR", 0x3C},
+	{"EIMSK", 0x3D},
+	{"GPIOR0", 0x3E},
+	{"EECR", 0x3F},
+	{"EEDR", 0x40},
+	{"EEARL", 0x41},
+	{"EEARH", 0x42},
+	{"GTCCR", 0x43},
+	{"TCCR0A", 0x44},
+	{"TCCR0B", 0x45},
+	{"TCNT0", 0x46},
+	{"OCR0A", 0x47},
+	{"OCR0B", 0x48},
+	{"GPIOR1", 0x4A},
+	{"GPIOR2", 0x4B},
+	{"SPCR", 0x4C},
+	{"SPSR", 0x4D},
+	{"SPDR", 0x4E},
+	{"ACSR", 0x50},
+	{"SMCR", 0x53},
+	{"MCUSR", 0x54},
+	{"MCUCR", 0x55},
+	{"SPMCSR", 0x57},
+	{"SPL", 0x5D},
+	{"SPH", 0x5E},
+	{"SREG", 0x5F},
+	{"WDTCSR", 0x60},
+	{"CLKPR", 0x61},
+	{"PRR", 0x64},
+	{"OSCCAL", 0x66},
+	{"PCICR", 0x68},
+	{"EICRA", 0x69},
+	{"PCMSK0", 0x6B},
+	{"PCMSK1", 0x6C},
+	{"PCMSK2", 0x6D},
+	{"TIMSK0", 0x6E},
+	{"TIMSK1", 0x6F},
+	{"TIMSK2", 0x70},
+	{"ADCL", 0x78},
+	{"ADCH", 0x79},
+	{"ADCSRA", 0x7A},
+	{"ADCSRB", 0x7B},
+	{"ADMUX", 0x7C},
+	{"DIDR0", 0x7E},
+	{"DIDR1", 0x7F},
+	{"TCCR1A", 0x80},
+	{"TCCR1B", 0x81},
+	{"TCCR1C", 0x82},
+	{"TCNT1L", 0x84},
+	{"TCNT1H", 0x85},
+	{"ICR1L", 0x86},
+	{"ICR1H", 0x87},
+	{"OCR1AL", 0x88},
+	{"OCR1AH", 0x89},
+	{"OCR1BL", 0x8A},
+	{"OCR1BH", 0x8B},
+	{"TCCR2A", 0xB0},
+	{"TCCR2B", 0xB1},
+	{"TCNT2", 0xB2},
+	{"OCR2A", 0xB3},
+	{"OCR2B", 0xB4},
+	{"ASSR", 0xB6},
+	{"TWBR", 0xB8},
+	{"TWSR", 0xB9},
+	{"TWAR", 0xBA},
+	{"TWDR", 0xBB},
+	{"TWCR", 0xBC},
+	{"TWAMR", 0xBD},
+	{"UCSR0A", 0xC0},
+	{"UCSR0B", 0xC1},
+	{"UCSR0C", 0xC2},
+	{"UBRR0L", 0xC4},
+	{"UBRR0H", 0xC5},
+	{"UDR0", 0xC6},
+}
+
+var atmega8IO = []IORegister{
+	{"TWBR", 0x20},
+	{"TWSR", 0x21},
+	{"TWAR", 0x22},
+	{"TWDR", 0x23},
+	{"ADCL", 0x24},
+	{"ADCH", 0x25},
+	{"ADCSRA", 0x26},
+	{"ADMUX", 0x27},
+	{"ACSR", 0x28},
+	{"UBRRL", 0x29},
+	{"UCSRB", 0x2A},
+	{"UCSRA", 0x2B},
+	{"UDR", 0x2C},
+	{"SPCR", 0x2D},
+	{"SPSR", 0x2E},
+	{"SPDR", 0x2F},
+	{"PIND", 0x30},
+	{"DDRD", 0x31},
+	{"PORTD", 0x32},
+	{"PINC", 0x33},
+	{"DDRC", 0x34},
+	{"PORTC", 0x35},
+	{"PINB", 0x36},
+	{"DDRB", 0x37},
+	{"PORTB", 0x38},
+	{"EECR", 0x3C},
+	{"EEDR", 0x3D},
+	{"EEARL", 0x3E},
+	{"EEARH", 0x3F},
+	{"UCSRC", 0x40},
+	{"UBRRH", 0x40},
+	{"WDTCR", 0x41},
+	{"ASSR", 0x42},
+	{"OCR2", 0x43},
+	{"TCNT2", 0x44},
+	{"TCCR2", 0x45},
+	{"ICR1L", 0x46},
+	{"ICR1H", 0x47},
+	{"OCR1BL", 0x48},
+	{"OCR1BH", 0x49},
+	{"OCR1AL", 0x4A},
+	{"OCR1AH", 0x4B},
+	{"TCNT1L", 0x4C},
+	{"TCNT1H", 0x4D},
+	{"TCCR1B", 0x4E},
+	{"TCCR1A", 0x4F},
+	{"SFIOR", 0x50},
+	{"OSCCAL", 0x51},
+	{"TCNT0", 0x52},
+	{"TCCR0", 0x53},
+	{"MCUCSR", 0x54},
+	{"MCUCR", 0x55},
+	{"TWCR", 0x56},
+	{"SPMCR", 0x57},
+	{"TIFR", 0x58},
+	{"TIMSK", 0x59},
+	{"GIFR", 0x5A},
+	{"GICR", 0x5B},
+	{"SPL", 0x5D},
+	{"SPH", 0x5E},
+	{"SREG", 0x5F},
+}
+
+var attinyX5IO = []IORegister{
+	{"ADCSRB", 0x23},
+	{"ADCL", 0x24},
+	{"ADCH", 0x25},
+	{"ADCSRA", 0x26},
+	{"ADMUX", 0x27},
+	{"ACSR", 0x28},
+	{"USICR", 0x2D},
+	{"USISR", 0x2E},
+	{"USIDR", 0x2F},
+	{"USIBR", 0x30},
+	{"GPIOR0", 0x31},
+	{"GPIOR1", 0x32},
+	{"GPIOR2", 0x33},
+	{"DIDR0", 0x34},
+	{"PCMSK", 0x35},
+	{"PINB", 0x36},
+	{"DDRB", 0x37},
+	{"PORTB", 0x38},
+	{"EECR", 0x3C},
+	{"EEDR", 0x3D},
+	{"EEARL", 0x3E},
+	{"EEARH", 0x3F},
+	{"PRR", 0x40},
+	{"WDTCR", 0x41},
+	{"DWDR", 0x42},
+	{"DTPS1", 0x43},
+	{"CLKPR", 0x46},
+	{"PLLCSR", 0x47},
+	{"OCR0B", 0x48},
+	{"OCR0A", 0x49},
+	{"TCCR0A", 0x4A},
+	{"OCR1B", 0x4B},
+	{"GTCCR", 0x4C},
+	{"OCR1C", 0x4D},
+	{"OCR1A", 0x4E},
+	{"TCNT1", 0x4F},
+	{"TCCR1", 0x50},
+	{"OSCCAL", 0x51},
+	{"TCNT0", 0x52},
+	{"TCCR0B", 0x53},
+	{"MCUSR", 0x54},
+	{"MCUCR", 0x55},
+	{"SPMCSR", 0x57},
+	{"TIFR", 0x58},
+	{"TIMSK", 0x59},
+	{"GIFR", 0x5A},
+	{"GIMSK", 0x5B},
+	{"SPL", 0x5D},
+	{"SPH", 0x5E},
+	{"SREG", 0x5F},
+}
+
+var at90usbX2IO = []IORegister{
+	{"PINB", 0x23},
+	{"DDRB", 0x24},
+	{"PORTB", 0x25},
+	{"PINC", 0x26},
+	{"DDRC", 0x27},
+	{"PORTC", 0x28},
+	{"PIND", 0x29},
+	{"DDRD", 0x2A},
+	{"PORTD", 0x2B},
+	{"TIFR0", 0x35},
+	{"TIFR1", 0x36},
+	{"PCIFR", 0x3B},
+	{"EIFR", 0x3C},
+	{"EIMSK", 0x3D},
+	{"GPIOR0", 0x3E},
+	{"EECR", 0x3F},
+	{"EEDR", 0x40},
+	{"EEARL", 0x41},
+	{"EEARH", 0x42},
+	{"GTCCR", 0x43},
+	{"TCCR0A", 0x44},
+	{"TCCR0B", 0x45},
+	{"TCNT0", 0x46},
+	{"OCR0A", 0x47},
+	{"OCR0B", 0x48},
+	{"PLLCSR", 0x49},
+	{"GPIOR1", 0x4A},
+	{"GPIOR2", 0x4B},
+	{"SPCR", 0x4C},
+	{"SPSR", 0x4D},
+	{"SPDR", 0x4E},
+	{"ACSR", 0x50},
+	{"DWDR", 0x51},
+	{"SMCR", 0x53},
+	{"MCUSR", 0x54},
+	{"MCUCR", 0x55},
+	{"SPMCSR", 0x57},
+	{"SPL", 0x5D},
+	{"SPH", 0x5E},
+	{"SREG", 0x5F},
+	{"WDTCSR", 0x60},
+	{"CLKPR", 0x61},
+	{"WDTCKD", 0x62},
+	{"REGCR", 0x63},
+	{"PRR0", 0x64},
+	{"PRR1", 0x65},
+	{"OSCCAL", 0x66},
+	{"PCICR", 0x68},
+	{"EICRA", 0x69},
+	{"EICRB", 0x6A},
+	{"PCMSK0", 0x6B},
+	{"PCMSK1", 0x6C},
+	{"TIMSK0", 0x6E},
+	{"TIMSK1", 0x6F},
+	{"TCCR1A", 0x80},
+	{"TCCR1B", 0x81},
+	{"TCCR1C", 0x82},
+	{"TCNT1L", 0x84},
+	{"TCNT1H", 0x85},
+	{"ICR1L", 0x86},
+	{"ICR1H", 0x87},
+	{"OCR1AL", 0x88},
+	{"OCR1AH", 0x89},
+	{"OCR1BL", 0x8A},
+	{"OCR1BH", 0x8B},
+	{"OCR1CL", 0x8C},
+	{"OCR1CH", 0x8D},
+	{"UCSR1A", 0xC8},
+	{"UCSR1B", 0xC9},
+	{"UCSR1C", 0xCA},
+	{"UCSR1D", 0xCB},
+	{"UBRR1L", 0xCC},
+	{"UBRR1H", 0xCD},
+	{"UDR1", 0xCE},
+}
+
+/*
+Interrupt vectors
+
+Addresses are in words. Devices with jmp and call use two
+words per vector so that each entry can hold a jmp.
+*/
+
+var atmega328pVectors = []Vector{
+	{"RESET", 0x00},
+	{"INT0", 0x02},
+	{"INT1", 0x04},
+	{"PCINT0", 0x06},
+	{"PCINT1", 0x08},
+	{"PCINT2", 0x0A},
+	{"WDT", 0x0C},
+	{"TIMER2_COMPA", 0x0E},
+	{"TIMER2_COMPB", 0x10},
+	{"TIMER2_OVF", 0x12},
+	{"TIMER1_CAPT", 0x14},
+	{"TIMER1_COMPA", 0x16},
+	{"TIMER1_COMPB", 0x18},
+	{"TIMER1_OVF", 0x1A},
+	{"TIMER0_COMPA", 0x1C},
+	{"TIMER0_COMPB", 0x1E},
+	{"TIMER0_OVF", 0x20},
+	{"SPI_STC", 0x22},
+	{"USART_RX", 0x24},
+	{"USART_UDRE", 0x26},
+	{"USART_TX", 0x28},
+	{"ADC", 0x2A},
+	{"EE_READY", 0x2C},
+	{"ANALOG_COMP", 0x2E},
+	{"TWI", 0x30},
+	{"SPM_READY", 0x32},
+}
+
+var atmega8Vectors = []Vector{
+	{"RESET", 0x00},
+	{"INT0", 0x01},
+	{"INT1", 0x02},
+	{"TIMER2_COMP", 0x03},
+	{"TIMER2_OVF", 0x04},
+	{"TIMER1_CAPT", 0x05},
+	{"TIMER1_COMPA", 0x06},
+	{"TIMER1_COMPB", 0x07},
+	{"TIMER1_OVF", 0x08},
+	{"TIMER0_OVF", 0x09},
+	{"SPI_STC", 0x0A},
+	{"USART_RXC", 0x0B},
+	{"USART_UDRE", 0x0C},
+	{"USART_TXC", 0x0D},
+	{"ADC", 0x0E},
+	{"EE_RDY", 0x0F},
+	{"ANA_COMP", 0x10},
+	{"TWI", 0x11},
+	{"SPM_RDY", 0x12},
+}
+
+var attinyX5Vectors = []Vector{
+	{"RESET", 0x00},
+	{"INT0", 0x01},
+	{"PCINT0", 0x02},
+	{"TIMER1_COMPA", 0x03},
+	{"TIMER1_OVF", 0x04},
+	{"TIMER0_OVF", 0x05},
+	{"EE_RDY", 0x06},
+	{"ANA_COMP", 0x07},
+	{"ADC", 0x08},
+	{"TIMER1_COMPB", 0x09},
+	{"TIMER0_COMPA", 0x0A},
+	{"TIMER0_COMPB", 0x0B},
+	{"WDT", 0x0C},
+	{"USI_START", 0x0D},
+	{"USI_OVF", 0x0E},
+}
+
+var at90usbX2Vectors = []Vector{
+	{"RESET", 0x00},
+	{"INT0", 0x02},
+	{"INT1", 0x04},
+	{"INT2", 0x06},
+	{"INT3", 0x08},
+	{"INT4", 0x0A},
+	{"INT5", 0x0C},
+	{"INT6", 0x0E},
+	{"INT7", 0x10},
+	{"PCINT0", 0x12},
+	{"PCINT1", 0x14},
+	{"USB_GEN", 0x16},
+	{"USB_COM", 0x18},
+	{"WDT", 0x1A},
+	{"TIMER1_CAPT", 0x1C},
+	{"TIMER1_COMPA", 0x1E},
+	{"TIMER1_COMPB", 0x20},
+	{"TIMER1_COMPC", 0x22},
+	{"TIMER1_OVF", 0x24},
+	{"TIMER0_COMPA", 0x26},
+	{"TIMER0_COMPB", 0x28},
+	{"TIMER0_OVF", 0x2A},
+	{"SPI_STC", 0x2C},
+	{"USART1_RX", 0x2E},
+	{"USART1_UDRE", 0x30},
+	{"USART1_TX", 0x32},
+	{"ANALOG_COMP", 0x34},
+	{"EE_READY", 0x36},
+	{"SPM_READY", 0x38},
+}
+
+var defaultVectors = []Vector{
+	{"RESET", 0x00},
+}
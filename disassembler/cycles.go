@@ -0,0 +1,301 @@
+package disassembler
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/silaspace/aria/language"
+)
+
+/* Address of the end of a subroutine, reached by its ret */
+const EXIT uint64 = math.MaxUint64
+
+/*
+The fewest and most cycles a piece of code can take. Max is
+not known when the code can loop, or calls a subroutine that
+can.
+*/
+type Range struct {
+	Bounded bool
+	Max     uint64
+	Min     uint64
+}
+
+/*
+A way from one instruction to the next, and the cycles it takes
+*/
+type step struct {
+	Cycles Range
+	To     uint64
+}
+
+/*
+Every way through the code from one address to another
+*/
+type paths struct {
+	From  uint64
+	Steps map[uint64][]step
+	To    uint64
+}
+
+/*
+Cycles taken from one word address to another, not counting
+the instruction at the second. Subroutines called on the way
+are followed to their ret.
+*/
+func (d *Disassembler) Cycles(from uint64, to uint64) (Range, error) {
+	return d.cycles(from, to, map[uint64]bool{})
+}
+
+func (d *Disassembler) cycles(from uint64, to uint64, calls map[uint64]bool) (Range, error) {
+	p := &paths{
+		From:  from,
+		Steps: map[uint64][]step{},
+		To:    to,
+	}
+
+	// Find every instruction reached from the first
+	queue := []uint64{from}
+
+	for len(queue) > 0 {
+		addr := queue[0]
+		queue = queue[1:]
+
+		if _, seen := p.Steps[addr]; seen || addr == to || addr == EXIT {
+			continue
+		}
+
+		steps, err := d.steps(addr, to == EXIT, calls)
+
+		if err != nil {
+			return Range{}, err
+		}
+
+		p.Steps[addr] = steps
+
+		for _, s := range steps {
+			queue = append(queue, s.To)
+		}
+	}
+
+	least, reached := p.shortest()
+
+	if !reached && to == EXIT {
+		return Range{}, fmt.Errorf("subroutine at 0x%04X never returns", from)
+	}
+
+	if !reached {
+		return Range{}, fmt.Errorf("0x%04X cannot be reached from 0x%04X", to, from)
+	}
+
+	most, bounded := p.longest()
+
+	return Range{
+		Bounded: bounded,
+		Max:     most,
+		Min:     least,
+	}, nil
+}
+
+/*
+The ways on from the instruction at an address. Within a
+subroutine, ret leads to its end.
+*/
+func (d *Disassembler) steps(addr uint64, subroutine bool, calls map[uint64]bool) ([]step, error) {
+	line, ok := d.Line(addr)
+
+	if !ok {
+		return nil, fmt.Errorf("0x%04X is not the start of an instruction", addr)
+	}
+
+	if line.Decoded == nil {
+		return nil, fmt.Errorf("0x%04X at 0x%04X is not an instruction", line.Words[0], addr)
+	}
+
+	instr := line.Decoded.Instruction
+	timing := instr.Cycles
+	next := addr + line.Decoded.Size()
+	target, _ := Target(line)
+
+	exactly := func(cycles uint64) Range {
+		return Range{true, cycles, cycles}
+	}
+
+	switch line.Decoded.Mnemonic {
+	case language.RJMP, language.JMP:
+		return []step{{exactly(timing.Cycles), target}}, nil
+
+	case language.RCALL, language.CALL:
+		if calls[target] {
+			return nil, fmt.Errorf("subroutine at 0x%04X calls itself", target)
+		}
+
+		calls[target] = true
+		sub, err := d.cycles(target, EXIT, calls)
+		delete(calls, target)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sub.Min += timing.Cycles
+		sub.Max += timing.Cycles
+		return []step{{sub, next}}, nil
+
+	case language.RET, language.RETI:
+		if subroutine {
+			return []step{{exactly(timing.Cycles), EXIT}}, nil
+		}
+
+		return []step{}, nil
+
+	case language.IJMP, language.ICALL:
+		return nil, fmt.Errorf("cannot follow %v at 0x%04X", line.Decoded.Mnemonic, addr)
+	}
+
+	switch {
+	case instr.IsBranch():
+		return []step{
+			{exactly(timing.Cycles), next},
+			{exactly(timing.Taken), target},
+		}, nil
+
+	case instr.IsSkip():
+		skipped, ok := d.Line(next)
+		size := uint64(1)
+
+		if ok && skipped.Decoded != nil {
+			size = skipped.Decoded.Size()
+		}
+
+		return []step{
+			{exactly(timing.Cycles), next},
+			{exactly(timing.Taken + size - 1), next + size},
+		}, nil
+
+	default:
+		return []step{{exactly(timing.Cycles), next}}, nil
+	}
+}
+
+/*
+The line starting at a word address
+*/
+func (d *Disassembler) Line(addr uint64) (Line, bool) {
+	for _, line := range d.Lines {
+		if line.Address == addr {
+			return line, true
+		}
+	}
+
+	return Line{}, false
+}
+
+/*
+Fewest cycles to the end, and whether it can be reached
+*/
+func (p *paths) shortest() (uint64, bool) {
+	dist := map[uint64]uint64{p.From: 0}
+	done := map[uint64]bool{}
+
+	for {
+		addr, found := uint64(0), false
+
+		for a, d := range dist {
+			if !done[a] && (!found || d < dist[addr]) {
+				addr, found = a, true
+			}
+		}
+
+		if !found {
+			return 0, false
+		}
+
+		if addr == p.To {
+			return dist[addr], true
+		}
+
+		done[addr] = true
+
+		for _, s := range p.Steps[addr] {
+			d := dist[addr] + s.Cycles.Min
+
+			if old, seen := dist[s.To]; !seen || d < old {
+				dist[s.To] = d
+			}
+		}
+	}
+}
+
+/*
+Most cycles to the end, which are unbounded if the code can
+loop on its way there
+*/
+func (p *paths) longest() (uint64, bool) {
+	const (
+		unvisited = 0
+		visiting  = 1
+		visited   = 2
+	)
+
+	// Instructions the end can be reached from
+	reaches := map[uint64]bool{p.To: true}
+
+	for changed := true; changed; {
+		changed = false
+
+		for addr, steps := range p.Steps {
+			for _, s := range steps {
+				if !reaches[addr] && reaches[s.To] {
+					reaches[addr] = true
+					changed = true
+				}
+			}
+		}
+	}
+
+	state := map[uint64]int{}
+	most := map[uint64]uint64{}
+	bounded := true
+
+	var visit func(addr uint64)
+
+	visit = func(addr uint64) {
+		state[addr] = visiting
+
+		for _, s := range p.Steps[addr] {
+			if !reaches[s.To] {
+				continue
+			}
+
+			if !s.Cycles.Bounded {
+				bounded = false
+			}
+
+			switch state[s.To] {
+			case unvisited:
+				visit(s.To)
+
+			// A loop on the way to the end
+			case visiting:
+				bounded = false
+				continue
+			}
+
+			most[addr] = max(most[addr], s.Cycles.Max+most[s.To])
+		}
+
+		state[addr] = visited
+	}
+
+	visit(p.From)
+	return most[p.From], bounded
+}
+
+func (r Range) String() string {
+	if !r.Bounded {
+		return fmt.Sprintf("min %v, max unbounded", r.Min)
+	}
+
+	return fmt.Sprintf("min %v, max %v", r.Min, r.Max)
+}
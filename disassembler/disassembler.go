@@ -0,0 +1,338 @@
+package disassembler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/language"
+)
+
+/* Erased words in a row, at least, which are left out of the lines */
+const ERASED_RUN int = 16
+
+/*
+An instruction, or a word of program memory that is not one,
+at a word address
+*/
+type Line struct {
+	Address uint64
+	Decoded *language.Decoded
+	Words   []uint16
+}
+
+/*
+Disassembles program memory into source aria can assemble
+again. Labels are given to the symbols of the program, if it
+has any, and with Named to the targets of every jump, call
+and branch. Long runs of erased flash, such as lie between the
+sections of a linked program, have no lines.
+*/
+type Disassembler struct {
+	Decoder *language.Decoder
+	Device  device.Device
+	Labels  map[uint64]string
+	Lines   []Line
+	Named   bool
+	Size    uint64 /* Words of program memory decoded */
+}
+
+/*
+Decode program memory from word address 0
+*/
+func (d *Disassembler) Disassemble(code []byte) {
+	if len(code)%2 != 0 {
+		code = append(code, 0)
+	}
+
+	words := make([]uint16, len(code)/2)
+
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint16(code[i*2:])
+	}
+
+	d.Size = uint64(len(words))
+
+	for i := 0; i < len(words); {
+		if run := erased(words[i:]); run >= ERASED_RUN {
+			i += run
+			continue
+		}
+
+		line := Line{
+			Address: uint64(i),
+			Words:   words[i : i+1],
+		}
+
+		decoded, err := d.Decoder.Decode(words[i:min(i+2, len(words))])
+
+		if err == nil {
+			line.Decoded = decoded
+			line.Words = words[i : i+int(decoded.Size())]
+		}
+
+		d.Lines = append(d.Lines, line)
+		i += len(line.Words)
+	}
+
+	if d.Named {
+		d.nameTargets()
+	}
+}
+
+/*
+Name a word address after a symbol, unless the name cannot be
+used as a label in aria or the address is already named
+*/
+func (d *Disassembler) AddLabel(address uint64, name string) bool {
+	if _, exists := d.Labels[address]; exists || !isLabel(name) {
+		return false
+	}
+
+	for _, label := range d.Labels {
+		if strings.EqualFold(label, name) {
+			return false
+		}
+	}
+
+	d.Labels[address] = name
+	return true
+}
+
+/*
+Give a label to the target of each jump, call and branch that
+is the start of a line
+*/
+func (d *Disassembler) nameTargets() {
+	starts := map[uint64]bool{}
+
+	for _, line := range d.Lines {
+		starts[line.Address] = true
+	}
+
+	for _, line := range d.Lines {
+		target, ok := Target(line)
+
+		if ok && starts[target] {
+			d.AddLabel(target, fmt.Sprintf("L%04X", target))
+		}
+	}
+}
+
+/*
+Word address jumped, called or branched to by a line
+*/
+func Target(line Line) (uint64, bool) {
+	if line.Decoded == nil {
+		return 0, false
+	}
+
+	decoded := line.Decoded
+
+	// Branches on a bit of SREG take the bit first
+	k, ok := decoded.Op2.(*language.Int)
+
+	if !ok {
+		k, ok = decoded.Op1.(*language.Int)
+	}
+
+	switch {
+	case !ok:
+		return 0, false
+
+	case decoded.Instruction.IsRelative():
+		return line.Address + 1 + k.Value, true
+
+	case decoded.Mnemonic == language.JMP || decoded.Mnemonic == language.CALL:
+		return k.Value, true
+
+	default:
+		return 0, false
+	}
+}
+
+/*
+Write the source of the program
+*/
+func (d *Disassembler) Write(w io.Writer, name string) error {
+	fmt.Fprintf(w, "; aria disassembly of %v\n", name)
+
+	if d.Device.Name != device.DEFAULT {
+		fmt.Fprintf(w, ".device %v\n", d.Device.Name)
+	}
+
+	fmt.Fprintf(w, "\n.%v\n", language.DIR_CSEG)
+	next := uint64(0)
+
+	for _, line := range d.Lines {
+		if line.Address != next {
+			writeErased(w, next, line.Address)
+		}
+
+		next = line.Address + uint64(len(line.Words))
+
+		if label, exists := d.Labels[line.Address]; exists {
+			fmt.Fprintf(w, "\n%v:\n", label)
+		}
+
+		words := []string{}
+
+		for _, word := range line.Words {
+			words = append(words, fmt.Sprintf("%04X", word))
+		}
+
+		comment := fmt.Sprintf("%04X: %v", line.Address, strings.Join(words, " "))
+		_, err := fmt.Fprintf(w, "\t%-28v; %v\n", d.Text(line), comment)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if next < d.Size {
+		writeErased(w, next, d.Size)
+	}
+
+	return nil
+}
+
+/*
+Note words of erased flash that have no lines. Source written
+by the disassembler places the code after them too early.
+*/
+func writeErased(w io.Writer, from uint64, to uint64) {
+	fmt.Fprintf(w, "\n\t; %04X to %04X erased, %v words of 0xFFFF\n", from, to-1, to-from)
+}
+
+/*
+Number of erased words at the start of program memory
+*/
+func erased(words []uint16) int {
+	n := 0
+
+	for n < len(words) && words[n] == 0xFFFF {
+		n++
+	}
+
+	return n
+}
+
+/*
+Source of a line, with .dw for words that are not instructions
+*/
+func (d *Disassembler) Text(line Line) string {
+	if line.Decoded == nil {
+		return fmt.Sprintf(".%v 0x%04X", language.DIR_DW, line.Words[0])
+	}
+
+	decoded := line.Decoded
+	operands := []string{}
+
+	for _, op := range []language.Value{decoded.Op1, decoded.Op2} {
+		if _, ok := op.(*language.Nil); ok {
+			continue
+		}
+
+		operands = append(operands, d.operand(line, op))
+	}
+
+	if len(operands) == 0 {
+		return string(decoded.Mnemonic)
+	}
+
+	return fmt.Sprintf("%-7v %v", decoded.Mnemonic, strings.Join(operands, ", "))
+}
+
+func (d *Disassembler) operand(line Line, op language.Value) string {
+	switch op := op.(type) {
+	case *language.Reg:
+		return fmt.Sprintf("r%v", op.Value)
+
+	case *language.RegPair:
+		return fmt.Sprintf("r%v:r%v", op.Value+1, op.Value)
+
+	case *language.RegPointer:
+		return op.Value
+
+	case *language.RegPointerPostInc:
+		return op.Value + "+"
+
+	case *language.RegPointerPreDec:
+		return "-" + op.Value
+
+	case *language.RegPointerDisp:
+		return fmt.Sprintf("%v+%v", op.Value, op.Disp)
+
+	case *language.Int:
+		return d.value(line, op)
+
+	default:
+		return op.Fmt()
+	}
+}
+
+/*
+An integer operand, or the label of the address it jumps to.
+Relative targets without a label are given from pc, as in
+rjmp pc+2.
+*/
+func (d *Disassembler) value(line Line, op *language.Int) string {
+	target, ok := Target(line)
+	isTarget := ok && (op == line.Decoded.Op2 || line.Decoded.Op2.Type() == language.NilType)
+
+	if isTarget {
+		if label, exists := d.Labels[target]; exists {
+			return label
+		}
+	}
+
+	switch {
+	case isTarget && line.Decoded.Instruction.IsRelative():
+		offset := int64(op.Value) + 1
+
+		if offset == 0 {
+			return string(language.PC)
+		}
+
+		return fmt.Sprintf("%v%+d", language.PC, offset)
+
+	case isTarget:
+		return fmt.Sprintf("0x%04X", op.Value)
+
+	case op.Value < 10:
+		return fmt.Sprint(op.Value)
+
+	case op.Value > 0xFF:
+		return fmt.Sprintf("0x%04X", op.Value)
+
+	default:
+		return fmt.Sprintf("0x%02X", op.Value)
+	}
+}
+
+/*
+Whether a name can be used as a label
+*/
+func isLabel(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, c := range name {
+		letter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+
+	// Names such as r16 are registers
+	if _, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(name), "r"), 10, 8); err == nil {
+		return false
+	}
+
+	return language.Exists(strings.ToLower(name), language.SYNTAX_AVRASM) == language.IDENT
+}
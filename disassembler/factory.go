@@ -0,0 +1,17 @@
+package disassembler
+
+import (
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/language"
+)
+
+func NewDisassembler(dev *device.Device) *Disassembler {
+	return &Disassembler{
+		Decoder: language.NewDecoder(dev),
+		Device:  *dev,
+		Labels:  map[uint64]string{},
+		Lines:   []Line{},
+		Named:   false,
+		Size:    0,
+	}
+}
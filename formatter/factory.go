@@ -0,0 +1,17 @@
+package formatter
+
+import "github.com/silaspace/aria/language"
+
+func NewFormatter(syntax language.Syntax) *Formatter {
+	return &Formatter{
+		Syntax: syntax,
+	}
+}
+
+func newSpelling(code string, syntax language.Syntax) *spelling {
+	return &spelling{
+		At:     0,
+		Code:   code,
+		Syntax: syntax,
+	}
+}
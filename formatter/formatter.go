@@ -0,0 +1,263 @@
+package formatter
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/lexer"
+	"github.com/silaspace/aria/parser"
+)
+
+/* Columns of the mnemonic, its operands and trailing comments */
+const (
+	STATEMENT_COLUMN int = 8
+	OPERAND_COLUMN   int = 16
+	COMMENT_COLUMN   int = 32
+)
+
+/*
+Formats source one line at a time, re-emitting each line from
+its parse tree. Lines which do not parse, or which are written
+in a way the formatter cannot reproduce, are left as they are.
+*/
+type Formatter struct {
+	Syntax language.Syntax
+}
+
+/*
+A line of output, with the comment following its code kept
+apart so that comments on consecutive lines can be aligned
+*/
+type line struct {
+	Code    string
+	Comment string
+}
+
+func (f *Formatter) Format(source string) string {
+	source = strings.TrimRight(strings.ReplaceAll(source, "\r\n", "\n"), "\n \t")
+
+	if strings.TrimSpace(source) == "" {
+		return ""
+	}
+
+	lines := []line{}
+	block, continued := false, false
+
+	for _, text := range strings.Split(source, "\n") {
+		text = strings.TrimRight(text, " \t\r")
+
+		// Block comments, preprocessor directives and the lines they continue onto
+		start, opens := f.commentStart(text)
+		verbatim := block || continued || opens || strings.HasPrefix(strings.TrimLeft(text, " \t"), "#")
+
+		block = f.blockOpen(text, block)
+		continued = strings.HasSuffix(text, "\\")
+
+		if verbatim {
+			lines = append(lines, line{Code: text})
+			continue
+		}
+
+		code := strings.TrimSpace(text[:start])
+		comment := text[start:]
+
+		switch {
+		case code == "" && comment == "":
+			lines = append(lines, line{})
+
+		// Comments on lines of their own stay at the margin, or else line up with statements
+		case code == "" && start == 0:
+			lines = append(lines, line{Code: comment})
+
+		case code == "":
+			lines = append(lines, line{Code: strings.Repeat(" ", STATEMENT_COLUMN) + comment})
+
+		default:
+			formatted, ok := f.statement(code)
+
+			if !ok {
+				lines = append(lines, line{Code: text})
+				continue
+			}
+
+			lines = append(lines, line{Code: formatted, Comment: comment})
+		}
+	}
+
+	return join(lines)
+}
+
+/*
+Join lines of output, aligning the comments of each run of
+consecutive lines with trailing comments
+*/
+func join(lines []line) string {
+	b := &strings.Builder{}
+
+	for i := 0; i < len(lines); {
+		end, column := i, COMMENT_COLUMN
+
+		for end < len(lines) && lines[end].Comment != "" {
+			column = max(column, utf8.RuneCountInString(lines[end].Code)+1)
+			end++
+		}
+
+		if end == i {
+			b.WriteString(lines[i].Code + "\n")
+			i++
+			continue
+		}
+
+		for ; i < end; i++ {
+			b.WriteString(pad(lines[i].Code, column) + lines[i].Comment + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+/*
+A statement formatted from its parse tree, which must parse to
+the same tree again
+*/
+func (f *Formatter) statement(code string) (string, bool) {
+	lines, ok := f.parse(code)
+
+	if !ok {
+		return "", false
+	}
+
+	formatted, ok := newSpelling(code, f.Syntax).statement(lines)
+
+	if !ok {
+		return "", false
+	}
+
+	again, ok := f.parse(formatted)
+
+	if !ok || fingerprint(again) != fingerprint(lines) {
+		return "", false
+	}
+
+	return formatted, true
+}
+
+/*
+Parse the code of a single line, which may hold labels and a
+statement following them
+*/
+func (f *Formatter) parse(code string) ([]parser.Line, bool) {
+	reader := handler.NewWebReader()
+	reader.Write([]byte(code))
+
+	lex := lexer.NewLexer(reader)
+	lex.Syntax = f.Syntax
+	p := parser.NewParser(lex)
+	lines := []parser.Line{}
+
+	for {
+		next := p.Next()
+
+		switch next.Type() {
+		case parser.EOFType:
+			return lines, len(lines) > 0
+
+		case parser.ErrorType, parser.ComType:
+			return nil, false
+		}
+
+		lines = append(lines, next)
+	}
+}
+
+func fingerprint(lines []parser.Line) string {
+	prints := []string{}
+
+	for _, line := range lines {
+		prints = append(prints, line.Fmt())
+	}
+
+	return strings.Join(prints, "\n")
+}
+
+/*
+Index of the comment ending a line, or the length of the line
+if it has none, and whether a block comment is opened before
+it. Comments start with a semicolon or two slashes, or with a
+hash in GNU syntax.
+*/
+func (f *Formatter) commentStart(text string) (int, bool) {
+	quote := byte(0)
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+
+		case quote != 0 && c == quote:
+			quote = 0
+
+		case quote != 0:
+			continue
+
+		case c == '"' || c == '\'':
+			quote = c
+
+		case c == ';', c == '#' && f.Syntax == language.SYNTAX_GNU:
+			return i, false
+
+		case strings.HasPrefix(text[i:], "//"):
+			return i, false
+
+		case strings.HasPrefix(text[i:], "/*"):
+			return i, true
+		}
+	}
+
+	return len(text), false
+}
+
+/*
+Whether a block comment is left open at the end of a line,
+given whether one was open at its start
+*/
+func (f *Formatter) blockOpen(text string, open bool) bool {
+	for {
+		if open {
+			end := strings.Index(text, "*/")
+
+			if end == -1 {
+				return true
+			}
+
+			text, open = text[end+2:], false
+			continue
+		}
+
+		start, opens := f.commentStart(text)
+
+		if !opens {
+			return false
+		}
+
+		text, open = text[start+2:], true
+	}
+}
+
+/*
+Text padded with spaces to a column, or followed by a single
+space if it reaches the column already
+*/
+func pad(text string, column int) string {
+	length := utf8.RuneCountInString(text)
+
+	if length >= column {
+		return text + " "
+	}
+
+	return text + strings.Repeat(" ", column-length)
+}
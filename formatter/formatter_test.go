@@ -0,0 +1,175 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/silaspace/aria/assembler"
+	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/preprocessor"
+)
+
+/*
+Source written as it may be by hand, which must assemble to the
+same program once formatted
+*/
+type formatCase struct {
+	Name   string
+	Syntax language.Syntax
+	Source string
+}
+
+var formatCases = []formatCase{
+	{"instructions", language.SYNTAX_AVRASM, `
+.device atmega328p
+.equ FOO=0x10
+start:   ldi r16,FOO
+  add r16 ,r17
+    rjmp start
+ jmp start
+`},
+	{"comments", language.SYNTAX_AVRASM, `
+; a comment of its own
+  ; an indented comment
+start: nop ; trailing
+	ldi r16, 1   // slashes
+/* a block
+   comment */ nop
+	rjmp start;no space
+`},
+	{"expressions", language.SYNTAX_AVRASM, `
+.equ a=(1+2)*3
+.equ b=1-(2-3)
+.equ c=(1<<4)|(1<<2)
+	ldi r16,low(a*b+c)
+	ldi r17,high((a+b)*c)
+	subi r16,-2
+	subi r17,0-2
+	ldi r18,0x1f
+	ldi r19,0b1010
+	ldi r20,017
+`},
+	{"data", language.SYNTAX_AVRASM, `
+.dseg
+buf: .byte 4
+.cseg
+table: .dw 1,2,   3 ; one; two
+	lds r16,buf
+	sts buf+1,r16
+.eseg
+cfg: .byte 2
+`},
+	{"registers", language.SYNTAX_AVRASM, `
+.device atmega328p
+.def temp=r16
+	ld temp,x+
+	st -y,temp
+	ldd r17,z+3
+	std y+2,r17
+	adiw r25:r24,1
+	sbiw r27:r26,2
+	lpm
+`},
+	{"macros and conditions", language.SYNTAX_AVRASM, `
+.macro setreg
+	ldi @0,@1
+	out @2,@0
+.endm
+.set n=1
+.ifdef n
+	setreg r16,0xFF,0x04
+.else
+	nop
+.endif
+.set n=n+1
+	ldi r17,n
+`},
+	{"gnu", language.SYNTAX_GNU, `
+	.device atmega328p
+	.text
+	.global main
+	.type main, @function
+main:	ldi r24,lo8(1234)
+	ldi r25,hi8(1234)
+	sbiw r25:r24,1  # count down
+	brne main
+	.data
+msg: .asciz "hello"
+	.set count, 4
+	val = count * 2
+`},
+}
+
+func TestFormatKeepsProgram(t *testing.T) {
+	for _, c := range formatCases {
+		formatted := NewFormatter(c.Syntax).Format(c.Source)
+
+		if formatted == c.Source {
+			t.Errorf("%v: the source is left as it is", c.Name)
+		}
+
+		before, err := assemble(c.Source, c.Syntax)
+
+		if err != nil {
+			t.Errorf("%v: %v", c.Name, err)
+			continue
+		}
+
+		after, err := assemble(formatted, c.Syntax)
+
+		if err != nil {
+			t.Errorf("%v: formatted source does not assemble, %v\n%v", c.Name, err, formatted)
+			continue
+		}
+
+		if !bytes.Equal(before, after) {
+			t.Errorf("%v: formatted source assembles to another program\n%v", c.Name, formatted)
+		}
+
+		if again := NewFormatter(c.Syntax).Format(formatted); again != formatted {
+			t.Errorf("%v: formatting again changes the source\n%v\n%v", c.Name, formatted, again)
+		}
+	}
+}
+
+/*
+The program assembled from source, as it would be written to
+an object file
+*/
+func assemble(source string, syntax language.Syntax) ([]byte, error) {
+	reader := handler.NewWebReader()
+	reader.Write([]byte(source))
+
+	pp := preprocessor.NewPreprocessor(func(path string) (preprocessor.Reader, error) {
+		return nil, fmt.Errorf("cannot include %v", path)
+	})
+
+	pp.Syntax = syntax
+	err := pp.Process("test.s", reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	asm := assembler.NewAssembler(pp, handler.NewWebWriter())
+	asm.File = "test.s"
+	asm.Lines = pp
+	asm.SetSyntax(syntax)
+	err = asm.Run()
+
+	if err != nil {
+		return nil, err
+	}
+
+	asm.Close()
+	program := &bytes.Buffer{}
+	err = asm.Object().WriteELF(program)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return program.Bytes(), nil
+}
@@ -0,0 +1,382 @@
+package formatter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/parser"
+)
+
+/*
+Renders the parse tree of a line of code. The lexer sends all
+text to lowercase, so names and strings are spelled as they
+are written in the code, found in the order they appear.
+*/
+type spelling struct {
+	At     int
+	Code   string
+	Syntax language.Syntax
+}
+
+/*
+Labels of a line at the margin, followed by the statement with
+its mnemonic and operands in columns
+*/
+func (s *spelling) statement(lines []parser.Line) (string, bool) {
+	labels := []string{}
+
+	for len(lines) > 0 && lines[0].Type() == parser.LabelType {
+		labels = append(labels, s.name(lines[0].(*parser.Label).Value)+":")
+		lines = lines[1:]
+	}
+
+	text := strings.Join(labels, " ")
+
+	if len(lines) == 0 {
+		return text, true
+	}
+
+	if len(lines) > 1 {
+		return "", false
+	}
+
+	mnemonic, operands, ok := s.line(lines[0])
+
+	if !ok {
+		return "", false
+	}
+
+	text = pad(text, STATEMENT_COLUMN) + mnemonic
+
+	if operands != "" {
+		text = pad(text, OPERAND_COLUMN) + operands
+	}
+
+	return text, true
+}
+
+func (s *spelling) line(l parser.Line) (string, string, bool) {
+	switch l := l.(type) {
+	case *parser.Instruction:
+		operands, ok := s.args([]parser.Arg{l.Op1, l.Op2})
+		return strings.ToLower(l.Mnemonic), operands, ok
+
+	case *parser.MacroCall:
+		name := s.name(l.Name)
+		operands, ok := s.args(l.Args)
+		return name, operands, ok
+
+	case *parser.Directive:
+		return s.directive(l)
+
+	default:
+		return "", "", false
+	}
+}
+
+func (s *spelling) directive(d *parser.Directive) (string, string, bool) {
+	mn := language.Mnemonic(d.Mnemonic)
+	mnemonic := "." + d.Mnemonic
+
+	if s.Syntax == language.SYNTAX_GNU {
+		switch mn {
+		// Content the parser skips is kept as it is written
+		case language.DIR_FILE, language.DIR_IDENT, language.DIR_SECTION, language.DIR_SIZE, language.DIR_TYPE:
+			return mnemonic, s.rest(d.Mnemonic), true
+		}
+	}
+
+	switch v := d.Value.(type) {
+	case *parser.NilDirVal:
+		return mnemonic, "", true
+
+	case *parser.IdentDirVal:
+		return mnemonic, s.name(v.Value), true
+
+	case *parser.ImmDirVal:
+		return mnemonic, v.Value, true
+
+	case *parser.ExprDirVal:
+		value, ok := s.expr(v.Value)
+		return mnemonic, value, ok
+
+	case *parser.ExprListDirVal:
+		values := []string{}
+
+		for _, expr := range v.Value {
+			value, ok := s.expr(expr)
+
+			if !ok {
+				return "", "", false
+			}
+
+			values = append(values, value)
+		}
+
+		return mnemonic, strings.Join(values, ", "), true
+
+	case *parser.AssignDirVal:
+		gnu := s.Syntax == language.SYNTAX_GNU
+		symbol := s.name(v.Symbol)
+		value, ok := s.expr(v.Value)
+
+		// A symbol given a value with = and no directive, in GNU syntax
+		if gnu && !strings.HasPrefix(s.Code, ".") {
+			return symbol + " = " + value, "", ok
+		}
+
+		if gnu {
+			return mnemonic, symbol + ", " + value, ok
+		}
+
+		return mnemonic, symbol + " = " + value, ok
+
+	case *parser.DefDirVal:
+		symbol := s.name(v.Symbol)
+		reg, ok := register(v.Value)
+		return mnemonic, symbol + " = " + reg, ok
+
+	default:
+		return "", "", false
+	}
+}
+
+/*
+Operands of an instruction or macro, leaving out those not given
+*/
+func (s *spelling) args(args []parser.Arg) (string, bool) {
+	operands := []string{}
+
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case *parser.Nil:
+			continue
+
+		case *parser.ArgReg:
+			reg, ok := register(arg.Value)
+
+			if !ok {
+				return "", false
+			}
+
+			operands = append(operands, reg)
+
+		case *parser.ArgExpr:
+			value, ok := s.expr(arg.Value)
+
+			if !ok {
+				return "", false
+			}
+
+			// An operand starting with a minus is read as a negated expression, or -X
+			if _, monop := arg.Value.(*parser.MonopExpr); strings.HasPrefix(value, "-") && !monop {
+				value = "(" + value + ")"
+			}
+
+			operands = append(operands, value)
+
+		default:
+			return "", false
+		}
+	}
+
+	return strings.Join(operands, ", "), true
+}
+
+/*
+An expression with the fewest brackets needed to parse as the
+same tree, with spaces around binary operators
+*/
+func (s *spelling) expr(e parser.Expr) (string, bool) {
+	switch e := e.(type) {
+	case *parser.Ident:
+		return s.name(e.Value), true
+
+	case *parser.Literal:
+		return literal(e), true
+
+	case *parser.String:
+		return s.quoted(e.Value), true
+
+	case *parser.MonopExpr:
+		operand, ok := s.expr(e.E1)
+
+		if t := e.E1.Type(); t == parser.ExprBinop || t == parser.ExprMonop {
+			operand = "(" + operand + ")"
+		}
+
+		return e.Symbol + operand, ok
+
+	case *parser.BinopExpr:
+		left, ok1 := s.expr(e.E1)
+		right, ok2 := s.expr(e.E2)
+
+		// Operators of equal precedence are applied from the left
+		if b, ok := e.E1.(*parser.BinopExpr); ok && b.Op.BindingPower < e.Op.BindingPower {
+			left = "(" + left + ")"
+		}
+
+		if b, ok := e.E2.(*parser.BinopExpr); ok && b.Op.BindingPower <= e.Op.BindingPower {
+			right = "(" + right + ")"
+		}
+
+		return left + " " + e.Symbol + " " + right, ok1 && ok2
+
+	case *parser.FuncExpr:
+		args := []string{}
+
+		for _, arg := range e.Args {
+			value, ok := s.expr(arg)
+
+			if !ok {
+				return "", false
+			}
+
+			args = append(args, value)
+		}
+
+		return fmt.Sprintf("%v(%v)", strings.ToLower(e.Symbol), strings.Join(args, ", ")), true
+
+	default:
+		return "", false
+	}
+}
+
+/*
+Literals with a prefix for their base, and hexadecimal digits
+in uppercase
+*/
+func literal(l *parser.Literal) string {
+	switch l.Base {
+	case 16:
+		return "0x" + strings.ToUpper(l.Value)
+	case 8:
+		return "0" + l.Value
+	case 2:
+		return "0b" + l.Value
+	default:
+		return l.Value
+	}
+}
+
+/*
+Registers in lowercase, and the pointer registers in uppercase
+*/
+func register(r parser.Reg) (string, bool) {
+	switch r := r.(type) {
+	case *parser.Register:
+		if _, err := strconv.Atoi(r.Value); err == nil {
+			return "r" + r.Value, true
+		}
+
+		return strings.ToLower(r.Value), true
+
+	// Pairs keep only their lower register
+	case *parser.RegPair:
+		low, err := strconv.Atoi(r.Value)
+		return fmt.Sprintf("r%v:r%v", low+1, low), err == nil
+
+	case *parser.RegPointer:
+		return strings.ToUpper(r.Value), true
+
+	case *parser.RegPointerPostInc:
+		return strings.ToUpper(r.Value) + "+", true
+
+	case *parser.RegPointerPreDec:
+		return "-" + strings.ToUpper(r.Value), true
+
+	case *parser.RegPointerDisp:
+		return strings.ToUpper(r.Value) + "+" + r.Disp, true
+
+	default:
+		return "", false
+	}
+}
+
+/*
+A name as it is spelled in the code, the next whole word after
+those already found which matches it. Hexadecimal numbers with
+a dollar sign, which are read as names, have uppercase digits.
+*/
+func (s *spelling) name(value string) string {
+	spelled := value
+
+	for i := s.At; i+len(value) <= len(s.Code); i++ {
+		if !strings.EqualFold(s.Code[i:i+len(value)], value) {
+			continue
+		}
+
+		before := i == 0 || !isNameByte(s.Code[i-1])
+		after := i+len(value) == len(s.Code) || !isNameByte(s.Code[i+len(value)])
+
+		if before && after {
+			spelled, s.At = s.Code[i:i+len(value)], i+len(value)
+			break
+		}
+	}
+
+	if isDollarHex(spelled) {
+		return "$" + strings.ToUpper(spelled[1:])
+	}
+
+	return spelled
+}
+
+/*
+A string as it is written in the code, with its quotes and any
+escapes in it
+*/
+func (s *spelling) quoted(value string) string {
+	start := strings.IndexByte(s.Code[s.At:], '"')
+
+	if start == -1 {
+		return strconv.Quote(value)
+	}
+
+	start += s.At
+
+	for i := start + 1; i < len(s.Code); i++ {
+		switch s.Code[i] {
+		case '\\':
+			i++
+
+		case '"':
+			s.At = i + 1
+			return s.Code[start:s.At]
+		}
+	}
+
+	return strconv.Quote(value)
+}
+
+/*
+The code following the first occurrence of a word, as for the
+content of a directive the parser skips
+*/
+func (s *spelling) rest(word string) string {
+	i := strings.Index(strings.ToLower(s.Code), strings.ToLower(word))
+
+	if i == -1 {
+		return ""
+	}
+
+	return strings.TrimSpace(s.Code[i+len(word):])
+}
+
+func isDollarHex(name string) bool {
+	if len(name) < 2 || name[0] != '$' {
+		return false
+	}
+
+	_, err := strconv.ParseUint(name[1:], 16, 64)
+	return err == nil
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c == '.' || c == '$' ||
+		('a' <= c && c <= 'z') ||
+		('A' <= c && c <= 'Z') ||
+		('0' <= c && c <= '9')
+}
@@ -60,6 +60,10 @@ func (l *List) Augment(v Value) error {
 	return nil
 }
 
+func (t *Text) Augment(v Value) error {
+	return nil
+}
+
 func (a *Assignment) Augment(v Value) error {
 	return nil
 }
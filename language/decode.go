@@ -0,0 +1,283 @@
+package language
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/silaspace/aria/device"
+)
+
+/*
+Instructions that are another instruction with its operands
+filled in, such as clr for eor of a register with itself. They
+are decoded as the instruction they stand for.
+*/
+var Aliases = map[Mnemonic]Mnemonic{
+	BRLO: BRCS,
+	BRSH: BRCC,
+	CBR:  ANDI,
+	CLR:  EOR,
+	LSL:  ADD,
+	ROL:  ADC,
+	SBR:  ORI,
+	SER:  LDI,
+	TST:  AND,
+}
+
+/*
+An instruction decoded from program memory, with the values
+of its operands, or Nil for those it does not have
+*/
+type Decoded struct {
+	Instruction Instruction
+	Mnemonic    Mnemonic
+	Op1         Value
+	Op2         Value
+}
+
+/*
+The bits of an instruction holding an operand, found by giving
+the operand's encoder every value it accepts. Registers and
+pointers are looked up by their encoding, while each bit of an
+integer is given the bit it is encoded in. Signed integers are
+sign extended from their highest bit.
+*/
+type Field struct {
+	Bits   []uint64
+	Mask   uint64
+	Sign   int
+	Values map[uint64]Value
+	Zero   uint64
+}
+
+type Pattern struct {
+	Fixed       int
+	Instruction Instruction
+	Mnemonic    Mnemonic
+	Op1         Field
+	Op2         Field
+}
+
+/*
+Decodes words of program memory into the instructions of a
+device, using the encoders of the instruction set in reverse.
+Instructions with more fixed bits are tried first, so that sec
+is preferred to bset 0.
+*/
+type Decoder struct {
+	Patterns []Pattern
+}
+
+func NewDecoder(dev *device.Device) *Decoder {
+	patterns := []Pattern{}
+
+	for _, mn := range InstructionSet(dev) {
+		if _, alias := Aliases[mn]; alias {
+			continue
+		}
+
+		instr, _ := GetInstr(string(mn), dev)
+
+		pattern := Pattern{
+			Instruction: instr,
+			Mnemonic:    mn,
+			Op1:         probe(instr.Op1),
+			Op2:         probe(instr.Op2),
+		}
+
+		width := 16
+
+		if instr.IsLong() {
+			width = 32
+		}
+
+		pattern.Fixed = width - bits.OnesCount64(pattern.Op1.Mask|pattern.Op2.Mask)
+		patterns = append(patterns, pattern)
+	}
+
+	sort.SliceStable(patterns, func(i, j int) bool {
+		if patterns[i].Fixed != patterns[j].Fixed {
+			return patterns[i].Fixed > patterns[j].Fixed
+		}
+
+		return patterns[i].Mnemonic < patterns[j].Mnemonic
+	})
+
+	return &Decoder{
+		Patterns: patterns,
+	}
+}
+
+/*
+Decode the instruction in the first of the given words. The
+second word is only needed by 32-bit instructions.
+*/
+func (d *Decoder) Decode(words []uint16) (*Decoded, error) {
+	if len(words) == 0 {
+		return nil, fmt.Errorf("no words to decode")
+	}
+
+	for _, pattern := range d.Patterns {
+		word := uint64(words[0])
+
+		if pattern.Instruction.IsLong() {
+			if len(words) < 2 {
+				continue
+			}
+
+			word = word<<16 | uint64(words[1])
+		}
+
+		mask := pattern.Op1.Mask | pattern.Op2.Mask
+
+		if word&^mask != pattern.Instruction.Base&^mask {
+			continue
+		}
+
+		op1, ok1 := pattern.Op1.decode(word)
+		op2, ok2 := pattern.Op2.decode(word)
+
+		if !ok1 || !ok2 {
+			continue
+		}
+
+		// Encoding the operands again must give the same word
+		instr := pattern.Instruction
+
+		if instr.Apply1(op1) != nil || instr.Apply2(op2) != nil || instr.Base != word {
+			continue
+		}
+
+		return &Decoded{
+			Instruction: pattern.Instruction,
+			Mnemonic:    pattern.Mnemonic,
+			Op1:         op1,
+			Op2:         op2,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("0x%04X is not an instruction", words[0])
+}
+
+/*
+Size of the instruction in words
+*/
+func (d *Decoded) Size() uint64 {
+	if d.Instruction.IsLong() {
+		return 2
+	}
+
+	return 1
+}
+
+func (f *Field) decode(word uint64) (Value, bool) {
+	if f.Mask == 0 {
+		return &Nil{}, true
+	}
+
+	if f.Values != nil {
+		val, exists := f.Values[word&f.Mask]
+		return val, exists
+	}
+
+	field := (word ^ f.Zero) & f.Mask
+	val := uint64(0)
+
+	for i, bit := range f.Bits {
+		if bit != 0 && field&bit == bit {
+			val |= 1 << i
+		}
+	}
+
+	if f.Sign >= 0 && val&(1<<f.Sign) != 0 {
+		val |= ^uint64(0) << f.Sign
+	}
+
+	return &Int{
+		Value: val,
+	}, true
+}
+
+/*
+Find the bits an operand is encoded in from its encoder
+*/
+func probe(op OpFunc) Field {
+	field := Field{
+		Sign: -1,
+	}
+
+	if op == nil {
+		return field
+	}
+
+	zero, err := op(0, &Int{Value: 0})
+
+	if err != nil {
+		field.Values = map[uint64]Value{}
+
+		for _, val := range operands() {
+			out, err := op(0, val)
+
+			if err != nil {
+				continue
+			}
+
+			if _, exists := field.Values[out]; !exists {
+				field.Values[out] = val
+			}
+
+			field.Mask |= out
+		}
+
+		return field
+	}
+
+	field.Zero = zero
+
+	// Integers of up to 22 bits, as in the address of a jmp
+	for i := 0; i < 22; i++ {
+		out, err := op(0, &Int{Value: 1 << i})
+
+		// The highest bit of a signed integer is only accepted when negative
+		if err != nil || out == zero {
+			neg, err := op(0, &Int{Value: -(uint64(1) << i)})
+			out = zero
+
+			if err == nil && neg != zero {
+				out = neg
+				field.Sign = i
+			}
+		}
+
+		field.Bits = append(field.Bits, out^zero)
+		field.Mask |= out ^ zero
+	}
+
+	return field
+}
+
+/*
+Every register, register pair and pointer an operand may be
+*/
+func operands() []Value {
+	values := []Value{}
+
+	for i := uint64(0); i < 32; i++ {
+		values = append(values, &Reg{Value: i}, &RegPair{Value: i})
+	}
+
+	for _, ptr := range []Mnemonic{X, Y, Z} {
+		values = append(values,
+			&RegPointer{Value: string(ptr)},
+			&RegPointerPostInc{Value: string(ptr)},
+			&RegPointerPreDec{Value: string(ptr)},
+		)
+
+		for disp := uint64(0); disp < 64; disp++ {
+			values = append(values, &RegPointerDisp{Value: string(ptr), Disp: disp})
+		}
+	}
+
+	return values
+}
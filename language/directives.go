@@ -1,22 +1,108 @@
 package language
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 type Directive struct {
 	Execute func(Assembler, Value) error
 }
 
 type Assembler interface {
+	AddAlias(string, uint64) error
 	AddSymbol(string, uint64) error
+	Align(uint64) error
+	Data([]uint64, uint64) error
+	Defined(string) bool
+	Else() error
+	ElseIf(Value) error
+	EndIf() error
+	EndMacro() error
+	Extern(string) error
+	Fill(uint64) error
+	Global(string) error
+	If(Value) error
+	Macro(string) error
+	Reserve(uint64) error
 	SetDevice(string) error
+	SetList(bool) error
+	SetListMacros(bool) error
+	SetSection(string) error
+	SetSegment(Segment) error
+	SetSymbol(string, uint64) error
 }
 
+type Segment int
+
+/* Memory segments */
+const (
+	CSEG Segment = 0 /* Flash, addressed in words */
+	DSEG Segment = 1 /* SRAM, addressed in bytes */
+	ESEG Segment = 2 /* EEPROM, addressed in bytes */
+)
+
 const (
-	DIR_DEVICE Mnemonic = "device"
-	DIR_EQU    Mnemonic = "equ"
+	DIR_BYTE     Mnemonic = "byte"
+	DIR_CSEG     Mnemonic = "cseg"
+	DIR_DEF      Mnemonic = "def"
+	DIR_DEVICE   Mnemonic = "device"
+	DIR_DSEG     Mnemonic = "dseg"
+	DIR_DW       Mnemonic = "dw"
+	DIR_ELIF     Mnemonic = "elif"
+	DIR_ELSE     Mnemonic = "else"
+	DIR_ENDIF    Mnemonic = "endif"
+	DIR_ENDM     Mnemonic = "endm"
+	DIR_ENDMACRO Mnemonic = "endmacro"
+	DIR_EQU      Mnemonic = "equ"
+	DIR_ESEG     Mnemonic = "eseg"
+	DIR_EXTERN   Mnemonic = "extern"
+	DIR_GLOBAL   Mnemonic = "global"
+	DIR_IF       Mnemonic = "if"
+	DIR_IFDEF    Mnemonic = "ifdef"
+	DIR_IFNDEF   Mnemonic = "ifndef"
+	DIR_LIST     Mnemonic = "list"
+	DIR_LISTMAC  Mnemonic = "listmac"
+	DIR_MACRO    Mnemonic = "macro"
+	DIR_NOLIST   Mnemonic = "nolist"
+	DIR_SECTION  Mnemonic = "section"
+	DIR_SET      Mnemonic = "set"
 )
 
 var Directives = map[Mnemonic]Directive{
+	DIR_BYTE: {
+		Execute: func(a Assembler, v Value) error {
+			switch v := v.(type) {
+			case *Int:
+				return a.Reserve(v.Value)
+
+			case *Error:
+				return errors.New(v.Value)
+
+			default:
+				return fmt.Errorf("expected int, got '%v'", v.Fmt())
+			}
+		},
+	},
+	DIR_CSEG: {
+		Execute: func(a Assembler, v Value) error {
+			return a.SetSegment(CSEG)
+		},
+	},
+	DIR_DEF: {
+		Execute: func(a Assembler, v Value) error {
+			switch v := v.(type) {
+			case *Assignment:
+				return a.AddAlias(v.Symbol, v.Value)
+
+			case *Error:
+				return errors.New(v.Value)
+
+			default:
+				return fmt.Errorf("expected register alias, got '%v'", v.Fmt())
+			}
+		},
+	},
 	DIR_DEVICE: {
 		Execute: func(a Assembler, v Value) error {
 			switch v := v.(type) {
@@ -34,6 +120,41 @@ var Directives = map[Mnemonic]Directive{
 			}
 		},
 	},
+	DIR_DSEG: {
+		Execute: func(a Assembler, v Value) error {
+			return a.SetSegment(DSEG)
+		},
+	},
+	DIR_DW: {
+		Execute: func(a Assembler, v Value) error {
+			return data(a, v, 2)
+		},
+	},
+	DIR_ELIF: {
+		Execute: func(a Assembler, v Value) error {
+			return a.ElseIf(v)
+		},
+	},
+	DIR_ELSE: {
+		Execute: func(a Assembler, v Value) error {
+			return a.Else()
+		},
+	},
+	DIR_ENDIF: {
+		Execute: func(a Assembler, v Value) error {
+			return a.EndIf()
+		},
+	},
+	DIR_ENDM: {
+		Execute: func(a Assembler, v Value) error {
+			return a.EndMacro()
+		},
+	},
+	DIR_ENDMACRO: {
+		Execute: func(a Assembler, v Value) error {
+			return a.EndMacro()
+		},
+	},
 	DIR_EQU: {
 		Execute: func(a Assembler, v Value) error {
 			switch v := v.(type) {
@@ -46,9 +167,138 @@ var Directives = map[Mnemonic]Directive{
 
 				return nil
 
+			case *Error:
+				return errors.New(v.Value)
+
 			default:
 				return fmt.Errorf("expected assignment, got '%v'", v)
 			}
 		},
 	},
+	DIR_ESEG: {
+		Execute: func(a Assembler, v Value) error {
+			return a.SetSegment(ESEG)
+		},
+	},
+	DIR_EXTERN: {
+		Execute: func(a Assembler, v Value) error {
+			switch v := v.(type) {
+			case *Ident:
+				return a.Extern(v.Value)
+
+			default:
+				return fmt.Errorf("expected symbol, got '%v'", v.Fmt())
+			}
+		},
+	},
+	DIR_GLOBAL: {
+		Execute: func(a Assembler, v Value) error {
+			switch v := v.(type) {
+			case *Ident:
+				return a.Global(v.Value)
+
+			default:
+				return fmt.Errorf("expected symbol, got '%v'", v.Fmt())
+			}
+		},
+	},
+	DIR_IF: {
+		Execute: func(a Assembler, v Value) error {
+			return a.If(v)
+		},
+	},
+	DIR_IFDEF: {
+		Execute: func(a Assembler, v Value) error {
+			switch v := v.(type) {
+			case *Ident:
+				return a.If(truth(a.Defined(v.Value)))
+
+			default:
+				return fmt.Errorf("expected symbol, got '%v'", v.Fmt())
+			}
+		},
+	},
+	DIR_IFNDEF: {
+		Execute: func(a Assembler, v Value) error {
+			switch v := v.(type) {
+			case *Ident:
+				return a.If(truth(!a.Defined(v.Value)))
+
+			default:
+				return fmt.Errorf("expected symbol, got '%v'", v.Fmt())
+			}
+		},
+	},
+	DIR_LIST: {
+		Execute: func(a Assembler, v Value) error {
+			return a.SetList(true)
+		},
+	},
+	DIR_LISTMAC: {
+		Execute: func(a Assembler, v Value) error {
+			return a.SetListMacros(true)
+		},
+	},
+	DIR_MACRO: {
+		Execute: func(a Assembler, v Value) error {
+			switch v := v.(type) {
+			case *Ident:
+				return a.Macro(v.Value)
+
+			default:
+				return fmt.Errorf("expected macro name, got '%v'", v.Fmt())
+			}
+		},
+	},
+	DIR_NOLIST: {
+		Execute: func(a Assembler, v Value) error {
+			return a.SetList(false)
+		},
+	},
+	DIR_SECTION: {
+		Execute: func(a Assembler, v Value) error {
+			switch v := v.(type) {
+			case *Ident:
+				return a.SetSection(v.Value)
+
+			default:
+				return fmt.Errorf("expected section name, got '%v'", v.Fmt())
+			}
+		},
+	},
+	DIR_SET: {
+		Execute: func(a Assembler, v Value) error {
+			switch v := v.(type) {
+			case *Assignment:
+				return a.SetSymbol(v.Symbol, v.Value)
+
+			case *Error:
+				return errors.New(v.Value)
+
+			default:
+				return fmt.Errorf("expected assignment, got '%v'", v)
+			}
+		},
+	},
+}
+
+func truth(b bool) Value {
+	if b {
+		return &Int{Value: 1}
+	}
+
+	return &Int{Value: 0}
+}
+
+func (s Segment) String() string {
+	switch s {
+	case CSEG:
+		return "cseg"
+	case DSEG:
+		return "dseg"
+	case ESEG:
+		return "eseg"
+	default:
+		return fmt.Sprintf("segment %d", int(s))
+	}
 }
@@ -0,0 +1,698 @@
+package language
+
+/*
+A short description of an instruction, as shown when editing
+source. Operands are named as in the instruction set manual,
+and Flags lists the flags of SREG it changes.
+*/
+type Doc struct {
+	Flags     string
+	Operands  string
+	Operation string
+	Summary   string
+}
+
+var Docs = map[Mnemonic]Doc{
+	ADC: {
+		Flags:     "Z, C, N, V, S, H",
+		Operands:  "Rd, Rr",
+		Operation: "Rd ← Rd + Rr + C",
+		Summary:   "Add with carry",
+	},
+
+	ADD: {
+		Flags:     "Z, C, N, V, S, H",
+		Operands:  "Rd, Rr",
+		Operation: "Rd ← Rd + Rr",
+		Summary:   "Add without carry",
+	},
+
+	AND: {
+		Flags:     "Z, N, V, S",
+		Operands:  "Rd, Rr",
+		Operation: "Rd ← Rd & Rr",
+		Summary:   "Logical AND",
+	},
+
+	ANDI: {
+		Flags:     "Z, N, V, S",
+		Operands:  "Rd, K",
+		Operation: "Rd ← Rd & K",
+		Summary:   "Logical AND with immediate",
+	},
+
+	ADIW: {
+		Flags:     "Z, C, N, V, S",
+		Operands:  "Rd+1:Rd, K",
+		Operation: "Rd+1:Rd ← Rd+1:Rd + K",
+		Summary:   "Add immediate to word",
+	},
+
+	ASR: {
+		Flags:     "Z, C, N, V, S",
+		Operands:  "Rd",
+		Operation: "Rd(n) ← Rd(n+1), C ← Rd(0), Rd(7) unchanged",
+		Summary:   "Arithmetic shift right",
+	},
+
+	BCLR: {
+		Flags:     "SREG(s)",
+		Operands:  "s",
+		Operation: "SREG(s) ← 0",
+		Summary:   "Clear a flag of SREG",
+	},
+
+	BLD: {
+		Operands:  "Rd, b",
+		Operation: "Rd(b) ← T",
+		Summary:   "Load a bit of a register from T",
+	},
+
+	BRBC: {
+		Operands:  "s, k",
+		Operation: "if SREG(s) = 0 then PC ← PC + k + 1",
+		Summary:   "Branch if a flag of SREG is clear",
+	},
+
+	BRBS: {
+		Operands:  "s, k",
+		Operation: "if SREG(s) = 1 then PC ← PC + k + 1",
+		Summary:   "Branch if a flag of SREG is set",
+	},
+
+	BRCC: {
+		Operands:  "k",
+		Operation: "if C = 0 then PC ← PC + k + 1",
+		Summary:   "Branch if carry clear",
+	},
+
+	BRCS: {
+		Operands:  "k",
+		Operation: "if C = 1 then PC ← PC + k + 1",
+		Summary:   "Branch if carry set",
+	},
+
+	BREAK: {
+		Operation: "Stop for the on-chip debugger",
+		Summary:   "Break",
+	},
+
+	BREQ: {
+		Operands:  "k",
+		Operation: "if Z = 1 then PC ← PC + k + 1",
+		Summary:   "Branch if equal",
+	},
+
+	BRGE: {
+		Operands:  "k",
+		Operation: "if S = 0 then PC ← PC + k + 1",
+		Summary:   "Branch if greater or equal, signed",
+	},
+
+	BRHC: {
+		Operands:  "k",
+		Operation: "if H = 0 then PC ← PC + k + 1",
+		Summary:   "Branch if half carry clear",
+	},
+
+	BRHS: {
+		Operands:  "k",
+		Operation: "if H = 1 then PC ← PC + k + 1",
+		Summary:   "Branch if half carry set",
+	},
+
+	BRID: {
+		Operands:  "k",
+		Operation: "if I = 0 then PC ← PC + k + 1",
+		Summary:   "Branch if interrupts disabled",
+	},
+
+	BIRE: {
+		Operands:  "k",
+		Operation: "if I = 1 then PC ← PC + k + 1",
+		Summary:   "Branch if interrupts enabled",
+	},
+
+	BRLO: {
+		Operands:  "k",
+		Operation: "if C = 1 then PC ← PC + k + 1",
+		Summary:   "Branch if lower, unsigned",
+	},
+
+	BRLT: {
+		Operands:  "k",
+		Operation: "if S = 1 then PC ← PC + k + 1",
+		Summary:   "Branch if less than, signed",
+	},
+
+	BRMI: {
+		Operands:  "k",
+		Operation: "if N = 1 then PC ← PC + k + 1",
+		Summary:   "Branch if minus",
+	},
+
+	BRNE: {
+		Operands:  "k",
+		Operation: "if Z = 0 then PC ← PC + k + 1",
+		Summary:   "Branch if not equal",
+	},
+
+	BRPL: {
+		Operands:  "k",
+		Operation: "if N = 0 then PC ← PC + k + 1",
+		Summary:   "Branch if plus",
+	},
+
+	BRSH: {
+		Operands:  "k",
+		Operation: "if C = 0 then PC ← PC + k + 1",
+		Summary:   "Branch if same or higher, unsigned",
+	},
+
+	BRTC: {
+		Operands:  "k",
+		Operation: "if T = 0 then PC ← PC + k + 1",
+		Summary:   "Branch if T clear",
+	},
+
+	BRTS: {
+		Operands:  "k",
+		Operation: "if T = 1 then PC ← PC + k + 1",
+		Summary:   "Branch if T set",
+	},
+
+	BRVC: {
+		Operands:  "k",
+		Operation: "if V = 0 then PC ← PC + k + 1",
+		Summary:   "Branch if overflow clear",
+	},
+
+	BRVS: {
+		Operands:  "k",
+		Operation: "if V = 1 then PC ← PC + k + 1",
+		Summary:   "Branch if overflow set",
+	},
+
+	BSET: {
+		Flags:     "SREG(s)",
+		Operands:  "s",
+		Operation: "SREG(s) ← 1",
+		Summary:   "Set a flag of SREG",
+	},
+
+	BST: {
+		Flags:     "T",
+		Operands:  "Rr, b",
+		Operation: "T ← Rr(b)",
+		Summary:   "Store a bit of a register in T",
+	},
+
+	CALL: {
+		Operands:  "k",
+		Operation: "STACK ← PC + 2, PC ← k",
+		Summary:   "Call a subroutine anywhere in flash",
+	},
+
+	CBI: {
+		Operands:  "A, b",
+		Operation: "I/O(A, b) ← 0",
+		Summary:   "Clear a bit of an I/O register",
+	},
+
+	CBR: {
+		Flags:     "Z, N, V, S",
+		Operands:  "Rd, K",
+		Operation: "Rd ← Rd & (0xFF - K)",
+		Summary:   "Clear bits of a register",
+	},
+
+	CLC: {
+		Flags:     "C",
+		Operation: "C ← 0",
+		Summary:   "Clear carry",
+	},
+
+	CLH: {
+		Flags:     "H",
+		Operation: "H ← 0",
+		Summary:   "Clear half carry",
+	},
+
+	CLI: {
+		Flags:     "I",
+		Operation: "I ← 0",
+		Summary:   "Disable interrupts",
+	},
+
+	CLN: {
+		Flags:     "N",
+		Operation: "N ← 0",
+		Summary:   "Clear negative",
+	},
+
+	CLR: {
+		Flags:     "Z, N, V, S",
+		Operands:  "Rd",
+		Operation: "Rd ← Rd ^ Rd",
+		Summary:   "Clear a register",
+	},
+
+	CLS: {
+		Flags:     "S",
+		Operation: "S ← 0",
+		Summary:   "Clear signed",
+	},
+
+	CLT: {
+		Flags:     "T",
+		Operation: "T ← 0",
+		Summary:   "Clear T",
+	},
+
+	CLV: {
+		Flags:     "V",
+		Operation: "V ← 0",
+		Summary:   "Clear overflow",
+	},
+
+	CLZ: {
+		Flags:     "Z",
+		Operation: "Z ← 0",
+		Summary:   "Clear zero",
+	},
+
+	COM: {
+		Flags:     "Z, C, N, V, S",
+		Operands:  "Rd",
+		Operation: "Rd ← 0xFF - Rd",
+		Summary:   "One's complement",
+	},
+
+	CP: {
+		Flags:     "Z, C, N, V, S, H",
+		Operands:  "Rd, Rr",
+		Operation: "Rd - Rr",
+		Summary:   "Compare",
+	},
+
+	CPC: {
+		Flags:     "Z, C, N, V, S, H",
+		Operands:  "Rd, Rr",
+		Operation: "Rd - Rr - C",
+		Summary:   "Compare with carry",
+	},
+
+	CPI: {
+		Flags:     "Z, C, N, V, S, H",
+		Operands:  "Rd, K",
+		Operation: "Rd - K",
+		Summary:   "Compare with immediate",
+	},
+
+	CPSE: {
+		Operands:  "Rd, Rr",
+		Operation: "if Rd = Rr then skip the next instruction",
+		Summary:   "Compare, skip if equal",
+	},
+
+	DEC: {
+		Flags:     "Z, N, V, S",
+		Operands:  "Rd",
+		Operation: "Rd ← Rd - 1",
+		Summary:   "Decrement",
+	},
+
+	EOR: {
+		Flags:     "Z, N, V, S",
+		Operands:  "Rd, Rr",
+		Operation: "Rd ← Rd ^ Rr",
+		Summary:   "Exclusive OR",
+	},
+
+	FMUL: {
+		Flags:     "Z, C",
+		Operands:  "Rd, Rr",
+		Operation: "R1:R0 ← (Rd × Rr) << 1, unsigned",
+		Summary:   "Fractional multiply, unsigned",
+	},
+
+	FMULS: {
+		Flags:     "Z, C",
+		Operands:  "Rd, Rr",
+		Operation: "R1:R0 ← (Rd × Rr) << 1, signed",
+		Summary:   "Fractional multiply, signed",
+	},
+
+	FMULSU: {
+		Flags:     "Z, C",
+		Operands:  "Rd, Rr",
+		Operation: "R1:R0 ← (Rd × Rr) << 1, signed by unsigned",
+		Summary:   "Fractional multiply, signed by unsigned",
+	},
+
+	ICALL: {
+		Operation: "STACK ← PC + 1, PC ← Z",
+		Summary:   "Call the subroutine Z points to",
+	},
+
+	IJMP: {
+		Operation: "PC ← Z",
+		Summary:   "Jump to the address in Z",
+	},
+
+	IN: {
+		Operands:  "Rd, A",
+		Operation: "Rd ← I/O(A)",
+		Summary:   "Read an I/O register",
+	},
+
+	INC: {
+		Flags:     "Z, N, V, S",
+		Operands:  "Rd",
+		Operation: "Rd ← Rd + 1",
+		Summary:   "Increment",
+	},
+
+	JMP: {
+		Operands:  "k",
+		Operation: "PC ← k",
+		Summary:   "Jump anywhere in flash",
+	},
+
+	LD: {
+		Operands:  "Rd, X",
+		Operation: "Rd ← (X), with X, Y or Z, optionally X+ or -X",
+		Summary:   "Load indirect from data space",
+	},
+
+	LDD: {
+		Operands:  "Rd, Y+q",
+		Operation: "Rd ← (Y + q), with Y or Z",
+		Summary:   "Load indirect with displacement",
+	},
+
+	LDI: {
+		Operands:  "Rd, K",
+		Operation: "Rd ← K",
+		Summary:   "Load immediate",
+	},
+
+	LDS: {
+		Operands:  "Rd, k",
+		Operation: "Rd ← (k)",
+		Summary:   "Load direct from data space",
+	},
+
+	LPM: {
+		Operation: "R0 ← (Z)",
+		Summary:   "Load from program memory",
+	},
+
+	LSL: {
+		Flags:     "Z, C, N, V, S, H",
+		Operands:  "Rd",
+		Operation: "Rd(n+1) ← Rd(n), Rd(0) ← 0, C ← Rd(7)",
+		Summary:   "Logical shift left",
+	},
+
+	LSR: {
+		Flags:     "Z, C, N, V, S",
+		Operands:  "Rd",
+		Operation: "Rd(n) ← Rd(n+1), Rd(7) ← 0, C ← Rd(0)",
+		Summary:   "Logical shift right",
+	},
+
+	MOV: {
+		Operands:  "Rd, Rr",
+		Operation: "Rd ← Rr",
+		Summary:   "Copy a register",
+	},
+
+	MUL: {
+		Flags:     "Z, C",
+		Operands:  "Rd, Rr",
+		Operation: "R1:R0 ← Rd × Rr, unsigned",
+		Summary:   "Multiply, unsigned",
+	},
+
+	MULS: {
+		Flags:     "Z, C",
+		Operands:  "Rd, Rr",
+		Operation: "R1:R0 ← Rd × Rr, signed",
+		Summary:   "Multiply, signed",
+	},
+
+	MULSU: {
+		Flags:     "Z, C",
+		Operands:  "Rd, Rr",
+		Operation: "R1:R0 ← Rd × Rr, signed by unsigned",
+		Summary:   "Multiply, signed by unsigned",
+	},
+
+	NEG: {
+		Flags:     "Z, C, N, V, S, H",
+		Operands:  "Rd",
+		Operation: "Rd ← 0x00 - Rd",
+		Summary:   "Two's complement",
+	},
+
+	NOP: {
+		Summary: "No operation",
+	},
+
+	OR: {
+		Flags:     "Z, N, V, S",
+		Operands:  "Rd, Rr",
+		Operation: "Rd ← Rd | Rr",
+		Summary:   "Logical OR",
+	},
+
+	ORI: {
+		Flags:     "Z, N, V, S",
+		Operands:  "Rd, K",
+		Operation: "Rd ← Rd | K",
+		Summary:   "Logical OR with immediate",
+	},
+
+	OUT: {
+		Operands:  "A, Rr",
+		Operation: "I/O(A) ← Rr",
+		Summary:   "Write an I/O register",
+	},
+
+	POP: {
+		Operands:  "Rd",
+		Operation: "Rd ← STACK",
+		Summary:   "Pop a register from the stack",
+	},
+
+	PUSH: {
+		Operands:  "Rr",
+		Operation: "STACK ← Rr",
+		Summary:   "Push a register onto the stack",
+	},
+
+	RCALL: {
+		Operands:  "k",
+		Operation: "STACK ← PC + 1, PC ← PC + k + 1",
+		Summary:   "Relative call to a subroutine",
+	},
+
+	RET: {
+		Operation: "PC ← STACK",
+		Summary:   "Return from a subroutine",
+	},
+
+	RETI: {
+		Flags:     "I",
+		Operation: "PC ← STACK, I ← 1",
+		Summary:   "Return from an interrupt",
+	},
+
+	RJMP: {
+		Operands:  "k",
+		Operation: "PC ← PC + k + 1",
+		Summary:   "Relative jump",
+	},
+
+	ROL: {
+		Flags:     "Z, C, N, V, S, H",
+		Operands:  "Rd",
+		Operation: "Rd(0) ← C, Rd(n+1) ← Rd(n), C ← Rd(7)",
+		Summary:   "Rotate left through carry",
+	},
+
+	ROR: {
+		Flags:     "Z, C, N, V, S",
+		Operands:  "Rd",
+		Operation: "Rd(7) ← C, Rd(n) ← Rd(n+1), C ← Rd(0)",
+		Summary:   "Rotate right through carry",
+	},
+
+	SBC: {
+		Flags:     "Z, C, N, V, S, H",
+		Operands:  "Rd, Rr",
+		Operation: "Rd ← Rd - Rr - C",
+		Summary:   "Subtract with carry",
+	},
+
+	SBCI: {
+		Flags:     "Z, C, N, V, S, H",
+		Operands:  "Rd, K",
+		Operation: "Rd ← Rd - K - C",
+		Summary:   "Subtract immediate with carry",
+	},
+
+	SBI: {
+		Operands:  "A, b",
+		Operation: "I/O(A, b) ← 1",
+		Summary:   "Set a bit of an I/O register",
+	},
+
+	SBIC: {
+		Operands:  "A, b",
+		Operation: "if I/O(A, b) = 0 then skip the next instruction",
+		Summary:   "Skip if a bit of an I/O register is clear",
+	},
+
+	SBIS: {
+		Operands:  "A, b",
+		Operation: "if I/O(A, b) = 1 then skip the next instruction",
+		Summary:   "Skip if a bit of an I/O register is set",
+	},
+
+	SBIW: {
+		Flags:     "Z, C, N, V, S",
+		Operands:  "Rd+1:Rd, K",
+		Operation: "Rd+1:Rd ← Rd+1:Rd - K",
+		Summary:   "Subtract immediate from word",
+	},
+
+	SBR: {
+		Flags:     "Z, N, V, S",
+		Operands:  "Rd, K",
+		Operation: "Rd ← Rd | K",
+		Summary:   "Set bits of a register",
+	},
+
+	SBRC: {
+		Operands:  "Rr, b",
+		Operation: "if Rr(b) = 0 then skip the next instruction",
+		Summary:   "Skip if a bit of a register is clear",
+	},
+
+	SBRS: {
+		Operands:  "Rr, b",
+		Operation: "if Rr(b) = 1 then skip the next instruction",
+		Summary:   "Skip if a bit of a register is set",
+	},
+
+	SEC: {
+		Flags:     "C",
+		Operation: "C ← 1",
+		Summary:   "Set carry",
+	},
+
+	SEH: {
+		Flags:     "H",
+		Operation: "H ← 1",
+		Summary:   "Set half carry",
+	},
+
+	SEI: {
+		Flags:     "I",
+		Operation: "I ← 1",
+		Summary:   "Enable interrupts",
+	},
+
+	SEN: {
+		Flags:     "N",
+		Operation: "N ← 1",
+		Summary:   "Set negative",
+	},
+
+	SER: {
+		Operands:  "Rd",
+		Operation: "Rd ← 0xFF",
+		Summary:   "Set all bits of a register",
+	},
+
+	SES: {
+		Flags:     "S",
+		Operation: "S ← 1",
+		Summary:   "Set signed",
+	},
+
+	SET: {
+		Flags:     "T",
+		Operation: "T ← 1",
+		Summary:   "Set T",
+	},
+
+	SEV: {
+		Flags:     "V",
+		Operation: "V ← 1",
+		Summary:   "Set overflow",
+	},
+
+	SEZ: {
+		Flags:     "Z",
+		Operation: "Z ← 1",
+		Summary:   "Set zero",
+	},
+
+	SLEEP: {
+		Operation: "Enter the sleep mode set in the sleep control register",
+		Summary:   "Sleep",
+	},
+
+	ST: {
+		Operands:  "X, Rr",
+		Operation: "(X) ← Rr, with X, Y or Z, optionally X+ or -X",
+		Summary:   "Store indirect to data space",
+	},
+
+	STD: {
+		Operands:  "Y+q, Rr",
+		Operation: "(Y + q) ← Rr, with Y or Z",
+		Summary:   "Store indirect with displacement",
+	},
+
+	STS: {
+		Operands:  "k, Rr",
+		Operation: "(k) ← Rr",
+		Summary:   "Store direct to data space",
+	},
+
+	SUB: {
+		Flags:     "Z, C, N, V, S, H",
+		Operands:  "Rd, Rr",
+		Operation: "Rd ← Rd - Rr",
+		Summary:   "Subtract without carry",
+	},
+
+	SUBI: {
+		Flags:     "Z, C, N, V, S, H",
+		Operands:  "Rd, K",
+		Operation: "Rd ← Rd - K",
+		Summary:   "Subtract immediate",
+	},
+
+	SWAP: {
+		Operands:  "Rd",
+		Operation: "Rd(7:4) ↔ Rd(3:0)",
+		Summary:   "Swap nibbles",
+	},
+
+	TST: {
+		Flags:     "Z, N, V, S",
+		Operands:  "Rd",
+		Operation: "Rd & Rd",
+		Summary:   "Test for zero or minus",
+	},
+
+	WDR: {
+		Operation: "Reset the watchdog timer",
+		Summary:   "Watchdog reset",
+	},
+}
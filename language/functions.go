@@ -1,82 +1,185 @@
 package language
 
+import (
+	"fmt"
+	"math"
+)
+
+type Param int
+
+/*
+What a function takes as each argument. Symbols are passed as
+1 if defined and 0 if not, and strings as their length, since
+nothing else about them can be used in an expression.
+*/
+const (
+	PARAM_INT    Param = 0
+	PARAM_REAL   Param = 1
+	PARAM_STRING Param = 2
+	PARAM_SYMBOL Param = 3
+)
+
+/*
+Functions of integers give Apply, and functions of fractional
+numbers give Real, whose result is truncated to an integer
+unless it is the argument of another such function
+*/
 type Function struct {
-	Apply func(uint64) uint64
+	Apply  func([]uint64) (uint64, error)
+	Params []Param
+	Real   func([]float64) (float64, error)
 }
 
 const (
-	FUNC_LOW   Mnemonic = "low"
-	FUNC_HIGH  Mnemonic = "high"
-	FUNC_BYTE2 Mnemonic = "byte2"
-	FUNC_BYTE3 Mnemonic = "byte3"
-	FUNC_BYTE4 Mnemonic = "byte4"
-	FUNC_LWRD  Mnemonic = "lwrd"
-	FUNC_HWRD  Mnemonic = "hwrd"
-	FUNC_PAGE  Mnemonic = "page"
-	FUNC_EXP2  Mnemonic = "exp2"
-	FUNC_LOG2  Mnemonic = "log2"
+	FUNC_LOW     Mnemonic = "low"
+	FUNC_HIGH    Mnemonic = "high"
+	FUNC_BYTE2   Mnemonic = "byte2"
+	FUNC_BYTE3   Mnemonic = "byte3"
+	FUNC_BYTE4   Mnemonic = "byte4"
+	FUNC_LWRD    Mnemonic = "lwrd"
+	FUNC_HWRD    Mnemonic = "hwrd"
+	FUNC_PAGE    Mnemonic = "page"
+	FUNC_EXP2    Mnemonic = "exp2"
+	FUNC_LOG2    Mnemonic = "log2"
+	FUNC_ABS     Mnemonic = "abs"
+	FUNC_INT     Mnemonic = "int"
+	FUNC_FRAC    Mnemonic = "frac"
+	FUNC_Q7      Mnemonic = "q7"
+	FUNC_Q15     Mnemonic = "q15"
+	FUNC_DEFINED Mnemonic = "defined"
+	FUNC_STRLEN  Mnemonic = "strlen"
 )
 
 var Functions = map[Mnemonic]Function{
 	FUNC_LOW: {
-		Apply: func(e uint64) uint64 {
-			return e & 0xFF
+		Apply: func(e []uint64) (uint64, error) {
+			return e[0] & 0xFF, nil
 		},
+		Params: []Param{PARAM_INT},
 	},
 
 	FUNC_HIGH: {
-		Apply: func(e uint64) uint64 {
-			return (e & 0xFF00) >> 8
+		Apply: func(e []uint64) (uint64, error) {
+			return (e[0] & 0xFF00) >> 8, nil
 		},
+		Params: []Param{PARAM_INT},
 	},
 
+	// The same as high, as in AVRASM2
 	FUNC_BYTE2: {
-		Apply: func(e uint64) uint64 {
-			return (e & 0xFF00) >> 8
+		Apply: func(e []uint64) (uint64, error) {
+			return (e[0] & 0xFF00) >> 8, nil
 		},
+		Params: []Param{PARAM_INT},
 	},
 
 	FUNC_BYTE3: {
-		Apply: func(e uint64) uint64 {
-			return (e & 0xFF0000) >> 16
+		Apply: func(e []uint64) (uint64, error) {
+			return (e[0] & 0xFF0000) >> 16, nil
 		},
+		Params: []Param{PARAM_INT},
 	},
 
 	FUNC_BYTE4: {
-		Apply: func(e uint64) uint64 {
-			return (e & 0xFF000000) >> 24
+		Apply: func(e []uint64) (uint64, error) {
+			return (e[0] & 0xFF000000) >> 24, nil
 		},
+		Params: []Param{PARAM_INT},
 	},
 
 	FUNC_LWRD: {
-		Apply: func(e uint64) uint64 {
-			return e & 0xFFFF
+		Apply: func(e []uint64) (uint64, error) {
+			return e[0] & 0xFFFF, nil
 		},
+		Params: []Param{PARAM_INT},
 	},
 
 	FUNC_HWRD: {
-		Apply: func(e uint64) uint64 {
-			return (e & 0xFFFF0000) >> 16
+		Apply: func(e []uint64) (uint64, error) {
+			return (e[0] & 0xFFFF0000) >> 16, nil
 		},
+		Params: []Param{PARAM_INT},
 	},
 
 	FUNC_PAGE: {
-		Apply: func(e uint64) uint64 {
-			return (e & 0xF0000) >> 16
+		Apply: func(e []uint64) (uint64, error) {
+			return (e[0] & 0x3F0000) >> 16, nil
 		},
+		Params: []Param{PARAM_INT},
 	},
 
 	FUNC_EXP2: {
-		Apply: func(e uint64) uint64 {
-			return exp(2, e)
+		Apply: func(e []uint64) (uint64, error) {
+			if e[0] > 63 {
+				return 0, fmt.Errorf("exp2 of %v is too large", e[0])
+			}
+
+			return exp(2, e[0]), nil
 		},
+		Params: []Param{PARAM_INT},
 	},
 
 	FUNC_LOG2: {
-		Apply: func(e uint64) uint64 {
-			return intlog(2, e)
+		Apply: func(e []uint64) (uint64, error) {
+			if e[0] == 0 {
+				return 0, fmt.Errorf("log2 of zero")
+			}
+
+			return intlog(2, e[0]), nil
+		},
+		Params: []Param{PARAM_INT},
+	},
+
+	FUNC_ABS: {
+		Params: []Param{PARAM_REAL},
+		Real: func(e []float64) (float64, error) {
+			return math.Abs(e[0]), nil
+		},
+	},
+
+	FUNC_INT: {
+		Params: []Param{PARAM_REAL},
+		Real: func(e []float64) (float64, error) {
+			return math.Trunc(e[0]), nil
 		},
 	},
+
+	FUNC_FRAC: {
+		Params: []Param{PARAM_REAL},
+		Real: func(e []float64) (float64, error) {
+			return e[0] - math.Trunc(e[0]), nil
+		},
+	},
+
+	// Sign and 7 bit fraction, as taken by fmul
+	FUNC_Q7: {
+		Params: []Param{PARAM_REAL},
+		Real: func(e []float64) (float64, error) {
+			return fixed(e[0], 7)
+		},
+	},
+
+	// Sign and 15 bit fraction
+	FUNC_Q15: {
+		Params: []Param{PARAM_REAL},
+		Real: func(e []float64) (float64, error) {
+			return fixed(e[0], 15)
+		},
+	},
+
+	FUNC_DEFINED: {
+		Apply: func(e []uint64) (uint64, error) {
+			return e[0], nil
+		},
+		Params: []Param{PARAM_SYMBOL},
+	},
+
+	FUNC_STRLEN: {
+		Apply: func(e []uint64) (uint64, error) {
+			return e[0], nil
+		},
+		Params: []Param{PARAM_STRING},
+	},
 }
 
 func exp(x uint64, y uint64) uint64 {
@@ -103,12 +206,26 @@ func intlog(a uint64, b uint64) uint64 {
 	var d uint64 = 0
 
 	for {
-		c2 := c * a
-		if c2 > b {
-			return c
-		} else {
-			d = d + 1
-			c = c2
+		// Stop before c overflows
+		if c > b/a {
+			return d
 		}
+
+		d = d + 1
+		c = c * a
 	}
 }
+
+/*
+A fraction from -1 up to 1 as a two's complement number with
+the given number of fraction bits
+*/
+func fixed(x float64, bits int) (float64, error) {
+	if x < -1 || x >= 1 {
+		return 0, fmt.Errorf("%v is outside the range of q%v, -1 up to 1", x, bits)
+	}
+
+	scaled := int64(math.Trunc(math.Ldexp(x, bits)))
+	mask := int64(1)<<(bits+1) - 1
+	return float64(scaled & mask), nil
+}
@@ -0,0 +1,188 @@
+package language_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/silaspace/aria/assembler"
+	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/preprocessor"
+)
+
+/*
+A function applied to arguments, and the result it must give,
+or the start of its error
+*/
+type functionCase struct {
+	Func  language.Mnemonic
+	Args  []float64
+	Want  float64
+	Error string
+}
+
+/* Negative integers are passed to functions of integers as two's complement */
+var functionCases = []functionCase{
+	{language.FUNC_ABS, []float64{-3}, 3, ""},
+	{language.FUNC_ABS, []float64{-0.5}, 0.5, ""},
+	{language.FUNC_ABS, []float64{2}, 2, ""},
+	{language.FUNC_INT, []float64{2.75}, 2, ""},
+	{language.FUNC_INT, []float64{-2.75}, -2, ""},
+	{language.FUNC_FRAC, []float64{2.75}, 0.75, ""},
+	{language.FUNC_FRAC, []float64{-2.25}, -0.25, ""},
+	{language.FUNC_Q7, []float64{0.5}, 0x40, ""},
+	{language.FUNC_Q7, []float64{-0.5}, 0xC0, ""},
+	{language.FUNC_Q7, []float64{-1}, 0x80, ""},
+	{language.FUNC_Q7, []float64{127.0 / 128}, 0x7F, ""},
+	{language.FUNC_Q7, []float64{1}, 0, "1 is outside the range of q7"},
+	{language.FUNC_Q7, []float64{-1.5}, 0, "-1.5 is outside the range of q7"},
+	{language.FUNC_Q15, []float64{0.5}, 0x4000, ""},
+	{language.FUNC_Q15, []float64{-1}, 0x8000, ""},
+	{language.FUNC_Q15, []float64{32767.0 / 32768}, 0x7FFF, ""},
+	{language.FUNC_Q15, []float64{1}, 0, "1 is outside the range of q15"},
+	{language.FUNC_Q15, []float64{-2}, 0, "-2 is outside the range of q15"},
+	{language.FUNC_DEFINED, []float64{0}, 0, ""},
+	{language.FUNC_DEFINED, []float64{1}, 1, ""},
+	{language.FUNC_STRLEN, []float64{0}, 0, ""},
+	{language.FUNC_STRLEN, []float64{5}, 5, ""},
+	{language.FUNC_LOG2, []float64{0}, 0, "log2 of zero"},
+	{language.FUNC_LOG2, []float64{1}, 0, ""},
+	{language.FUNC_LOG2, []float64{5}, 2, ""},
+	{language.FUNC_LOG2, []float64{1024}, 10, ""},
+	{language.FUNC_LOG2, []float64{1023}, 9, ""},
+	{language.FUNC_LOG2, []float64{-1}, 63, ""},
+	{language.FUNC_EXP2, []float64{0}, 1, ""},
+	{language.FUNC_EXP2, []float64{10}, 1024, ""},
+	{language.FUNC_EXP2, []float64{63}, 1 << 63, ""},
+	{language.FUNC_EXP2, []float64{64}, 0, "exp2 of 64 is too large"},
+	{language.FUNC_BYTE2, []float64{0x123456}, 0x34, ""},
+	{language.FUNC_BYTE2, []float64{-1}, 0xFF, ""},
+	{language.FUNC_BYTE2, []float64{0xFF}, 0, ""},
+}
+
+func TestFunctions(t *testing.T) {
+	for _, c := range functionCases {
+		name := fmt.Sprintf("%v(%v)", c.Func, c.Args)
+		function := language.Functions[c.Func]
+		got, err := apply(function, c.Args)
+
+		switch {
+		case c.Error != "" && (err == nil || !strings.HasPrefix(err.Error(), c.Error)):
+			t.Errorf("%v: expected error '%v', got %v", name, c.Error, err)
+
+		case c.Error == "" && err != nil:
+			t.Errorf("%v: %v", name, err)
+
+		case c.Error == "" && got != c.Want:
+			t.Errorf("%v is %v, not %v", name, got, c.Want)
+		}
+	}
+}
+
+/*
+A function applied to its arguments, as integers for functions
+of integers
+*/
+func apply(function language.Function, args []float64) (float64, error) {
+	if function.Real != nil {
+		return function.Real(args)
+	}
+
+	ints := []uint64{}
+
+	for _, arg := range args {
+		ints = append(ints, uint64(int64(arg)))
+	}
+
+	val, err := function.Apply(ints)
+	return float64(val), err
+}
+
+/*
+An expression as written in source, and the value it must give,
+or the start of its error
+*/
+type exprCase struct {
+	Expr  string
+	Want  uint64
+	Error string
+}
+
+var exprCases = []exprCase{
+	{"abs(-3)", 3, ""},
+	{"int(-2.5)", 0xFFFFFFFFFFFFFFFE, ""},
+	{"q7(-1.0)", 0x80, ""},
+	{"q7(1/3)", 0x2A, ""},
+	{"q7(frac(-0.25))", 0xE0, ""},
+	{"q15(-0.5)", 0xC000, ""},
+	{"q7(1.0)", 0, "1 is outside the range of q7"},
+	{"log2(0)", 0, "log2 of zero"},
+	{"log2(5)", 2, ""},
+	{"exp2(log2(1000))", 512, ""},
+	{"byte2(0x123456)", 0x34, ""},
+	{"defined(defined_here)", 1, ""},
+	{"defined(nowhere)", 0, ""},
+	{"defined(1)", 0, "defined expects a symbol"},
+	{"strlen(\"abc\")", 3, ""},
+	{"strlen(\"\")", 0, ""},
+	{"strlen(3)", 0, "strlen expects a string"},
+
+	// Arguments are separated by commas, and must be as many as the function takes
+	{"low(1, 2)", 0, "Function low takes 1 arguments, got 2"},
+	{"q7(0.5, 0.25)", 0, "Function q7 takes 1 arguments, got 2"},
+	{"low()", 0, "Unexpected token"},
+	{"low(1 2)", 0, "Expected operator"},
+	{"low(1", 0, "Unexpected token"},
+	{"low 1", 0, "Expected operator"},
+	{"low((1, 2))", 0, "Unexpected token"},
+	{"low(high(0x1234) + 1)", 0x13, ""},
+}
+
+func TestFunctionExprs(t *testing.T) {
+	for _, c := range exprCases {
+		source := fmt.Sprintf(".equ defined_here = 1\n.equ value = %v\n", c.Expr)
+		symbols, err := assemble(source)
+
+		switch {
+		case c.Error != "" && (err == nil || !strings.HasPrefix(err.Error(), c.Error)):
+			t.Errorf("%v: expected error '%v', got %v", c.Expr, c.Error, err)
+
+		case c.Error == "" && err != nil:
+			t.Errorf("%v: %v", c.Expr, err)
+
+		case c.Error == "" && symbols["value"] != c.Want:
+			t.Errorf("%v is 0x%X, not 0x%X", c.Expr, symbols["value"], c.Want)
+		}
+	}
+}
+
+/*
+Symbols defined by source
+*/
+func assemble(source string) (map[string]uint64, error) {
+	reader := handler.NewWebReader()
+	reader.Write([]byte(source))
+
+	pp := preprocessor.NewPreprocessor(func(path string) (preprocessor.Reader, error) {
+		return nil, fmt.Errorf("cannot include %v", path)
+	})
+
+	err := pp.Process("test.s", reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	asm := assembler.NewAssembler(pp, handler.NewWebWriter())
+	asm.File = "test.s"
+	asm.Lines = pp
+	err = asm.Run()
+
+	if err != nil {
+		return nil, err
+	}
+
+	asm.Close()
+	return asm.Symbols, nil
+}
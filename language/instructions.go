@@ -3,6 +3,8 @@ package language
 import (
 	"encoding/binary"
 	"fmt"
+
+	"github.com/silaspace/aria/device"
 )
 
 type Flag int
@@ -14,115 +16,142 @@ type Instruction struct {
 	Op1      OpFunc
 	Op2      OpFunc
 	Flags    Flag
+	Cycles   Timing
+	Timings  map[device.Core]Timing /* Cores timing it differently from Cycles */
+	Requires device.Flag
+}
+
+/*
+Cycles an instruction takes. Instructions looked up for a
+device take the timing of its core, so a core timing an
+instruction differently from the others gives it in Timings
+rather than in an entry of its own.
+
+Branches take Taken cycles when the branch is taken, and skips
+when the next instruction is skipped, with one more cycle if
+the instruction skipped is two words long.
+*/
+type Timing struct {
+	Cycles uint64
+	Taken  uint64
 }
 
 const (
 	LONG     Flag = 1
 	RELATIVE Flag = 1 << 1
+	SKIP     Flag = 1 << 2 /* Skips the next instruction on a condition */
 )
 
 const (
-	ADC   Mnemonic = "adc"
-	ADD   Mnemonic = "add"
-	AND   Mnemonic = "and"
-	ANDI  Mnemonic = "andi"
-	ADIW  Mnemonic = "adiw" /* AVR */
-	ASR   Mnemonic = "asr"
-	BCLR  Mnemonic = "bclr"
-	BLD   Mnemonic = "bld"
-	BRBC  Mnemonic = "brbc"
-	BRBS  Mnemonic = "brbs"
-	BRCC  Mnemonic = "brcc"
-	BRCS  Mnemonic = "brcs"
-	BREQ  Mnemonic = "breq"
-	BRGE  Mnemonic = "brge"
-	BRHC  Mnemonic = "brhc"
-	BRHS  Mnemonic = "brhs"
-	BRID  Mnemonic = "brid"
-	BIRE  Mnemonic = "brie"
-	BRLO  Mnemonic = "brlo"
-	BRLT  Mnemonic = "brlt"
-	BRMI  Mnemonic = "brmi"
-	BRNE  Mnemonic = "brne"
-	BRPL  Mnemonic = "brpl"
-	BRSH  Mnemonic = "brsh"
-	BRTC  Mnemonic = "brtc"
-	BRTS  Mnemonic = "brts"
-	BRVC  Mnemonic = "brvc"
-	BRVS  Mnemonic = "brvs"
-	BSET  Mnemonic = "bset"
-	BST   Mnemonic = "bst"
-	CBI   Mnemonic = "cbi"
-	CBR   Mnemonic = "cbr"
-	CLC   Mnemonic = "clc"
-	CLH   Mnemonic = "clh"
-	CLI   Mnemonic = "cli"
-	CLN   Mnemonic = "cln"
-	CLR   Mnemonic = "clr"
-	CLS   Mnemonic = "cls"
-	CLT   Mnemonic = "clt"
-	CLV   Mnemonic = "clv"
-	CLZ   Mnemonic = "clz"
-	COM   Mnemonic = "com"
-	CP    Mnemonic = "cp"
-	CPC   Mnemonic = "cpc"
-	CPI   Mnemonic = "cpi"
-	CPSE  Mnemonic = "cpse"
-	DEC   Mnemonic = "dec"
-	EOR   Mnemonic = "eor"
-	ICALL Mnemonic = "icall"
-	IJMP  Mnemonic = "ijmp"
-	IN    Mnemonic = "in"
-	INC   Mnemonic = "inc"
-	JMP   Mnemonic = "jmp" /* AVRe */
-	LD    Mnemonic = "ld"
-	LDD   Mnemonic = "ldd" /* AVR */
-	LDI   Mnemonic = "ldi"
-	LDS   Mnemonic = "lds"
-	LPM   Mnemonic = "lpm" /* AVR */
-	LSL   Mnemonic = "lsl"
-	LSR   Mnemonic = "lsr"
-	MOV   Mnemonic = "mov"
-	NEG   Mnemonic = "neg"
-	NOP   Mnemonic = "nop"
-	OR    Mnemonic = "or"
-	ORI   Mnemonic = "ori"
-	OUT   Mnemonic = "out"
-	POP   Mnemonic = "pop"
-	PUSH  Mnemonic = "push"
-	RCALL Mnemonic = "rcall"
-	RET   Mnemonic = "ret"
-	RETI  Mnemonic = "reti"
-	RJMP  Mnemonic = "rjmp"
-	ROL   Mnemonic = "rol"
-	ROR   Mnemonic = "ror"
-	SBC   Mnemonic = "sbc"
-	SBCI  Mnemonic = "sbci"
-	SBI   Mnemonic = "sbi"
-	SBIC  Mnemonic = "sbic"
-	SBIS  Mnemonic = "sbis"
-	SBIW  Mnemonic = "sbiw" /* AVR */
-	SBR   Mnemonic = "sbr"
-	SBRC  Mnemonic = "sbrc"
-	SBRS  Mnemonic = "sbrs"
-	SEC   Mnemonic = "sec"
-	SEH   Mnemonic = "seh"
-	SEI   Mnemonic = "sei"
-	SEN   Mnemonic = "sen"
-	SER   Mnemonic = "ser"
-	SES   Mnemonic = "ses"
-	SET   Mnemonic = "set"
-	SEV   Mnemonic = "sev"
-	SEZ   Mnemonic = "sez"
-	SLEEP Mnemonic = "sleep"
-	ST    Mnemonic = "st"
-	STD   Mnemonic = "std" /* AVR */
-	STS   Mnemonic = "sts"
-	SUB   Mnemonic = "sub"
-	SUBI  Mnemonic = "subi"
-	SWAP  Mnemonic = "swap"
-	TST   Mnemonic = "tst"
-	WDR   Mnemonic = "wdr"
+	ADC    Mnemonic = "adc"
+	ADD    Mnemonic = "add"
+	AND    Mnemonic = "and"
+	ANDI   Mnemonic = "andi"
+	ADIW   Mnemonic = "adiw" /* AVR */
+	ASR    Mnemonic = "asr"
+	BCLR   Mnemonic = "bclr"
+	BLD    Mnemonic = "bld"
+	BRBC   Mnemonic = "brbc"
+	BRBS   Mnemonic = "brbs"
+	BRCC   Mnemonic = "brcc"
+	BRCS   Mnemonic = "brcs"
+	BREAK  Mnemonic = "break" /* AVR */
+	BREQ   Mnemonic = "breq"
+	BRGE   Mnemonic = "brge"
+	BRHC   Mnemonic = "brhc"
+	BRHS   Mnemonic = "brhs"
+	BRID   Mnemonic = "brid"
+	BIRE   Mnemonic = "brie"
+	BRLO   Mnemonic = "brlo"
+	BRLT   Mnemonic = "brlt"
+	BRMI   Mnemonic = "brmi"
+	BRNE   Mnemonic = "brne"
+	BRPL   Mnemonic = "brpl"
+	BRSH   Mnemonic = "brsh"
+	BRTC   Mnemonic = "brtc"
+	BRTS   Mnemonic = "brts"
+	BRVC   Mnemonic = "brvc"
+	BRVS   Mnemonic = "brvs"
+	BSET   Mnemonic = "bset"
+	BST    Mnemonic = "bst"
+	CALL   Mnemonic = "call" /* AVRe */
+	CBI    Mnemonic = "cbi"
+	CBR    Mnemonic = "cbr"
+	CLC    Mnemonic = "clc"
+	CLH    Mnemonic = "clh"
+	CLI    Mnemonic = "cli"
+	CLN    Mnemonic = "cln"
+	CLR    Mnemonic = "clr"
+	CLS    Mnemonic = "cls"
+	CLT    Mnemonic = "clt"
+	CLV    Mnemonic = "clv"
+	CLZ    Mnemonic = "clz"
+	COM    Mnemonic = "com"
+	CP     Mnemonic = "cp"
+	CPC    Mnemonic = "cpc"
+	CPI    Mnemonic = "cpi"
+	CPSE   Mnemonic = "cpse"
+	DEC    Mnemonic = "dec"
+	EOR    Mnemonic = "eor"
+	FMUL   Mnemonic = "fmul"   /* AVRe */
+	FMULS  Mnemonic = "fmuls"  /* AVRe */
+	FMULSU Mnemonic = "fmulsu" /* AVRe */
+	ICALL  Mnemonic = "icall"
+	IJMP   Mnemonic = "ijmp"
+	IN     Mnemonic = "in"
+	INC    Mnemonic = "inc"
+	JMP    Mnemonic = "jmp" /* AVRe */
+	LD     Mnemonic = "ld"
+	LDD    Mnemonic = "ldd" /* AVR */
+	LDI    Mnemonic = "ldi"
+	LDS    Mnemonic = "lds"
+	LPM    Mnemonic = "lpm" /* AVR */
+	LSL    Mnemonic = "lsl"
+	LSR    Mnemonic = "lsr"
+	MOV    Mnemonic = "mov"
+	MUL    Mnemonic = "mul"   /* AVRe */
+	MULS   Mnemonic = "muls"  /* AVRe */
+	MULSU  Mnemonic = "mulsu" /* AVRe */
+	NEG    Mnemonic = "neg"
+	NOP    Mnemonic = "nop"
+	OR     Mnemonic = "or"
+	ORI    Mnemonic = "ori"
+	OUT    Mnemonic = "out"
+	POP    Mnemonic = "pop"
+	PUSH   Mnemonic = "push"
+	RCALL  Mnemonic = "rcall"
+	RET    Mnemonic = "ret"
+	RETI   Mnemonic = "reti"
+	RJMP   Mnemonic = "rjmp"
+	ROL    Mnemonic = "rol"
+	ROR    Mnemonic = "ror"
+	SBC    Mnemonic = "sbc"
+	SBCI   Mnemonic = "sbci"
+	SBI    Mnemonic = "sbi"
+	SBIC   Mnemonic = "sbic"
+	SBIS   Mnemonic = "sbis"
+	SBIW   Mnemonic = "sbiw" /* AVR */
+	SBR    Mnemonic = "sbr"
+	SBRC   Mnemonic = "sbrc"
+	SBRS   Mnemonic = "sbrs"
+	SEC    Mnemonic = "sec"
+	SEH    Mnemonic = "seh"
+	SEI    Mnemonic = "sei"
+	SEN    Mnemonic = "sen"
+	SER    Mnemonic = "ser"
+	SES    Mnemonic = "ses"
+	SET    Mnemonic = "set"
+	SEV    Mnemonic = "sev"
+	SEZ    Mnemonic = "sez"
+	SLEEP  Mnemonic = "sleep"
+	ST     Mnemonic = "st"
+	STD    Mnemonic = "std" /* AVR */
+	STS    Mnemonic = "sts"
+	SUB    Mnemonic = "sub"
+	SUBI   Mnemonic = "subi"
+	SWAP   Mnemonic = "swap"
+	TST    Mnemonic = "tst"
+	WDR    Mnemonic = "wdr"
 )
 
 /*
@@ -142,10 +171,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0001 11rd dddd rrrr
 	*/
 	ADC: {
-		Base:  0x1c00,
-		Op1:   Rd,
-		Op2:   Rr,
-		Flags: 0,
+		Base:   0x1c00,
+		Op1:    Rd,
+		Op2:    Rr,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -153,10 +183,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0000 11rd dddd rrrr
 	*/
 	ADD: {
-		Base:  0x0c00,
-		Op1:   Rd,
-		Op2:   Rr,
-		Flags: 0,
+		Base:   0x0c00,
+		Op1:    Rd,
+		Op2:    Rr,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -164,10 +195,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0010 00rd dddd rrrr
 	*/
 	AND: {
-		Base:  0x2000,
-		Op1:   Rd,
-		Op2:   Rr,
-		Flags: 0,
+		Base:   0x2000,
+		Op1:    Rd,
+		Op2:    Rr,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -175,10 +207,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0111 KKKK dddd KKKK
 	*/
 	ANDI: {
-		Base:  0x7000,
-		Op1:   Rd_high,
-		Op2:   k_8,
-		Flags: 0,
+		Base:   0x7000,
+		Op1:    Rd_high,
+		Op2:    k_8,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -186,10 +219,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 010d dddd 0101
 	*/
 	ASR: {
-		Base:  0x9405,
-		Op1:   Rd,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9405,
+		Op1:    Rd,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -197,10 +231,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding 1001 0100 1sss 1000
 	*/
 	BCLR: {
-		Base:  0x9488,
-		Op1:   s,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9488,
+		Op1:    s,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -208,10 +243,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 100d dddd 0bbb
 	*/
 	BLD: {
-		Base:  0xF800,
-		Op1:   Rd,
-		Op2:   b,
-		Flags: 0,
+		Base:   0xF800,
+		Op1:    Rd,
+		Op2:    b,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -219,10 +255,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 01kk kkkk ksss
 	*/
 	BRBC: {
-		Base:  0xF400,
-		Op1:   b,
-		Op2:   k_6,
-		Flags: RELATIVE,
+		Base:   0xF400,
+		Op1:    b,
+		Op2:    k_6,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -230,10 +267,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 00kk kkkk ksss
 	*/
 	BRBS: {
-		Base:  0xF000,
-		Op1:   b,
-		Op2:   k_6,
-		Flags: RELATIVE,
+		Base:   0xF000,
+		Op1:    b,
+		Op2:    k_6,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -241,10 +279,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 01kk kkkk k000
 	*/
 	BRCC: {
-		Base:  0xF400,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF400,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -252,10 +291,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 00kk kkkk k000
 	*/
 	BRCS: {
-		Base:  0xF000,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF000,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -263,10 +303,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 00kk kkkk k001
 	*/
 	BREQ: {
-		Base:  0xF001,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF001,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -274,10 +315,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 01kk kkkk k100
 	*/
 	BRGE: {
-		Base:  0xF401,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF404,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -285,10 +327,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 01kk kkkk k101
 	*/
 	BRHC: {
-		Base:  0xF405,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF405,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -296,10 +339,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 00kk kkkk k101
 	*/
 	BRHS: {
-		Base:  0xF005,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF005,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -307,10 +351,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 01kk kkkk k111
 	*/
 	BRID: {
-		Base:  0xF407,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF407,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -318,10 +363,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 00kk kkkk k111
 	*/
 	BIRE: {
-		Base:  0xF007,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF007,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -329,10 +375,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 00kk kkkk k000
 	*/
 	BRLO: {
-		Base:  0xF000,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF000,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -340,10 +387,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 00kk kkkk k100
 	*/
 	BRLT: {
-		Base:  0xF004,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF004,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -351,10 +399,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 00kk kkkk k010
 	*/
 	BRMI: {
-		Base:  0xF002,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF002,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -362,10 +411,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 01kk kkkk k001
 	*/
 	BRNE: {
-		Base:  0xF401,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF401,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -373,10 +423,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 01kk kkkk k010
 	*/
 	BRPL: {
-		Base:  0xF402,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF402,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -384,10 +435,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 01kk kkkk k000
 	*/
 	BRSH: {
-		Base:  0xF400,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF400,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -395,10 +447,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 01kk kkkk k110
 	*/
 	BRTC: {
-		Base:  0xF406,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF406,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -406,10 +459,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 00kk kkkk k110
 	*/
 	BRTS: {
-		Base:  0xF006,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF006,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -417,10 +471,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 01kk kkkk k011
 	*/
 	BRVC: {
-		Base:  0xF403,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF403,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -428,10 +483,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 00kk kkkk k011
 	*/
 	BRVS: {
-		Base:  0xF003,
-		Op1:   k_6,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xF003,
+		Op1:    k_6,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -439,10 +495,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 0sss 1000
 	*/
 	BSET: {
-		Base:  0x9408,
-		Op1:   s,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9408,
+		Op1:    s,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -450,10 +507,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 101d dddd 0bbb
 	*/
 	BST: {
-		Base:  0xFA00,
-		Op1:   Rd,
-		Op2:   b,
-		Flags: 0,
+		Base:   0xFA00,
+		Op1:    Rd,
+		Op2:    b,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -461,10 +519,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 1000 AAAA Abbb
 	*/
 	CBI: {
-		Base:  0x9800,
-		Op1:   A_5,
-		Op2:   b,
-		Flags: 0,
+		Base:   0x9800,
+		Op1:    A_5,
+		Op2:    b,
+		Flags:  0,
+		Cycles: Timing{2, 0},
 	},
 
 	/*
@@ -472,10 +531,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0111 KKKK dddd KKKK
 	*/
 	CBR: {
-		Base:  0x7000,
-		Op1:   Rd,
-		Op2:   k_8_compliment,
-		Flags: 0,
+		Base:   0x7000,
+		Op1:    Rd_high,
+		Op2:    k_8_compliment,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -483,10 +543,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 1000 1000
 	*/
 	CLC: {
-		Base:  0x9488,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9488,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -494,10 +555,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 1101 1000
 	*/
 	CLH: {
-		Base:  0x94D8,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x94D8,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -505,10 +567,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 1111 1000
 	*/
 	CLI: {
-		Base:  0x94F8,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x94F8,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -516,10 +579,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 1010 1000
 	*/
 	CLN: {
-		Base:  0x94A8,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x94A8,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -527,10 +591,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0010 01dd dddd dddd
 	*/
 	CLR: {
-		Base:  0x2400,
-		Op1:   R,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x2400,
+		Op1:    R,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -538,10 +603,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 1100 1000
 	*/
 	CLS: {
-		Base:  0x94C8,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x94C8,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -549,10 +615,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 1110 1000
 	*/
 	CLT: {
-		Base:  0x94E8,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x94E8,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -560,10 +627,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 1011 1000
 	*/
 	CLV: {
-		Base:  0x94B8,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x94B8,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -571,10 +639,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 1001 1000
 	*/
 	CLZ: {
-		Base:  0x9498,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9498,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -582,10 +651,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 010d dddd 0000
 	*/
 	COM: {
-		Base:  0x9400,
-		Op1:   Rd,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9400,
+		Op1:    Rd,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -593,10 +663,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0001 01rd dddd rrrr
 	*/
 	CP: {
-		Base:  0x1400,
-		Op1:   Rd,
-		Op2:   Rr,
-		Flags: 0,
+		Base:   0x1400,
+		Op1:    Rd,
+		Op2:    Rr,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -604,10 +675,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0000 01rd dddd rrrr
 	*/
 	CPC: {
-		Base:  0x0400,
-		Op1:   Rd,
-		Op2:   Rr,
-		Flags: 0,
+		Base:   0x0400,
+		Op1:    Rd,
+		Op2:    Rr,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -615,10 +687,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0011 KKKK dddd KKKK
 	*/
 	CPI: {
-		Base:  0x3000,
-		Op1:   Rd_high,
-		Op2:   k_8,
-		Flags: 0,
+		Base:   0x3000,
+		Op1:    Rd_high,
+		Op2:    k_8,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -626,10 +699,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0001 00rd dddd rrrr
 	*/
 	CPSE: {
-		Base:  0x1000,
-		Op1:   Rd,
-		Op2:   Rr,
-		Flags: 0,
+		Base:   0x1000,
+		Op1:    Rd,
+		Op2:    Rr,
+		Flags:  SKIP,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -637,10 +711,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 010d dddd 1010
 	*/
 	DEC: {
-		Base:  0x940A,
-		Op1:   Rd,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x940A,
+		Op1:    Rd,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -648,10 +723,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0010 01rd dddd rrrr
 	*/
 	EOR: {
-		Base:  0x2400,
-		Op1:   Rd,
-		Op2:   Rr,
-		Flags: 0,
+		Base:   0x2400,
+		Op1:    Rd,
+		Op2:    Rr,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -659,10 +735,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0101 0000 1001
 	*/
 	ICALL: {
-		Base:  0x9609,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9509,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{3, 0},
 	},
 
 	/*
@@ -670,10 +747,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 0000 1001
 	*/
 	IJMP: {
-		Base:  0x9409,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9409,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{2, 0},
 	},
 
 	/*
@@ -681,10 +759,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1011 0AAd dddd AAAA
 	*/
 	IN: {
-		Base:  0xB000,
-		Op1:   Rd,
-		Op2:   A_6,
-		Flags: 0,
+		Base:   0xB000,
+		Op1:    Rd,
+		Op2:    A_6,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -692,10 +771,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 010d dddd 0011
 	*/
 	INC: {
-		Base:  0x9403,
-		Op1:   Rd,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9403,
+		Op1:    Rd,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -724,10 +804,11 @@ var AVR_core = map[Mnemonic]Instruction{
 					(ix)   1001 000d dddd 1010
 	*/
 	LD: {
-		Base:  0x8000,
-		Op1:   Rd,
-		Op2:   R_pointer,
-		Flags: 0,
+		Base:   0x8000,
+		Op1:    Rd,
+		Op2:    R_pointer,
+		Flags:  0,
+		Cycles: Timing{2, 0},
 	},
 
 	/*
@@ -735,10 +816,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1110 KKKK dddd KKKK
 	*/
 	LDI: {
-		Base:  0xE000,
-		Op1:   Rd_high,
-		Op2:   k_8,
-		Flags: 0,
+		Base:   0xE000,
+		Op1:    Rd_high,
+		Op2:    k_8,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -746,10 +828,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 000d dddd 0000 kkkk kkkk kkkk kkkk
 	*/
 	LDS: {
-		Base:  0x90000000,
-		Op1:   R_long,
-		Op2:   k_16,
-		Flags: LONG,
+		Base:   0x90000000,
+		Op1:    R_long,
+		Op2:    k_16,
+		Flags:  LONG,
+		Cycles: Timing{2, 0},
 	},
 
 	/*
@@ -757,10 +840,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0000 11dd dddd dddd
 	*/
 	LSL: {
-		Base:  0x0C00,
-		Op1:   R,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x0C00,
+		Op1:    R,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -768,10 +852,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 010d dddd 0110
 	*/
 	LSR: {
-		Base:  0x9406,
-		Op1:   Rd,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9406,
+		Op1:    Rd,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -779,10 +864,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0010 11rd dddd rrrr
 	*/
 	MOV: {
-		Base:  0x2C00,
-		Op1:   Rd,
-		Op2:   Rr,
-		Flags: 0,
+		Base:   0x2C00,
+		Op1:    Rd,
+		Op2:    Rr,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -790,10 +876,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 010d dddd 0001
 	*/
 	NEG: {
-		Base:  0x9401,
-		Op1:   Rd,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9401,
+		Op1:    Rd,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -801,10 +888,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0000 0000 0000 0000
 	*/
 	NOP: {
-		Base:  0x0000,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x0000,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -812,10 +900,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0010 10rd dddd rrrr
 	*/
 	OR: {
-		Base:  0x4800,
-		Op1:   Rd,
-		Op2:   Rr,
-		Flags: 0,
+		Base:   0x2800,
+		Op1:    Rd,
+		Op2:    Rr,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -823,10 +912,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0110 KKKK dddd KKKK
 	*/
 	ORI: {
-		Base:  0x6000,
-		Op1:   Rd_high,
-		Op2:   k_8,
-		Flags: 0,
+		Base:   0x6000,
+		Op1:    Rd_high,
+		Op2:    k_8,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -834,10 +924,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1011 1AAr rrrr AAAA
 	*/
 	OUT: {
-		Base:  0xB800,
-		Op1:   A_6,
-		Op2:   Rd,
-		Flags: 0,
+		Base:   0xB800,
+		Op1:    A_6,
+		Op2:    Rd,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -845,10 +936,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 000d dddd 1111
 	*/
 	POP: {
-		Base:  0x900F,
-		Op1:   Rd,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x900F,
+		Op1:    Rd,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{2, 0},
 	},
 
 	/*
@@ -856,10 +948,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 001d dddd 1111
 	*/
 	PUSH: {
-		Base:  0x9200,
-		Op1:   Rd,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x920F,
+		Op1:    Rd,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{2, 0},
 	},
 
 	/*
@@ -867,10 +960,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1101 kkkk kkkk kkkk
 	*/
 	RCALL: {
-		Base:  0xD000,
-		Op1:   k_12,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xD000,
+		Op1:    k_12,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{3, 0},
 	},
 
 	/*
@@ -878,10 +972,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0101 0000 1000
 	*/
 	RET: {
-		Base:  0x9508,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9508,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{4, 0},
 	},
 
 	/*
@@ -889,10 +984,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0101 0001 1000
 	*/
 	RETI: {
-		Base:  0x9518,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9518,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{4, 0},
 	},
 
 	/*
@@ -900,10 +996,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1100 kkkk kkkk kkkk
 	*/
 	RJMP: {
-		Base:  0xc000,
-		Op1:   k_12,
-		Op2:   nil,
-		Flags: RELATIVE,
+		Base:   0xc000,
+		Op1:    k_12,
+		Op2:    nil,
+		Flags:  RELATIVE,
+		Cycles: Timing{2, 0},
 	},
 
 	/*
@@ -911,10 +1008,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0001 11dd dddd dddd
 	*/
 	ROL: {
-		Base:  0x1C00,
-		Op1:   R,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x1C00,
+		Op1:    R,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -922,10 +1020,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 010d dddd 0111
 	*/
 	ROR: {
-		Base:  0x9407,
-		Op1:   Rd,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9407,
+		Op1:    Rd,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -933,10 +1032,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0000 10rd dddd rrrr
 	*/
 	SBC: {
-		Base:  0x0800,
-		Op1:   Rd,
-		Op2:   Rr,
-		Flags: 0,
+		Base:   0x0800,
+		Op1:    Rd,
+		Op2:    Rr,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -944,10 +1044,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0100 KKKK dddd KKKK
 	*/
 	SBCI: {
-		Base:  0x4000,
-		Op1:   Rd_high,
-		Op2:   k_8,
-		Flags: 0,
+		Base:   0x4000,
+		Op1:    Rd_high,
+		Op2:    k_8,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -955,10 +1056,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 1010 AAAA Abbb
 	*/
 	SBI: {
-		Base:  0x9A00,
-		Op1:   A_5,
-		Op2:   b,
-		Flags: 0,
+		Base:   0x9A00,
+		Op1:    A_5,
+		Op2:    b,
+		Flags:  0,
+		Cycles: Timing{2, 0},
 	},
 
 	/*
@@ -966,10 +1068,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 1001 AAAA Abbb
 	*/
 	SBIC: {
-		Base:  0x9900,
-		Op1:   A_5,
-		Op2:   b,
-		Flags: 0,
+		Base:   0x9900,
+		Op1:    A_5,
+		Op2:    b,
+		Flags:  SKIP,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -977,10 +1080,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 1011 AAAA Abbb
 	*/
 	SBIS: {
-		Base:  0x9B00,
-		Op1:   A_5,
-		Op2:   b,
-		Flags: 0,
+		Base:   0x9B00,
+		Op1:    A_5,
+		Op2:    b,
+		Flags:  SKIP,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -988,10 +1092,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0110 KKKK dddd KKKK
 	*/
 	SBR: {
-		Base:  0x6000,
-		Op1:   Rd,
-		Op2:   k_8,
-		Flags: 0,
+		Base:   0x6000,
+		Op1:    Rd_high,
+		Op2:    k_8,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -999,10 +1104,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 110r rrrr 0bbb
 	*/
 	SBRC: {
-		Base:  0xFC00,
-		Op1:   Rd,
-		Op2:   b,
-		Flags: 0,
+		Base:   0xFC00,
+		Op1:    Rd,
+		Op2:    b,
+		Flags:  SKIP,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -1010,10 +1116,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1111 111r rrrr 0bbb
 	*/
 	SBRS: {
-		Base:  0xFD00,
-		Op1:   Rd,
-		Op2:   b,
-		Flags: 0,
+		Base:   0xFE00,
+		Op1:    Rd,
+		Op2:    b,
+		Flags:  SKIP,
+		Cycles: Timing{1, 2},
 	},
 
 	/*
@@ -1021,10 +1128,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 0000 1000
 	*/
 	SEC: {
-		Base:  0x9408,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9408,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -1032,10 +1140,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 0101 1000
 	*/
 	SEH: {
-		Base:  0x9458,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9458,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -1043,10 +1152,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 0111 1000
 	*/
 	SEI: {
-		Base:  0x9478,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9478,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -1054,10 +1164,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 0010 1000
 	*/
 	SEN: {
-		Base:  0x9428,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9428,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -1065,10 +1176,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1110 1111 dddd 1111
 	*/
 	SER: {
-		Base:  0xEF0F,
-		Op1:   Rd_high,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0xEF0F,
+		Op1:    Rd_high,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -1076,10 +1188,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 0100 1000
 	*/
 	SES: {
-		Base:  0x9448,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9448,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -1087,10 +1200,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 0110 1000
 	*/
 	SET: {
-		Base:  0x9468,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9468,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -1098,10 +1212,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 0011 1000
 	*/
 	SEV: {
-		Base:  0x9438,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9438,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -1109,10 +1224,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0100 0001 1000
 	*/
 	SEZ: {
-		Base:  0x9418,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9418,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -1120,10 +1236,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0101 1000 1000
 	*/
 	SLEEP: {
-		Base:  0x9588,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9588,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -1152,10 +1269,11 @@ var AVR_core = map[Mnemonic]Instruction{
 					(ix)   1001 001r rrrr 0010
 	*/
 	ST: {
-		Base:  0x8200,
-		Op1:   R_pointer,
-		Op2:   Rd,
-		Flags: 0,
+		Base:   0x8200,
+		Op1:    R_pointer,
+		Op2:    Rd,
+		Flags:  0,
+		Cycles: Timing{2, 0},
 	},
 
 	/*
@@ -1163,10 +1281,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 001d dddd 0000 kkkk kkkk kkkk kkkk
 	*/
 	STS: {
-		Base:  0x92000000,
-		Op1:   k_16,
-		Op2:   R_long,
-		Flags: LONG,
+		Base:   0x92000000,
+		Op1:    k_16,
+		Op2:    R_long,
+		Flags:  LONG,
+		Cycles: Timing{2, 0},
 	},
 
 	/*
@@ -1174,10 +1293,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0001 10rd dddd rrrr
 	*/
 	SUB: {
-		Base:  0x1800,
-		Op1:   Rd,
-		Op2:   Rr,
-		Flags: 0,
+		Base:   0x1800,
+		Op1:    Rd,
+		Op2:    Rr,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -1185,10 +1305,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0101 KKKK dddd KKKK
 	*/
 	SUBI: {
-		Base:  0x5000,
-		Op1:   Rd,
-		Op2:   k_8,
-		Flags: 0,
+		Base:   0x5000,
+		Op1:    Rd_high,
+		Op2:    k_8,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -1196,10 +1317,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 010d dddd 0010
 	*/
 	SWAP: {
-		Base:  0x9400,
-		Op1:   Rd,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x9402,
+		Op1:    Rd,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -1207,10 +1329,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  0010 00dd dddd dddd
 	*/
 	TST: {
-		Base:  0x2000,
-		Op1:   R,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x2000,
+		Op1:    R,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 
 	/*
@@ -1218,10 +1341,11 @@ var AVR_core = map[Mnemonic]Instruction{
 		Encoding  1001 0101 1010 1000
 	*/
 	WDR: {
-		Base:  0x95A8,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x95A8,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{1, 0},
 	},
 }
 
@@ -1240,10 +1364,24 @@ var AVR = map[Mnemonic]Instruction{
 		Encoding  1001 0110 KKdd KKKK
 	*/
 	ADIW: {
-		Base:  0x9600,
-		Op1:   R_pair,
-		Op2:   k_6_ii,
-		Flags: 0,
+		Base:   0x9600,
+		Op1:    R_pair,
+		Op2:    k_6_ii,
+		Flags:  0,
+		Cycles: Timing{2, 0},
+	},
+
+	/*
+		Syntax    BREAK
+		Encoding  1001 0101 1001 1000
+	*/
+	BREAK: {
+		Base:     0x9598,
+		Op1:      nil,
+		Op2:      nil,
+		Flags:    0,
+		Cycles:   Timing{1, 0},
+		Requires: device.BREAK,
 	},
 
 	/*
@@ -1254,10 +1392,11 @@ var AVR = map[Mnemonic]Instruction{
 					(ii)   10q0 qq0d dddd 0qqq
 	*/
 	LDD: {
-		Base:  0x8000,
-		Op1:   Rd,
-		Op2:   R_pointer_disp,
-		Flags: 0,
+		Base:   0x8000,
+		Op1:    Rd,
+		Op2:    R_pointer_disp,
+		Flags:  0,
+		Cycles: Timing{2, 0},
 	},
 
 	/*
@@ -1265,10 +1404,11 @@ var AVR = map[Mnemonic]Instruction{
 		Encoding  1001 0101 1100 1000
 	*/
 	LPM: {
-		Base:  0x95C8,
-		Op1:   nil,
-		Op2:   nil,
-		Flags: 0,
+		Base:   0x95C8,
+		Op1:    nil,
+		Op2:    nil,
+		Flags:  0,
+		Cycles: Timing{3, 0},
 	},
 
 	/*
@@ -1276,10 +1416,11 @@ var AVR = map[Mnemonic]Instruction{
 		Encoding  1001 0111 KKdd KKKK
 	*/
 	SBIW: {
-		Base:  0x9B00,
-		Op1:   R_pair,
-		Op2:   k_6_ii,
-		Flags: 0,
+		Base:   0x9700,
+		Op1:    R_pair,
+		Op2:    k_6_ii,
+		Flags:  0,
+		Cycles: Timing{2, 0},
 	},
 
 	/*
@@ -1290,10 +1431,11 @@ var AVR = map[Mnemonic]Instruction{
 					(ii)   10q0 qq1r rrrr 0qqq
 	*/
 	STD: {
-		Base:  0x8200,
-		Op1:   R_pointer_disp,
-		Op2:   Rd,
-		Flags: 0,
+		Base:   0x8200,
+		Op1:    R_pointer_disp,
+		Op2:    Rd,
+		Flags:  0,
+		Cycles: Timing{2, 0},
 	},
 }
 
@@ -1307,21 +1449,120 @@ Additionally, it defines the instructions below.
 */
 
 var AVRe = map[Mnemonic]Instruction{
+	/*
+		Syntax    CALL k
+		Encoding  1001 010k kkkk 111k kkkk kkkk kkkk kkkk
+	*/
+	CALL: {
+		Base:     0x940E0000,
+		Op1:      k_22,
+		Op2:      nil,
+		Flags:    LONG,
+		Cycles:   Timing{4, 0},
+		Requires: device.JMP_CALL,
+	},
+
+	/*
+		Syntax    FMUL Rd, Rr
+		Encoding  0000 0011 0ddd 1rrr
+	*/
+	FMUL: {
+		Base:     0x0308,
+		Op1:      Rd_fmul,
+		Op2:      Rr_fmul,
+		Flags:    0,
+		Cycles:   Timing{2, 0},
+		Requires: device.MUL,
+	},
+
+	/*
+		Syntax    FMULS Rd, Rr
+		Encoding  0000 0011 1ddd 0rrr
+	*/
+	FMULS: {
+		Base:     0x0380,
+		Op1:      Rd_fmul,
+		Op2:      Rr_fmul,
+		Flags:    0,
+		Cycles:   Timing{2, 0},
+		Requires: device.MUL,
+	},
+
+	/*
+		Syntax    FMULSU Rd, Rr
+		Encoding  0000 0011 1ddd 1rrr
+	*/
+	FMULSU: {
+		Base:     0x0388,
+		Op1:      Rd_fmul,
+		Op2:      Rr_fmul,
+		Flags:    0,
+		Cycles:   Timing{2, 0},
+		Requires: device.MUL,
+	},
+
 	/*
 		Syntax    JMP k
 		Encoding  1001 010k kkkk 110k kkkk kkkk kkkk kkkk
 	*/
 	JMP: {
-		Base:  0x940C0000,
-		Op1:   k_22,
-		Op2:   nil,
-		Flags: LONG,
+		Base:     0x940C0000,
+		Op1:      k_22,
+		Op2:      nil,
+		Flags:    LONG,
+		Cycles:   Timing{3, 0},
+		Requires: device.JMP_CALL,
+	},
+
+	/*
+		Syntax    MUL Rd, Rr
+		Encoding  1001 11rd dddd rrrr
+	*/
+	MUL: {
+		Base:     0x9C00,
+		Op1:      Rd,
+		Op2:      Rr,
+		Flags:    0,
+		Cycles:   Timing{2, 0},
+		Requires: device.MUL,
+	},
+
+	/*
+		Syntax    MULS Rd, Rr
+		Encoding  0000 0010 dddd rrrr
+	*/
+	MULS: {
+		Base:     0x0200,
+		Op1:      Rd_high,
+		Op2:      Rr_high,
+		Flags:    0,
+		Cycles:   Timing{2, 0},
+		Requires: device.MUL,
+	},
+
+	/*
+		Syntax    MULSU Rd, Rr
+		Encoding  0000 0011 0ddd 0rrr
+	*/
+	MULSU: {
+		Base:     0x0300,
+		Op1:      Rd_fmul,
+		Op2:      Rr_fmul,
+		Flags:    0,
+		Cycles:   Timing{2, 0},
+		Requires: device.MUL,
 	},
 }
 
 func (instr *Instruction) Apply1(op Value) error {
-	if _, ok := op.(*Nil); ok && instr.Op1 == nil {
-		return nil
+	_, isNil := op.(*Nil)
+
+	if instr.Op1 == nil {
+		if isNil {
+			return nil
+		}
+
+		return fmt.Errorf("instruction takes no operands")
 	}
 
 	newBase, err := instr.Op1(instr.Base, op)
@@ -1335,8 +1576,14 @@ func (instr *Instruction) Apply1(op Value) error {
 }
 
 func (instr *Instruction) Apply2(op Value) error {
-	if _, ok := op.(*Nil); ok && instr.Op2 == nil {
-		return nil
+	_, isNil := op.(*Nil)
+
+	if instr.Op2 == nil {
+		if isNil {
+			return nil
+		}
+
+		return fmt.Errorf("instruction takes one operand, not two")
 	}
 
 	newBase, err := instr.Op2(instr.Base, op)
@@ -1372,6 +1619,58 @@ func (instr *Instruction) IsRelative() bool {
 	return (instr.Flags & RELATIVE) == RELATIVE
 }
 
+func (instr *Instruction) IsSkip() bool {
+	return (instr.Flags & SKIP) == SKIP
+}
+
+/*
+Whether the instruction may branch, which rjmp and rcall,
+though relative, always do
+*/
+func (instr *Instruction) IsBranch() bool {
+	return instr.IsRelative() && instr.Cycles.Taken != 0
+}
+
 func (instr *Instruction) Print() {
 	fmt.Printf("INSTR: %X\n", instr.Base)
 }
+
+/*
+Fewest cycles the instruction can take
+*/
+func (instr *Instruction) MinCycles() uint64 {
+	return instr.Cycles.Cycles
+}
+
+/*
+Most cycles the instruction can take, which for a skip is when
+it skips a two word instruction
+*/
+func (instr *Instruction) MaxCycles() uint64 {
+	switch {
+	case instr.IsSkip():
+		return instr.Cycles.Taken + 1
+
+	default:
+		return max(instr.Cycles.Cycles, instr.Cycles.Taken)
+	}
+}
+
+/*
+Cycles as in the instruction set manual, such as 1/2 for a
+branch and 1/2/3 for a skip
+*/
+func (instr *Instruction) FmtCycles() string {
+	t := instr.Cycles
+
+	switch {
+	case instr.IsSkip():
+		return fmt.Sprintf("%v/%v/%v", t.Cycles, t.Taken, t.Taken+1)
+
+	case t.Taken != 0:
+		return fmt.Sprintf("%v/%v", t.Cycles, t.Taken)
+
+	default:
+		return fmt.Sprint(t.Cycles)
+	}
+}
@@ -2,6 +2,7 @@ package language
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/silaspace/aria/device"
 )
@@ -18,7 +19,7 @@ const (
 	REG   KeywordType = 4
 )
 
-func Exists(key string) KeywordType {
+func Exists(key string, syntax Syntax) KeywordType {
 
 	mn := Mnemonic(key)
 
@@ -34,11 +35,11 @@ func Exists(key string) KeywordType {
 		return INSTR
 	}
 
-	if _, exists := Directives[mn]; exists {
+	if _, exists := syntax.Directives()[mn]; exists {
 		return DIR
 	}
 
-	if _, exists := Functions[mn]; exists {
+	if _, exists := syntax.Functions()[mn]; exists {
 		return FUNC
 	}
 
@@ -50,6 +51,37 @@ func Exists(key string) KeywordType {
 }
 
 func GetInstr(key string, dev *device.Device) (Instruction, error) {
+	instr, err := getCoreInstr(key, dev)
+
+	if err != nil {
+		return instr, err
+	}
+
+	if timing, exists := instr.Timings[dev.DeviceCore]; exists {
+		instr.Cycles = timing
+	}
+
+	/*
+		Not every device implements the full instruction set
+		of its core, so check the device has the features the
+		instruction depends on.
+	*/
+
+	missing := instr.Requires &^ dev.Flags
+
+	if missing != 0 {
+		return instr, fmt.Errorf(
+			"'%v' is not available on the %v, which lacks %v",
+			key,
+			dev.Name,
+			missing,
+		)
+	}
+
+	return instr, nil
+}
+
+func getCoreInstr(key string, dev *device.Device) (Instruction, error) {
 	mn := Mnemonic(key)
 
 	var instr Instruction
@@ -78,7 +110,7 @@ func GetInstr(key string, dev *device.Device) (Instruction, error) {
 			return instr, nil
 		}
 
-		return instr, fmt.Errorf("'%v' does not exist in the AVR instruction set", key)
+		return instr, fmt.Errorf("'%v' does not exist in the AVR instruction set of the %v", key, dev.Name)
 
 	case device.AVRe:
 		if instr, exist = AVRe[mn]; exist {
@@ -93,13 +125,42 @@ func GetInstr(key string, dev *device.Device) (Instruction, error) {
 			return instr, nil
 		}
 
-		return instr, fmt.Errorf("'%v' does not exist in the AVRe instruction set", key)
+		return instr, fmt.Errorf("'%v' does not exist in the AVRe instruction set of the %v", key, dev.Name)
 
 	default:
 		return instr, fmt.Errorf("device core '%v' not implemented", dev.DeviceCore)
 	}
 }
 
+/*
+All instructions available on a device, taking both its
+core and its features into account, sorted by mnemonic.
+*/
+func InstructionSet(dev *device.Device) []Mnemonic {
+	set := []Mnemonic{}
+	seen := map[Mnemonic]bool{}
+
+	for _, table := range []map[Mnemonic]Instruction{AVR_core, AVR, AVRe} {
+		for mn := range table {
+			if seen[mn] {
+				continue
+			}
+
+			seen[mn] = true
+
+			if _, err := GetInstr(string(mn), dev); err == nil {
+				set = append(set, mn)
+			}
+		}
+	}
+
+	sort.Slice(set, func(i, j int) bool {
+		return set[i] < set[j]
+	})
+
+	return set
+}
+
 func GetOp(key string) (Operator, error) {
 	mn := Mnemonic(key)
 	op, exists := Operators[mn]
@@ -111,9 +172,9 @@ func GetOp(key string) (Operator, error) {
 	return op, nil
 }
 
-func GetDir(key string) (Directive, error) {
+func GetDir(key string, syntax Syntax) (Directive, error) {
 	mn := Mnemonic(key)
-	dir, exists := Directives[mn]
+	dir, exists := syntax.Directives()[mn]
 
 	if !exists {
 		return dir, fmt.Errorf("directive '%v' does not exist", key)
@@ -122,17 +183,37 @@ func GetDir(key string) (Directive, error) {
 	return dir, nil
 }
 
-func GetFunc(key string) (Function, error) {
+func GetFunc(key string, syntax Syntax) (Function, error) {
 	mn := Mnemonic(key)
-	function, exists := Functions[mn]
+	function, exists := syntax.Functions()[mn]
 
 	if !exists {
-		return function, fmt.Errorf("directive '%v' does not exist", key)
+		return function, fmt.Errorf("function '%v' does not exist", key)
 	}
 
 	return function, nil
 }
 
+func IsConditional(key string) bool {
+	switch Mnemonic(key) {
+	case DIR_IF, DIR_IFDEF, DIR_IFNDEF, DIR_ELIF, DIR_ELSE, DIR_ENDIF:
+		return true
+
+	default:
+		return false
+	}
+}
+
+func IsDirective(key string, syntax Syntax) bool {
+	_, exists := syntax.Directives()[Mnemonic(key)]
+	return exists
+}
+
+func IsEndMacro(key string) bool {
+	mn := Mnemonic(key)
+	return mn == DIR_ENDM || mn == DIR_ENDMACRO
+}
+
 func IsPC(key string) bool {
 	mn := Mnemonic(key)
 	return mn == PC
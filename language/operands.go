@@ -99,7 +99,7 @@ func s(base uint64, op Value) (uint64, error) {
 		if op.Value > 7 {
 			return 0, errors.New("bit greater than 7")
 		}
-		return base | ((op.Value << 4) & 0x0030), nil
+		return base | ((op.Value << 4) & 0x0070), nil
 
 	case *Error:
 		return 0, errors.New(op.Value)
@@ -177,6 +177,30 @@ func Rd_high(base uint64, op Value) (uint64, error) {
 	}
 }
 
+/*
+Name         Rd_fmul
+Description  destination register for fractional multiply (r16 to r23)
+Encoding     0000 0000 0ddd 0000
+*/
+func Rd_fmul(base uint64, op Value) (uint64, error) {
+	switch op := op.(type) {
+	case *Reg:
+		if op.Value > 31 {
+			return 0, errors.New("register specified does not exist")
+		}
+		if op.Value < 16 || op.Value > 23 {
+			return 0, errors.New("instructions only operate on registers r16 to r23")
+		}
+		return base | ((op.Value << 4) & 0x0070), nil
+
+	case *Error:
+		return 0, errors.New(op.Value)
+
+	default:
+		return 0, fmt.Errorf("expected reg, got %+v", op.Fmt())
+	}
+}
+
 /*
 Name         R_long
 Description  encode register in 32 bit instructions
@@ -219,6 +243,54 @@ func Rr(base uint64, op Value) (uint64, error) {
 	}
 }
 
+/*
+Name         Rr_high
+Description  source register (r16 to r31)
+Encoding     0000 0000 0000 rrrr
+*/
+func Rr_high(base uint64, op Value) (uint64, error) {
+	switch op := op.(type) {
+	case *Reg:
+		if op.Value > 31 {
+			return 0, errors.New("register specified does not exist")
+		}
+		if op.Value < 16 {
+			return 0, errors.New("instructions only operate on the high registers")
+		}
+		return base | (op.Value & 0x000F), nil
+
+	case *Error:
+		return 0, errors.New(op.Value)
+
+	default:
+		return 0, fmt.Errorf("expected reg, got %+v", op.Fmt())
+	}
+}
+
+/*
+Name         Rr_fmul
+Description  source register for fractional multiply (r16 to r23)
+Encoding     0000 0000 0000 0rrr
+*/
+func Rr_fmul(base uint64, op Value) (uint64, error) {
+	switch op := op.(type) {
+	case *Reg:
+		if op.Value > 31 {
+			return 0, errors.New("register specified does not exist")
+		}
+		if op.Value < 16 || op.Value > 23 {
+			return 0, errors.New("instructions only operate on registers r16 to r23")
+		}
+		return base | (op.Value & 0x0007), nil
+
+	case *Error:
+		return 0, errors.New(op.Value)
+
+	default:
+		return 0, fmt.Errorf("expected reg, got %+v", op.Fmt())
+	}
+}
+
 /*
 Name         Rd+1:Rd
 Description  upper register pairs - d ∈ {24,26,28,30}
@@ -253,10 +325,10 @@ Encoding     0000 000k kkkk 000k kkkk kkkk kkkk kkkk
 func k_22(base uint64, op Value) (uint64, error) {
 	switch op := op.(type) {
 	case *Int:
-		if op.Value > 4194304 {
+		if op.Value > 4194303 {
 			return 0, errors.New("k larger than 22 bits")
 		}
-		return base | (op.Value << 25 & 0x01F00000) | (op.Value & 0x0001FFFF), nil
+		return base | ((op.Value << 3) & 0x01F00000) | (op.Value & 0x0001FFFF), nil
 
 	case *Error:
 		return 0, errors.New(op.Value)
@@ -295,7 +367,7 @@ Encoding     0000 kkkk kkkk kkkk
 func k_12(base uint64, op Value) (uint64, error) {
 	switch op := op.(type) {
 	case *Int:
-		if int16(op.Value) > 4096 {
+		if k := int64(op.Value); k < -2048 || k > 2047 {
 			return 0, errors.New("k larger than 12 bits")
 		}
 		return base | (op.Value & 0x0FFF), nil
@@ -353,16 +425,16 @@ func k_8_compliment(base uint64, op Value) (uint64, error) {
 
 /*
 Name         k_6
-Description  6 bit constant for relative jump
+Description  7 bit signed constant for relative branch
 Encoding     0000 00kk kkkk k000
 */
 func k_6(base uint64, op Value) (uint64, error) {
 	switch op := op.(type) {
 	case *Int:
-		if int16(op.Value) > 63 {
-			return 0, errors.New("k larger than 6 bits")
+		if k := int64(op.Value); k < -64 || k > 63 {
+			return 0, errors.New("branch target out of range")
 		}
-		return base | (op.Value & 0x03F8), nil
+		return base | ((op.Value << 3) & 0x03F8), nil
 
 	case *Error:
 		return 0, errors.New(op.Value)
@@ -383,7 +455,7 @@ func k_6_ii(base uint64, op Value) (uint64, error) {
 		if int16(op.Value) > 63 {
 			return 0, errors.New("k larger than 6 bits")
 		}
-		return base | (op.Value & 0x00CF), nil
+		return base | ((op.Value << 2) & 0x00C0) | (op.Value & 0x000F), nil
 
 	case *Error:
 		return 0, errors.New(op.Value)
@@ -46,6 +46,10 @@ func (l *List) Fmt() string {
 	return fmt.Sprintf("list (%+v)", l.Value)
 }
 
+func (t *Text) Fmt() string {
+	return fmt.Sprintf("text (%q)", t.Value)
+}
+
 func (a *Assignment) Fmt() string {
 	return fmt.Sprintf("assignment (%v = %v)", a.Symbol, a.Value)
 }
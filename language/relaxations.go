@@ -0,0 +1,49 @@
+package language
+
+/*
+The short and long forms of each unconditional jump and call
+*/
+var Jumps = map[Mnemonic][2]Mnemonic{
+	CALL:  {RCALL, CALL},
+	JMP:   {RJMP, JMP},
+	RCALL: {RCALL, CALL},
+	RJMP:  {RJMP, JMP},
+}
+
+/*
+Each conditional branch and the branch taken on the opposite
+condition, used to skip over a jump to a target beyond the
+range of the branch
+*/
+var Branches = map[Mnemonic]Mnemonic{
+	BRBC: BRBS,
+	BRBS: BRBC,
+	BRCC: BRCS,
+	BRCS: BRCC,
+	BREQ: BRNE,
+	BRGE: BRLT,
+	BRHC: BRHS,
+	BRHS: BRHC,
+	BRID: BIRE,
+	BIRE: BRID,
+	BRLO: BRSH,
+	BRLT: BRGE,
+	BRMI: BRPL,
+	BRNE: BREQ,
+	BRPL: BRMI,
+	BRSH: BRLO,
+	BRTC: BRTS,
+	BRTS: BRTC,
+	BRVC: BRVS,
+	BRVS: BRVC,
+}
+
+func IsJump(key string) bool {
+	_, exists := Jumps[Mnemonic(key)]
+	return exists
+}
+
+func IsBranch(key string) bool {
+	_, exists := Branches[Mnemonic(key)]
+	return exists
+}
@@ -0,0 +1,43 @@
+package language
+
+type Relocation int
+
+/*
+How an instruction's operand is patched when it refers to a
+symbol whose address is only known once the program is linked
+*/
+const (
+	RELOC_NONE      Relocation = 0 /* Operand cannot be relocated */
+	RELOC_BRANCH    Relocation = 1 /* 7 bit relative word offset */
+	RELOC_JUMP      Relocation = 2 /* 12 bit relative word offset */
+	RELOC_CALL      Relocation = 3 /* 22 bit absolute word address */
+	RELOC_ADDRESS   Relocation = 4 /* 16 bit data address */
+	RELOC_IMMEDIATE Relocation = 5 /* 8 bit immediate */
+	RELOC_ADIW      Relocation = 6 /* 6 bit immediate of adiw and sbiw */
+)
+
+func GetRelocation(key string) Relocation {
+	switch Mnemonic(key) {
+	case BRBC, BRBS, BRCC, BRCS, BREQ, BRGE, BRHC, BRHS, BRID, BIRE,
+		BRLO, BRLT, BRMI, BRNE, BRPL, BRSH, BRTC, BRTS, BRVC, BRVS:
+		return RELOC_BRANCH
+
+	case RJMP, RCALL:
+		return RELOC_JUMP
+
+	case JMP, CALL:
+		return RELOC_CALL
+
+	case LDS, STS:
+		return RELOC_ADDRESS
+
+	case ANDI, CPI, LDI, ORI, SBCI, SBR, SUBI:
+		return RELOC_IMMEDIATE
+
+	case ADIW, SBIW:
+		return RELOC_ADIW
+
+	default:
+		return RELOC_NONE
+	}
+}
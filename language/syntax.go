@@ -0,0 +1,277 @@
+package language
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Syntax int
+
+/* Dialects of assembly language */
+const (
+	SYNTAX_AVRASM Syntax = 0 /* Atmel's AVRASM2, the default */
+	SYNTAX_GNU    Syntax = 1 /* avr-as from GNU binutils */
+)
+
+var Syntaxes = map[string]Syntax{
+	"avrasm": SYNTAX_AVRASM,
+	"gnu":    SYNTAX_GNU,
+}
+
+func GetSyntax(key string) (Syntax, error) {
+	syntax, exists := Syntaxes[key]
+
+	if !exists {
+		return syntax, fmt.Errorf("unknown syntax '%v', expected avrasm or gnu", key)
+	}
+
+	return syntax, nil
+}
+
+func (s Syntax) Directives() map[Mnemonic]Directive {
+	if s == SYNTAX_GNU {
+		return GnuDirectives
+	}
+
+	return Directives
+}
+
+func (s Syntax) Functions() map[Mnemonic]Function {
+	if s == SYNTAX_GNU {
+		return GnuFunctions
+	}
+
+	return Functions
+}
+
+const (
+	DIR_ASCII   Mnemonic = "ascii"
+	DIR_ASCIZ   Mnemonic = "asciz"
+	DIR_BALIGN  Mnemonic = "balign"
+	DIR_BSS     Mnemonic = "bss"
+	DIR_DATA    Mnemonic = "data"
+	DIR_FILE    Mnemonic = "file"
+	DIR_GLOBL   Mnemonic = "globl"
+	DIR_IDENT   Mnemonic = "ident"
+	DIR_P2ALIGN Mnemonic = "p2align"
+	DIR_SIZE    Mnemonic = "size"
+	DIR_SKIP    Mnemonic = "skip"
+	DIR_SPACE   Mnemonic = "space"
+	DIR_STRING  Mnemonic = "string"
+	DIR_TEXT    Mnemonic = "text"
+	DIR_TYPE    Mnemonic = "type"
+	DIR_WORD    Mnemonic = "word"
+)
+
+/*
+Directives of avr-as. Unlike in AVRASM2, .byte places bytes
+rather than reserving them, and segments are chosen with
+.text, .data and .section. Directives describing symbols for
+debuggers, such as .type and .size, are accepted and ignored.
+*/
+var GnuDirectives = gnuDirectives()
+
+func gnuDirectives() map[Mnemonic]Directive {
+	dirs := map[Mnemonic]Directive{
+		DIR_ASCII: {
+			Execute: func(a Assembler, v Value) error {
+				return text(a, v, false)
+			},
+		},
+		DIR_ASCIZ: {
+			Execute: func(a Assembler, v Value) error {
+				return text(a, v, true)
+			},
+		},
+		DIR_BALIGN: {
+			Execute: func(a Assembler, v Value) error {
+				switch v := v.(type) {
+				case *Int:
+					return a.Align(v.Value)
+
+				case *Error:
+					return errors.New(v.Value)
+
+				default:
+					return fmt.Errorf("expected alignment, got '%v'", v.Fmt())
+				}
+			},
+		},
+		DIR_BSS: {
+			Execute: func(a Assembler, v Value) error {
+				return a.SetSection(".bss")
+			},
+		},
+		DIR_BYTE: {
+			Execute: func(a Assembler, v Value) error {
+				return data(a, v, 1)
+			},
+		},
+		DIR_DATA: {
+			Execute: func(a Assembler, v Value) error {
+				return a.SetSection(".data")
+			},
+		},
+		DIR_P2ALIGN: {
+			Execute: func(a Assembler, v Value) error {
+				switch v := v.(type) {
+				case *Int:
+					if v.Value > 15 {
+						return fmt.Errorf("alignment of 2^%v bytes is too large", v.Value)
+					}
+
+					return a.Align(1 << v.Value)
+
+				case *Error:
+					return errors.New(v.Value)
+
+				default:
+					return fmt.Errorf("expected alignment, got '%v'", v.Fmt())
+				}
+			},
+		},
+		DIR_SPACE: {
+			Execute: func(a Assembler, v Value) error {
+				switch v := v.(type) {
+				case *Int:
+					return a.Fill(v.Value)
+
+				case *Error:
+					return errors.New(v.Value)
+
+				default:
+					return fmt.Errorf("expected size, got '%v'", v.Fmt())
+				}
+			},
+		},
+		DIR_TEXT: {
+			Execute: func(a Assembler, v Value) error {
+				return a.SetSection(".text")
+			},
+		},
+		DIR_WORD: {
+			Execute: func(a Assembler, v Value) error {
+				return data(a, v, 2)
+			},
+		},
+	}
+
+	ignored := Directive{
+		Execute: func(a Assembler, v Value) error {
+			return nil
+		},
+	}
+
+	for _, mn := range []Mnemonic{DIR_FILE, DIR_IDENT, DIR_SIZE, DIR_TYPE} {
+		dirs[mn] = ignored
+	}
+
+	// Shared with AVRASM2
+	shared := []Mnemonic{
+		DIR_DEVICE, DIR_ELIF, DIR_ELSE, DIR_ENDIF, DIR_ENDM, DIR_ENDMACRO, DIR_EQU, DIR_EXTERN, DIR_GLOBAL, DIR_IF,
+		DIR_IFDEF, DIR_IFNDEF, DIR_LIST, DIR_LISTMAC, DIR_MACRO, DIR_NOLIST, DIR_SECTION, DIR_SET,
+	}
+
+	for _, mn := range shared {
+		dirs[mn] = Directives[mn]
+	}
+
+	dirs[DIR_GLOBL] = Directives[DIR_GLOBAL]
+	dirs[DIR_SKIP] = dirs[DIR_SPACE]
+	dirs[DIR_STRING] = dirs[DIR_ASCIZ]
+	return dirs
+}
+
+func data(a Assembler, v Value, size uint64) error {
+	switch v := v.(type) {
+	case *List:
+		return a.Data(v.Value, size)
+
+	case *Error:
+		return errors.New(v.Value)
+
+	default:
+		return fmt.Errorf("expected list of values, got '%v'", v.Fmt())
+	}
+}
+
+/*
+Place the bytes of strings, each followed by a zero byte if
+terminated. Each rune of a string in GNU syntax is one byte.
+*/
+func text(a Assembler, v Value, terminated bool) error {
+	switch v := v.(type) {
+	case *Text:
+		bytes := []uint64{}
+
+		for _, str := range v.Value {
+			for _, r := range str {
+				bytes = append(bytes, uint64(r)&0xFF)
+			}
+
+			if terminated {
+				bytes = append(bytes, 0)
+			}
+		}
+
+		return a.Data(bytes, 1)
+
+	case *Error:
+		return errors.New(v.Value)
+
+	default:
+		return fmt.Errorf("expected strings, got '%v'", v.Fmt())
+	}
+}
+
+const (
+	FUNC_LO8    Mnemonic = "lo8"
+	FUNC_HI8    Mnemonic = "hi8"
+	FUNC_HH8    Mnemonic = "hh8"
+	FUNC_HLO8   Mnemonic = "hlo8"
+	FUNC_HHI8   Mnemonic = "hhi8"
+	FUNC_PM     Mnemonic = "pm"
+	FUNC_GS     Mnemonic = "gs"
+	FUNC_PM_LO8 Mnemonic = "pm_lo8"
+	FUNC_PM_HI8 Mnemonic = "pm_hi8"
+	FUNC_PM_HH8 Mnemonic = "pm_hh8"
+)
+
+/*
+Functions of avr-as. Labels in program memory are byte
+addresses in GNU syntax, and pm gives the word address used
+by jumps and lpm tables. Without trampolines, gs is pm.
+*/
+var GnuFunctions = map[Mnemonic]Function{
+	FUNC_LO8:    Functions[FUNC_LOW],
+	FUNC_HI8:    Functions[FUNC_HIGH],
+	FUNC_HH8:    Functions[FUNC_BYTE3],
+	FUNC_HLO8:   Functions[FUNC_BYTE3],
+	FUNC_HHI8:   Functions[FUNC_BYTE4],
+	FUNC_PM:     words(nil),
+	FUNC_GS:     words(nil),
+	FUNC_PM_LO8: words(Functions[FUNC_LOW].Apply),
+	FUNC_PM_HI8: words(Functions[FUNC_HIGH].Apply),
+	FUNC_PM_HH8: words(Functions[FUNC_BYTE3].Apply),
+}
+
+/*
+A function of the word address of a byte address in program
+memory
+*/
+func words(apply func([]uint64) (uint64, error)) Function {
+	return Function{
+		Apply: func(e []uint64) (uint64, error) {
+			if e[0]%2 != 0 {
+				return 0, fmt.Errorf("program memory address 0x%X is not word aligned", e[0])
+			}
+
+			if apply == nil {
+				return e[0] / 2, nil
+			}
+
+			return apply([]uint64{e[0] / 2})
+		},
+		Params: []Param{PARAM_INT},
+	}
+}
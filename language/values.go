@@ -21,6 +21,7 @@ const (
 	IntType               ValType = 9
 	ListType              ValType = 10
 	AssignType            ValType = 11
+	TextType              ValType = 12
 )
 
 type Nil struct{}
@@ -68,6 +69,10 @@ type List struct {
 	Value []uint64
 }
 
+type Text struct {
+	Value []string
+}
+
 type Assignment struct {
 	Symbol string
 	Value  uint64
@@ -117,6 +122,10 @@ func (l *List) Type() ValType {
 	return ListType
 }
 
+func (t *Text) Type() ValType {
+	return TextType
+}
+
 func (a *Assignment) Type() ValType {
 	return AssignType
 }
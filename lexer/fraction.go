@@ -0,0 +1,47 @@
+package lexer
+
+import "io"
+
+/*
+Digits after the decimal point of a number such as 0.75. A
+point not followed by a digit is a dot after a whole number.
+*/
+func Fraction(l *Lexer) State {
+	digits := false
+
+	for {
+		nextRune, err := l.GetRune()
+
+		if err == io.EOF {
+			if !digits {
+				l.DiscardRune() // Discard .
+				l.Emit(TK_IMM)
+				l.AddToBuffer('.')
+				l.EmitControl()
+				return End
+			}
+
+			l.Emit(TK_FRAC)
+			return End
+		}
+
+		switch nextRune {
+		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			l.AddToBuffer(nextRune)
+			digits = true
+
+		default:
+			if !digits {
+				l.DiscardRune() // Discard .
+				l.Emit(TK_IMM)
+				l.AddToBuffer('.')
+				l.EmitControl()
+			} else {
+				l.Emit(TK_FRAC)
+			}
+
+			l.GiveBack(nextRune)
+			return Start
+		}
+	}
+}
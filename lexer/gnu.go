@@ -0,0 +1,179 @@
+package lexer
+
+import (
+	"io"
+	"strconv"
+)
+
+/*
+Runes starting a token only in GNU syntax, giving the state to
+read it in, or nil if the rune is read as in AVRASM2. A hash
+starts a comment, a slash may start a block comment, and a
+dollar sign starts a hexadecimal number.
+*/
+func gnuStart(l *Lexer, r rune) State {
+	switch r {
+	case '#':
+		return Comment
+
+	case '/':
+		l.AddToBuffer(r)
+		return Slash
+
+	case '$':
+		return X
+
+	default:
+		return nil
+	}
+}
+
+func Slash(l *Lexer) State {
+	nextRune, err := l.GetRune()
+
+	if err == io.EOF {
+		l.EmitOperator()
+		return End
+	}
+
+	if nextRune == '*' {
+		l.Buff = []rune{}
+		return BlockComment
+	}
+
+	l.GiveBack(nextRune)
+	l.EmitOperator()
+	return Start
+}
+
+/*
+A comment between slash star and star slash, which may span
+several lines. The end of each line is still emitted so that
+line numbers stay correct.
+*/
+func BlockComment(l *Lexer) State {
+	star := false
+
+	for {
+		nextRune, err := l.GetRune()
+
+		if err == io.EOF {
+			l.Buff = []rune("Unterminated comment")
+			l.Emit(TK_ERR)
+			return Error
+		}
+
+		switch {
+		case star && nextRune == '/':
+			return Start
+
+		case nextRune == '\n':
+			l.AddToBuffer(nextRune)
+			l.EmitControl()
+		}
+
+		star = nextRune == '*'
+	}
+}
+
+/*
+A string in GNU syntax, which keeps the case of its contents
+and understands the escapes of C. Each rune of the token is a
+byte, so runes outside ASCII are split into their UTF-8 bytes.
+*/
+func GnuString(l *Lexer) State {
+	for {
+		nextRune, err := l.GetRawRune()
+
+		if err == io.EOF {
+			return unterminated(l)
+		}
+
+		switch nextRune {
+		case '"':
+			l.Emit(TK_STR)
+			return Start
+
+		case '\n':
+			return unterminated(l)
+
+		case '\\':
+			escaped, err := escape(l)
+
+			if err != nil {
+				return unterminated(l)
+			}
+
+			l.AddToBuffer(escaped)
+
+		default:
+			for _, b := range []byte(string(nextRune)) {
+				l.AddToBuffer(rune(b))
+			}
+		}
+	}
+}
+
+var escapes = map[rune]rune{
+	'b': '\b',
+	'f': '\f',
+	'n': '\n',
+	'r': '\r',
+	't': '\t',
+}
+
+/*
+The rune given by an escape after a backslash: a letter from
+escapes, up to three octal digits, hex digits after x, or any
+other rune as itself
+*/
+func escape(l *Lexer) (rune, error) {
+	nextRune, err := l.GetRawRune()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if r, exists := escapes[nextRune]; exists {
+		return r, nil
+	}
+
+	switch {
+	case nextRune >= '0' && nextRune <= '7':
+		return digits(l, []rune{nextRune}, 8, 3)
+
+	case nextRune == 'x' || nextRune == 'X':
+		return digits(l, []rune{}, 16, 2)
+
+	default:
+		return nextRune, nil
+	}
+}
+
+/*
+Read digits of a number in an escape, up to a maximum count,
+giving back the rune after the last digit
+*/
+func digits(l *Lexer, number []rune, base int, max int) (rune, error) {
+	for len(number) < max {
+		nextRune, err := l.GetRawRune()
+
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := strconv.ParseUint(string(nextRune), base, 16); err != nil {
+			l.GiveBack(nextRune)
+			break
+		}
+
+		number = append(number, nextRune)
+	}
+
+	if len(number) == 0 {
+		return 'x', nil
+	}
+
+	value, _ := strconv.ParseUint(string(number), base, 16)
+	return rune(value & 0xFF), nil
+}
@@ -14,10 +14,12 @@ type Reader interface {
 }
 
 type Lexer struct {
-	In    Reader
-	Out   chan Token
-	State State
-	Buff  []rune
+	In     Reader
+	Out    chan Token
+	State  State
+	Buff   []rune
+	Back   []rune
+	Syntax language.Syntax
 }
 
 func (l *Lexer) AddToBuffer(r rune) {
@@ -72,7 +74,7 @@ func (l *Lexer) EmitControl() {
 }
 
 func (l *Lexer) EmitIdent() {
-	identType := language.Exists(string(l.Buff))
+	identType := language.Exists(string(l.Buff), l.Syntax)
 
 	switch identType {
 	case language.INSTR:
@@ -112,6 +114,13 @@ func (l *Lexer) EmitRegister() {
 }
 
 func (l *Lexer) GetRune() (rune, error) {
+	// Runes given back are read again first
+	if length := len(l.Back); length > 0 {
+		nextRune := l.Back[length-1]
+		l.Back = l.Back[:length-1]
+		return nextRune, nil
+	}
+
 	nextRune, err := l.In.Next()
 
 	if err != nil {
@@ -122,6 +131,34 @@ func (l *Lexer) GetRune() (rune, error) {
 	return lowerRune, nil
 }
 
+/*
+Read a rune without changing its case, for the contents of
+strings in GNU syntax
+*/
+func (l *Lexer) GetRawRune() (rune, error) {
+	if length := len(l.Back); length > 0 {
+		nextRune := l.Back[length-1]
+		l.Back = l.Back[:length-1]
+		return nextRune, nil
+	}
+
+	nextRune, err := l.In.Next()
+
+	if err != nil {
+		return ' ', err
+	}
+
+	return nextRune, nil
+}
+
+/*
+Give back a rune read too far, to be read again by the next
+state
+*/
+func (l *Lexer) GiveBack(r rune) {
+	l.Back = append(l.Back, r)
+}
+
 func (l *Lexer) Next() Token {
 	for {
 		select {
@@ -134,5 +171,6 @@ func (l *Lexer) Next() Token {
 }
 
 func (l *Lexer) Reset() {
+	l.Back = []rune{}
 	l.State = Start
 }
@@ -1,6 +1,10 @@
 package lexer
 
-import "io"
+import (
+	"io"
+
+	"github.com/silaspace/aria/language"
+)
 
 func Start(l *Lexer) State {
 	for {
@@ -10,6 +14,12 @@ func Start(l *Lexer) State {
 			return End
 		}
 
+		if l.Syntax == language.SYNTAX_GNU {
+			if state := gnuStart(l, nextRune); state != nil {
+				return state
+			}
+		}
+
 		switch nextRune {
 		case ';':
 			return Comment
@@ -17,15 +27,16 @@ func Start(l *Lexer) State {
 		case ' ', '\t':
 			continue
 
+		// Return after each token, so that it is read before the buffer fills
 		case '\n', '.', ',', ':', '(', ')':
 			l.AddToBuffer(nextRune)
 			l.EmitControl()
-			continue
+			return Start
 
 		case '~', '*', '/', '%', '+', '-', '^', '?':
 			l.AddToBuffer(nextRune)
 			l.EmitOperator()
-			continue
+			return Start
 
 		case '<':
 			l.AddToBuffer(nextRune)
@@ -51,6 +62,9 @@ func Start(l *Lexer) State {
 			l.AddToBuffer(nextRune)
 			return Bar
 
+		case '"':
+			return String
+
 		case 'r':
 			l.AddToBuffer(nextRune)
 			return R
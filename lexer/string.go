@@ -0,0 +1,52 @@
+package lexer
+
+import (
+	"io"
+
+	"github.com/silaspace/aria/language"
+)
+
+/*
+A string between double quotes, in which a backslash keeps the
+character after it
+*/
+func String(l *Lexer) State {
+	if l.Syntax == language.SYNTAX_GNU {
+		return GnuString
+	}
+
+	for {
+		nextRune, err := l.GetRune()
+
+		if err == io.EOF {
+			return unterminated(l)
+		}
+
+		switch nextRune {
+		case '"':
+			l.Emit(TK_STR)
+			return Start
+
+		case '\n':
+			return unterminated(l)
+
+		case '\\':
+			escaped, err := l.GetRune()
+
+			if err == io.EOF {
+				return unterminated(l)
+			}
+
+			l.AddToBuffer(escaped)
+
+		default:
+			l.AddToBuffer(nextRune)
+		}
+	}
+}
+
+func unterminated(l *Lexer) State {
+	l.Buff = []rune("Unterminated string")
+	l.Emit(TK_ERR)
+	return Error
+}
@@ -29,11 +29,13 @@ const (
 
 	TK_OP Type = 40
 
-	TK_REG Type = 50
-	TK_IMM Type = 51
-	TK_HEX Type = 52
-	TK_OCT Type = 53
-	TK_BIN Type = 54
+	TK_REG  Type = 50
+	TK_IMM  Type = 51
+	TK_HEX  Type = 52
+	TK_OCT  Type = 53
+	TK_BIN  Type = 54
+	TK_FRAC Type = 55
+	TK_STR  Type = 56
 )
 
 func (t *Token) IsEOF() bool {
@@ -72,9 +74,11 @@ var _pmap = map[Type]string{
 
 	TK_OP: "OP ",
 
-	TK_REG: "REG",
-	TK_IMM: "IMM",
-	TK_HEX: "HEX",
-	TK_OCT: "OCT",
-	TK_BIN: "BIN",
+	TK_REG:  "REG",
+	TK_IMM:  "IMM",
+	TK_HEX:  "HEX",
+	TK_OCT:  "OCT",
+	TK_BIN:  "BIN",
+	TK_FRAC: "FRC",
+	TK_STR:  "STR",
 }
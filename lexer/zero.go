@@ -21,7 +21,11 @@ func Zero(l *Lexer) State {
 			l.Emit(TK_IMM)
 			return Start
 
-		case '\n', '.', ',', ':', '(', ')':
+		case '.':
+			l.AddToBuffer(nextRune)
+			return Fraction
+
+		case '\n', ',', ':', '(', ')':
 			l.Emit(TK_IMM)
 			l.AddToBuffer(nextRune)
 			l.EmitControl()
@@ -0,0 +1,16 @@
+package linker
+
+import (
+	"github.com/silaspace/aria/device"
+)
+
+func NewLinker(target string) *Linker {
+	d := device.DefaultDevice()
+
+	return &Linker{
+		Device:  *d,
+		Globals: map[string]*Definition{},
+		Inputs:  []*Input{},
+		Target:  target,
+	}
+}
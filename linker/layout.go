@@ -0,0 +1,315 @@
+package linker
+
+import (
+	"bufio"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/object"
+)
+
+/*
+A layout file names regions of the device's memories, then
+gives the order sections are placed in them. Sections are
+matched by name, with * and ? as wildcards, and placed in the
+order of the rules, objects in the order they were given.
+Sections matching no rule follow in the first region of their
+memory.
+
+	; Bootloader in the last 4 KiB of flash
+	region app  flash  length 0x7000
+	region boot flash  origin 0x7000
+	region ram  sram
+	region rom  eeprom
+
+	place .vectors  in app  align 2
+	place .text*    in app
+	place .boot*    in boot
+
+Origins are addresses in the region's memory, bytes for flash,
+and default to the start of the memory. Lengths default to the
+rest of the memory.
+*/
+type Layout struct {
+	Regions []*Region
+	Rules   []Rule
+}
+
+/*
+A part of one memory of the device, and how much of it has
+been filled with sections
+*/
+type Region struct {
+	Data   []byte
+	Length uint64
+	Memory object.SectionKind
+	Name   string
+	Origin uint64
+	Used   uint64
+}
+
+type Rule struct {
+	Align   uint64
+	Pattern string
+	Region  *Region
+}
+
+var Memories = map[string]object.SectionKind{
+	"flash":  object.Code,
+	"sram":   object.Data,
+	"eeprom": object.EEPROM,
+}
+
+/*
+One region covering the whole of each memory, named after the
+default section placed in it
+*/
+func DefaultLayout(dev *device.Device) *Layout {
+	layout := &Layout{
+		Regions: []*Region{},
+		Rules:   []Rule{},
+	}
+
+	names := []string{"text", "data", "eeprom"}
+
+	for i, kind := range []object.SectionKind{object.Code, object.Data, object.EEPROM} {
+		begin, end := Bounds(kind, dev)
+
+		layout.Regions = append(layout.Regions, &Region{
+			Length: end - begin,
+			Memory: kind,
+			Name:   names[i],
+			Origin: begin,
+		})
+	}
+
+	return layout
+}
+
+func ParseLayout(text string, dev *device.Device) (*Layout, error) {
+	layout := &Layout{
+		Regions: []*Region{},
+		Rules:   []Rule{},
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		content, _, _ := strings.Cut(scanner.Text(), ";")
+		fields := strings.Fields(strings.ToLower(content))
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+
+		switch fields[0] {
+		case "region":
+			err = layout.parseRegion(fields[1:], dev)
+
+		case "place":
+			err = layout.parseRule(fields[1:])
+
+		default:
+			err = fmt.Errorf("unknown statement '%v'", fields[0])
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%v on line %v", err, line)
+		}
+	}
+
+	return layout, scanner.Err()
+}
+
+func (l *Layout) parseRegion(fields []string, dev *device.Device) error {
+	if len(fields) < 2 {
+		return fmt.Errorf("expected region name and memory")
+	}
+
+	name := fields[0]
+	kind, exists := Memories[fields[1]]
+
+	if !exists {
+		return fmt.Errorf("unknown memory '%v'", fields[1])
+	}
+
+	if l.Region(name) != nil {
+		return fmt.Errorf("duplicate region %v", name)
+	}
+
+	begin, end := Bounds(kind, dev)
+	region := &Region{
+		Memory: kind,
+		Name:   name,
+		Origin: begin,
+	}
+
+	options, err := parseOptions(fields[2:], "origin", "length")
+
+	if err != nil {
+		return err
+	}
+
+	if origin, exists := options["origin"]; exists {
+		region.Origin = origin
+	}
+
+	if region.Origin < begin || region.Origin > end {
+		return fmt.Errorf("region %v starts outside of %v", name, fields[1])
+	}
+
+	region.Length = end - region.Origin
+
+	if length, exists := options["length"]; exists {
+		region.Length = length
+	}
+
+	if region.Origin+region.Length > end {
+		return fmt.Errorf(
+			"region %v exceeds %v size of %v bytes by %v bytes",
+			name,
+			fields[1],
+			end-begin,
+			region.Origin+region.Length-end,
+		)
+	}
+
+	if kind == object.Code && region.Origin%2 != 0 {
+		return fmt.Errorf("region %v must start on a word boundary", name)
+	}
+
+	for _, other := range l.Regions {
+		if other.Memory == kind && region.Origin < other.Origin+other.Length && other.Origin < region.Origin+region.Length {
+			return fmt.Errorf("region %v overlaps region %v", name, other.Name)
+		}
+	}
+
+	l.Regions = append(l.Regions, region)
+	return nil
+}
+
+func (l *Layout) parseRule(fields []string) error {
+	if len(fields) < 3 || fields[1] != "in" {
+		return fmt.Errorf("expected 'place section in region'")
+	}
+
+	if _, err := path.Match(fields[0], ""); err != nil {
+		return fmt.Errorf("bad section pattern '%v'", fields[0])
+	}
+
+	region := l.Region(fields[2])
+
+	if region == nil {
+		return fmt.Errorf("unknown region '%v'", fields[2])
+	}
+
+	options, err := parseOptions(fields[3:], "align")
+
+	if err != nil {
+		return err
+	}
+
+	rule := Rule{
+		Align:   1,
+		Pattern: fields[0],
+		Region:  region,
+	}
+
+	if align, exists := options["align"]; exists {
+		rule.Align = align
+	}
+
+	if rule.Align == 0 {
+		return fmt.Errorf("alignment must be at least 1")
+	}
+
+	l.Rules = append(l.Rules, rule)
+	return nil
+}
+
+/*
+Options given as a keyword followed by a number
+*/
+func parseOptions(fields []string, keywords ...string) (map[string]uint64, error) {
+	options := map[string]uint64{}
+
+	for len(fields) > 0 {
+		keyword := fields[0]
+		known := false
+
+		for _, k := range keywords {
+			known = known || k == keyword
+		}
+
+		if !known {
+			return nil, fmt.Errorf("unexpected '%v'", keyword)
+		}
+
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("expected value after %v", keyword)
+		}
+
+		value, err := strconv.ParseUint(fields[1], 0, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("'%v' is not a number", fields[1])
+		}
+
+		options[keyword] = value
+		fields = fields[2:]
+	}
+
+	return options, nil
+}
+
+func (l *Layout) Region(name string) *Region {
+	for _, region := range l.Regions {
+		if region.Name == name {
+			return region
+		}
+	}
+
+	return nil
+}
+
+/*
+The first region of a memory, which holds any sections not
+placed by a rule
+*/
+func (l *Layout) First(kind object.SectionKind) *Region {
+	for _, region := range l.Regions {
+		if region.Memory == kind {
+			return region
+		}
+	}
+
+	return nil
+}
+
+/*
+Address of the region in the address space of the GNU tools
+*/
+func (r *Region) Address() uint64 {
+	return r.Memory.Base() + r.Origin
+}
+
+/*
+Range of addresses of a memory of the device, in bytes
+*/
+func Bounds(kind object.SectionKind, dev *device.Device) (uint64, uint64) {
+	switch kind {
+	case object.Code:
+		return 0, uint64(dev.FlashSize) * 2
+	case object.Data:
+		return uint64(dev.RAMStart), uint64(dev.RAMStart) + uint64(dev.RAMSize)
+	default:
+		return 0, uint64(dev.EEPROMSize)
+	}
+}
@@ -0,0 +1,377 @@
+package linker
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/object"
+)
+
+/*
+A relocatable object to be linked, with the address and region
+each of its sections is placed at and the symbols it defines
+*/
+type Input struct {
+	Bases   map[string]uint64
+	File    *object.File
+	Locals  map[string]*Definition
+	Name    string
+	Regions map[string]*Region
+}
+
+/*
+A symbol placed in the linked program. The kind and section
+are only meaningful for symbols that are not absolute.
+*/
+type Definition struct {
+	Absolute bool
+	Address  uint64
+	Input    *Input
+	Kind     object.SectionKind
+	Section  string
+	Symbol   object.Symbol
+}
+
+/*
+Links objects for a device, placing their sections as given by
+the layout file in Script, or one after another in each memory
+if there is none
+*/
+type Linker struct {
+	Device  device.Device
+	Globals map[string]*Definition
+	Inputs  []*Input
+	Layout  *Layout
+	Script  string
+	Target  string
+}
+
+func (l *Linker) Add(name string, file *object.File) error {
+	if !file.Relocatable {
+		return fmt.Errorf("%v is not a relocatable object", name)
+	}
+
+	l.Inputs = append(l.Inputs, &Input{
+		Bases:   map[string]uint64{},
+		File:    file,
+		Locals:  map[string]*Definition{},
+		Name:    name,
+		Regions: map[string]*Region{},
+	})
+
+	return nil
+}
+
+/*
+Link the objects into a single program
+*/
+func (l *Linker) Link() (*object.File, error) {
+	if len(l.Inputs) == 0 {
+		return nil, fmt.Errorf("no objects to link")
+	}
+
+	steps := []func() error{
+		l.SetDevice,
+		l.SetLayout,
+		l.Place,
+		l.Resolve,
+		l.Relocate,
+	}
+
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+
+	return l.Output(), nil
+}
+
+/*
+Select the device given on the command line, or the device
+the objects were assembled for
+*/
+func (l *Linker) SetDevice() error {
+	name := l.Target
+	from := ""
+
+	for _, in := range l.Inputs {
+		if in.File.Device == "" || in.File.Device == string(device.DEFAULT) {
+			continue
+		}
+
+		if l.Target != "" && in.File.Device != l.Target {
+			return fmt.Errorf("%v is for device '%v', not '%v' given on the command line", in.Name, in.File.Device, l.Target)
+		}
+
+		if name != "" && in.File.Device != name {
+			return fmt.Errorf("%v is for device '%v' but %v is for '%v'", in.Name, in.File.Device, from, name)
+		}
+
+		name = in.File.Device
+		from = in.Name
+	}
+
+	if name == "" {
+		return nil
+	}
+
+	dev, err := device.NewDevice(name)
+
+	if err != nil {
+		return err
+	}
+
+	l.Device = *dev
+	return nil
+}
+
+/*
+Read the layout file now the sizes of the memories are known
+*/
+func (l *Linker) SetLayout() error {
+	if l.Script == "" {
+		l.Layout = DefaultLayout(&l.Device)
+		return nil
+	}
+
+	layout, err := ParseLayout(l.Script, &l.Device)
+
+	if err != nil {
+		return fmt.Errorf("%v of the layout file", err)
+	}
+
+	l.Layout = layout
+	return nil
+}
+
+/*
+Place the sections of every object in the regions given by the
+rules of the layout, then any remaining sections in the first
+region of their memory. Code is kept aligned to whole words.
+*/
+func (l *Linker) Place() error {
+	errs := []error{}
+
+	for _, rule := range l.Layout.Rules {
+		for _, in := range l.Inputs {
+			for _, sec := range in.File.Sections {
+				if _, placed := in.Regions[sec.Name]; placed {
+					continue
+				}
+
+				if match, _ := path.Match(rule.Pattern, sec.Name); !match {
+					continue
+				}
+
+				errs = append(errs, l.place(in, sec, rule.Region, rule.Align))
+			}
+		}
+	}
+
+	for _, in := range l.Inputs {
+		for _, sec := range in.File.Sections {
+			if _, placed := in.Regions[sec.Name]; placed {
+				continue
+			}
+
+			region := l.Layout.First(sec.Kind)
+
+			if region == nil {
+				errs = append(errs, fmt.Errorf("no region in the layout for section %v of %v", sec.Name, in.Name))
+				continue
+			}
+
+			errs = append(errs, l.place(in, sec, region, 1))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	// Copy the contents of each section to its place
+	for _, in := range l.Inputs {
+		for _, sec := range in.File.Sections {
+			region := in.Regions[sec.Name]
+
+			if !sec.HasData() {
+				continue
+			}
+
+			if region.Data == nil {
+				region.Data = make([]byte, region.Used)
+			}
+
+			copy(region.Data[in.Bases[sec.Name]-region.Address():], sec.Data)
+		}
+	}
+
+	return nil
+}
+
+/*
+Place a section after those already in the region, reporting
+the section that overflows the region if it does not fit
+*/
+func (l *Linker) place(in *Input, sec object.Section, region *Region, alignment uint64) error {
+	if sec.Kind != region.Memory {
+		return fmt.Errorf("section %v of %v cannot be placed in region %v", sec.Name, in.Name, region.Name)
+	}
+
+	if sec.Kind == object.Code {
+		alignment = max(alignment, 2)
+	}
+
+	offset := align(region.Origin+region.Used, alignment) - region.Origin
+	in.Bases[sec.Name] = region.Address() + offset
+	in.Regions[sec.Name] = region
+	region.Used = offset + sec.Length()
+
+	if region.Used > region.Length {
+		return fmt.Errorf(
+			"section %v of %v overflows region %v of %v bytes by %v bytes",
+			sec.Name,
+			in.Name,
+			region.Name,
+			region.Length,
+			region.Used-region.Length,
+		)
+	}
+
+	return nil
+}
+
+/*
+Define the symbols of each object at their final addresses,
+and check every symbol imported by one object is exported by
+exactly one other
+*/
+func (l *Linker) Resolve() error {
+	for _, in := range l.Inputs {
+		for _, sym := range in.File.Symbols {
+			if sym.Extern {
+				continue
+			}
+
+			def := &Definition{
+				Absolute: true,
+				Address:  sym.Value,
+				Input:    in,
+				Symbol:   sym,
+			}
+
+			if sym.Section != "" {
+				i := in.File.SectionIndex(sym.Section)
+
+				if i < 0 {
+					return fmt.Errorf("symbol %v in %v refers to missing section %v", sym.Name, in.Name, sym.Section)
+				}
+
+				def.Absolute = false
+				def.Address = in.Bases[sym.Section] + sym.Value
+				def.Kind = in.File.Sections[i].Kind
+				def.Section = "." + in.Regions[sym.Section].Name
+			}
+
+			in.Locals[sym.Name] = def
+
+			if !sym.Global {
+				continue
+			}
+
+			if prev, exists := l.Globals[sym.Name]; exists {
+				return fmt.Errorf("duplicate symbol %v defined in %v and %v", sym.Name, prev.Input.Name, in.Name)
+			}
+
+			l.Globals[sym.Name] = def
+		}
+	}
+
+	// Report every undefined symbol at once
+	errs := []error{}
+
+	for _, in := range l.Inputs {
+		for _, sym := range in.File.Symbols {
+			if _, exists := l.Globals[sym.Name]; sym.Extern && !exists {
+				errs = append(errs, fmt.Errorf("undefined symbol %v referenced in %v", sym.Name, in.Name))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+/*
+Find the definition of a symbol referred to by an object
+*/
+func (l *Linker) Lookup(in *Input, name string) (*Definition, error) {
+	if def, exists := in.Locals[name]; exists {
+		return def, nil
+	}
+
+	if def, exists := l.Globals[name]; exists {
+		return def, nil
+	}
+
+	return nil, fmt.Errorf("undefined symbol %v referenced in %v", name, in.Name)
+}
+
+/*
+The linked program, with every symbol at its final address
+*/
+func (l *Linker) Output() *object.File {
+	file := &object.File{
+		Arch:     l.Device.Arch,
+		Device:   string(l.Device.Name),
+		Entry:    0,
+		Sections: []object.Section{},
+		Symbols:  []object.Symbol{},
+	}
+
+	// Each region filled with sections becomes a section itself
+	for _, region := range l.Layout.Regions {
+		if region.Used == 0 {
+			continue
+		}
+
+		file.Sections = append(file.Sections, object.Section{
+			Name:    "." + region.Name,
+			Kind:    region.Memory,
+			Address: region.Address(),
+			Data:    region.Data,
+			Size:    region.Used,
+		})
+	}
+
+	for _, in := range l.Inputs {
+		names := []string{}
+
+		for name := range in.Locals {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			def := in.Locals[name]
+			sym := def.Symbol
+			sym.Value = def.Address
+
+			if !def.Absolute {
+				sym.Section = def.Section
+			}
+
+			file.Symbols = append(file.Symbols, sym)
+		}
+	}
+
+	return file
+}
+
+func align(address uint64, alignment uint64) uint64 {
+	return (address + alignment - 1) / alignment * alignment
+}
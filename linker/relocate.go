@@ -0,0 +1,148 @@
+package linker
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/silaspace/aria/object"
+)
+
+/*
+Patch every instruction referring to a symbol with the value
+of the symbol now that it has been placed
+*/
+func (l *Linker) Relocate() error {
+	for _, in := range l.Inputs {
+		for _, rel := range in.File.Relocations {
+			err := l.Apply(in, rel)
+
+			if err != nil {
+				return fmt.Errorf("%v in %v at %v+0x%X", err, in.Name, rel.Section, rel.Offset)
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+Value of a symbol in aria's units, a word address in program
+memory or a byte address in data memory or eeprom
+*/
+func (d *Definition) Value() int64 {
+	if d.Absolute {
+		return int64(d.Address)
+	}
+
+	if d.Kind == object.Code {
+		return int64(d.Address-object.TEXT_ADDRESS) / 2
+	}
+
+	return int64(d.Address - d.Kind.Base())
+}
+
+func (l *Linker) Apply(in *Input, rel object.Relocation) error {
+	i := in.File.SectionIndex(rel.Section)
+
+	if i < 0 {
+		return fmt.Errorf("relocation refers to missing section")
+	}
+
+	sec := in.File.Sections[i]
+	region := in.Regions[rel.Section]
+
+	if region.Data == nil || rel.Offset+2 > sec.Length() {
+		return fmt.Errorf("relocation is outside of the section")
+	}
+
+	def, err := l.Lookup(in, rel.Symbol)
+
+	if err != nil {
+		return err
+	}
+
+	at := in.Bases[rel.Section] + rel.Offset
+	field := region.Data[at-region.Address():]
+	word := binary.LittleEndian.Uint16(field)
+	v := def.Value() + rel.Addend
+
+	switch rel.Type {
+	case object.R_AVR_7_PCREL, object.R_AVR_13_PCREL:
+		if !def.Absolute && def.Kind != object.Code {
+			return fmt.Errorf("%v is not in program memory", rel.Symbol)
+		}
+
+		// Offsets are relative to the following instruction
+		v -= int64(at-object.TEXT_ADDRESS)/2 + 1
+
+		if rel.Type == object.R_AVR_7_PCREL {
+			if v < -64 || v > 63 {
+				return fmt.Errorf("branch to %v out of range", rel.Symbol)
+			}
+
+			word |= uint16(v<<3) & 0x03F8
+		} else {
+			if v < -2048 || v > 2047 {
+				return fmt.Errorf("relative jump to %v out of range", rel.Symbol)
+			}
+
+			word |= uint16(v) & 0x0FFF
+		}
+
+	case object.R_AVR_CALL:
+		if rel.Offset+4 > sec.Length() {
+			return fmt.Errorf("relocation is outside of the section")
+		}
+
+		if v < 0 || v > 0x3FFFFF {
+			return fmt.Errorf("address of %v out of range", rel.Symbol)
+		}
+
+		word |= uint16((v>>17)&0x1F)<<4 | uint16((v>>16)&0x01)
+		binary.LittleEndian.PutUint16(field[2:], uint16(v))
+
+	case object.R_AVR_16:
+		if v < 0 || v > 0xFFFF {
+			return fmt.Errorf("address of %v out of range", rel.Symbol)
+		}
+
+		word = uint16(v)
+
+	case object.R_AVR_LDI, object.R_AVR_LO8_LDI, object.R_AVR_HI8_LDI, object.R_AVR_HH8_LDI,
+		object.R_AVR_LO8_LDI_PM, object.R_AVR_HI8_LDI_PM, object.R_AVR_HH8_LDI_PM:
+		switch rel.Type {
+		case object.R_AVR_LDI:
+			if v < -128 || v > 255 {
+				return fmt.Errorf("value of %v out of range", rel.Symbol)
+			}
+
+		case object.R_AVR_LO8_LDI_PM, object.R_AVR_HI8_LDI_PM, object.R_AVR_HH8_LDI_PM:
+			if !def.Absolute && def.Kind != object.Code {
+				return fmt.Errorf("%v is not in program memory", rel.Symbol)
+			}
+		}
+
+		switch rel.Type {
+		case object.R_AVR_HI8_LDI, object.R_AVR_HI8_LDI_PM:
+			v >>= 8
+
+		case object.R_AVR_HH8_LDI, object.R_AVR_HH8_LDI_PM:
+			v >>= 16
+		}
+
+		word |= uint16(v&0xF0)<<4 | uint16(v&0x0F)
+
+	case object.R_AVR_6_ADIW:
+		if v < 0 || v > 63 {
+			return fmt.Errorf("value of %v out of range", rel.Symbol)
+		}
+
+		word |= uint16(v&0x30)<<2 | uint16(v&0x0F)
+
+	default:
+		return fmt.Errorf("unsupported relocation %v", rel.Type)
+	}
+
+	binary.LittleEndian.PutUint16(field, word)
+	return nil
+}
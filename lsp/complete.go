@@ -0,0 +1,120 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/silaspace/aria/assembler"
+	"github.com/silaspace/aria/language"
+)
+
+/* General purpose registers, r0 to r31 */
+const REGISTERS int = 32
+
+/*
+Everything that may be written in the document: mnemonics of
+the device, directives, registers, I/O registers of the device
+and the symbols of the source. The client narrows them down to
+those matching what has been typed.
+*/
+func (s *Server) complete(p PositionParams) (interface{}, error) {
+	doc, err := s.document(p.TextDocument.URI)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dev := doc.Device()
+	items := []CompletionItem{}
+
+	for _, mn := range language.InstructionSet(dev) {
+		items = append(items, CompletionItem{
+			Detail: language.Docs[mn].Summary,
+			Kind:   COMPLETE_KEYWORD,
+			Label:  string(mn),
+		})
+	}
+
+	directives := []language.Mnemonic{}
+
+	for dir := range doc.Syntax().Directives() {
+		directives = append(directives, dir)
+	}
+
+	for _, dir := range sortMnemonics(directives) {
+		items = append(items, CompletionItem{
+			Detail: "directive",
+			Kind:   COMPLETE_KEYWORD,
+			Label:  "." + string(dir),
+		})
+	}
+
+	for i := 0; i < REGISTERS; i++ {
+		items = append(items, CompletionItem{
+			Detail: "register",
+			Kind:   COMPLETE_VARIABLE,
+			Label:  fmt.Sprintf("r%v", i),
+		})
+	}
+
+	named := []language.Mnemonic{}
+
+	for reg := range language.Registers {
+		named = append(named, reg)
+	}
+
+	for _, reg := range sortMnemonics(named) {
+		items = append(items, CompletionItem{
+			Detail: "register",
+			Kind:   COMPLETE_VARIABLE,
+			Label:  string(reg),
+		})
+	}
+
+	for _, reg := range dev.IO {
+		items = append(items, CompletionItem{
+			Detail: fmt.Sprintf("I/O register at 0x%02X", reg.Address),
+			Kind:   COMPLETE_CONSTANT,
+			Label:  reg.Name,
+		})
+	}
+
+	if doc.Asm == nil {
+		return items, nil
+	}
+
+	for _, sym := range doc.Asm.SortedSymbols() {
+		if sym.IsPredefined() || sym.IsNumeric() {
+			continue
+		}
+
+		items = append(items, CompletionItem{
+			Detail: fmt.Sprintf("%v %v", sym.Kind, sym.FmtValue()),
+			Kind:   completionKind(sym.Kind),
+			Label:  sym.Name,
+		})
+	}
+
+	return items, nil
+}
+
+func completionKind(kind assembler.SymbolKind) int {
+	switch kind {
+	case assembler.LABEL:
+		return COMPLETE_FUNCTION
+	case assembler.DEF, assembler.SET:
+		return COMPLETE_VARIABLE
+	case assembler.MACRO:
+		return COMPLETE_SNIPPET
+	default:
+		return COMPLETE_CONSTANT
+	}
+}
+
+func sortMnemonics(keys []language.Mnemonic) []language.Mnemonic {
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
@@ -0,0 +1,193 @@
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/silaspace/aria/assembler"
+)
+
+/* Errors of the assembler and preprocessor end with where they were found */
+var locatedPattern = regexp.MustCompile(`^(.*) on line (\d+)(?: of (.+))?$`)
+
+/*
+A document open in the client, along with the assembler that
+last assembled it and the error it stopped at, if any. Code
+holds the bytes listed against each line of the document.
+*/
+type Document struct {
+	Asm   *assembler.Assembler
+	Code  map[uint64][]assembler.Entry
+	Error error
+	File  string
+	Lines []string
+	URI   string
+}
+
+func (d *Document) SetText(text string) {
+	d.Lines = strings.Split(text, "\n")
+}
+
+func (d *Document) Text() string {
+	return strings.Join(d.Lines, "\n")
+}
+
+/*
+Gather the listing of the last assembly by the lines of the
+document they came from, leaving out lines of included files
+*/
+func (d *Document) Index() {
+	d.Code = map[uint64][]assembler.Entry{}
+
+	if d.Asm == nil {
+		return
+	}
+
+	for line, entries := range d.Asm.Listing.Entries {
+		origin := d.Asm.Origin(line)
+
+		if origin.File == d.File {
+			d.Code[origin.Line] = append(d.Code[origin.Line], entries...)
+		}
+	}
+}
+
+/*
+The error the document was last assembled with, placed on the
+line it was found on. Errors in included files are placed on
+the line including them, where it can be found.
+*/
+func (d *Document) Diagnostics() []Diagnostic {
+	if d.Error == nil {
+		return []Diagnostic{}
+	}
+
+	message := d.Error.Error()
+	line := uint64(1)
+
+	if match := locatedPattern.FindStringSubmatch(message); match != nil {
+		number, _ := strconv.ParseUint(match[2], 10, 64)
+
+		switch match[3] {
+		case "", d.File:
+			message, line = match[1], number
+
+		default:
+			line = d.includeLine(match[3])
+		}
+	}
+
+	return []Diagnostic{{
+		Message:  message,
+		Range:    d.lineRange(line),
+		Severity: SEVERITY_ERROR,
+		Source:   "aria",
+	}}
+}
+
+/*
+Line of the #include naming a file, or the first line if the
+file is included by another
+*/
+func (d *Document) includeLine(file string) uint64 {
+	name := filepath.Base(file)
+
+	for i, text := range d.Lines {
+		if strings.Contains(text, "#include") && strings.Contains(text, name) {
+			return uint64(i + 1)
+		}
+	}
+
+	return 1
+}
+
+/*
+Text of a line of the document, numbered from one
+*/
+func (d *Document) Line(line uint64) string {
+	if line == 0 || line > uint64(len(d.Lines)) {
+		return ""
+	}
+
+	return strings.TrimSuffix(d.Lines[line-1], "\r")
+}
+
+/*
+Range of a line numbered from one, without its indentation
+*/
+func (d *Document) lineRange(line uint64) Range {
+	return textRange(d.Line(line), line)
+}
+
+func textRange(text string, line uint64) Range {
+	trimmed := strings.TrimLeft(text, " \t")
+	start := len(text) - len(trimmed)
+
+	return Range{
+		Start: position(text, line, start),
+		End:   position(text, line, len(strings.TrimRight(text, " \t"))),
+	}
+}
+
+/*
+Position of a byte of a line numbered from one
+*/
+func position(text string, line uint64, index int) Position {
+	if line > 0 {
+		line--
+	}
+
+	return Position{
+		Character: len(utf16.Encode([]rune(text[:index]))),
+		Line:      int(line),
+	}
+}
+
+/*
+Index of the byte at a character of a line, counted in UTF-16
+code units as the client counts them
+*/
+func byteIndex(text string, character int) int {
+	units := 0
+
+	for i, r := range text {
+		if units >= character {
+			return i
+		}
+
+		units += len(utf16.Encode([]rune{r}))
+	}
+
+	return len(text)
+}
+
+/*
+Path of the file a URI names, or the URI itself if it does not
+name a file
+*/
+func uriFile(uri string) string {
+	u, err := url.Parse(uri)
+
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+
+	return filepath.FromSlash(u.Path)
+}
+
+func fileURI(file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+
+	u := url.URL{
+		Scheme: "file",
+		Path:   filepath.ToSlash(file),
+	}
+
+	return u.String()
+}
@@ -0,0 +1,32 @@
+package lsp
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/silaspace/aria/assembler"
+)
+
+func NewServer(in io.Reader, out io.Writer, analyse Analyser) *Server {
+	return &Server{
+		Analyse:     analyse,
+		Documents:   map[string]*Document{},
+		In:          bufio.NewReader(in),
+		Initialized: false,
+		Out:         out,
+		Shutdown:    false,
+	}
+}
+
+func NewDocument(uri string, text string) *Document {
+	doc := &Document{
+		Asm:   nil,
+		Code:  map[uint64][]assembler.Entry{},
+		Error: nil,
+		File:  uriFile(uri),
+		URI:   uri,
+	}
+
+	doc.SetText(text)
+	return doc
+}
@@ -0,0 +1,172 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/silaspace/aria/assembler"
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/language"
+)
+
+/*
+Describe the instruction or symbol at a position: what an
+instruction does, how the line was encoded and the cycles it
+takes, or the value of a symbol and where it was defined
+*/
+func (s *Server) hover(p PositionParams) (interface{}, error) {
+	doc, err := s.document(p.TextDocument.URI)
+
+	if err != nil {
+		return nil, err
+	}
+
+	word, rng, ok := doc.Word(p.Position)
+
+	if !ok {
+		return nil, nil
+	}
+
+	line := uint64(p.Position.Line + 1)
+	text := ""
+
+	if language.Exists(strings.ToLower(word), doc.Syntax()) == language.INSTR {
+		text = doc.describeInstruction(language.Mnemonic(strings.ToLower(word)), line)
+	} else if sym, ok := doc.Symbol(word, line); ok {
+		text = doc.describeSymbol(sym, word)
+	} else {
+		return nil, nil
+	}
+
+	return Hover{
+		Contents: MarkupContent{
+			Kind:  "markdown",
+			Value: text,
+		},
+		Range: rng,
+	}, nil
+}
+
+func (d *Document) describeInstruction(mn language.Mnemonic, line uint64) string {
+	doc := language.Docs[mn]
+	b := &strings.Builder{}
+
+	fmt.Fprintf(b, "**%v**", mn)
+
+	if doc.Operands != "" {
+		fmt.Fprintf(b, " %v", doc.Operands)
+	}
+
+	fmt.Fprintf(b, "\n\n%v\n", doc.Summary)
+
+	if doc.Operation != "" {
+		fmt.Fprintf(b, "\n```\n%v\n```\n", doc.Operation)
+	}
+
+	if doc.Flags != "" {
+		fmt.Fprintf(b, "\nFlags: %v\n", doc.Flags)
+	}
+
+	dev := d.Device()
+	instr, err := language.GetInstr(string(mn), dev)
+
+	if err != nil {
+		fmt.Fprintf(b, "\n%v\n", err)
+		return b.String()
+	}
+
+	// Lines relaxed or expanded from a macro may encode several instructions
+	entries := d.Code[line]
+
+	if len(entries) == 0 {
+		fmt.Fprintf(b, "\nCycles: %v on the %v\n", instr.FmtCycles(), dev.Name)
+		return b.String()
+	}
+
+	fmt.Fprintf(b, "\nCycles: %v on the %v\n", assembler.LineCycles(entries), dev.Name)
+
+	for _, entry := range entries {
+		fmt.Fprintf(b, "\nEncoding: `%v` at 0x%06X\n", strings.Join(assembler.Words(entry.Bytes), " "), entry.Address)
+	}
+
+	return b.String()
+}
+
+func (d *Document) describeSymbol(sym *assembler.Symbol, word string) string {
+	b := &strings.Builder{}
+
+	switch sym.Kind {
+	case assembler.DEF:
+		fmt.Fprintf(b, "**def** `%v` = r%v\n", word, sym.Value)
+
+	case assembler.MACRO:
+		fmt.Fprintf(b, "**macro** `%v`\n", word)
+
+	case assembler.EXTERN:
+		fmt.Fprintf(b, "**extern** `%v`, defined by another object\n", word)
+
+	case assembler.LABEL:
+		unit := "byte"
+
+		if sym.Segment == language.CSEG && d.Syntax() == language.SYNTAX_AVRASM {
+			unit = "word"
+		}
+
+		fmt.Fprintf(b, "**label** `%v` = 0x%06X (%v), a %v address in .%v\n", word, sym.Value, sym.Value, unit, sym.Segment)
+
+	default:
+		fmt.Fprintf(b, "**%v** `%v` = 0x%06X (%v)\n", sym.Kind, word, sym.Value, sym.Value)
+	}
+
+	switch {
+	case sym.Defined.File == assembler.DEVICE_FILE:
+		dev := d.Device()
+
+		if reg, ok := ioRegister(dev, sym.Name); ok {
+			fmt.Fprintf(b, "\nI/O register of the %v, at 0x%02X in the data space\n", dev.Name, reg.Address)
+		} else {
+			fmt.Fprintf(b, "\nDefined for the %v\n", dev.Name)
+		}
+
+	case sym.Defined.File == assembler.COMMAND_LINE:
+		fmt.Fprintf(b, "\nDefined on the command line\n")
+
+	case sym.Defined.File == d.File:
+		fmt.Fprintf(b, "\nDefined on line %v\n", sym.Defined.Line)
+
+	default:
+		fmt.Fprintf(b, "\nDefined on line %v of %v\n", sym.Defined.Line, sym.Defined.File)
+	}
+
+	return b.String()
+}
+
+/*
+The device the document was last assembled for, or the default
+device if it could not be assembled
+*/
+func (d *Document) Device() *device.Device {
+	if d.Asm == nil {
+		return device.DefaultDevice()
+	}
+
+	return &d.Asm.Device
+}
+
+func (d *Document) Syntax() language.Syntax {
+	if d.Asm == nil {
+		return language.SYNTAX_AVRASM
+	}
+
+	return d.Asm.Syntax
+}
+
+func ioRegister(dev *device.Device, name string) (device.IORegister, bool) {
+	for _, reg := range dev.IO {
+		if strings.EqualFold(reg.Name, name) {
+			return reg, true
+		}
+	}
+
+	return device.IORegister{}, false
+}
@@ -0,0 +1,245 @@
+package lsp
+
+import (
+	"os"
+	"strings"
+
+	"github.com/silaspace/aria/assembler"
+)
+
+func (s *Server) definition(p PositionParams) (interface{}, error) {
+	doc, err := s.document(p.TextDocument.URI)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sym, ok := doc.SymbolAt(p.Position)
+
+	// Symbols of the device and command line have nowhere to go to
+	if !ok || sym.IsPredefined() {
+		return nil, nil
+	}
+
+	return s.location(sym.Defined, sym.Name), nil
+}
+
+func (s *Server) references(p ReferenceParams) (interface{}, error) {
+	doc, err := s.document(p.TextDocument.URI)
+
+	if err != nil {
+		return nil, err
+	}
+
+	locations := []Location{}
+	sym, ok := doc.SymbolAt(p.Position)
+
+	if !ok {
+		return locations, nil
+	}
+
+	if p.Context.IncludeDeclaration && !sym.IsPredefined() {
+		locations = append(locations, s.location(sym.Defined, sym.Name))
+	}
+
+	for _, ref := range sym.References {
+		locations = append(locations, s.location(ref, sym.Name))
+	}
+
+	return locations, nil
+}
+
+/*
+Location of a symbol on a line of a file, covering the symbol
+where it is written on the line, or else the whole line
+*/
+func (s *Server) location(loc assembler.Location, name string) Location {
+	doc := s.source(loc.File)
+	text := doc.Line(loc.Line)
+
+	// Local labels are written without the label they belong to
+	names := []string{name}
+
+	if i := strings.LastIndex(name, "."); i > 0 {
+		names = append(names, name[i:])
+	}
+
+	for _, name := range names {
+		if start, ok := find(text, name); ok {
+			return Location{
+				Range: Range{
+					Start: position(text, loc.Line, start),
+					End:   position(text, loc.Line, start+len(name)),
+				},
+				URI: doc.URI,
+			}
+		}
+	}
+
+	return Location{
+		Range: textRange(text, loc.Line),
+		URI:   doc.URI,
+	}
+}
+
+/*
+The open document of a file, or else the file as it is saved,
+which is empty if it cannot be read
+*/
+func (s *Server) source(file string) *Document {
+	for _, doc := range s.Documents {
+		if doc.File == file {
+			return doc
+		}
+	}
+
+	data, _ := os.ReadFile(file)
+	return NewDocument(fileURI(file), string(data))
+}
+
+/*
+The word at a position, such as a symbol, mnemonic or register,
+with the range it covers. Words in comments are not found.
+*/
+func (d *Document) Word(pos Position) (string, Range, bool) {
+	line := uint64(pos.Line + 1)
+	text := d.Line(line)
+	code := text[:commentStart(text)]
+	i := byteIndex(text, pos.Character)
+
+	if i > len(code) {
+		return "", Range{}, false
+	}
+
+	start, end := i, i
+
+	for start > 0 && isWordByte(code[start-1]) {
+		start--
+	}
+
+	for end < len(code) && isWordByte(code[end]) {
+		end++
+	}
+
+	if start == end {
+		return "", Range{}, false
+	}
+
+	return code[start:end], Range{
+		Start: position(text, line, start),
+		End:   position(text, line, end),
+	}, true
+}
+
+/*
+The symbol named by the word at a position
+*/
+func (d *Document) SymbolAt(pos Position) (*assembler.Symbol, bool) {
+	word, _, ok := d.Word(pos)
+
+	if !ok {
+		return nil, false
+	}
+
+	return d.Symbol(word, uint64(pos.Line+1))
+}
+
+/*
+A symbol as named on a line, where a name starting with a dot
+belongs to the last label before the line without one
+*/
+func (d *Document) Symbol(word string, line uint64) (*assembler.Symbol, bool) {
+	if d.Asm == nil {
+		return nil, false
+	}
+
+	name := strings.ToLower(word)
+
+	if strings.HasPrefix(name, ".") {
+		name = d.scope(line) + name
+	}
+
+	sym, exists := d.Asm.Table[name]
+	return sym, exists
+}
+
+/*
+Name of the last label defined in the document at or before a
+line, leaving out local labels
+*/
+func (d *Document) scope(line uint64) string {
+	scope, at := "", uint64(0)
+
+	for name, sym := range d.Asm.Table {
+		if sym.Kind != assembler.LABEL || sym.Defined.File != d.File || strings.ContainsAny(name, ".$") {
+			continue
+		}
+
+		if sym.Defined.Line <= line && sym.Defined.Line >= at {
+			scope, at = name, sym.Defined.Line
+		}
+	}
+
+	return scope
+}
+
+/*
+Index of the first byte of a name written in a line, as a
+whole word and outside of any comment
+*/
+func find(text string, name string) (int, bool) {
+	code := text[:commentStart(text)]
+
+	for i := 0; i+len(name) <= len(code); i++ {
+		if !strings.EqualFold(code[i:i+len(name)], name) {
+			continue
+		}
+
+		before := i == 0 || !isWordByte(code[i-1])
+		after := i+len(name) == len(code) || !isWordByte(code[i+len(name)])
+
+		if before && after {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+/*
+Index of the semicolon starting the comment of a line, or the
+length of the line if it has none
+*/
+func commentStart(text string) int {
+	quote := byte(0)
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+
+		case quote != 0 && c == quote:
+			quote = 0
+
+		case quote != 0:
+			continue
+
+		case c == '"' || c == '\'':
+			quote = c
+
+		case c == ';':
+			return i
+		}
+	}
+
+	return len(text)
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '.' ||
+		('a' <= c && c <= 'z') ||
+		('A' <= c && c <= 'Z') ||
+		('0' <= c && c <= '9')
+}
@@ -0,0 +1,174 @@
+package lsp
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/silaspace/aria/assembler"
+	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/preprocessor"
+)
+
+const navigateSource = `.device atmega328p
+.def tmp = r16
+.equ K = 3
+main:
+	ldi tmp, K
+	subi tmp, K
+	rjmp main
+	rcall later
+later:	mov r0, tmp
+	rjmp main
+`
+
+/*
+A symbol asked about where it is written on a line, and the
+lines of its declaration and references, in that order
+*/
+type referenceCase struct {
+	Name  string
+	Line  int
+	Lines []int
+}
+
+var referenceCases = []referenceCase{
+	{"tmp", 2, []int{2, 5, 6, 9}},
+	{"K", 3, []int{3, 5, 6}},
+	{"main", 4, []int{4, 7, 10}},
+	{"main", 7, []int{4, 7, 10}},
+	{"later", 8, []int{9, 8}},
+	{"r16", 2, []int{}},
+}
+
+/*
+Source with an error after the uses, as while it is being typed,
+has the same references as source without one
+*/
+func TestReferences(t *testing.T) {
+	for _, source := range []string{navigateSource, navigateSource + "\tbogus r1\n"} {
+		s, uri := openDocument(t, source)
+
+		for _, c := range referenceCases {
+			params := ReferenceParams{
+				Context:      ReferenceContext{IncludeDeclaration: true},
+				Position:     wordPosition(t, source, c.Line, c.Name),
+				TextDocument: TextDocumentIdentifier{URI: uri},
+			}
+
+			result, err := s.references(params)
+
+			if err != nil {
+				t.Fatalf("%v: %v", c.Name, err)
+			}
+
+			lines := []int{}
+
+			for _, loc := range result.([]Location) {
+				lines = append(lines, loc.Range.Start.Line+1)
+			}
+
+			if !reflect.DeepEqual(lines, c.Lines) {
+				t.Errorf("%v on line %v: references on lines %v, not %v", c.Name, c.Line, lines, c.Lines)
+			}
+		}
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	s, uri := openDocument(t, navigateSource+"\tbogus r1\n")
+
+	for _, c := range referenceCases {
+		params := PositionParams{
+			Position:     wordPosition(t, navigateSource, c.Line, c.Name),
+			TextDocument: TextDocumentIdentifier{URI: uri},
+		}
+
+		result, err := s.definition(params)
+
+		if err != nil {
+			t.Fatalf("%v: %v", c.Name, err)
+		}
+
+		loc, ok := result.(Location)
+
+		switch {
+		case len(c.Lines) == 0 && ok:
+			t.Errorf("%v on line %v: expected no definition, got line %v", c.Name, c.Line, loc.Range.Start.Line+1)
+
+		case len(c.Lines) > 0 && !ok:
+			t.Errorf("%v on line %v: expected a definition on line %v", c.Name, c.Line, c.Lines[0])
+
+		case ok && loc.Range.Start.Line+1 != c.Lines[0]:
+			t.Errorf("%v on line %v: defined on line %v, not %v", c.Name, c.Line, loc.Range.Start.Line+1, c.Lines[0])
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	s, uri := openDocument(t, navigateSource+"\tbogus r1\n")
+	diagnostics := s.Documents[uri].Diagnostics()
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected a diagnostic, got %v", diagnostics)
+	}
+
+	if line := diagnostics[0].Range.Start.Line + 1; line != 11 {
+		t.Errorf("diagnostic on line %v, not 11", line)
+	}
+
+	if !strings.Contains(diagnostics[0].Message, "bogus") {
+		t.Errorf("diagnostic '%v' does not name the instruction", diagnostics[0].Message)
+	}
+}
+
+/*
+A server with a document open and assembled
+*/
+func openDocument(t *testing.T, source string) (*Server, string) {
+	s := NewServer(strings.NewReader(""), io.Discard, analyse)
+	doc := NewDocument("file:///test.s", source)
+	s.Documents[doc.URI] = doc
+
+	if err := s.analyse(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	return s, doc.URI
+}
+
+/*
+Position of a word written on a line numbered from one
+*/
+func wordPosition(t *testing.T, source string, line int, word string) Position {
+	text := strings.Split(source, "\n")[line-1]
+	i, ok := find(text, word)
+
+	if !ok {
+		t.Fatalf("'%v' is not on line %v", word, line)
+	}
+
+	return position(text, uint64(line), i)
+}
+
+func analyse(file string, source string) (*assembler.Assembler, error) {
+	reader := handler.NewWebReader()
+	reader.Write([]byte(source))
+
+	pp := preprocessor.NewPreprocessor(func(path string) (preprocessor.Reader, error) {
+		return nil, fmt.Errorf("cannot include %v", path)
+	})
+
+	err := pp.Process(file, reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	asm := assembler.NewAssembler(pp, handler.NewWebWriter())
+	asm.File = file
+	asm.Lines = pp
+	return asm, asm.Run()
+}
@@ -0,0 +1,199 @@
+package lsp
+
+import (
+	"encoding/json"
+)
+
+/* Version of JSON-RPC spoken by the protocol */
+const JSONRPC string = "2.0"
+
+/* Error codes of JSON-RPC, and of the protocol */
+const (
+	PARSE_ERROR      int = -32700
+	INVALID_REQUEST  int = -32600
+	METHOD_NOT_FOUND int = -32601
+	INVALID_PARAMS   int = -32602
+	NOT_INITIALIZED  int = -32002
+	REQUEST_FAILED   int = -32803
+)
+
+/* Severity of a diagnostic reporting an error */
+const SEVERITY_ERROR int = 1
+
+/* Type of a message logged reporting an error */
+const MESSAGE_ERROR int = 1
+
+/* Kinds of completion item, as the client shows them */
+const (
+	COMPLETE_FUNCTION int = 3
+	COMPLETE_VARIABLE int = 6
+	COMPLETE_KEYWORD  int = 14
+	COMPLETE_SNIPPET  int = 15
+	COMPLETE_CONSTANT int = 21
+)
+
+/* Documents are synchronised by sending their full text on every change */
+const SYNC_FULL int = 1
+
+/*
+A request or notification from the client. Notifications have
+no ID, and are not answered.
+*/
+type Request struct {
+	ID      *json.RawMessage `json:"id,omitempty"`
+	JSONRPC string           `json:"jsonrpc"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type Response struct {
+	ID      *json.RawMessage `json:"id"`
+	JSONRPC string           `json:"jsonrpc"`
+	Result  interface{}      `json:"result"`
+}
+
+type Failure struct {
+	Error   *ResponseError   `json:"error"`
+	ID      *json.RawMessage `json:"id"`
+	JSONRPC string           `json:"jsonrpc"`
+}
+
+type Notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return e.Message
+}
+
+/*
+A position in a document, counting lines and characters from
+zero, where characters are UTF-16 code units
+*/
+type Position struct {
+	Character int `json:"character"`
+	Line      int `json:"line"`
+}
+
+type Range struct {
+	End   Position `json:"end"`
+	Start Position `json:"start"`
+}
+
+type Location struct {
+	Range Range  `json:"range"`
+	URI   string `json:"uri"`
+}
+
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   ServerInfo         `json:"serverInfo"`
+}
+
+type ServerCapabilities struct {
+	CompletionProvider CompletionOptions `json:"completionProvider"`
+	DefinitionProvider bool              `json:"definitionProvider"`
+	HoverProvider      bool              `json:"hoverProvider"`
+	ReferencesProvider bool              `json:"referencesProvider"`
+	TextDocumentSync   int               `json:"textDocumentSync"`
+}
+
+type CompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters"`
+}
+
+type ServerInfo struct {
+	Name string `json:"name"`
+}
+
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type TextDocumentItem struct {
+	LanguageID string `json:"languageId"`
+	Text       string `json:"text"`
+	URI        string `json:"uri"`
+	Version    int    `json:"version"`
+}
+
+type VersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+type DidOpenParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+/*
+Changes to a document, each giving its full text as the server
+asks for full synchronisation
+*/
+type DidChangeParams struct {
+	ContentChanges []ContentChange                 `json:"contentChanges"`
+	TextDocument   VersionedTextDocumentIdentifier `json:"textDocument"`
+}
+
+type ContentChange struct {
+	Text string `json:"text"`
+}
+
+type DidCloseParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type PositionParams struct {
+	Position     Position               `json:"position"`
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type ReferenceParams struct {
+	Context      ReferenceContext       `json:"context"`
+	Position     Position               `json:"position"`
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type ReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+type Diagnostic struct {
+	Message  string `json:"message"`
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source"`
+}
+
+type PublishDiagnosticsParams struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	URI         string       `json:"uri"`
+}
+
+type LogMessageParams struct {
+	Message string `json:"message"`
+	Type    int    `json:"type"`
+}
+
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    Range         `json:"range"`
+}
+
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type CompletionItem struct {
+	Detail string `json:"detail,omitempty"`
+	Kind   int    `json:"kind"`
+	Label  string `json:"label"`
+}
@@ -0,0 +1,368 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/silaspace/aria/assembler"
+)
+
+/* Header giving the length of each message */
+const CONTENT_LENGTH string = "Content-Length"
+
+/*
+Preprocesses and assembles the text of a document, as though it
+were the named file. The assembler is given back even when the
+source has an error, with what it learnt before the error, but
+may be nil if the source could not be read at all.
+*/
+type Analyser func(file string, source string) (*assembler.Assembler, error)
+
+/*
+A language server speaking the Language Server Protocol over
+a pair of streams, usually stdin and stdout. Each document the
+client opens is assembled again whenever it changes, giving
+the diagnostics, symbols and listing that requests are
+answered from.
+*/
+type Server struct {
+	Analyse     Analyser
+	Documents   map[string]*Document /* By URI */
+	In          *bufio.Reader
+	Initialized bool
+	Out         io.Writer
+	Shutdown    bool
+}
+
+/*
+Answer messages from the client until it asks the server to
+exit. Exiting without first being asked to shut down is an
+error, as is the client closing its end.
+*/
+func (s *Server) Run() error {
+	for {
+		body, err := s.read()
+
+		if err == io.EOF {
+			return fmt.Errorf("the client closed the connection without exit")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		req := Request{}
+		err = json.Unmarshal(body, &req)
+
+		if err != nil {
+			err = s.fail(nil, &ResponseError{PARSE_ERROR, err.Error()})
+
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.Shutdown {
+				return fmt.Errorf("the client exited without shutdown")
+			}
+
+			return nil
+		}
+
+		err = s.handle(req)
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+/*
+Answer a request, or act on a notification. Only errors writing
+to the client are returned, as others are sent to the client.
+*/
+func (s *Server) handle(req Request) error {
+	isRequest := req.ID != nil
+
+	if !s.Initialized && req.Method != "initialize" {
+		if isRequest {
+			return s.fail(req.ID, &ResponseError{NOT_INITIALIZED, "the server has not been initialized"})
+		}
+
+		return nil
+	}
+
+	if !isRequest {
+		err := s.notification(req.Method, req.Params)
+
+		// Notifications cannot be answered, so their errors are logged
+		if err != nil {
+			return s.log(err)
+		}
+
+		return nil
+	}
+
+	result, err := s.request(req.Method, req.Params)
+
+	if err == nil {
+		return s.respond(req.ID, result)
+	}
+
+	if rerr, ok := err.(*ResponseError); ok {
+		return s.fail(req.ID, rerr)
+	}
+
+	return s.fail(req.ID, &ResponseError{REQUEST_FAILED, err.Error()})
+}
+
+func (s *Server) request(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "initialize":
+		s.Initialized = true
+
+		return InitializeResult{
+			Capabilities: ServerCapabilities{
+				CompletionProvider: CompletionOptions{
+					TriggerCharacters: []string{"."},
+				},
+				DefinitionProvider: true,
+				HoverProvider:      true,
+				ReferencesProvider: true,
+				TextDocumentSync:   SYNC_FULL,
+			},
+			ServerInfo: ServerInfo{
+				Name: "aria",
+			},
+		}, nil
+
+	case "shutdown":
+		s.Shutdown = true
+		return nil, nil
+
+	case "textDocument/hover":
+		p := PositionParams{}
+
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+
+		return s.hover(p)
+
+	case "textDocument/definition":
+		p := PositionParams{}
+
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+
+		return s.definition(p)
+
+	case "textDocument/references":
+		p := ReferenceParams{}
+
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+
+		return s.references(p)
+
+	case "textDocument/completion":
+		p := PositionParams{}
+
+		if err := decode(params, &p); err != nil {
+			return nil, err
+		}
+
+		return s.complete(p)
+
+	default:
+		return nil, &ResponseError{METHOD_NOT_FOUND, fmt.Sprintf("unknown method %v", method)}
+	}
+}
+
+func (s *Server) notification(method string, params json.RawMessage) error {
+	switch method {
+	case "textDocument/didOpen":
+		p := DidOpenParams{}
+
+		if err := decode(params, &p); err != nil {
+			return err
+		}
+
+		doc := NewDocument(p.TextDocument.URI, p.TextDocument.Text)
+		s.Documents[doc.URI] = doc
+		return s.analyse(doc)
+
+	case "textDocument/didChange":
+		p := DidChangeParams{}
+
+		if err := decode(params, &p); err != nil {
+			return err
+		}
+
+		doc, exists := s.Documents[p.TextDocument.URI]
+
+		if !exists || len(p.ContentChanges) == 0 {
+			return nil
+		}
+
+		doc.SetText(p.ContentChanges[len(p.ContentChanges)-1].Text)
+		return s.analyse(doc)
+
+	case "textDocument/didClose":
+		p := DidCloseParams{}
+
+		if err := decode(params, &p); err != nil {
+			return err
+		}
+
+		delete(s.Documents, p.TextDocument.URI)
+
+		// Diagnostics of a closed document are cleared
+		return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			Diagnostics: []Diagnostic{},
+			URI:         p.TextDocument.URI,
+		})
+
+	default:
+		// Other notifications, such as didSave and $/cancelRequest, are ignored
+		return nil
+	}
+}
+
+/*
+Assemble a document again and publish its diagnostics
+*/
+func (s *Server) analyse(doc *Document) error {
+	doc.Asm, doc.Error = s.Analyse(doc.File, doc.Text())
+	doc.Index()
+
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		Diagnostics: doc.Diagnostics(),
+		URI:         doc.URI,
+	})
+}
+
+/*
+The open document a request is about
+*/
+func (s *Server) document(uri string) (*Document, error) {
+	doc, exists := s.Documents[uri]
+
+	if !exists {
+		return nil, &ResponseError{INVALID_PARAMS, fmt.Sprintf("document %v is not open", uri)}
+	}
+
+	return doc, nil
+}
+
+func (s *Server) respond(id *json.RawMessage, result interface{}) error {
+	return s.write(Response{
+		ID:      id,
+		JSONRPC: JSONRPC,
+		Result:  result,
+	})
+}
+
+func (s *Server) fail(id *json.RawMessage, err *ResponseError) error {
+	return s.write(Failure{
+		Error:   err,
+		ID:      id,
+		JSONRPC: JSONRPC,
+	})
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return s.write(Notification{
+		JSONRPC: JSONRPC,
+		Method:  method,
+		Params:  params,
+	})
+}
+
+/*
+Show a message in the client's log
+*/
+func (s *Server) log(err error) error {
+	return s.notify("window/logMessage", LogMessageParams{
+		Message: err.Error(),
+		Type:    MESSAGE_ERROR,
+	})
+}
+
+/*
+Read the body of the next message, following its headers
+*/
+func (s *Server) read() ([]byte, error) {
+	length := -1
+
+	for {
+		line, err := s.In.ReadString('\n')
+
+		if err != nil {
+			return nil, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			break
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+
+		if !ok {
+			return nil, fmt.Errorf("malformed header '%v'", line)
+		}
+
+		if strings.EqualFold(strings.TrimSpace(name), CONTENT_LENGTH) {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+
+			if err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid %v '%v'", CONTENT_LENGTH, strings.TrimSpace(value))
+			}
+		}
+	}
+
+	if length < 0 {
+		return nil, fmt.Errorf("message without a %v header", CONTENT_LENGTH)
+	}
+
+	body := make([]byte, length)
+	_, err := io.ReadFull(s.In, body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+func (s *Server) write(msg interface{}) error {
+	body, err := json.Marshal(msg)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(s.Out, "%v: %v\r\n\r\n%s", CONTENT_LENGTH, len(body), body)
+	return err
+}
+
+func decode(params json.RawMessage, v interface{}) error {
+	err := json.Unmarshal(params, v)
+
+	if err != nil {
+		return &ResponseError{INVALID_PARAMS, err.Error()}
+	}
+
+	return nil
+}
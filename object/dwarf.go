@@ -0,0 +1,228 @@
+package object
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"debug/elf"
+	"encoding/binary"
+	"path/filepath"
+)
+
+/* DWARF 2 encodings not provided by debug/dwarf */
+const (
+	DW_LANG_MIPS_ASSEMBLER uint16 = 0x8001
+
+	DW_FORM_ADDR   uint64 = 0x01
+	DW_FORM_DATA2  uint64 = 0x05
+	DW_FORM_DATA4  uint64 = 0x06
+	DW_FORM_STRING uint64 = 0x08
+
+	DW_LNS_COPY         byte = 1
+	DW_LNS_ADVANCE_PC   byte = 2
+	DW_LNS_ADVANCE_LINE byte = 3
+	DW_LNS_SET_FILE     byte = 4
+
+	DW_LNE_END_SEQUENCE byte = 1
+	DW_LNE_SET_ADDRESS  byte = 2
+
+	LINE_OPCODE_BASE byte = 13
+)
+
+/*
+The source line of the instruction at Address
+*/
+type LineRow struct {
+	Address uint64
+	File    string
+	Line    uint64
+}
+
+/*
+Debug information for a single compile unit, covering the
+code from LowPC up to HighPC
+*/
+type Debug struct {
+	Name     string
+	Dir      string
+	Producer string
+	LowPC    uint64
+	HighPC   uint64
+	Rows     []LineRow
+}
+
+/*
+The .debug_abbrev, .debug_info and .debug_line sections
+*/
+func (d *Debug) sections() []*elfSection {
+	return []*elfSection{
+		{name: ".debug_abbrev", typ: elf.SHT_PROGBITS, data: d.abbrev(), align: 1},
+		{name: ".debug_info", typ: elf.SHT_PROGBITS, data: d.info(), align: 1},
+		{name: ".debug_line", typ: elf.SHT_PROGBITS, data: d.line(), align: 1},
+	}
+}
+
+func (d *Debug) abbrev() []byte {
+	buf := bytes.Buffer{}
+
+	buf.Write(uleb(1))
+	buf.Write(uleb(uint64(dwarf.TagCompileUnit)))
+	buf.WriteByte(0) // No children
+
+	attrs := [][2]uint64{
+		{uint64(dwarf.AttrProducer), DW_FORM_STRING},
+		{uint64(dwarf.AttrLanguage), DW_FORM_DATA2},
+		{uint64(dwarf.AttrName), DW_FORM_STRING},
+		{uint64(dwarf.AttrCompDir), DW_FORM_STRING},
+		{uint64(dwarf.AttrLowpc), DW_FORM_ADDR},
+		{uint64(dwarf.AttrHighpc), DW_FORM_ADDR},
+		{uint64(dwarf.AttrStmtList), DW_FORM_DATA4},
+	}
+
+	for _, attr := range attrs {
+		buf.Write(uleb(attr[0]))
+		buf.Write(uleb(attr[1]))
+	}
+
+	buf.Write([]byte{0, 0, 0})
+	return buf.Bytes()
+}
+
+func (d *Debug) info() []byte {
+	body := bytes.Buffer{}
+	binary.Write(&body, binary.LittleEndian, uint16(2)) // Version
+	binary.Write(&body, binary.LittleEndian, uint32(0)) // Abbreviations offset
+	body.WriteByte(4)                                   // Address size
+
+	body.Write(uleb(1))
+	body.Write(cstring(d.Producer))
+	binary.Write(&body, binary.LittleEndian, DW_LANG_MIPS_ASSEMBLER)
+	body.Write(cstring(d.Name))
+	body.Write(cstring(d.Dir))
+	binary.Write(&body, binary.LittleEndian, uint32(d.LowPC))
+	binary.Write(&body, binary.LittleEndian, uint32(d.HighPC))
+	binary.Write(&body, binary.LittleEndian, uint32(0)) // Offset in .debug_line
+
+	return withLength(body.Bytes())
+}
+
+/*
+Line number program mapping each address to a file and line
+*/
+func (d *Debug) line() []byte {
+	files := []string{}
+	index := map[string]uint64{}
+
+	for _, row := range d.Rows {
+		if _, exists := index[row.File]; !exists {
+			files = append(files, row.File)
+			index[row.File] = uint64(len(files))
+		}
+	}
+
+	// Header following the header length
+	header := bytes.Buffer{}
+	header.WriteByte(1)    // Minimum instruction length
+	header.WriteByte(1)    // Default is_stmt
+	header.WriteByte(0xFB) // Line base of -5
+	header.WriteByte(14)   // Line range
+	header.WriteByte(LINE_OPCODE_BASE)
+	header.Write([]byte{0, 1, 1, 1, 1, 0, 0, 0, 1, 0, 0, 1})
+	header.WriteByte(0) // No include directories
+
+	for _, file := range files {
+		header.Write(cstring(filepath.ToSlash(file)))
+		header.Write(uleb(0)) // Directory
+		header.Write(uleb(0)) // Modification time
+		header.Write(uleb(0)) // Length
+	}
+
+	header.WriteByte(0)
+
+	// Line number program
+	program := bytes.Buffer{}
+
+	if len(d.Rows) > 0 {
+		program.Write([]byte{0, 5, DW_LNE_SET_ADDRESS})
+		binary.Write(&program, binary.LittleEndian, uint32(d.Rows[0].Address))
+
+		address := d.Rows[0].Address
+		file := uint64(1)
+		line := int64(1)
+
+		for _, row := range d.Rows {
+			if f := index[row.File]; f != file {
+				program.WriteByte(DW_LNS_SET_FILE)
+				program.Write(uleb(f))
+				file = f
+			}
+
+			if delta := int64(row.Line) - line; delta != 0 {
+				program.WriteByte(DW_LNS_ADVANCE_LINE)
+				program.Write(sleb(delta))
+				line = int64(row.Line)
+			}
+
+			if row.Address != address {
+				program.WriteByte(DW_LNS_ADVANCE_PC)
+				program.Write(uleb(row.Address - address))
+				address = row.Address
+			}
+
+			program.WriteByte(DW_LNS_COPY)
+		}
+
+		if d.HighPC > address {
+			program.WriteByte(DW_LNS_ADVANCE_PC)
+			program.Write(uleb(d.HighPC - address))
+		}
+
+		program.Write([]byte{0, 1, DW_LNE_END_SEQUENCE})
+	}
+
+	body := bytes.Buffer{}
+	binary.Write(&body, binary.LittleEndian, uint16(2)) // Version
+	binary.Write(&body, binary.LittleEndian, uint32(header.Len()))
+	body.Write(header.Bytes())
+	body.Write(program.Bytes())
+
+	return withLength(body.Bytes())
+}
+
+func withLength(body []byte) []byte {
+	out := binary.LittleEndian.AppendUint32([]byte{}, uint32(len(body)))
+	return append(out, body...)
+}
+
+func cstring(s string) []byte {
+	return append([]byte(s), 0)
+}
+
+func uleb(v uint64) []byte {
+	out := []byte{}
+
+	for {
+		b := byte(v & 0x7F)
+		v >>= 7
+
+		if v == 0 {
+			return append(out, b)
+		}
+
+		out = append(out, b|0x80)
+	}
+}
+
+func sleb(v int64) []byte {
+	out := []byte{}
+
+	for {
+		b := byte(v & 0x7F)
+		v >>= 7
+
+		if (v == 0 && b&0x40 == 0) || (v == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+
+		out = append(out, b|0x80)
+	}
+}
@@ -0,0 +1,383 @@
+package object
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"io"
+)
+
+const (
+	ELF_HEADER_SIZE  uint64 = 52
+	PROG_HEADER_SIZE uint64 = 32
+	SECT_HEADER_SIZE uint64 = 40
+	SYMBOL_SIZE      uint64 = 16
+	RELA_SIZE        uint64 = 12
+)
+
+/* Section holding the name of the device an object is for */
+const DEVICE_SECTION string = ".aria.device"
+
+/*
+A section as it is written to the ELF file, including those
+generated by the writer such as the symbol and string tables
+*/
+type elfSection struct {
+	name    string
+	typ     elf.SectionType
+	flags   elf.SectionFlag
+	addr    uint64
+	data    []byte
+	size    uint64
+	link    uint32
+	info    uint32
+	align   uint64
+	entsize uint64
+	offset  uint64
+	rom     bool /* Initial values stored in flash */
+}
+
+type stringTable struct {
+	data  []byte
+	index map[string]uint32
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{
+		data:  []byte{0},
+		index: map[string]uint32{"": 0},
+	}
+}
+
+func (t *stringTable) add(s string) uint32 {
+	if i, exists := t.index[s]; exists {
+		return i
+	}
+
+	i := uint32(len(t.data))
+	t.data = append(append(t.data, s...), 0)
+	t.index[s] = i
+	return i
+}
+
+/*
+Write the file as a 32 bit little endian ELF executable for
+the AVR, readable by the GNU binutils and avr-gdb
+*/
+func (f *File) WriteELF(w io.Writer) error {
+	sections := []*elfSection{{}}
+	programs := []elf.Prog32{}
+
+	// Sections holding the program, in the order given
+	lma := uint64(0)
+
+	for _, sec := range f.Sections {
+		es := &elfSection{
+			name:  sec.Name,
+			typ:   elf.SHT_PROGBITS,
+			flags: elf.SHF_ALLOC | elf.SHF_WRITE,
+			addr:  sec.Address,
+			data:  sec.Data,
+			size:  sec.Length(),
+			align: 1,
+		}
+
+		if sec.Kind == Code {
+			es.flags = elf.SHF_ALLOC | elf.SHF_EXECINSTR
+			es.align = 2
+		}
+
+		if !sec.HasData() {
+			es.typ = elf.SHT_NOBITS
+		}
+
+		if sec.Kind == Data && sec.HasData() {
+			es.rom = true
+		}
+
+		sections = append(sections, es)
+
+		if sec.Kind == Code {
+			lma = max(lma, sec.Address+sec.Length())
+		}
+	}
+
+	if f.Debug != nil {
+		sections = append(sections, f.Debug.sections()...)
+	}
+
+	// The device is recorded so objects can be linked for it, and programs run on it
+	if f.Device != "" {
+		sections = append(sections, &elfSection{
+			name:  DEVICE_SECTION,
+			typ:   elf.SHT_PROGBITS,
+			data:  cstring(f.Device),
+			align: 1,
+		})
+	}
+
+	// Symbol table, with local symbols before global ones
+	strtab := newStringTable()
+	symtab := bytes.Buffer{}
+	binary.Write(&symtab, binary.LittleEndian, elf.Sym32{})
+
+	index := map[string]uint32{}
+	locals := uint32(1)
+
+	for _, global := range []bool{false, true} {
+		for _, sym := range f.Symbols {
+			if sym.Global != global {
+				continue
+			}
+
+			index[sym.Name] = uint32(symtab.Len()) / uint32(SYMBOL_SIZE)
+			binary.Write(&symtab, binary.LittleEndian, f.elfSymbol(sym, strtab))
+
+			if !global {
+				locals++
+			}
+		}
+	}
+
+	// Relocations of each section, linked to the symbol table
+	relas := []*elfSection{}
+
+	for i, sec := range f.Sections {
+		rela := bytes.Buffer{}
+
+		for _, rel := range f.Relocations {
+			if rel.Section != sec.Name {
+				continue
+			}
+
+			binary.Write(&rela, binary.LittleEndian, elf.Rela32{
+				Off:    uint32(rel.Offset),
+				Info:   elf.R_INFO32(index[rel.Symbol], uint32(rel.Type)),
+				Addend: int32(rel.Addend),
+			})
+		}
+
+		if rela.Len() == 0 {
+			continue
+		}
+
+		relas = append(relas, &elfSection{
+			name:    ".rela" + sec.Name,
+			typ:     elf.SHT_RELA,
+			flags:   elf.SHF_INFO_LINK,
+			data:    rela.Bytes(),
+			info:    uint32(i + 1),
+			align:   4,
+			entsize: RELA_SIZE,
+		})
+	}
+
+	symtabIndex := uint32(len(sections) + len(relas))
+
+	for _, rela := range relas {
+		rela.link = symtabIndex
+		sections = append(sections, rela)
+	}
+
+	sections = append(sections, &elfSection{
+		name:    ".symtab",
+		typ:     elf.SHT_SYMTAB,
+		data:    symtab.Bytes(),
+		link:    symtabIndex + 1,
+		info:    locals,
+		align:   4,
+		entsize: SYMBOL_SIZE,
+	})
+
+	sections = append(sections, &elfSection{
+		name:  ".strtab",
+		typ:   elf.SHT_STRTAB,
+		data:  strtab.data,
+		align: 1,
+	})
+
+	shstrtab := newStringTable()
+	shstrtabIndex := len(sections)
+
+	sections = append(sections, &elfSection{
+		name:  ".shstrtab",
+		typ:   elf.SHT_STRTAB,
+		align: 1,
+	})
+
+	names := make([]uint32, len(sections))
+
+	for i, sec := range sections[1:] {
+		names[i+1] = shstrtab.add(sec.name)
+	}
+
+	sections[shstrtabIndex].data = shstrtab.data
+
+	// Count the loadable segments before laying out the file
+	for _, sec := range sections {
+		if !f.Relocatable && sec.flags&elf.SHF_ALLOC != 0 && sec.size > 0 {
+			programs = append(programs, elf.Prog32{})
+		}
+	}
+
+	offset := ELF_HEADER_SIZE + PROG_HEADER_SIZE*uint64(len(programs))
+
+	for _, sec := range sections[1:] {
+		if sec.typ == elf.SHT_NOBITS {
+			sec.offset = offset
+			continue
+		}
+
+		offset = align(offset, sec.align)
+		sec.offset = offset
+		offset += uint64(len(sec.data))
+	}
+
+	shoff := align(offset, 4)
+
+	// Program headers, with initialised data loaded after the code
+	n := 0
+
+	for _, sec := range sections {
+		if f.Relocatable || sec.flags&elf.SHF_ALLOC == 0 || sec.size == 0 {
+			continue
+		}
+
+		prog := elf.Prog32{
+			Type:   uint32(elf.PT_LOAD),
+			Off:    uint32(sec.offset),
+			Vaddr:  uint32(sec.addr),
+			Paddr:  uint32(sec.addr),
+			Filesz: uint32(len(sec.data)),
+			Memsz:  uint32(sec.size),
+			Flags:  uint32(elf.PF_R | elf.PF_W),
+			Align:  1,
+		}
+
+		if sec.flags&elf.SHF_EXECINSTR != 0 {
+			prog.Flags = uint32(elf.PF_R | elf.PF_X)
+		} else if sec.rom {
+			prog.Paddr = uint32(lma)
+			lma += sec.size
+		}
+
+		programs[n] = prog
+		n++
+	}
+
+	// ELF header
+	typ := elf.ET_EXEC
+	phoff := ELF_HEADER_SIZE
+
+	if f.Relocatable {
+		typ = elf.ET_REL
+		phoff = 0
+	}
+
+	header := elf.Header32{
+		Type:      uint16(typ),
+		Machine:   uint16(elf.EM_AVR),
+		Version:   uint32(elf.EV_CURRENT),
+		Entry:     uint32(f.Entry),
+		Phoff:     uint32(phoff),
+		Shoff:     uint32(shoff),
+		Flags:     f.Arch,
+		Ehsize:    uint16(ELF_HEADER_SIZE),
+		Phentsize: uint16(PROG_HEADER_SIZE),
+		Phnum:     uint16(len(programs)),
+		Shentsize: uint16(SECT_HEADER_SIZE),
+		Shnum:     uint16(len(sections)),
+		Shstrndx:  uint16(shstrtabIndex),
+	}
+
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS32)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	out := bytes.Buffer{}
+	binary.Write(&out, binary.LittleEndian, header)
+
+	for _, prog := range programs {
+		binary.Write(&out, binary.LittleEndian, prog)
+	}
+
+	for _, sec := range sections[1:] {
+		if sec.typ == elf.SHT_NOBITS {
+			continue
+		}
+
+		out.Write(make([]byte, sec.offset-uint64(out.Len())))
+		out.Write(sec.data)
+	}
+
+	out.Write(make([]byte, shoff-uint64(out.Len())))
+
+	for i, sec := range sections {
+		shdr := elf.Section32{}
+
+		if i > 0 {
+			shdr = elf.Section32{
+				Name:      names[i],
+				Type:      uint32(sec.typ),
+				Flags:     uint32(sec.flags),
+				Addr:      uint32(sec.addr),
+				Off:       uint32(sec.offset),
+				Size:      uint32(sec.size),
+				Link:      sec.link,
+				Info:      sec.info,
+				Addralign: uint32(sec.align),
+				Entsize:   uint32(sec.entsize),
+			}
+		}
+
+		if sec.typ != elf.SHT_NOBITS && i > 0 {
+			shdr.Size = uint32(len(sec.data))
+		}
+
+		binary.Write(&out, binary.LittleEndian, shdr)
+	}
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func (f *File) elfSymbol(sym Symbol, strtab *stringTable) elf.Sym32 {
+	bind := elf.STB_LOCAL
+	typ := elf.STT_NOTYPE
+	shndx := uint16(elf.SHN_ABS)
+
+	if sym.Global || sym.Extern {
+		bind = elf.STB_GLOBAL
+	}
+
+	switch sym.Kind {
+	case Object:
+		typ = elf.STT_OBJECT
+	case Func:
+		typ = elf.STT_FUNC
+	}
+
+	if i := f.SectionIndex(sym.Section); i >= 0 {
+		shndx = uint16(i + 1)
+	}
+
+	if sym.Extern {
+		shndx = uint16(elf.SHN_UNDEF)
+	}
+
+	return elf.Sym32{
+		Name:  strtab.add(sym.Name),
+		Value: uint32(sym.Value),
+		Info:  elf.ST_INFO(bind, typ),
+		Shndx: shndx,
+	}
+}
+
+func align(offset uint64, alignment uint64) uint64 {
+	if alignment <= 1 {
+		return offset
+	}
+
+	return (offset + alignment - 1) / alignment * alignment
+}
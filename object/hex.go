@@ -0,0 +1,142 @@
+package object
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+/* Types of Intel HEX records */
+const (
+	HEX_DATA            byte = 0x00
+	HEX_EOF             byte = 0x01
+	HEX_SEGMENT_ADDRESS byte = 0x02
+	HEX_START_SEGMENT   byte = 0x03
+	HEX_LINEAR_ADDRESS  byte = 0x04
+	HEX_START_LINEAR    byte = 0x05
+)
+
+/* Flash of the largest devices, in bytes */
+const MAX_PROGRAM_SIZE int = 0x400000
+
+/*
+Read a program in Intel HEX, as written by avr-objcopy, into a
+single code section. Gaps between records are filled as erased
+flash.
+*/
+func ReadHex(r io.Reader) (*File, error) {
+	scanner := bufio.NewScanner(r)
+	image := []byte{}
+	written := []bool{}
+	base := 0
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(text, ":") {
+			return nil, fmt.Errorf("expected ':' at the start of record on line %v", line)
+		}
+
+		record, err := hex.DecodeString(text[1:])
+
+		if err != nil || len(record) < 5 || len(record) != int(record[0])+5 {
+			return nil, fmt.Errorf("malformed record on line %v", line)
+		}
+
+		sum := byte(0)
+
+		for _, b := range record {
+			sum += b
+		}
+
+		if sum != 0 {
+			return nil, fmt.Errorf("checksum mismatch on line %v", line)
+		}
+
+		data := record[4 : len(record)-1]
+		offset := int(record[1])<<8 | int(record[2])
+
+		switch record[3] {
+		case HEX_DATA:
+			start := base + offset
+
+			if start+len(data) > MAX_PROGRAM_SIZE {
+				return nil, fmt.Errorf("address 0x%X out of range on line %v", start, line)
+			}
+
+			for len(image) < start+len(data) {
+				image = append(image, 0xFF)
+				written = append(written, false)
+			}
+
+			for i, b := range data {
+				if written[start+i] {
+					return nil, fmt.Errorf("address 0x%X written twice on line %v", start+i, line)
+				}
+
+				image[start+i] = b
+				written[start+i] = true
+			}
+
+		case HEX_EOF:
+			return program(image), nil
+
+		case HEX_SEGMENT_ADDRESS, HEX_LINEAR_ADDRESS:
+			if len(data) != 2 {
+				return nil, fmt.Errorf("malformed address record on line %v", line)
+			}
+
+			base = int(data[0])<<8 | int(data[1])
+
+			if record[3] == HEX_SEGMENT_ADDRESS {
+				base <<= 4
+			} else {
+				base <<= 16
+			}
+
+		case HEX_START_SEGMENT, HEX_START_LINEAR:
+			continue
+
+		default:
+			return nil, fmt.Errorf("unknown record type 0x%02X on line %v", record[3], line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("missing end of file record")
+}
+
+/*
+A program of a single code section, as read from a binary
+image of flash
+*/
+func ReadBinary(data []byte) *File {
+	return program(data)
+}
+
+func program(image []byte) *File {
+	return &File{
+		Relocations: []Relocation{},
+		Sections: []Section{
+			{
+				Name:    ".text",
+				Kind:    Code,
+				Address: TEXT_ADDRESS,
+				Data:    image,
+				Size:    uint64(len(image)),
+			},
+		},
+		Symbols: []Symbol{},
+	}
+}
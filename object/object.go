@@ -0,0 +1,201 @@
+package object
+
+import "fmt"
+
+/*
+Base addresses used by avr-gcc to place each memory of the
+device in a single address space
+*/
+const (
+	TEXT_ADDRESS   uint64 = 0x000000
+	DATA_ADDRESS   uint64 = 0x800000
+	EEPROM_ADDRESS uint64 = 0x810000
+)
+
+type SectionKind int
+
+const (
+	Code   SectionKind = 0
+	Data   SectionKind = 1
+	EEPROM SectionKind = 2
+)
+
+type SymbolKind int
+
+type RelocType uint32
+
+/* ELF relocation types for the AVR, as used by the GNU tools */
+const (
+	R_AVR_7_PCREL    RelocType = 2
+	R_AVR_13_PCREL   RelocType = 3
+	R_AVR_16         RelocType = 4
+	R_AVR_LO8_LDI    RelocType = 6
+	R_AVR_HI8_LDI    RelocType = 7
+	R_AVR_HH8_LDI    RelocType = 8
+	R_AVR_LO8_LDI_PM RelocType = 12 /* Bytes of a word address in program memory */
+	R_AVR_HI8_LDI_PM RelocType = 13
+	R_AVR_HH8_LDI_PM RelocType = 14
+	R_AVR_CALL       RelocType = 18
+	R_AVR_LDI        RelocType = 19
+	R_AVR_6_ADIW     RelocType = 21
+)
+
+const (
+	NoType SymbolKind = 0
+	Object SymbolKind = 1
+	Func   SymbolKind = 2
+)
+
+/*
+A contiguous block of memory. Sections without data, such as
+space reserved in SRAM, take up Size bytes in memory but none
+in the file.
+*/
+type Section struct {
+	Name    string
+	Kind    SectionKind
+	Address uint64
+	Data    []byte
+	Size    uint64
+}
+
+/*
+A symbol in a section, or an absolute value when Section is
+empty. Values are byte addresses in the section's memory, or
+offsets from the start of the section in relocatable files.
+Extern symbols are defined by another file.
+*/
+type Symbol struct {
+	Name    string
+	Section string
+	Kind    SymbolKind
+	Value   uint64
+	Global  bool
+	Extern  bool
+}
+
+/*
+A reference to Symbol from the field at Offset bytes into
+Section, to be patched once the symbol's address is known.
+The addend is in the units of the symbol's memory, words for
+code and bytes for data, as in aria's expressions.
+*/
+type Relocation struct {
+	Section string
+	Offset  uint64
+	Symbol  string
+	Type    RelocType
+	Addend  int64
+}
+
+/*
+An executable program, or a relocatable object when the file
+has been assembled but not yet linked
+*/
+type File struct {
+	Arch        uint32
+	Debug       *Debug
+	Device      string
+	Entry       uint64
+	Relocatable bool
+	Relocations []Relocation
+	Sections    []Section
+	Symbols     []Symbol
+}
+
+func (t RelocType) String() string {
+	switch t {
+	case R_AVR_7_PCREL:
+		return "R_AVR_7_PCREL"
+	case R_AVR_13_PCREL:
+		return "R_AVR_13_PCREL"
+	case R_AVR_16:
+		return "R_AVR_16"
+	case R_AVR_LO8_LDI:
+		return "R_AVR_LO8_LDI"
+	case R_AVR_HI8_LDI:
+		return "R_AVR_HI8_LDI"
+	case R_AVR_HH8_LDI:
+		return "R_AVR_HH8_LDI"
+	case R_AVR_LO8_LDI_PM:
+		return "R_AVR_LO8_LDI_PM"
+	case R_AVR_HI8_LDI_PM:
+		return "R_AVR_HI8_LDI_PM"
+	case R_AVR_HH8_LDI_PM:
+		return "R_AVR_HH8_LDI_PM"
+	case R_AVR_CALL:
+		return "R_AVR_CALL"
+	case R_AVR_LDI:
+		return "R_AVR_LDI"
+	case R_AVR_6_ADIW:
+		return "R_AVR_6_ADIW"
+	default:
+		return fmt.Sprintf("R_AVR_%d", uint32(t))
+	}
+}
+
+/*
+Address of the first byte of the memory in the address space
+of the GNU tools
+*/
+func (k SectionKind) Base() uint64 {
+	switch k {
+	case Code:
+		return TEXT_ADDRESS
+	case Data:
+		return DATA_ADDRESS
+	default:
+		return EEPROM_ADDRESS
+	}
+}
+
+func (s *Section) HasData() bool {
+	return s.Data != nil
+}
+
+func (s *Section) Length() uint64 {
+	if s.HasData() {
+		return uint64(len(s.Data))
+	}
+
+	return s.Size
+}
+
+/*
+Index of the named section, or -1 if it does not exist
+*/
+func (f *File) SectionIndex(name string) int {
+	for i, sec := range f.Sections {
+		if sec.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+/*
+Contents of one memory from its first address up to the end
+of the last section in it, with any gaps between sections
+filled with the given byte
+*/
+func (f *File) Image(kind SectionKind, fill byte) []byte {
+	image := []byte{}
+
+	for _, sec := range f.Sections {
+		if sec.Kind != kind || !sec.HasData() {
+			continue
+		}
+
+		start := sec.Address - kind.Base()
+		end := start + sec.Length()
+
+		for uint64(len(image)) < end {
+			image = append(image, fill)
+		}
+
+		copy(image[start:], sec.Data)
+	}
+
+	return image
+}
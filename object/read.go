@@ -0,0 +1,186 @@
+package object
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+Read a relocatable object written by WriteELF
+*/
+func ReadELF(r io.ReaderAt) (*File, error) {
+	file, err := ReadProgram(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !file.Relocatable {
+		return nil, fmt.Errorf("not a relocatable object file")
+	}
+
+	return file, nil
+}
+
+/*
+Read a program from an ELF file, whether it has been linked or
+is a relocatable object
+*/
+func ReadProgram(r io.ReaderAt) (*File, error) {
+	ef, err := elf.NewFile(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer ef.Close()
+
+	if ef.Machine != elf.EM_AVR || ef.Class != elf.ELFCLASS32 {
+		return nil, fmt.Errorf("not an AVR object file")
+	}
+
+	// The architecture is kept in the flags of the header
+	header := elf.Header32{}
+	err = binary.Read(io.NewSectionReader(r, 0, int64(ELF_HEADER_SIZE)), binary.LittleEndian, &header)
+
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{
+		Arch:        header.Flags,
+		Entry:       uint64(ef.Entry),
+		Relocatable: ef.Type == elf.ET_REL,
+		Relocations: []Relocation{},
+		Sections:    []Section{},
+		Symbols:     []Symbol{},
+	}
+
+	// Sections of the program, and the device they are for
+	for _, sec := range ef.Sections {
+		if sec.Name == DEVICE_SECTION {
+			data, err := sec.Data()
+
+			if err != nil {
+				return nil, err
+			}
+
+			file.Device = strings.TrimRight(string(data), "\x00")
+			continue
+		}
+
+		if sec.Flags&elf.SHF_ALLOC == 0 {
+			continue
+		}
+
+		section := Section{
+			Name:    sec.Name,
+			Kind:    Data,
+			Address: sec.Addr,
+			Size:    sec.Size,
+		}
+
+		switch {
+		case sec.Flags&elf.SHF_EXECINSTR != 0:
+			section.Kind = Code
+
+		case sec.Name == ".eeprom" || strings.HasPrefix(sec.Name, ".eeprom."):
+			section.Kind = EEPROM
+		}
+
+		if sec.Type != elf.SHT_NOBITS {
+			section.Data, err = sec.Data()
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		file.Sections = append(file.Sections, section)
+	}
+
+	// Symbols, skipping those naming sections and files
+	symbols, err := ef.Symbols()
+
+	if err != nil && err != elf.ErrNoSymbols {
+		return nil, err
+	}
+
+	for _, sym := range symbols {
+		typ := elf.ST_TYPE(sym.Info)
+
+		if typ == elf.STT_SECTION || typ == elf.STT_FILE {
+			continue
+		}
+
+		symbol := Symbol{
+			Name:   sym.Name,
+			Value:  sym.Value,
+			Global: elf.ST_BIND(sym.Info) == elf.STB_GLOBAL,
+			Extern: sym.Section == elf.SHN_UNDEF,
+		}
+
+		switch typ {
+		case elf.STT_FUNC:
+			symbol.Kind = Func
+		case elf.STT_OBJECT:
+			symbol.Kind = Object
+		}
+
+		if sym.Section < elf.SHN_LORESERVE && sym.Section != elf.SHN_UNDEF {
+			symbol.Section = ef.Sections[sym.Section].Name
+		}
+
+		// Undefined symbols are imported rather than exported
+		if symbol.Extern {
+			symbol.Global = false
+		}
+
+		file.Symbols = append(file.Symbols, symbol)
+	}
+
+	// Relocations, with symbols numbered from one
+	for _, sec := range ef.Sections {
+		if sec.Type != elf.SHT_RELA {
+			continue
+		}
+
+		data, err := sec.Data()
+
+		if err != nil {
+			return nil, err
+		}
+
+		target := ef.Sections[sec.Info].Name
+		reader := bytes.NewReader(data)
+
+		for reader.Len() > 0 {
+			rela := elf.Rela32{}
+			err := binary.Read(reader, binary.LittleEndian, &rela)
+
+			if err != nil {
+				return nil, err
+			}
+
+			index := elf.R_SYM32(rela.Info)
+
+			if index == 0 || int(index) > len(symbols) {
+				return nil, fmt.Errorf("relocation in %v refers to missing symbol %v", target, index)
+			}
+
+			file.Relocations = append(file.Relocations, Relocation{
+				Section: target,
+				Offset:  uint64(rela.Off),
+				Symbol:  symbols[index-1].Name,
+				Type:    RelocType(elf.R_TYPE32(rela.Info)),
+				Addend:  int64(rela.Addend),
+			})
+		}
+	}
+
+	return file, nil
+}
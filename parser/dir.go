@@ -15,6 +15,7 @@ const (
 	DirValExpr     DirValType = 4
 	DirValExprList DirValType = 5
 	DirValAssign   DirValType = 6
+	DirValDef      DirValType = 7
 )
 
 type ErrorDirVal struct {
@@ -44,6 +45,11 @@ type AssignDirVal struct {
 	Value  Expr
 }
 
+type DefDirVal struct {
+	Symbol string
+	Value  Reg
+}
+
 func (e *ErrorDirVal) Type() DirValType {
 	return DirValErr
 }
@@ -71,3 +77,7 @@ func (e *ExprListDirVal) Type() DirValType {
 func (e *AssignDirVal) Type() DirValType {
 	return DirValAssign
 }
+
+func (d *DefDirVal) Type() DirValType {
+	return DirValDef
+}
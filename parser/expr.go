@@ -16,6 +16,7 @@ const (
 	ExprMonop ExprType = 3
 	ExprBinop ExprType = 4
 	ExprFunc  ExprType = 5
+	ExprStr   ExprType = 6
 )
 
 type ErrorExpr struct {
@@ -31,6 +32,10 @@ type Literal struct {
 	Value string
 }
 
+type String struct {
+	Value string
+}
+
 type BinopExpr struct {
 	E1     Expr
 	E2     Expr
@@ -45,7 +50,7 @@ type MonopExpr struct {
 }
 
 type FuncExpr struct {
-	E1     Expr
+	Args   []Expr
 	Symbol string
 	Func   language.Function
 }
@@ -62,6 +67,10 @@ func (l *Literal) Type() ExprType {
 	return ExprLit
 }
 
+func (s *String) Type() ExprType {
+	return ExprStr
+}
+
 func (b *BinopExpr) Type() ExprType {
 	return ExprBinop
 }
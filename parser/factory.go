@@ -6,7 +6,8 @@ const BUFFER_LEN int = 30
 
 func NewParser(in *lexer.Lexer) *Parser {
 	return &Parser{
-		Lexer: in,
-		Line:  1,
+		Lexer:  in,
+		Line:   1,
+		Syntax: in.Syntax,
 	}
 }
@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/lexer"
+)
+
+/*
+The value of a directive written differently in GNU syntax,
+or nil if it is written as in AVRASM2
+*/
+func GnuDir(p *Parser, mn language.Mnemonic) DirVal {
+	switch mn {
+	case language.DIR_ASCII, language.DIR_ASCIZ, language.DIR_BYTE, language.DIR_STRING, language.DIR_WORD:
+		return DirExprList(p)
+
+	case language.DIR_EQU, language.DIR_SET:
+		return DirSymbolValue(p)
+
+	case language.DIR_GLOBL:
+		return DirIdent(p)
+
+	// Flags and type of a section, such as "ax",@progbits, are ignored
+	case language.DIR_SECTION:
+		dirval := DirName(p)
+
+		if _, ok := dirval.(*ErrorDirVal); ok {
+			return dirval
+		}
+
+		if err, ok := SkipLine(p).(*ErrorDirVal); ok {
+			return err
+		}
+
+		return dirval
+
+	case language.DIR_BSS, language.DIR_DATA, language.DIR_TEXT:
+		return DirNil(p)
+
+	case language.DIR_BALIGN, language.DIR_P2ALIGN, language.DIR_SKIP, language.DIR_SPACE:
+		return DirExpr(p)
+
+	case language.DIR_FILE, language.DIR_IDENT, language.DIR_SIZE, language.DIR_TYPE:
+		return DirSkip(p)
+
+	default:
+		return nil
+	}
+}
+
+/*
+Expressions separated by commas, such as the values of .byte
+or the strings of .ascii
+*/
+func DirExprList(p *Parser) DirVal {
+	exprs := []Expr{}
+	p.GetNextToken() // Consume directive
+
+	for {
+		expr := ParseExpr(p, 0)
+
+		if err, ok := expr.(*ErrorExpr); ok {
+			return &ErrorDirVal{
+				err.Value,
+			}
+		}
+
+		exprs = append(exprs, expr)
+
+		if p.GetCurrentToken().Type != lexer.TK_COMMA {
+			return &ExprListDirVal{
+				Value: exprs,
+			}
+		}
+
+		p.GetNextToken() // Consume ','
+	}
+}
+
+/*
+A symbol and its value separated by a comma, as in .set sym, 1
+*/
+func DirSymbolValue(p *Parser) DirVal {
+	token := p.GetNextToken()
+
+	if token.Type != lexer.TK_IDENT {
+		return &ErrorDirVal{
+			fmt.Sprintf(
+				"Expected ident, got '%v'",
+				token.Print(),
+			),
+		}
+	}
+
+	nextToken := p.GetNextToken()
+
+	if nextToken.Type != lexer.TK_COMMA && nextToken.Type != lexer.TK_EQ {
+		return &ErrorDirVal{
+			fmt.Sprintf(
+				"Expected , or =, got '%v'",
+				nextToken.Print(),
+			),
+		}
+	}
+
+	p.GetNextToken() // Consume ',' or '='
+
+	return &AssignDirVal{
+		Symbol: token.Value,
+		Value:  ParseExpr(p, 0),
+	}
+}
+
+/*
+Skip the rest of the line, for directives which are accepted
+but have no effect
+*/
+func DirSkip(p *Parser) DirVal {
+	p.GetNextToken() // Consume directive
+	return SkipLine(p)
+}
+
+func SkipLine(p *Parser) DirVal {
+	for {
+		token := p.GetCurrentToken()
+
+		switch token.Type {
+		case lexer.TK_COM, lexer.TK_EOF, lexer.TK_LINE:
+			return &NilDirVal{}
+
+		case lexer.TK_ERR:
+			return &ErrorDirVal{
+				token.Value,
+			}
+		}
+
+		p.GetNextToken()
+	}
+}
+
+/*
+A symbol given a value with =, as in sym = 1, which may be
+given a new value later as with .set
+*/
+func Assign(p *Parser, symbol string) Line {
+	p.GetNextToken() // Consume '='
+
+	dirval := &AssignDirVal{
+		Symbol: symbol,
+		Value:  ParseExpr(p, 0),
+	}
+
+	return EndDir(p, language.DIR_SET, dirval)
+}
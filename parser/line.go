@@ -9,6 +9,7 @@ const (
 	ComType   LineType = 4
 	ErrorType LineType = 5
 	EOFType   LineType = 6
+	MacroType LineType = 7
 )
 
 type Line interface {
@@ -49,6 +50,12 @@ type Instruction struct {
 	Line     uint64
 }
 
+type MacroCall struct {
+	Name string
+	Args []Arg
+	Line uint64
+}
+
 func (e *EOF) Type() LineType {
 	return EOFType
 }
@@ -73,6 +80,10 @@ func (i *Instruction) Type() LineType {
 	return InstrType
 }
 
+func (m *MacroCall) Type() LineType {
+	return MacroType
+}
+
 func (e *EOF) Number() uint64 {
 	return e.Line
 }
@@ -96,3 +107,7 @@ func (d *Directive) Number() uint64 {
 func (i *Instruction) Number() uint64 {
 	return i.Line
 }
+
+func (m *MacroCall) Number() uint64 {
+	return m.Line
+}
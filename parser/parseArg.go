@@ -11,12 +11,8 @@ func ParseArg(p *Parser) Arg {
 	token := p.GetCurrentToken()
 
 	switch token.Type {
-	case lexer.TK_EOF, lexer.TK_COM:
-		return &Nil{}
-
-	case lexer.TK_LINE:
-		// Increment line number
-		p.Line++
+	// The caller consumes the end of the line
+	case lexer.TK_EOF, lexer.TK_COM, lexer.TK_LINE:
 		return &Nil{}
 
 	case lexer.TK_REG:
@@ -63,7 +59,6 @@ func ParseExprArg(p *Parser) Arg {
 		default:
 			op, _ := language.GetOp(token.Value)
 			tbp := GetPrecedence(token, true)
-			p.GetNextToken()
 			expr := ParseExpr(p, tbp)
 
 			e := &MonopExpr{
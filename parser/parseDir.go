@@ -11,6 +11,8 @@ func DirIdent(p *Parser) DirVal {
 
 	switch token.Type {
 	case lexer.TK_IDENT:
+		p.GetNextToken() // Consume ident
+
 		return &IdentDirVal{
 			Value: token.Value,
 		}
@@ -25,6 +27,55 @@ func DirIdent(p *Parser) DirVal {
 	}
 }
 
+/*
+A name made of identifiers and numbers separated by dots, such
+as the name of a section like .text.boot
+*/
+func DirName(p *Parser) DirVal {
+	name := ""
+	token := p.GetNextToken()
+
+	for {
+		switch token.Type {
+		case lexer.TK_DOT:
+			name += "."
+
+		case lexer.TK_IDENT, lexer.TK_DIR, lexer.TK_INSTR, lexer.TK_FUNC, lexer.TK_IMM, lexer.TK_FRAC:
+			name += token.Value
+
+		default:
+			if name == "" {
+				return &ErrorDirVal{
+					fmt.Sprintf(
+						"Expected name, got '%v'",
+						token.Print(),
+					),
+				}
+			}
+
+			return &IdentDirVal{
+				Value: name,
+			}
+		}
+
+		token = p.GetNextToken()
+	}
+}
+
+func DirNil(p *Parser) DirVal {
+	p.GetNextToken() // Consume directive
+	return &NilDirVal{}
+}
+
+func DirExpr(p *Parser) DirVal {
+	p.GetNextToken() // Consume directive
+	expr := ParseExpr(p, 0)
+
+	return &ExprDirVal{
+		Value: expr,
+	}
+}
+
 func DirAssign(p *Parser) DirVal {
 	token := p.GetNextToken()
 
@@ -60,3 +111,43 @@ func DirAssign(p *Parser) DirVal {
 		}
 	}
 }
+
+func DirDef(p *Parser) DirVal {
+	token := p.GetNextToken()
+
+	if token.Type != lexer.TK_IDENT {
+		return &ErrorDirVal{
+			fmt.Sprintf(
+				"Expected ident, got '%v'",
+				token.Print(),
+			),
+		}
+	}
+
+	nextToken := p.GetNextToken()
+
+	if nextToken.Type != lexer.TK_EQ {
+		return &ErrorDirVal{
+			fmt.Sprintf(
+				"Expected =, got '%v'",
+				nextToken.Print(),
+			),
+		}
+	}
+
+	regToken := p.GetNextToken()
+
+	if regToken.Type != lexer.TK_REG {
+		return &ErrorDirVal{
+			fmt.Sprintf(
+				"Expected register, got '%v'",
+				regToken.Print(),
+			),
+		}
+	}
+
+	return &DefDirVal{
+		Symbol: token.Value,
+		Value:  ParseReg(p),
+	}
+}
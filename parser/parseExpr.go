@@ -19,7 +19,7 @@ func GetPrecedence(token lexer.Token, unary bool) int {
 
 		return op.BindingPower
 
-	case lexer.TK_HEX, lexer.TK_IMM, lexer.TK_OCT, lexer.TK_BIN, lexer.TK_IDENT:
+	case lexer.TK_HEX, lexer.TK_IMM, lexer.TK_OCT, lexer.TK_BIN, lexer.TK_FRAC, lexer.TK_STR, lexer.TK_IDENT:
 		return 2
 
 	default:
@@ -52,11 +52,52 @@ func ParseLeft(p *Parser) Expr {
 	switch token.Type {
 	case lexer.TK_IDENT:
 		val := token.Value
-		p.GetNextToken()
+
+		// A local label given with its scope, such as func.loop
+		for p.GetNextToken().Type == lexer.TK_DOT {
+			token := p.GetNextToken()
+
+			if token.Type != lexer.TK_IDENT {
+				return &ErrorExpr{
+					Value: fmt.Sprintf(
+						"Unexpected token %v after DOT",
+						token.Print(),
+					),
+				}
+			}
+
+			val += "." + token.Value
+		}
+
 		return &Ident{
 			Value: val,
 		}
 
+	// A local label scoped to the label before it
+	case lexer.TK_DOT:
+		token := p.GetNextToken()
+
+		// The location counter in GNU syntax, as in rjmp .-2
+		if token.Type != lexer.TK_IDENT && p.Syntax == language.SYNTAX_GNU {
+			return &Ident{
+				Value: ".",
+			}
+		}
+
+		if token.Type != lexer.TK_IDENT {
+			return &ErrorExpr{
+				Value: fmt.Sprintf(
+					"Unexpected token %v after DOT",
+					token.Print(),
+				),
+			}
+		}
+
+		p.GetNextToken()
+		return &Ident{
+			Value: "." + token.Value,
+		}
+
 	case lexer.TK_HEX:
 		val := token.Value
 		p.GetNextToken()
@@ -73,6 +114,22 @@ func ParseLeft(p *Parser) Expr {
 			Value: val,
 		}
 
+	// A number with a fractional part, such as 0.75
+	case lexer.TK_FRAC:
+		val := token.Value
+		p.GetNextToken()
+		return &Literal{
+			Base:  10,
+			Value: val,
+		}
+
+	case lexer.TK_STR:
+		val := token.Value
+		p.GetNextToken()
+		return &String{
+			Value: val,
+		}
+
 	case lexer.TK_OCT:
 		val := token.Value
 		p.GetNextToken()
@@ -96,18 +153,7 @@ func ParseLeft(p *Parser) Expr {
 		return expr
 
 	case lexer.TK_FUNC:
-		function, _ := language.GetFunc(token.Value)
-		p.GetNextToken()
-
-		p.GetNextToken() // Consume '('
-		expr := ParseExpr(p, 0)
-		p.GetNextToken() // Consume ')'
-
-		return &FuncExpr{
-			E1:     expr,
-			Symbol: token.Value,
-			Func:   function,
-		}
+		return ParseFunc(p)
 
 	// Deal specifically with program counter
 	case lexer.TK_REG:
@@ -194,3 +240,64 @@ func ParseOp(p *Parser, left Expr) Expr {
 		}
 	}
 }
+
+/*
+A function and its arguments, separated by commas, which must
+be as many as the function takes
+*/
+func ParseFunc(p *Parser) Expr {
+	name := p.GetCurrentToken().Value
+	function, _ := language.GetFunc(name, p.Syntax)
+
+	if token := p.GetNextToken(); token.Type != lexer.TK_LBRAC {
+		return &ErrorExpr{
+			Value: fmt.Sprintf(
+				"Expected ( after function %v, got %v",
+				name,
+				token.Print(),
+			),
+		}
+	}
+
+	args := []Expr{}
+
+	for {
+		p.GetNextToken() // Consume '(' or ','
+		args = append(args, ParseExpr(p, 0))
+
+		token := p.GetCurrentToken()
+
+		if token.Type == lexer.TK_RBRAC {
+			break
+		}
+
+		if token.Type != lexer.TK_COMMA {
+			return &ErrorExpr{
+				Value: fmt.Sprintf(
+					"Unexpected token %v in arguments of %v",
+					token.Print(),
+					name,
+				),
+			}
+		}
+	}
+
+	p.GetNextToken() // Consume ')'
+
+	if len(args) != len(function.Params) {
+		return &ErrorExpr{
+			Value: fmt.Sprintf(
+				"Function %v takes %v arguments, got %v",
+				name,
+				len(function.Params),
+				len(args),
+			),
+		}
+	}
+
+	return &FuncExpr{
+		Args:   args,
+		Symbol: name,
+		Func:   function,
+	}
+}
@@ -26,6 +26,9 @@ func ParseLine(p *Parser) Line {
 		case lexer.TK_IDENT:
 			return Lab(p)
 
+		case lexer.TK_IMM:
+			return NumLab(p)
+
 		case lexer.TK_DOT:
 			return Dot(p)
 
@@ -66,15 +69,98 @@ func Lab(p *Parser) Line {
 			Line:  p.Line,
 		}
 
+	// A symbol given a value in GNU syntax, as with .set
+	case lexer.TK_EQ:
+		if p.Syntax == language.SYNTAX_GNU {
+			return Assign(p, ident)
+		}
+
+		return Macro(p, ident)
+
+	// An identifier not followed by a colon invokes a macro
 	default:
+		return Macro(p, ident)
+	}
+}
+
+/*
+A numeric local label, which may be defined any number of times
+and is referred to as Nb or Nf, the nearest N before or after
+*/
+func NumLab(p *Parser) Line {
+	number := p.GetCurrentToken().Value
+	token := p.GetNextToken()
+
+	if token.Type != lexer.TK_COLON {
 		return &Error{
 			Value: fmt.Sprintf(
-				"Unexpected token %v after IDENT",
+				"Unexpected token %v after IMM",
 				token.Print(),
 			),
 			Line: p.Line,
 		}
 	}
+
+	return &Label{
+		Value: number,
+		Line:  p.Line,
+	}
+}
+
+func Macro(p *Parser, name string) Line {
+	args := []Arg{}
+
+	for {
+		token := p.GetCurrentToken()
+
+		switch token.Type {
+		case lexer.TK_COM, lexer.TK_EOF:
+			return &MacroCall{
+				Name: name,
+				Args: args,
+				Line: p.Line,
+			}
+
+		case lexer.TK_LINE:
+			// Increment line number
+			p.Line++
+
+			return &MacroCall{
+				Name: name,
+				Args: args,
+				Line: p.Line - 1,
+			}
+		}
+
+		arg := ParseArg(p)
+
+		if err, ok := arg.(*ArgError); ok {
+			return &Error{
+				Value: err.Value,
+				Line:  p.Line,
+			}
+		}
+
+		args = append(args, arg)
+		nextToken := p.GetCurrentToken()
+
+		switch nextToken.Type {
+		case lexer.TK_COMMA:
+			p.GetNextToken() // Consume ','
+
+		case lexer.TK_COM, lexer.TK_EOF, lexer.TK_LINE:
+			continue
+
+		default:
+			return &Error{
+				Value: fmt.Sprintf(
+					"Unexpected token %v after IDENT",
+					nextToken.Print(),
+				),
+				Line: p.Line,
+			}
+		}
+	}
 }
 
 func Dot(p *Parser) Line {
@@ -84,7 +170,38 @@ func Dot(p *Parser) Line {
 	case lexer.TK_DIR:
 		return Dir(p)
 
-	case lexer.TK_IDENT, lexer.TK_FUNC, lexer.TK_INSTR:
+	// Directives may share a name with an instruction, such as .set
+	case lexer.TK_INSTR:
+		if language.IsDirective(token.Value, p.Syntax) {
+			return Dir(p)
+		}
+
+		return &Error{
+			Value: fmt.Sprintf(
+				"Keyword '%v' is not a directive",
+				token.Value,
+			),
+			Line: p.Line,
+		}
+
+	// A local label scoped to the label before it, such as .loop:
+	case lexer.TK_IDENT:
+		if p.GetNextToken().Type == lexer.TK_COLON {
+			return &Label{
+				Value: "." + token.Value,
+				Line:  p.Line,
+			}
+		}
+
+		return &Error{
+			Value: fmt.Sprintf(
+				"Keyword '%v' is not a directive",
+				token.Value,
+			),
+			Line: p.Line,
+		}
+
+	case lexer.TK_FUNC:
 		return &Error{
 			Value: fmt.Sprintf(
 				"Keyword '%v' is not a directive",
@@ -108,53 +225,106 @@ func Dir(p *Parser) Line {
 	token := p.GetCurrentToken()
 	mn := language.Mnemonic(token.Value)
 
-	switch mn {
-	case language.DIR_DEVICE:
-		dirval := DirIdent(p)
+	var dirval DirVal
 
-		switch dirval := dirval.(type) {
-		case *ErrorDirVal:
-			return &Error{
-				Value: dirval.Value,
-				Line:  p.Line,
-			}
+	if p.Syntax == language.SYNTAX_GNU {
+		dirval = GnuDir(p, mn)
+	}
 
-		default:
-			return &Directive{
-				Mnemonic: string(mn),
-				Value:    dirval,
-				Line:     p.Line,
-			}
+	if dirval == nil {
+		dirval = AvrasmDir(p, mn)
+	}
+
+	if dirval == nil {
+		return &Error{
+			Value: fmt.Sprintf(
+				"Unexpected directive '%v'",
+				mn,
+			),
+			Line: p.Line,
 		}
+	}
 
-	case language.DIR_EQU:
-		dirval := DirAssign(p)
+	return EndDir(p, mn, dirval)
+}
 
-		switch dirval := dirval.(type) {
-		case *ErrorDirVal:
-			return &Error{
-				Value: dirval.Value,
-				Line:  p.Line,
-			}
+/*
+A directive once its value has been parsed, which must be
+followed by the end of the line
+*/
+func EndDir(p *Parser, mn language.Mnemonic, dirval DirVal) Line {
+	if err, ok := dirval.(*ErrorDirVal); ok {
+		return &Error{
+			Value: err.Value,
+			Line:  p.Line,
+		}
+	}
 
-		default:
-			return &Directive{
-				Mnemonic: string(mn),
-				Value:    dirval,
-				Line:     p.Line - 1,
-			}
+	nextToken := p.GetCurrentToken()
+
+	switch nextToken.Type {
+	case lexer.TK_COM, lexer.TK_EOF:
+		return &Directive{
+			Mnemonic: string(mn),
+			Value:    dirval,
+			Line:     p.Line,
+		}
+
+	case lexer.TK_LINE:
+		// Increment line number
+		p.Line++
+
+		return &Directive{
+			Mnemonic: string(mn),
+			Value:    dirval,
+			Line:     p.Line - 1,
 		}
 
 	default:
 		return &Error{
 			Value: fmt.Sprintf(
-				"Unexpected directive '%v'",
-				mn,
+				"Unexpected token %v after DIR",
+				nextToken.Print(),
 			),
+			Line: p.Line,
 		}
 	}
 }
 
+/*
+The value of a directive of AVRASM2, or nil if there is no
+such directive
+*/
+func AvrasmDir(p *Parser, mn language.Mnemonic) DirVal {
+	switch mn {
+	case language.DIR_DEVICE, language.DIR_EXTERN, language.DIR_GLOBAL, language.DIR_IFDEF, language.DIR_IFNDEF,
+		language.DIR_MACRO:
+		return DirIdent(p)
+
+	case language.DIR_EQU, language.DIR_SET:
+		return DirAssign(p)
+
+	case language.DIR_DEF:
+		return DirDef(p)
+
+	case language.DIR_SECTION:
+		return DirName(p)
+
+	case language.DIR_CSEG, language.DIR_DSEG, language.DIR_ESEG, language.DIR_ELSE, language.DIR_ENDIF,
+		language.DIR_ENDM, language.DIR_ENDMACRO, language.DIR_LIST, language.DIR_LISTMAC, language.DIR_NOLIST:
+		return DirNil(p)
+
+	case language.DIR_BYTE, language.DIR_IF, language.DIR_ELIF:
+		return DirExpr(p)
+
+	case language.DIR_DW:
+		return DirExprList(p)
+
+	default:
+		return nil
+	}
+}
+
 func Instr(p *Parser) Line {
 	token := p.GetCurrentToken()
 	p.GetNextToken() // Consume instruction
@@ -1,12 +1,14 @@
 package parser
 
 import (
+	"github.com/silaspace/aria/language"
 	"github.com/silaspace/aria/lexer"
 )
 
 type Parser struct {
 	Lexer  *lexer.Lexer
 	Line   uint64
+	Syntax language.Syntax
 	curtok lexer.Token
 }
 
@@ -37,3 +37,8 @@ func (e *AssignDirVal) Fmt() string {
 	estr := e.Value.Fmt()
 	return fmt.Sprintf("%v = %v", e.Symbol, estr)
 }
+
+func (d *DefDirVal) Fmt() string {
+	rstr := d.Value.Fmt()
+	return fmt.Sprintf("%v = %v", d.Symbol, rstr)
+}
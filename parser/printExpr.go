@@ -1,6 +1,9 @@
 package parser
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 func (e *ErrorExpr) Fmt() string {
 	return fmt.Sprintf("EXPR_ERR %v", e.Value)
@@ -25,7 +28,16 @@ func (m *MonopExpr) Fmt() string {
 	return fmt.Sprintf("(%v %v)", m.Symbol, estr)
 }
 
+func (s *String) Fmt() string {
+	return fmt.Sprintf("%q", s.Value)
+}
+
 func (f *FuncExpr) Fmt() string {
-	estr := f.E1.Fmt()
-	return fmt.Sprintf("%v (%v)", f.Symbol, estr)
+	args := []string{}
+
+	for _, arg := range f.Args {
+		args = append(args, arg.Fmt())
+	}
+
+	return fmt.Sprintf("%v (%v)", f.Symbol, strings.Join(args, ", "))
 }
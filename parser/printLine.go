@@ -1,6 +1,9 @@
 package parser
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 func (e *EOF) Fmt() string {
 	return fmt.Sprintf("%v : EOF\n", e.Line)
@@ -28,3 +31,13 @@ func (i *Instruction) Fmt() string {
 	argstr2 := i.Op2.Fmt()
 	return fmt.Sprintf("%v : INSTR '%v' '%v', '%v'\n", i.Line, i.Mnemonic, argstr1, argstr2)
 }
+
+func (m *MacroCall) Fmt() string {
+	args := []string{}
+
+	for _, arg := range m.Args {
+		args = append(args, fmt.Sprintf("'%v'", arg.Fmt()))
+	}
+
+	return fmt.Sprintf("%v : MACRO '%v' %v\n", m.Line, m.Name, strings.Join(args, ", "))
+}
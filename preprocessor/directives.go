@@ -0,0 +1,208 @@
+package preprocessor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+A block of source in #if and #endif. Blocks in a block being
+skipped are skipped whatever their condition, and once one
+branch of a block is taken the others are skipped.
+*/
+type Condition struct {
+	Active bool
+	Else   bool
+	File   string
+	Line   uint64
+	Parent bool
+	Taken  bool
+}
+
+/*
+Whether lines are being preprocessed rather than skipped
+*/
+func (p *Preprocessor) Active() bool {
+	if len(p.Conditions) == 0 {
+		return true
+	}
+
+	return p.Conditions[len(p.Conditions)-1].Active
+}
+
+func (p *Preprocessor) Directive(name string, args []Token, line uint64) error {
+	switch name {
+	case "if", "ifdef", "ifndef":
+		return p.If(name, args, line)
+
+	case "elif", "else":
+		return p.Else(name, args)
+
+	case "endif":
+		if len(p.Conditions) == 0 {
+			return fmt.Errorf("#endif without #if")
+		}
+
+		p.Conditions = p.Conditions[:len(p.Conditions)-1]
+		return nil
+	}
+
+	// Other directives are skipped with the lines around them
+	if !p.Active() {
+		return nil
+	}
+
+	switch name {
+	case "define":
+		return p.Define(args)
+
+	case "undef":
+		args = Trim(args)
+
+		if len(args) != 1 || args[0].Kind != TOK_IDENT {
+			return fmt.Errorf("expected name of macro after #undef")
+		}
+
+		delete(p.Macros, args[0].Text)
+		return nil
+
+	case "include":
+		return p.includeDirective(args)
+
+	case "error":
+		return fmt.Errorf("#error %v", Join(Trim(args)))
+
+	case "pragma":
+		// Pragmas other than once are ignored, as they are by AVRASM2
+		if text := Join(Trim(args)); text == "once" {
+			p.Once[p.File()] = true
+		}
+
+		return nil
+
+	default:
+		return fmt.Errorf("unknown directive #%v", name)
+	}
+}
+
+func (p *Preprocessor) If(name string, args []Token, line uint64) error {
+	cond := Condition{
+		File:   p.File(),
+		Line:   line,
+		Parent: p.Active(),
+	}
+
+	if !cond.Parent {
+		p.Conditions = append(p.Conditions, cond)
+		return nil
+	}
+
+	var value bool
+
+	switch name {
+	case "if":
+		val, err := p.Eval(args)
+
+		if err != nil {
+			return err
+		}
+
+		value = val != 0
+
+	default:
+		args = Trim(args)
+
+		if len(args) != 1 || args[0].Kind != TOK_IDENT {
+			return fmt.Errorf("expected name of macro after #%v", name)
+		}
+
+		value = p.IsDefined(args[0].Text) == (name == "ifdef")
+	}
+
+	cond.Active = value
+	cond.Taken = value
+	p.Conditions = append(p.Conditions, cond)
+	return nil
+}
+
+func (p *Preprocessor) Else(name string, args []Token) error {
+	if len(p.Conditions) == 0 {
+		return fmt.Errorf("#%v without #if", name)
+	}
+
+	top := &p.Conditions[len(p.Conditions)-1]
+
+	if top.Else {
+		return fmt.Errorf("#%v after #else", name)
+	}
+
+	if !top.Parent || top.Taken {
+		top.Active = false
+		top.Else = name == "else"
+		return nil
+	}
+
+	value := true
+
+	if name == "elif" {
+		val, err := p.Eval(args)
+
+		if err != nil {
+			return err
+		}
+
+		value = val != 0
+	}
+
+	top.Active = value
+	top.Taken = value
+	top.Else = name == "else"
+	return nil
+}
+
+/*
+Whether a name is a macro, or was defined on the command line
+*/
+func (p *Preprocessor) IsDefined(name string) bool {
+	_, macro := p.Macros[name]
+	_, defined := p.Defined[name]
+	return macro || defined
+}
+
+/*
+Include the file named by #include "file" or #include <file>,
+replacing macros first if it is named by neither
+*/
+func (p *Preprocessor) includeDirective(args []Token) error {
+	args = Trim(args)
+
+	if len(args) > 0 && args[0].Kind == TOK_IDENT {
+		expanded, err := p.Expand(args, map[string]bool{})
+
+		if err != nil {
+			return err
+		}
+
+		comment := false
+		args = Trim(Tokenise(Join(expanded), &comment))
+	}
+
+	if len(args) == 1 && args[0].Kind == TOK_STRING && strings.HasPrefix(args[0].Text, "\"") {
+		name, err := strconv.Unquote(args[0].Text)
+
+		if err != nil {
+			return fmt.Errorf("invalid file name %v after #include", args[0].Text)
+		}
+
+		return p.IncludeFile(name, false)
+	}
+
+	text := Join(args)
+
+	if strings.HasPrefix(text, "<") && strings.HasSuffix(text, ">") && len(text) > 2 {
+		return p.IncludeFile(text[1:len(text)-1], true)
+	}
+
+	return fmt.Errorf("expected \"file\" or <file> after #include")
+}
@@ -0,0 +1,108 @@
+package preprocessor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/silaspace/aria/assembler"
+	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/lexer"
+	"github.com/silaspace/aria/parser"
+)
+
+/*
+Evaluate the condition of #if or #elif as an expression of the
+assembler. Names that are not macros are 0, unless they were
+defined on the command line, and defined(X) is 1 if X is
+defined.
+*/
+func (p *Preprocessor) Eval(args []Token) (uint64, error) {
+	tokens := []Token{}
+	args = Trim(args)
+
+	for i := 0; i < len(args); i++ {
+		if args[i].Text != "defined" {
+			tokens = append(tokens, args[i])
+			continue
+		}
+
+		next := skipSpace(args, i+1, 1)
+		bracket := next < len(args) && args[next].Text == "("
+
+		if bracket {
+			next = skipSpace(args, next+1, 1)
+		}
+
+		if next >= len(args) || args[next].Kind != TOK_IDENT {
+			return 0, fmt.Errorf("expected name of macro after defined")
+		}
+
+		name := args[next].Text
+
+		if bracket {
+			next = skipSpace(args, next+1, 1)
+
+			if next >= len(args) || args[next].Text != ")" {
+				return 0, fmt.Errorf("missing ) after defined(%v", name)
+			}
+		}
+
+		tokens = append(tokens, Token{Kind: TOK_NUMBER, Text: fmt.Sprint(boolToInt(p.IsDefined(name)))})
+		i = next
+	}
+
+	expanded, err := p.Expand(tokens, map[string]bool{})
+
+	if err != nil {
+		return 0, err
+	}
+
+	for i, token := range expanded {
+		switch token.Kind {
+		case TOK_IDENT:
+			expanded[i].Text = "0"
+
+			if value, exists := p.Defined[token.Text]; exists && value != "" {
+				expanded[i].Text = "(" + value + ")"
+			} else if exists {
+				expanded[i].Text = "1"
+			}
+
+		// Suffixes of C integers, as in 1UL
+		case TOK_NUMBER:
+			if !strings.HasPrefix(token.Text, "$") {
+				expanded[i].Text = strings.TrimRight(token.Text, "uUlL")
+			}
+		}
+	}
+
+	text := Join(expanded)
+
+	if strings.TrimSpace(text) == "" {
+		return 0, fmt.Errorf("expected expression")
+	}
+
+	reader := handler.NewWebReader()
+	reader.Write([]byte(text))
+
+	l := lexer.NewLexer(reader)
+	l.Syntax = p.Syntax
+
+	ps := parser.NewParser(l)
+	ps.GetNextToken()
+	expr := parser.ParseExpr(ps, 0)
+
+	if token := ps.GetCurrentToken(); !token.IsEOF() {
+		return 0, fmt.Errorf("unexpected token %v in condition", token.Print())
+	}
+
+	return assembler.EvalExpr(expr, map[string]uint64{}, false, 0)
+}
+
+func boolToInt(b bool) uint64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,16 @@
+package preprocessor
+
+func NewPreprocessor(open Opener) *Preprocessor {
+	return &Preprocessor{
+		Conditions: []Condition{},
+		Defined:    map[string]string{},
+		Files:      []string{},
+		Include:    []string{},
+		Lines:      []Line{},
+		Macros:     map[string]*Macro{},
+		Once:       map[string]bool{},
+		Open:       open,
+		Output:     []rune{},
+		Pos:        0,
+	}
+}
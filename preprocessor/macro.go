@@ -0,0 +1,291 @@
+package preprocessor
+
+import (
+	"fmt"
+	"strconv"
+)
+
+/*
+A macro given with #define. Function-like macros are defined
+with a list of parameters directly after their name, as in
+#define HIGH(x) ((x) >> 8).
+*/
+type Macro struct {
+	Body     []Token
+	Function bool
+	Name     string
+	Params   []string
+}
+
+/*
+Define a macro from the tokens following #define, replacing
+any macro of the same name
+*/
+func (p *Preprocessor) Define(args []Token) error {
+	args = Trim(args)
+
+	if len(args) == 0 || args[0].Kind != TOK_IDENT {
+		return fmt.Errorf("expected name of macro after #define")
+	}
+
+	macro := &Macro{
+		Name:   args[0].Text,
+		Params: []string{},
+	}
+
+	rest := args[1:]
+
+	// Parameters only follow a name with no space between
+	if len(rest) > 0 && rest[0].Text == "(" {
+		macro.Function = true
+		i := 1
+
+		for {
+			for i < len(rest) && rest[i].Kind == TOK_SPACE {
+				i++
+			}
+
+			if i < len(rest) && rest[i].Text == ")" && len(macro.Params) == 0 {
+				break
+			}
+
+			if i == len(rest) || rest[i].Kind != TOK_IDENT {
+				return fmt.Errorf("expected parameter of macro %v", macro.Name)
+			}
+
+			macro.Params = append(macro.Params, rest[i].Text)
+			i++
+
+			for i < len(rest) && rest[i].Kind == TOK_SPACE {
+				i++
+			}
+
+			if i < len(rest) && rest[i].Text == ")" {
+				break
+			}
+
+			if i == len(rest) || rest[i].Text != "," {
+				return fmt.Errorf("expected , or ) in parameters of macro %v", macro.Name)
+			}
+
+			i++
+		}
+
+		rest = rest[i+1:]
+	}
+
+	macro.Body = Trim(rest)
+	p.Macros[macro.Name] = macro
+	return nil
+}
+
+/*
+Replace the macros in a list of tokens. Macros being replaced
+are disabled, so a macro naming itself is left as it is.
+*/
+func (p *Preprocessor) Expand(tokens []Token, disabled map[string]bool) ([]Token, error) {
+	expanded := []Token{}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		macro, exists := p.Macros[token.Text]
+
+		if token.Kind != TOK_IDENT || !exists || disabled[token.Text] {
+			expanded = append(expanded, token)
+			continue
+		}
+
+		args := [][]Token{}
+
+		if macro.Function {
+			next := i + 1
+
+			for next < len(tokens) && tokens[next].Kind == TOK_SPACE {
+				next++
+			}
+
+			// The name of a function-like macro alone is not replaced
+			if next == len(tokens) || tokens[next].Text != "(" {
+				expanded = append(expanded, token)
+				continue
+			}
+
+			var err error
+			args, i, err = arguments(macro, tokens, next)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		body, err := p.substitute(macro, args, disabled)
+
+		if err != nil {
+			return nil, err
+		}
+
+		inner := map[string]bool{macro.Name: true}
+
+		for name := range disabled {
+			inner[name] = true
+		}
+
+		body, err = p.Expand(body, inner)
+
+		if err != nil {
+			return nil, err
+		}
+
+		expanded = append(expanded, body...)
+	}
+
+	return expanded, nil
+}
+
+/*
+The arguments of a call to a function-like macro, beginning at
+the opening bracket, and the index of the closing bracket
+*/
+func arguments(macro *Macro, tokens []Token, open int) ([][]Token, int, error) {
+	args := [][]Token{}
+	arg := []Token{}
+	depth := 0
+
+	for i := open + 1; i < len(tokens); i++ {
+		token := tokens[i]
+
+		switch {
+		case token.Text == "(":
+			depth++
+
+		case token.Text == ")" && depth > 0:
+			depth--
+
+		case token.Text == ")":
+			args = append(args, Trim(arg))
+
+			// A macro of no parameters is called with no arguments
+			if len(macro.Params) == 0 && len(args) == 1 && len(args[0]) == 0 {
+				args = [][]Token{}
+			}
+
+			if len(args) != len(macro.Params) {
+				return nil, 0, fmt.Errorf(
+					"macro %v takes %v arguments, got %v",
+					macro.Name,
+					len(macro.Params),
+					len(args),
+				)
+			}
+
+			return args, i, nil
+
+		case token.Text == "," && depth == 0:
+			args = append(args, Trim(arg))
+			arg = []Token{}
+			continue
+		}
+
+		arg = append(arg, token)
+	}
+
+	return nil, 0, fmt.Errorf("missing ) in arguments of macro %v", macro.Name)
+}
+
+/*
+The body of a macro with its parameters replaced. Arguments are
+expanded first, unless they are made into a string with # or
+pasted to another token with ##.
+*/
+func (p *Preprocessor) substitute(macro *Macro, args [][]Token, disabled map[string]bool) ([]Token, error) {
+	params := map[string][]Token{}
+
+	for i, param := range macro.Params {
+		params[param] = args[i]
+	}
+
+	body := macro.Body
+	result := []Token{}
+
+	for i := 0; i < len(body); i++ {
+		token := body[i]
+
+		if macro.Function && token.Text == "#" {
+			next := skipSpace(body, i+1, 1)
+
+			if next < len(body) {
+				if arg, ok := params[body[next].Text]; ok && body[next].Kind == TOK_IDENT {
+					result = append(result, Token{Kind: TOK_STRING, Text: strconv.Quote(Join(arg))})
+					i = next
+					continue
+				}
+			}
+		}
+
+		arg, ok := params[token.Text]
+
+		if token.Kind != TOK_IDENT || !ok {
+			result = append(result, token)
+			continue
+		}
+
+		before := skipSpace(body, i-1, -1)
+		after := skipSpace(body, i+1, 1)
+
+		if (before >= 0 && body[before].Kind == TOK_PASTE) || (after < len(body) && body[after].Kind == TOK_PASTE) {
+			result = append(result, arg...)
+			continue
+		}
+
+		expanded, err := p.Expand(arg, disabled)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, expanded...)
+	}
+
+	return paste(result), nil
+}
+
+/*
+Join the tokens either side of each ##
+*/
+func paste(tokens []Token) []Token {
+	result := []Token{}
+
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind != TOK_PASTE {
+			result = append(result, tokens[i])
+			continue
+		}
+
+		result = Trim(result)
+		next := skipSpace(tokens, i+1, 1)
+		text := ""
+
+		if len(result) > 0 {
+			text = result[len(result)-1].Text
+			result = result[:len(result)-1]
+		}
+
+		if next < len(tokens) {
+			text += tokens[next].Text
+		}
+
+		comment := false
+		result = append(result, Tokenise(text, &comment)...)
+		i = next
+	}
+
+	return result
+}
+
+func skipSpace(tokens []Token, i int, step int) int {
+	for i >= 0 && i < len(tokens) && tokens[i].Kind == TOK_SPACE {
+		i += step
+	}
+
+	return i
+}
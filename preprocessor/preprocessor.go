@@ -0,0 +1,315 @@
+package preprocessor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/silaspace/aria/language"
+)
+
+const MAX_INCLUDE_DEPTH int = 32
+
+type Reader interface {
+	Next() (rune, error)
+	Close()
+}
+
+/*
+Open a file to be included, given its path
+*/
+type Opener func(path string) (Reader, error)
+
+/*
+A line of output, with the file and line it was read from and
+the text it had there
+*/
+type Line struct {
+	File   string
+	Number uint64
+	Text   string
+}
+
+/*
+A C-like preprocessor run over the source before it is read by
+the lexer, as in AVRASM2. Lines holding directives or skipped
+by a conditional are left empty, and included files take the
+place of the #include, so every line of output comes from one
+line of a file.
+*/
+type Preprocessor struct {
+	Conditions []Condition
+	Defined    map[string]string
+	Files      []string
+	Include    []string
+	Lines      []Line
+	Macros     map[string]*Macro
+	Main       string
+	Once       map[string]bool
+	Open       Opener
+	Output     []rune
+	Pos        int
+	Syntax     language.Syntax
+}
+
+/*
+Preprocess a source file, and any files it includes
+*/
+func (p *Preprocessor) Process(name string, in Reader) error {
+	p.Main = name
+	text, err := readAll(in)
+
+	if err != nil {
+		return err
+	}
+
+	err = p.file(name, text)
+
+	if err != nil {
+		return err
+	}
+
+	p.Pos = 0
+	return nil
+}
+
+func (p *Preprocessor) file(name string, text string) error {
+	if len(p.Files) == MAX_INCLUDE_DEPTH {
+		return fmt.Errorf("files included too deeply, more than %v", MAX_INCLUDE_DEPTH)
+	}
+
+	p.Files = append(p.Files, name)
+	depth := len(p.Conditions)
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	comment := false
+	opened := uint64(0)
+
+	for i := 0; i < len(lines); i++ {
+		number := uint64(i + 1)
+
+		if !comment {
+			opened = number
+		}
+
+		joined := strings.TrimRight(lines[i], "\r")
+		first := i
+
+		// A backslash at the end of a line joins the next line to it
+		for strings.HasSuffix(joined, "\\") && i+1 < len(lines) {
+			i++
+			joined = joined[:len(joined)-1] + strings.TrimRight(lines[i], "\r")
+		}
+
+		err := p.line(name, number, joined, strings.TrimRight(lines[first], "\r"), &comment)
+
+		if err != nil {
+			return err
+		}
+
+		for j := first + 1; j <= i; j++ {
+			p.emit(name, uint64(j+1), strings.TrimRight(lines[j], "\r"), "")
+		}
+	}
+
+	if comment {
+		return p.errorAt(name, opened, errors.New("unterminated comment"))
+	}
+
+	if len(p.Conditions) > depth {
+		top := p.Conditions[len(p.Conditions)-1]
+		return p.errorAt(top.File, top.Line, errors.New("missing #endif for conditional block"))
+	}
+
+	p.Files = p.Files[:len(p.Files)-1]
+	return nil
+}
+
+/*
+Preprocess a line, which is a directive, a line skipped by a
+conditional, or a line whose macros are to be expanded
+*/
+func (p *Preprocessor) line(file string, number uint64, text string, original string, comment *bool) error {
+	name, rest, ok := directive(text)
+	ok = ok && !*comment
+	tokens := Tokenise(rest, comment)
+
+	if ok {
+		p.emit(file, number, original, "")
+		err := p.Directive(name, tokens, number)
+
+		if err != nil {
+			return p.errorAt(file, number, err)
+		}
+
+		return nil
+	}
+
+	if !p.Active() {
+		p.emit(file, number, original, "")
+		return nil
+	}
+
+	expanded, err := p.Expand(tokens, map[string]bool{})
+
+	if err != nil {
+		return p.errorAt(file, number, err)
+	}
+
+	p.emit(file, number, original, Join(expanded))
+	return nil
+}
+
+func (p *Preprocessor) emit(file string, number uint64, original string, output string) {
+	p.Lines = append(p.Lines, Line{
+		File:   file,
+		Number: number,
+		Text:   original,
+	})
+
+	p.Output = append(p.Output, []rune(output+"\n")...)
+}
+
+/*
+Name of the file being preprocessed
+*/
+func (p *Preprocessor) File() string {
+	return p.Files[len(p.Files)-1]
+}
+
+/*
+Include a file, searching the directory of the current file
+first unless the name is given in angle brackets
+*/
+func (p *Preprocessor) IncludeFile(name string, system bool) error {
+	if p.Open == nil {
+		return fmt.Errorf("cannot include %v, files cannot be opened here", name)
+	}
+
+	dirs := p.Include
+
+	if !system {
+		dirs = append([]string{filepath.Dir(p.File())}, dirs...)
+	}
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+
+		if filepath.IsAbs(name) {
+			path = name
+		}
+
+		in, err := p.Open(path)
+
+		if err != nil {
+			continue
+		}
+
+		text, err := readAll(in)
+
+		if err != nil {
+			return err
+		}
+
+		if p.Once[path] {
+			return nil
+		}
+
+		return p.file(path, text)
+	}
+
+	return fmt.Errorf("cannot find include file %v", name)
+}
+
+/*
+The file and line a line of output came from
+*/
+func (p *Preprocessor) Origin(line uint64) (string, uint64) {
+	if line == 0 || line > uint64(len(p.Lines)) {
+		return p.Main, line
+	}
+
+	origin := p.Lines[line-1]
+	return origin.File, origin.Number
+}
+
+/*
+Text of each line of output as it was written in its file
+*/
+func (p *Preprocessor) Source() []string {
+	source := []string{}
+
+	for _, line := range p.Lines {
+		source = append(source, line.Text)
+	}
+
+	return source
+}
+
+func (p *Preprocessor) Next() (rune, error) {
+	if p.Pos >= len(p.Output) {
+		return 0, io.EOF
+	}
+
+	nextRune := p.Output[p.Pos]
+	p.Pos++
+	return nextRune, nil
+}
+
+func (p *Preprocessor) Reset() error {
+	p.Pos = 0
+	return nil
+}
+
+func (p *Preprocessor) Close() {
+
+}
+
+/*
+An error naming the line it was found on, which is not named
+again by the lines including the file it is in
+*/
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (p *Preprocessor) errorAt(file string, line uint64, err error) error {
+	if _, located := err.(*Error); located {
+		return err
+	}
+
+	if file == p.Main {
+		return &Error{fmt.Sprintf("%v on line %v", err, line)}
+	}
+
+	return &Error{fmt.Sprintf("%v on line %v of %v", err, line, file)}
+}
+
+/*
+Read all of a file, closing it once read
+*/
+func readAll(in Reader) (string, error) {
+	defer in.Close()
+
+	var text strings.Builder
+
+	for {
+		nextRune, err := in.Next()
+
+		if err == io.EOF {
+			return text.String(), nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		text.WriteRune(nextRune)
+	}
+}
@@ -0,0 +1,206 @@
+package preprocessor
+
+import (
+	"strings"
+)
+
+type TokenKind int
+
+const (
+	TOK_IDENT   TokenKind = 0
+	TOK_NUMBER  TokenKind = 1
+	TOK_STRING  TokenKind = 2
+	TOK_SPACE   TokenKind = 3
+	TOK_PUNCT   TokenKind = 4
+	TOK_PASTE   TokenKind = 5
+	TOK_COMMENT TokenKind = 6
+)
+
+type Token struct {
+	Kind TokenKind
+	Text string
+}
+
+/* Directives of the preprocessor, following a # */
+var Directives = map[string]bool{
+	"define":  true,
+	"elif":    true,
+	"else":    true,
+	"endif":   true,
+	"error":   true,
+	"if":      true,
+	"ifdef":   true,
+	"ifndef":  true,
+	"include": true,
+	"pragma":  true,
+	"undef":   true,
+}
+
+/*
+The name of the directive on a line and the text following
+it. Lines beginning with a # that does not name a directive
+are not directives, as they are comments in GNU syntax.
+*/
+func directive(text string) (string, string, bool) {
+	trimmed := strings.TrimLeft(text, " \t")
+
+	if !strings.HasPrefix(trimmed, "#") {
+		return "", text, false
+	}
+
+	rest := strings.TrimLeft(trimmed[1:], " \t")
+	end := 0
+
+	for end < len(rest) && isIdent(rest[end], end == 0) {
+		end++
+	}
+
+	if !Directives[rest[:end]] {
+		return "", text, false
+	}
+
+	return rest[:end], rest[end:], true
+}
+
+/*
+Split a line into tokens. Comments beginning with ; are kept
+as they are, while those in C style are removed, and comment
+is set while a block comment is left open at the end of the
+line.
+*/
+func Tokenise(text string, comment *bool) []Token {
+	tokens := []Token{}
+	i := 0
+
+	for i < len(text) {
+		if *comment {
+			end := strings.Index(text[i:], "*/")
+
+			if end == -1 {
+				break
+			}
+
+			*comment = false
+			i += end + 2
+			tokens = append(tokens, Token{Kind: TOK_SPACE, Text: " "})
+			continue
+		}
+
+		c := text[i]
+		start := i
+
+		switch {
+		case c == ';':
+			tokens = append(tokens, Token{Kind: TOK_COMMENT, Text: text[i:]})
+			return tokens
+
+		case strings.HasPrefix(text[i:], "//"):
+			return tokens
+
+		case strings.HasPrefix(text[i:], "/*"):
+			*comment = true
+			i += 2
+			continue
+
+		case strings.HasPrefix(text[i:], "##"):
+			i += 2
+			tokens = append(tokens, Token{Kind: TOK_PASTE, Text: "##"})
+			continue
+
+		case c == ' ' || c == '\t':
+			for i < len(text) && (text[i] == ' ' || text[i] == '\t') {
+				i++
+			}
+
+			tokens = append(tokens, Token{Kind: TOK_SPACE, Text: text[start:i]})
+			continue
+
+		case c == '"' || c == '\'':
+			i++
+
+			for i < len(text) && text[i] != c {
+				if text[i] == '\\' {
+					i++
+				}
+
+				i++
+			}
+
+			i = min(i+1, len(text))
+			tokens = append(tokens, Token{Kind: TOK_STRING, Text: text[start:i]})
+			continue
+
+		// Numbers include hexadecimal in the form $FF
+		case isDigit(c) || (c == '$' && i+1 < len(text) && isIdent(text[i+1], false)):
+			i++
+
+			for i < len(text) && (isIdent(text[i], false) || text[i] == '.') {
+				i++
+			}
+
+			tokens = append(tokens, Token{Kind: TOK_NUMBER, Text: text[start:i]})
+			continue
+
+		case isIdent(c, true):
+			for i < len(text) && isIdent(text[i], false) {
+				i++
+			}
+
+			tokens = append(tokens, Token{Kind: TOK_IDENT, Text: text[start:i]})
+			continue
+		}
+
+		i++
+		tokens = append(tokens, Token{Kind: TOK_PUNCT, Text: text[start:i]})
+	}
+
+	return tokens
+}
+
+/*
+Text of a list of tokens
+*/
+func Join(tokens []Token) string {
+	var text strings.Builder
+
+	for _, token := range tokens {
+		text.WriteString(token.Text)
+	}
+
+	return text.String()
+}
+
+/*
+Tokens without leading or trailing space, or comments
+*/
+func Trim(tokens []Token) []Token {
+	trimmed := []Token{}
+
+	for _, token := range tokens {
+		if token.Kind != TOK_COMMENT {
+			trimmed = append(trimmed, token)
+		}
+	}
+
+	for len(trimmed) > 0 && trimmed[0].Kind == TOK_SPACE {
+		trimmed = trimmed[1:]
+	}
+
+	for len(trimmed) > 0 && trimmed[len(trimmed)-1].Kind == TOK_SPACE {
+		trimmed = trimmed[:len(trimmed)-1]
+	}
+
+	return trimmed
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdent(c byte, first bool) bool {
+	if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+		return true
+	}
+
+	return !first && isDigit(c)
+}
@@ -0,0 +1,220 @@
+package simulator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/language"
+)
+
+func NewSimulator(dev *device.Device) *Simulator {
+	sim := &Simulator{
+		Data:    make([]byte, dev.RAMStart+dev.RAMSize),
+		Decoded: make([]*language.Decoded, dev.FlashSize),
+		Decoder: language.NewDecoder(dev),
+		Device:  *dev,
+		EEPROM:  make([]byte, dev.EEPROMSize),
+		Flash:   make([]uint16, dev.FlashSize),
+		Hooks:   map[uint64][]Peripheral{},
+	}
+
+	for i := range sim.Flash {
+		sim.Flash[i] = 0xFFFF
+	}
+
+	for i := range sim.EEPROM {
+		sim.EEPROM[i] = 0xFF
+	}
+
+	sim.attachDevice()
+	sim.Reset()
+	return sim
+}
+
+/*
+A GPIO port, given its letter, if the device has it
+*/
+func NewPort(sim *Simulator, name string) (*Port, bool) {
+	addrs, ok := sim.Registers("PIN"+name, "DDR"+name, "PORT"+name)
+
+	if !ok {
+		return nil, false
+	}
+
+	return &Port{
+		Direction: addrs[1],
+		Input:     addrs[0],
+		Name:      name,
+		Output:    addrs[2],
+		Sim:       sim,
+	}, true
+}
+
+/*
+A timer, if the device has all of its registers and vectors,
+adding its interrupts to the simulator
+*/
+func NewTimer(sim *Simulator, config TimerConfig) (*Timer, bool) {
+	suffix := ""
+
+	if config.Bits == 16 {
+		suffix = "L"
+	}
+
+	names := []string{config.Clock, config.Count + suffix, config.Flags, config.Mask}
+	vectors := []string{config.Overflow.Vector}
+
+	for _, compare := range config.Compare {
+		names = append(names, compare.Register+suffix)
+		vectors = append(vectors, compare.Vector)
+	}
+
+	for _, match := range config.CTC {
+		names = append(names, match.Register)
+	}
+
+	addrs, ok := sim.Registers(names...)
+
+	if !ok {
+		return nil, false
+	}
+
+	for _, name := range vectors {
+		if _, ok := sim.Vector(name); !ok {
+			return nil, false
+		}
+	}
+
+	timer := &Timer{
+		Clock:      addrs[0],
+		Count:      addrs[1],
+		Max:        1<<config.Bits - 1,
+		Name:       config.Name,
+		Overflowed: Bit{addrs[2], 1 << config.Overflow.Bit},
+		Prescalers: config.Prescalers,
+		Sim:        sim,
+		Wide:       config.Bits == 16,
+	}
+
+	sim.AddInterrupt(config.Overflow.Vector, Bit{addrs[3], 1 << config.Overflow.Bit}, timer.Overflowed, true)
+
+	for i, compare := range config.Compare {
+		flag := Bit{addrs[2], 1 << compare.Bit}
+		timer.Compare = append(timer.Compare, addrs[4+i])
+		timer.Matched = append(timer.Matched, flag)
+		sim.AddInterrupt(compare.Vector, Bit{addrs[3], 1 << compare.Bit}, flag, true)
+	}
+
+	for i, match := range config.CTC {
+		addr := addrs[4+len(config.Compare)+i]
+		timer.CTC = append(timer.CTC, Condition{Bit{addr, match.Mask}, match.Value})
+	}
+
+	return timer, true
+}
+
+/*
+A USART, if the device has all of its registers and vectors,
+adding its interrupts to the simulator
+*/
+func NewUSART(sim *Simulator, config USARTConfig) (*USART, bool) {
+	addrs, ok := sim.Registers(config.Control, config.Data, config.Status)
+
+	if !ok {
+		return nil, false
+	}
+
+	for _, name := range []string{config.Complete, config.Empty, config.Receive} {
+		if _, ok := sim.Vector(name); !ok {
+			return nil, false
+		}
+	}
+
+	usart := &USART{
+		Control: addrs[0],
+		Data:    addrs[1],
+		Name:    config.Name,
+		Sim:     sim,
+		Status:  addrs[2],
+	}
+
+	sim.AddInterrupt(config.Receive, Bit{usart.Control, USART_RXCIE}, Bit{usart.Status, USART_RXC}, false)
+	sim.AddInterrupt(config.Empty, Bit{usart.Control, USART_UDRIE}, Bit{usart.Status, USART_UDRE}, false)
+	sim.AddInterrupt(config.Complete, Bit{usart.Control, USART_TXCIE}, Bit{usart.Status, USART_TXC}, true)
+	return usart, true
+}
+
+func NewStub(sim *Simulator, conn io.ReadWriter) *Stub {
+	return &Stub{
+		Breakpoints: map[uint64]bool{},
+		Conn:        conn,
+		NoAck:       false,
+		Packets:     make(chan string),
+		Sim:         sim,
+	}
+}
+
+/*
+A trace of the PC, the flags of SREG, the pins of each port and
+the named I/O registers, with times from a clock at fcpu Hz
+*/
+func NewTrace(sim *Simulator, w io.Writer, fcpu uint64, registers []string) (*Trace, error) {
+	if fcpu == 0 {
+		return nil, fmt.Errorf("the clock frequency of a trace must be above 0 Hz")
+	}
+
+	trace := &Trace{
+		FCPU:    fcpu,
+		Output:  w,
+		Signals: []*Signal{},
+		Sim:     sim,
+		Writer:  bufio.NewWriter(w),
+	}
+
+	trace.Add("", "PC", bits.Len(uint(len(sim.Flash)-1)), func() uint64 {
+		return sim.PC
+	})
+
+	for i, name := range "ITHSVNZC" {
+		flag := FLAG_I >> i
+
+		trace.Add("SREG", string(name), 1, func() uint64 {
+			if sim.Flag(flag) {
+				return 1
+			}
+
+			return 0
+		})
+	}
+
+	for _, p := range sim.Peripherals {
+		port, ok := p.(*Port)
+
+		if !ok {
+			continue
+		}
+
+		for bit := 0; bit < 8; bit++ {
+			trace.Add("PORT"+port.Name, fmt.Sprintf("P%v%v", port.Name, bit), 1, func() uint64 {
+				return uint64(port.Pins()>>bit) & 1
+			})
+		}
+	}
+
+	for _, name := range registers {
+		addr, ok := sim.Register(name)
+
+		if !ok {
+			return nil, fmt.Errorf("the %v has no I/O register '%v'", sim.Device.Name, name)
+		}
+
+		trace.Add("IO", name, 8, func() uint64 {
+			return uint64(sim.Data[addr])
+		})
+	}
+
+	return trace, nil
+}
@@ -0,0 +1,548 @@
+package simulator
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+Addresses of memories in the address space of avr-gdb, which
+places each memory at the same base as the ELF files of the
+GNU tools
+*/
+const (
+	GDB_FLASH  uint64 = 0x000000
+	GDB_DATA   uint64 = 0x800000
+	GDB_EEPROM uint64 = 0x810000
+)
+
+/* Registers as numbered by avr-gdb */
+const (
+	GDB_SREG uint64 = 32
+	GDB_SP   uint64 = 33
+	GDB_PC   uint64 = 34
+)
+
+/* Signals reported when the program stops */
+const (
+	SIGINT  int = 2
+	SIGILL  int = 4
+	SIGTRAP int = 5
+)
+
+/* Largest packet the stub takes, in bytes, as told to the debugger */
+const GDB_PACKET_SIZE uint64 = 0x4000
+
+/* Steps run between looking for an interrupt from the debugger */
+const GDB_POLL int = 1024
+
+/* Sent by the debugger to stop a running program */
+const interrupt string = "\x03"
+
+/*
+A stub serving the GDB remote serial protocol, so that avr-gdb
+can debug the program in the simulator. Breakpoints are at
+byte addresses in flash, as given by the debugger.
+*/
+type Stub struct {
+	Breakpoints map[uint64]bool
+	Conn        io.ReadWriter
+	NoAck       bool
+	Packets     chan string
+	Sim         *Simulator
+}
+
+/*
+Serve the debugger until it detaches, kills the program or
+closes the connection
+*/
+func (g *Stub) Serve() error {
+	go g.read()
+
+	for packet := range g.Packets {
+		switch packet {
+		case interrupt:
+			continue
+
+		// Killing the program needs no reply
+		case "k":
+			return nil
+		}
+
+		reply, done := g.handle(packet)
+		err := g.send(reply)
+
+		if err != nil || done {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+Read packets from the connection, acknowledging each unless
+acknowledgement has been turned off
+*/
+func (g *Stub) read() {
+	defer close(g.Packets)
+	r := bufio.NewReader(g.Conn)
+
+	for {
+		c, err := r.ReadByte()
+
+		if err != nil {
+			return
+		}
+
+		switch c {
+		case '$':
+			data, err := r.ReadString('#')
+
+			if err != nil {
+				return
+			}
+
+			sum := make([]byte, 2)
+
+			if _, err := io.ReadFull(r, sum); err != nil {
+				return
+			}
+
+			data = strings.TrimSuffix(data, "#")
+			valid := fmt.Sprintf("%02x", checksum(data)) == strings.ToLower(string(sum))
+
+			if g.NoAck {
+				valid = true
+			} else if valid {
+				g.Conn.Write([]byte("+"))
+			} else {
+				g.Conn.Write([]byte("-"))
+			}
+
+			if valid {
+				g.Packets <- data
+			}
+
+		case interrupt[0]:
+			g.Packets <- interrupt
+		}
+	}
+}
+
+func (g *Stub) send(data string) error {
+	_, err := fmt.Fprintf(g.Conn, "$%v#%02x", data, checksum(data))
+	return err
+}
+
+/*
+Reply to a packet, and whether the debugger has detached
+*/
+func (g *Stub) handle(packet string) (string, bool) {
+	if packet == "" {
+		return "", false
+	}
+
+	args := packet[1:]
+
+	switch packet[0] {
+	case '?':
+		return stopReply(SIGTRAP), false
+
+	case 'g':
+		return g.readRegisters(), false
+
+	case 'G':
+		return g.writeRegisters(args), false
+
+	case 'p':
+		return g.readRegister(args), false
+
+	case 'P':
+		return g.writeRegister(args), false
+
+	case 'm':
+		return g.readMemory(args), false
+
+	case 'M':
+		return g.writeMemory(args), false
+
+	case 'c':
+		return g.resume(args, false), false
+
+	case 's':
+		return g.resume(args, true), false
+
+	case 'Z', 'z':
+		return g.breakpoint(packet[0] == 'Z', args), false
+
+	case 'H':
+		return "OK", false
+
+	case 'D':
+		return "OK", true
+
+	case 'q', 'Q':
+		return g.query(packet), false
+
+	default:
+		// An empty reply tells the debugger the packet is not supported
+		return "", false
+	}
+}
+
+func (g *Stub) query(packet string) string {
+	switch {
+	case strings.HasPrefix(packet, "qSupported"):
+		return fmt.Sprintf("PacketSize=%x;QStartNoAckMode+", GDB_PACKET_SIZE)
+
+	case packet == "QStartNoAckMode":
+		g.NoAck = true
+		return "OK"
+
+	case packet == "qAttached":
+		return "1"
+
+	case packet == "qC":
+		return "QC1"
+
+	case packet == "qfThreadInfo":
+		return "m1"
+
+	case packet == "qsThreadInfo":
+		return "l"
+
+	default:
+		return ""
+	}
+}
+
+/*
+Registers r0 to r31, SREG, SP and the PC as a byte address,
+each little endian
+*/
+func (g *Stub) registers() []byte {
+	regs := append([]byte{}, g.Sim.Data[:32]...)
+	regs = append(regs, g.Sim.Data[SREG], byte(g.Sim.SP()), byte(g.Sim.SP()>>8))
+	pc := g.Sim.PC * 2
+	return append(regs, byte(pc), byte(pc>>8), byte(pc>>16), byte(pc>>24))
+}
+
+func (g *Stub) readRegisters() string {
+	return hex.EncodeToString(g.registers())
+}
+
+func (g *Stub) writeRegisters(args string) string {
+	regs, err := hex.DecodeString(args)
+
+	if err != nil || len(regs) < 39 {
+		return "E01"
+	}
+
+	copy(g.Sim.Data[:32], regs)
+	g.Sim.Data[SREG] = regs[32]
+	g.Sim.SetSP(uint64(regs[33]) | uint64(regs[34])<<8)
+	g.setPC(uint64(regs[35]) | uint64(regs[36])<<8 | uint64(regs[37])<<16 | uint64(regs[38])<<24)
+	return "OK"
+}
+
+func (g *Stub) readRegister(args string) string {
+	n, err := strconv.ParseUint(args, 16, 64)
+
+	if err != nil || n > GDB_PC {
+		return "E01"
+	}
+
+	regs := g.registers()
+
+	switch n {
+	case GDB_SP:
+		return hex.EncodeToString(regs[33:35])
+
+	case GDB_PC:
+		return hex.EncodeToString(regs[35:39])
+
+	default:
+		return hex.EncodeToString(regs[n : n+1])
+	}
+}
+
+func (g *Stub) writeRegister(args string) string {
+	num, value, ok := strings.Cut(args, "=")
+	n, err := strconv.ParseUint(num, 16, 64)
+
+	if !ok || err != nil || n > GDB_PC {
+		return "E01"
+	}
+
+	data, err := hex.DecodeString(value)
+
+	if err != nil || len(data) == 0 {
+		return "E01"
+	}
+
+	// Values are little endian
+	val := uint64(0)
+
+	for i := len(data) - 1; i >= 0; i-- {
+		val = val<<8 | uint64(data[i])
+	}
+
+	switch n {
+	case GDB_SREG:
+		g.Sim.Data[SREG] = byte(val)
+
+	case GDB_SP:
+		g.Sim.SetSP(val)
+
+	case GDB_PC:
+		g.setPC(val)
+
+	default:
+		g.Sim.SetReg(n, byte(val))
+	}
+
+	return "OK"
+}
+
+/*
+Set the PC from a byte address
+*/
+func (g *Stub) setPC(addr uint64) {
+	g.Sim.PC = g.Sim.wrap(addr / 2)
+}
+
+func (g *Stub) readMemory(args string) string {
+	addr, length, err := memoryArgs(args)
+
+	// Each byte read is two hex digits of the reply
+	if err != nil || length > GDB_PACKET_SIZE/2 || !g.fits(addr, length) {
+		return "E01"
+	}
+
+	data := make([]byte, length)
+
+	for i := range data {
+		val, ok := g.peek(addr + uint64(i))
+
+		if !ok {
+			return "E01"
+		}
+
+		data[i] = val
+	}
+
+	return hex.EncodeToString(data)
+}
+
+func (g *Stub) writeMemory(args string) string {
+	area, value, _ := strings.Cut(args, ":")
+	addr, length, err := memoryArgs(area)
+
+	if err != nil || !g.fits(addr, length) {
+		return "E01"
+	}
+
+	data, err := hex.DecodeString(value)
+
+	if err != nil || uint64(len(data)) != length {
+		return "E01"
+	}
+
+	for i, val := range data {
+		if !g.poke(addr+uint64(i), val) {
+			return "E01"
+		}
+	}
+
+	return "OK"
+}
+
+/*
+Whether bytes from an address all lie in the memory holding it
+*/
+func (g *Stub) fits(addr uint64, length uint64) bool {
+	end := uint64(len(g.Sim.Flash)) * 2
+
+	switch {
+	case addr >= GDB_EEPROM:
+		end = GDB_EEPROM + uint64(len(g.Sim.EEPROM))
+
+	case addr >= GDB_DATA:
+		end = GDB_DATA + uint64(len(g.Sim.Data))
+	}
+
+	return addr <= end && length <= end-addr
+}
+
+/*
+Read a byte of any memory, without the effects of reading it
+from the program
+*/
+func (g *Stub) peek(addr uint64) (byte, bool) {
+	switch {
+	case addr >= GDB_EEPROM:
+		addr -= GDB_EEPROM
+
+		if addr >= uint64(len(g.Sim.EEPROM)) {
+			return 0, false
+		}
+
+		return g.Sim.EEPROM[addr], true
+
+	case addr >= GDB_DATA:
+		addr -= GDB_DATA
+
+		if addr >= uint64(len(g.Sim.Data)) {
+			return 0, false
+		}
+
+		return g.Sim.Data[addr], true
+
+	default:
+		if addr >= uint64(len(g.Sim.Flash))*2 {
+			return 0, false
+		}
+
+		return g.Sim.LoadFlash(addr), true
+	}
+}
+
+/*
+Write a byte of any memory. Writing flash, as the debugger's
+load command does, forgets the instructions decoded there.
+*/
+func (g *Stub) poke(addr uint64, val byte) bool {
+	switch {
+	case addr >= GDB_EEPROM:
+		addr -= GDB_EEPROM
+
+		if addr >= uint64(len(g.Sim.EEPROM)) {
+			return false
+		}
+
+		g.Sim.EEPROM[addr] = val
+
+	case addr >= GDB_DATA:
+		addr -= GDB_DATA
+
+		if addr >= uint64(len(g.Sim.Data)) {
+			return false
+		}
+
+		g.Sim.Data[addr] = val
+
+	default:
+		if addr >= uint64(len(g.Sim.Flash))*2 {
+			return false
+		}
+
+		g.Sim.StoreFlash(addr, val)
+	}
+
+	return true
+}
+
+func (g *Stub) breakpoint(insert bool, args string) string {
+	fields := strings.Split(args, ",")
+
+	// Software and hardware breakpoints are the same to the simulator
+	if len(fields) < 2 || (fields[0] != "0" && fields[0] != "1") {
+		return ""
+	}
+
+	addr, err := strconv.ParseUint(fields[1], 16, 64)
+
+	if err != nil {
+		return "E01"
+	}
+
+	if insert {
+		g.Breakpoints[addr] = true
+	} else {
+		delete(g.Breakpoints, addr)
+	}
+
+	return "OK"
+}
+
+/*
+Continue or single step, from an address if one is given, and
+give the reason the program stopped
+*/
+func (g *Stub) resume(args string, step bool) string {
+	if args != "" {
+		addr, err := strconv.ParseUint(args, 16, 64)
+
+		if err != nil {
+			return "E01"
+		}
+
+		g.setPC(addr)
+	}
+
+	g.Sim.Halted = RUNNING
+
+	for n := 1; ; n++ {
+		err := g.Sim.Step()
+
+		if err != nil {
+			return stopReply(SIGILL)
+		}
+
+		if step || g.Sim.Halted != RUNNING || g.Breakpoints[g.Sim.PC*2] {
+			return stopReply(SIGTRAP)
+		}
+
+		if n%GDB_POLL != 0 {
+			continue
+		}
+
+		select {
+		case packet, ok := <-g.Packets:
+			if !ok || packet == interrupt {
+				return stopReply(SIGINT)
+			}
+
+		default:
+		}
+	}
+}
+
+func stopReply(signal int) string {
+	return fmt.Sprintf("S%02x", signal)
+}
+
+/*
+Address and length of memory given as addr,length
+*/
+func memoryArgs(args string) (uint64, uint64, error) {
+	start, size, ok := strings.Cut(args, ",")
+
+	if !ok {
+		return 0, 0, fmt.Errorf("expected addr,length, got '%v'", args)
+	}
+
+	addr, err := strconv.ParseUint(start, 16, 64)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	length, err := strconv.ParseUint(size, 16, 64)
+	return addr, length, err
+}
+
+func checksum(data string) byte {
+	sum := byte(0)
+
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+
+	return sum
+}
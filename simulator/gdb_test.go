@@ -0,0 +1,92 @@
+package simulator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/silaspace/aria/device"
+)
+
+/*
+A packet sent to the stub, and the reply it must give
+*/
+type packetCase struct {
+	Name   string
+	Packet string
+	Reply  string
+}
+
+var packetCases = []packetCase{
+	{"read flash", "m0,4", "ffffffff"},
+	{"read registers", "m800010,2", "0000"},
+	{"read past the packet size", "m0,2001", "E01"},
+	{"read the whole address space", "m0,ffffffffffff", "E01"},
+	{"read past the end of flash", fmt.Sprintf("m%x,2", 0x8000-1), "E01"},
+	{"read past the end of SRAM", fmt.Sprintf("m%x,2", GDB_DATA+0x8FF), "E01"},
+	{"read between flash and SRAM", "m10000,1", "E01"},
+	{"write SRAM", "M800100,2:abcd", "OK"},
+	{"write past the end of EEPROM", fmt.Sprintf("M%x,2:abcd", GDB_EEPROM+0x3FF), "E01"},
+	{"write the whole address space", "Mffffffffffffffff,1:ab", "E01"},
+	{"write more than given", "M800100,1:abcd", "E01"},
+}
+
+func TestStubMemory(t *testing.T) {
+	dev := device.DeviceMap[device.ATMEGA328P]
+	sim := NewSimulator(&dev)
+	conn, debugger := net.Pipe()
+	defer debugger.Close()
+
+	go NewStub(sim, conn).Serve()
+	r := bufio.NewReader(debugger)
+
+	for _, c := range packetCases {
+		fmt.Fprintf(debugger, "$%v#%02x", c.Packet, checksum(c.Packet))
+		reply, err := readReply(r)
+
+		if err != nil {
+			t.Fatalf("%v: %v", c.Name, err)
+		}
+
+		if reply != c.Reply {
+			t.Errorf("%v: '%v' has reply '%v', not '%v'", c.Name, c.Packet, reply, c.Reply)
+		}
+	}
+}
+
+/*
+The data of the next reply of the stub, after the acknowledgement
+of the packet sent
+*/
+func readReply(r *bufio.Reader) (string, error) {
+	ack, err := r.ReadByte()
+
+	if err != nil {
+		return "", err
+	}
+
+	if ack != '+' {
+		return "", fmt.Errorf("expected '+', got '%c'", ack)
+	}
+
+	if c, err := r.ReadByte(); err != nil || c != '$' {
+		return "", fmt.Errorf("expected a reply")
+	}
+
+	data, err := r.ReadString('#')
+
+	if err != nil {
+		return "", err
+	}
+
+	sum := make([]byte, 2)
+
+	if _, err := io.ReadFull(r, sum); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(data, "#"), nil
+}
@@ -0,0 +1,75 @@
+package simulator
+
+/* Letters of the ports a device may have */
+const PORT_NAMES string = "ABCDEFGH"
+
+/*
+A GPIO port of eight pins, with its PINx, DDRx and PORTx
+registers. Pins set as outputs by DDRx drive the level in
+PORTx; inputs read the level driven on them from outside, or
+else high if PORTx turns on their pull-up. Writing a one to a
+bit of PINx toggles the bit of PORTx. PINx is kept up to date
+in the data space as cycles pass.
+*/
+type Port struct {
+	Direction uint64
+	Driven    byte /* Pins driven from outside */
+	External  byte /* Levels driven on them */
+	Input     uint64
+	Name      string
+	Output    uint64
+	Sim       *Simulator
+}
+
+func (p *Port) Addresses() []uint64 {
+	return []uint64{p.Input}
+}
+
+func (p *Port) Load(addr uint64, val byte) byte {
+	return p.Pins()
+}
+
+func (p *Port) Store(addr uint64, val byte) byte {
+	p.Sim.Data[p.Output] ^= val
+	return p.Pins()
+}
+
+func (p *Port) Reset() {
+	p.Driven = 0
+	p.External = 0
+}
+
+func (p *Port) Tick(cycles uint64) {
+	p.Sim.Data[p.Input] = p.Pins()
+}
+
+/*
+Levels of the pins, as read from PINx
+*/
+func (p *Port) Pins() byte {
+	ddr := p.Sim.Data[p.Direction]
+	port := p.Sim.Data[p.Output]
+	inputs := p.External&p.Driven | port&^p.Driven
+	return port&ddr | inputs&^ddr
+}
+
+/*
+Drive a pin from outside, as a button or another device would
+*/
+func (p *Port) SetPin(bit uint, high bool) {
+	p.Driven |= 1 << bit
+
+	if high {
+		p.External |= 1 << bit
+	} else {
+		p.External &^= 1 << bit
+	}
+}
+
+/*
+Stop driving a pin, leaving it to its pull-up
+*/
+func (p *Port) Release(bit uint) {
+	p.Driven &^= 1 << bit
+	p.External &^= 1 << bit
+}
@@ -0,0 +1,811 @@
+package simulator
+
+import (
+	"github.com/silaspace/aria/language"
+)
+
+/*
+Execute a decoded instruction. The PC has already been moved
+past the instruction when it is called.
+*/
+type Operation func(s *Simulator, d *language.Decoded) error
+
+/*
+Operations of each instruction of the implemented cores. The
+aliases of language.Aliases are decoded as the instruction they
+stand for, so have no operation of their own.
+*/
+var Operations = map[language.Mnemonic]Operation{
+	language.ADC:    adc,
+	language.ADD:    add,
+	language.ADIW:   adiw,
+	language.AND:    and,
+	language.ANDI:   andi,
+	language.ASR:    asr,
+	language.BCLR:   bclr,
+	language.BIRE:   branch(FLAG_I, true),
+	language.BLD:    bld,
+	language.BRBC:   brbc,
+	language.BRBS:   brbs,
+	language.BRCC:   branch(FLAG_C, false),
+	language.BRCS:   branch(FLAG_C, true),
+	language.BREAK:  brk,
+	language.BREQ:   branch(FLAG_Z, true),
+	language.BRGE:   branch(FLAG_S, false),
+	language.BRHC:   branch(FLAG_H, false),
+	language.BRHS:   branch(FLAG_H, true),
+	language.BRID:   branch(FLAG_I, false),
+	language.BRLT:   branch(FLAG_S, true),
+	language.BRMI:   branch(FLAG_N, true),
+	language.BRNE:   branch(FLAG_Z, false),
+	language.BRPL:   branch(FLAG_N, false),
+	language.BRTC:   branch(FLAG_T, false),
+	language.BRTS:   branch(FLAG_T, true),
+	language.BRVC:   branch(FLAG_V, false),
+	language.BRVS:   branch(FLAG_V, true),
+	language.BSET:   bset,
+	language.BST:    bst,
+	language.CALL:   call,
+	language.CBI:    cbi,
+	language.CLC:    flag(FLAG_C, false),
+	language.CLH:    flag(FLAG_H, false),
+	language.CLI:    flag(FLAG_I, false),
+	language.CLN:    flag(FLAG_N, false),
+	language.CLS:    flag(FLAG_S, false),
+	language.CLT:    flag(FLAG_T, false),
+	language.CLV:    flag(FLAG_V, false),
+	language.CLZ:    flag(FLAG_Z, false),
+	language.COM:    com,
+	language.CP:     cp,
+	language.CPC:    cpc,
+	language.CPI:    cpi,
+	language.CPSE:   cpse,
+	language.DEC:    dec,
+	language.EOR:    eor,
+	language.FMUL:   fmul,
+	language.FMULS:  fmuls,
+	language.FMULSU: fmulsu,
+	language.ICALL:  icall,
+	language.IJMP:   ijmp,
+	language.IN:     in,
+	language.INC:    inc,
+	language.JMP:    jmp,
+	language.LD:     ld,
+	language.LDD:    ld,
+	language.LDI:    ldi,
+	language.LDS:    lds,
+	language.LPM:    lpm,
+	language.LSR:    lsr,
+	language.MOV:    mov,
+	language.MUL:    mul,
+	language.MULS:   muls,
+	language.MULSU:  mulsu,
+	language.NEG:    neg,
+	language.NOP:    nop,
+	language.OR:     or,
+	language.ORI:    ori,
+	language.OUT:    out,
+	language.POP:    pop,
+	language.PUSH:   push,
+	language.RCALL:  rcall,
+	language.RET:    ret,
+	language.RETI:   reti,
+	language.RJMP:   rjmp,
+	language.ROR:    ror,
+	language.SBC:    sbc,
+	language.SBCI:   sbci,
+	language.SBI:    sbi,
+	language.SBIC:   sbic,
+	language.SBIS:   sbis,
+	language.SBIW:   sbiw,
+	language.SBRC:   sbrc,
+	language.SBRS:   sbrs,
+	language.SEC:    flag(FLAG_C, true),
+	language.SEH:    flag(FLAG_H, true),
+	language.SEI:    flag(FLAG_I, true),
+	language.SEN:    flag(FLAG_N, true),
+	language.SES:    flag(FLAG_S, true),
+	language.SET:    flag(FLAG_T, true),
+	language.SEV:    flag(FLAG_V, true),
+	language.SEZ:    flag(FLAG_Z, true),
+	language.SLEEP:  sleep,
+	language.ST:     st,
+	language.STD:    st,
+	language.STS:    sts,
+	language.SUB:    sub,
+	language.SUBI:   subi,
+	language.SWAP:   swap,
+	language.WDR:    nop,
+}
+
+/* -------- Arithmetic and logic -------- */
+
+func add(s *Simulator, d *language.Decoded) error {
+	rd, rr := s.Reg(reg(d.Op1)), s.Reg(reg(d.Op2))
+	r := rd + rr
+	s.SetReg(reg(d.Op1), r)
+	s.addFlags(rd, rr, r)
+	return nil
+}
+
+func adc(s *Simulator, d *language.Decoded) error {
+	rd, rr := s.Reg(reg(d.Op1)), s.Reg(reg(d.Op2))
+	r := rd + rr + carry(s)
+	s.SetReg(reg(d.Op1), r)
+	s.addFlags(rd, rr, r)
+	return nil
+}
+
+func adiw(s *Simulator, d *language.Decoded) error {
+	rd := s.Pair(reg(d.Op1))
+	r := (rd + imm(d.Op2)) & 0xFFFF
+	s.SetPair(reg(d.Op1), r)
+
+	s.SetFlag(FLAG_V, rd&0x8000 == 0 && r&0x8000 != 0)
+	s.SetFlag(FLAG_C, rd&0x8000 != 0 && r&0x8000 == 0)
+	s.wordFlags(r)
+	return nil
+}
+
+func sub(s *Simulator, d *language.Decoded) error {
+	rd, rr := s.Reg(reg(d.Op1)), s.Reg(reg(d.Op2))
+	r := rd - rr
+	s.SetReg(reg(d.Op1), r)
+	s.subFlags(rd, rr, r, false)
+	return nil
+}
+
+func subi(s *Simulator, d *language.Decoded) error {
+	rd, k := s.Reg(reg(d.Op1)), byte(imm(d.Op2))
+	r := rd - k
+	s.SetReg(reg(d.Op1), r)
+	s.subFlags(rd, k, r, false)
+	return nil
+}
+
+func sbc(s *Simulator, d *language.Decoded) error {
+	rd, rr := s.Reg(reg(d.Op1)), s.Reg(reg(d.Op2))
+	r := rd - rr - carry(s)
+	s.SetReg(reg(d.Op1), r)
+	s.subFlags(rd, rr, r, true)
+	return nil
+}
+
+func sbci(s *Simulator, d *language.Decoded) error {
+	rd, k := s.Reg(reg(d.Op1)), byte(imm(d.Op2))
+	r := rd - k - carry(s)
+	s.SetReg(reg(d.Op1), r)
+	s.subFlags(rd, k, r, true)
+	return nil
+}
+
+func sbiw(s *Simulator, d *language.Decoded) error {
+	rd := s.Pair(reg(d.Op1))
+	r := (rd - imm(d.Op2)) & 0xFFFF
+	s.SetPair(reg(d.Op1), r)
+
+	s.SetFlag(FLAG_V, rd&0x8000 != 0 && r&0x8000 == 0)
+	s.SetFlag(FLAG_C, rd&0x8000 == 0 && r&0x8000 != 0)
+	s.wordFlags(r)
+	return nil
+}
+
+func cp(s *Simulator, d *language.Decoded) error {
+	rd, rr := s.Reg(reg(d.Op1)), s.Reg(reg(d.Op2))
+	s.subFlags(rd, rr, rd-rr, false)
+	return nil
+}
+
+func cpc(s *Simulator, d *language.Decoded) error {
+	rd, rr := s.Reg(reg(d.Op1)), s.Reg(reg(d.Op2))
+	s.subFlags(rd, rr, rd-rr-carry(s), true)
+	return nil
+}
+
+func cpi(s *Simulator, d *language.Decoded) error {
+	rd, k := s.Reg(reg(d.Op1)), byte(imm(d.Op2))
+	s.subFlags(rd, k, rd-k, false)
+	return nil
+}
+
+func and(s *Simulator, d *language.Decoded) error {
+	r := s.Reg(reg(d.Op1)) & s.Reg(reg(d.Op2))
+	s.SetReg(reg(d.Op1), r)
+	s.logicFlags(r)
+	return nil
+}
+
+func andi(s *Simulator, d *language.Decoded) error {
+	r := s.Reg(reg(d.Op1)) & byte(imm(d.Op2))
+	s.SetReg(reg(d.Op1), r)
+	s.logicFlags(r)
+	return nil
+}
+
+func or(s *Simulator, d *language.Decoded) error {
+	r := s.Reg(reg(d.Op1)) | s.Reg(reg(d.Op2))
+	s.SetReg(reg(d.Op1), r)
+	s.logicFlags(r)
+	return nil
+}
+
+func ori(s *Simulator, d *language.Decoded) error {
+	r := s.Reg(reg(d.Op1)) | byte(imm(d.Op2))
+	s.SetReg(reg(d.Op1), r)
+	s.logicFlags(r)
+	return nil
+}
+
+func eor(s *Simulator, d *language.Decoded) error {
+	r := s.Reg(reg(d.Op1)) ^ s.Reg(reg(d.Op2))
+	s.SetReg(reg(d.Op1), r)
+	s.logicFlags(r)
+	return nil
+}
+
+func com(s *Simulator, d *language.Decoded) error {
+	r := 0xFF - s.Reg(reg(d.Op1))
+	s.SetReg(reg(d.Op1), r)
+	s.logicFlags(r)
+	s.SetFlag(FLAG_C, true)
+	return nil
+}
+
+func neg(s *Simulator, d *language.Decoded) error {
+	rd := s.Reg(reg(d.Op1))
+	r := 0 - rd
+	s.SetReg(reg(d.Op1), r)
+
+	s.SetFlag(FLAG_H, (r|rd)&0x08 != 0)
+	s.SetFlag(FLAG_V, r == 0x80)
+	s.SetFlag(FLAG_C, r != 0)
+	s.resultFlags(r)
+	return nil
+}
+
+func inc(s *Simulator, d *language.Decoded) error {
+	r := s.Reg(reg(d.Op1)) + 1
+	s.SetReg(reg(d.Op1), r)
+	s.SetFlag(FLAG_V, r == 0x80)
+	s.resultFlags(r)
+	return nil
+}
+
+func dec(s *Simulator, d *language.Decoded) error {
+	r := s.Reg(reg(d.Op1)) - 1
+	s.SetReg(reg(d.Op1), r)
+	s.SetFlag(FLAG_V, r == 0x7F)
+	s.resultFlags(r)
+	return nil
+}
+
+func asr(s *Simulator, d *language.Decoded) error {
+	rd := s.Reg(reg(d.Op1))
+	r := rd>>1 | rd&0x80
+	s.SetReg(reg(d.Op1), r)
+	s.shiftFlags(rd, r)
+	return nil
+}
+
+func lsr(s *Simulator, d *language.Decoded) error {
+	rd := s.Reg(reg(d.Op1))
+	r := rd >> 1
+	s.SetReg(reg(d.Op1), r)
+	s.shiftFlags(rd, r)
+	return nil
+}
+
+func ror(s *Simulator, d *language.Decoded) error {
+	rd := s.Reg(reg(d.Op1))
+	r := rd>>1 | carry(s)<<7
+	s.SetReg(reg(d.Op1), r)
+	s.shiftFlags(rd, r)
+	return nil
+}
+
+func swap(s *Simulator, d *language.Decoded) error {
+	rd := s.Reg(reg(d.Op1))
+	s.SetReg(reg(d.Op1), rd<<4|rd>>4)
+	return nil
+}
+
+/* -------- Multiplication -------- */
+
+func mul(s *Simulator, d *language.Decoded) error {
+	r := uint64(s.Reg(reg(d.Op1))) * uint64(s.Reg(reg(d.Op2)))
+	s.product(r, false)
+	return nil
+}
+
+func muls(s *Simulator, d *language.Decoded) error {
+	r := int64(int8(s.Reg(reg(d.Op1)))) * int64(int8(s.Reg(reg(d.Op2))))
+	s.product(uint64(r), false)
+	return nil
+}
+
+func mulsu(s *Simulator, d *language.Decoded) error {
+	r := int64(int8(s.Reg(reg(d.Op1)))) * int64(s.Reg(reg(d.Op2)))
+	s.product(uint64(r), false)
+	return nil
+}
+
+func fmul(s *Simulator, d *language.Decoded) error {
+	r := uint64(s.Reg(reg(d.Op1))) * uint64(s.Reg(reg(d.Op2)))
+	s.product(r, true)
+	return nil
+}
+
+func fmuls(s *Simulator, d *language.Decoded) error {
+	r := int64(int8(s.Reg(reg(d.Op1)))) * int64(int8(s.Reg(reg(d.Op2))))
+	s.product(uint64(r), true)
+	return nil
+}
+
+func fmulsu(s *Simulator, d *language.Decoded) error {
+	r := int64(int8(s.Reg(reg(d.Op1)))) * int64(s.Reg(reg(d.Op2)))
+	s.product(uint64(r), true)
+	return nil
+}
+
+/* -------- Bits and flags -------- */
+
+func bset(s *Simulator, d *language.Decoded) error {
+	s.SetFlag(1<<imm(d.Op1), true)
+	return nil
+}
+
+func bclr(s *Simulator, d *language.Decoded) error {
+	s.SetFlag(1<<imm(d.Op1), false)
+	return nil
+}
+
+/*
+Set or clear one flag of SREG, as in sec and clc
+*/
+func flag(f byte, set bool) Operation {
+	return func(s *Simulator, d *language.Decoded) error {
+		s.SetFlag(f, set)
+		return nil
+	}
+}
+
+func bst(s *Simulator, d *language.Decoded) error {
+	s.SetFlag(FLAG_T, s.Reg(reg(d.Op1))&(1<<imm(d.Op2)) != 0)
+	return nil
+}
+
+func bld(s *Simulator, d *language.Decoded) error {
+	r := s.Reg(reg(d.Op1)) &^ (1 << imm(d.Op2))
+
+	if s.Flag(FLAG_T) {
+		r |= 1 << imm(d.Op2)
+	}
+
+	s.SetReg(reg(d.Op1), r)
+	return nil
+}
+
+func sbi(s *Simulator, d *language.Decoded) error {
+	return s.updateIO(imm(d.Op1), func(r byte) byte {
+		return r | 1<<imm(d.Op2)
+	})
+}
+
+func cbi(s *Simulator, d *language.Decoded) error {
+	return s.updateIO(imm(d.Op1), func(r byte) byte {
+		return r &^ (1 << imm(d.Op2))
+	})
+}
+
+/* -------- Data transfer -------- */
+
+func mov(s *Simulator, d *language.Decoded) error {
+	s.SetReg(reg(d.Op1), s.Reg(reg(d.Op2)))
+	return nil
+}
+
+func ldi(s *Simulator, d *language.Decoded) error {
+	s.SetReg(reg(d.Op1), byte(imm(d.Op2)))
+	return nil
+}
+
+func ld(s *Simulator, d *language.Decoded) error {
+	addr := s.pointer(d.Op2)
+	r, err := s.Load(addr)
+
+	if err != nil {
+		return err
+	}
+
+	s.SetReg(reg(d.Op1), r)
+	return nil
+}
+
+func st(s *Simulator, d *language.Decoded) error {
+	r := s.Reg(reg(d.Op2))
+	return s.Store(s.pointer(d.Op1), r)
+}
+
+func lds(s *Simulator, d *language.Decoded) error {
+	r, err := s.Load(imm(d.Op2))
+
+	if err != nil {
+		return err
+	}
+
+	s.SetReg(reg(d.Op1), r)
+	return nil
+}
+
+func sts(s *Simulator, d *language.Decoded) error {
+	return s.Store(imm(d.Op1), s.Reg(reg(d.Op2)))
+}
+
+func lpm(s *Simulator, d *language.Decoded) error {
+	s.SetReg(0, s.LoadFlash(s.Pair(30)))
+	return nil
+}
+
+func in(s *Simulator, d *language.Decoded) error {
+	r, err := s.Load(imm(d.Op2) + 0x20)
+
+	if err != nil {
+		return err
+	}
+
+	s.SetReg(reg(d.Op1), r)
+	return nil
+}
+
+func out(s *Simulator, d *language.Decoded) error {
+	return s.Store(imm(d.Op1)+0x20, s.Reg(reg(d.Op2)))
+}
+
+func push(s *Simulator, d *language.Decoded) error {
+	return s.Push(s.Reg(reg(d.Op1)))
+}
+
+func pop(s *Simulator, d *language.Decoded) error {
+	r, err := s.Pop()
+
+	if err != nil {
+		return err
+	}
+
+	s.SetReg(reg(d.Op1), r)
+	return nil
+}
+
+/* -------- Control -------- */
+
+func rjmp(s *Simulator, d *language.Decoded) error {
+	s.PC = s.wrap(s.PC + imm(d.Op1))
+	return nil
+}
+
+func jmp(s *Simulator, d *language.Decoded) error {
+	s.PC = s.wrap(imm(d.Op1))
+	return nil
+}
+
+func ijmp(s *Simulator, d *language.Decoded) error {
+	s.PC = s.wrap(s.Pair(30))
+	return nil
+}
+
+func rcall(s *Simulator, d *language.Decoded) error {
+	err := s.PushPC(s.PC)
+	s.PC = s.wrap(s.PC + imm(d.Op1))
+	return err
+}
+
+func call(s *Simulator, d *language.Decoded) error {
+	err := s.PushPC(s.PC)
+	s.PC = s.wrap(imm(d.Op1))
+	return err
+}
+
+func icall(s *Simulator, d *language.Decoded) error {
+	err := s.PushPC(s.PC)
+	s.PC = s.wrap(s.Pair(30))
+	return err
+}
+
+func ret(s *Simulator, d *language.Decoded) error {
+	pc, err := s.PopPC()
+
+	if err != nil {
+		return err
+	}
+
+	s.PC = pc
+	return nil
+}
+
+func reti(s *Simulator, d *language.Decoded) error {
+	s.SetFlag(FLAG_I, true)
+	return ret(s, d)
+}
+
+/*
+Branch if a flag of SREG is set or clear, as in breq and brne
+*/
+func branch(f byte, set bool) Operation {
+	return func(s *Simulator, d *language.Decoded) error {
+		if s.Flag(f) == set {
+			s.jump(s.PC + imm(d.Op1))
+		}
+
+		return nil
+	}
+}
+
+func brbs(s *Simulator, d *language.Decoded) error {
+	if s.Flag(1 << imm(d.Op1)) {
+		s.jump(s.PC + imm(d.Op2))
+	}
+
+	return nil
+}
+
+func brbc(s *Simulator, d *language.Decoded) error {
+	if !s.Flag(1 << imm(d.Op1)) {
+		s.jump(s.PC + imm(d.Op2))
+	}
+
+	return nil
+}
+
+func cpse(s *Simulator, d *language.Decoded) error {
+	if s.Reg(reg(d.Op1)) == s.Reg(reg(d.Op2)) {
+		return s.skip()
+	}
+
+	return nil
+}
+
+func sbrc(s *Simulator, d *language.Decoded) error {
+	if s.Reg(reg(d.Op1))&(1<<imm(d.Op2)) == 0 {
+		return s.skip()
+	}
+
+	return nil
+}
+
+func sbrs(s *Simulator, d *language.Decoded) error {
+	if s.Reg(reg(d.Op1))&(1<<imm(d.Op2)) != 0 {
+		return s.skip()
+	}
+
+	return nil
+}
+
+func sbic(s *Simulator, d *language.Decoded) error {
+	r, err := s.Load(imm(d.Op1) + 0x20)
+
+	if err != nil || r&(1<<imm(d.Op2)) != 0 {
+		return err
+	}
+
+	return s.skip()
+}
+
+func sbis(s *Simulator, d *language.Decoded) error {
+	r, err := s.Load(imm(d.Op1) + 0x20)
+
+	if err != nil || r&(1<<imm(d.Op2)) == 0 {
+		return err
+	}
+
+	return s.skip()
+}
+
+func nop(s *Simulator, d *language.Decoded) error {
+	return nil
+}
+
+/*
+Sleep until an interrupt, or stop if none could wake the device
+*/
+func sleep(s *Simulator, d *language.Decoded) error {
+	if s.canWake() {
+		s.Sleeping = true
+	} else {
+		s.Halted = SLEEP
+	}
+
+	return nil
+}
+
+func brk(s *Simulator, d *language.Decoded) error {
+	s.Halted = BREAK
+	return nil
+}
+
+/* -------- Helpers -------- */
+
+/*
+Skip the next instruction, which is two words long for jmp,
+call, lds and sts
+*/
+func (s *Simulator) skip() error {
+	next, err := s.Fetch(s.PC)
+
+	if err != nil {
+		return err
+	}
+
+	s.jump(s.PC + next.Size())
+	return nil
+}
+
+/*
+Take a branch or skip, which takes more cycles than not
+*/
+func (s *Simulator) jump(pc uint64) {
+	s.PC = s.wrap(pc)
+	s.taken = true
+}
+
+/*
+Address a pointer operand points to, moving the pointer first
+when it is predecremented and after when it is postincremented
+*/
+func (s *Simulator) pointer(op language.Value) uint64 {
+	switch op := op.(type) {
+	case *language.RegPointer:
+		return s.Pair(pointerReg(op.Value))
+
+	case *language.RegPointerPostInc:
+		n := pointerReg(op.Value)
+		addr := s.Pair(n)
+		s.SetPair(n, (addr+1)&0xFFFF)
+		return addr
+
+	case *language.RegPointerPreDec:
+		n := pointerReg(op.Value)
+		addr := (s.Pair(n) - 1) & 0xFFFF
+		s.SetPair(n, addr)
+		return addr
+
+	case *language.RegPointerDisp:
+		return s.Pair(pointerReg(op.Value)) + op.Disp
+
+	default:
+		return 0
+	}
+}
+
+func pointerReg(name string) uint64 {
+	switch language.Mnemonic(name) {
+	case language.X:
+		return 26
+	case language.Y:
+		return 28
+	default:
+		return 30
+	}
+}
+
+/*
+Read, change and write back an I/O register, given its I/O
+address, as sbi and cbi do
+*/
+func (s *Simulator) updateIO(addr uint64, update func(byte) byte) error {
+	r, err := s.Load(addr + 0x20)
+
+	if err != nil {
+		return err
+	}
+
+	return s.Store(addr+0x20, update(r))
+}
+
+/*
+Write the product of a multiplication to r1:r0. The fractional
+multiplications shift it left by one, setting C from the bit
+shifted out.
+*/
+func (s *Simulator) product(r uint64, fractional bool) {
+	r &= 0xFFFF
+	s.SetFlag(FLAG_C, r&0x8000 != 0)
+
+	if fractional {
+		r = (r << 1) & 0xFFFF
+	}
+
+	s.SetPair(0, r)
+	s.SetFlag(FLAG_Z, r == 0)
+}
+
+func (s *Simulator) addFlags(rd byte, rr byte, r byte) {
+	c := rd&rr | rr&^r | ^r&rd
+
+	s.SetFlag(FLAG_H, c&0x08 != 0)
+	s.SetFlag(FLAG_V, (rd&rr&^r|^rd&^rr&r)&0x80 != 0)
+	s.SetFlag(FLAG_C, c&0x80 != 0)
+	s.resultFlags(r)
+}
+
+/*
+Flags of a subtraction or comparison. With carry, Z is only
+kept set if the result is zero, so that comparisons of several
+bytes give Z for the whole value.
+*/
+func (s *Simulator) subFlags(rd byte, rr byte, r byte, withCarry bool) {
+	c := ^rd&rr | rr&r | r&^rd
+	z := s.Flag(FLAG_Z)
+
+	s.SetFlag(FLAG_H, c&0x08 != 0)
+	s.SetFlag(FLAG_V, (rd&^rr&^r|^rd&rr&r)&0x80 != 0)
+	s.SetFlag(FLAG_C, c&0x80 != 0)
+	s.resultFlags(r)
+
+	if withCarry {
+		s.SetFlag(FLAG_Z, z && r == 0)
+	}
+}
+
+func (s *Simulator) logicFlags(r byte) {
+	s.SetFlag(FLAG_V, false)
+	s.resultFlags(r)
+}
+
+/*
+Flags of a shift right, where C is the bit shifted out
+*/
+func (s *Simulator) shiftFlags(rd byte, r byte) {
+	s.SetFlag(FLAG_C, rd&0x01 != 0)
+	s.SetFlag(FLAG_V, (r&0x80 != 0) != (rd&0x01 != 0))
+	s.resultFlags(r)
+}
+
+/*
+N and Z from a result, and S from N and V
+*/
+func (s *Simulator) resultFlags(r byte) {
+	s.SetFlag(FLAG_N, r&0x80 != 0)
+	s.SetFlag(FLAG_Z, r == 0)
+	s.SetFlag(FLAG_S, s.Flag(FLAG_N) != s.Flag(FLAG_V))
+}
+
+func (s *Simulator) wordFlags(r uint64) {
+	s.SetFlag(FLAG_N, r&0x8000 != 0)
+	s.SetFlag(FLAG_Z, r == 0)
+	s.SetFlag(FLAG_S, s.Flag(FLAG_N) != s.Flag(FLAG_V))
+}
+
+func carry(s *Simulator) byte {
+	if s.Flag(FLAG_C) {
+		return 1
+	}
+
+	return 0
+}
+
+/*
+Register of an operand. Operands are those the decoder gives
+for the instruction, so are always of the type expected.
+*/
+func reg(op language.Value) uint64 {
+	switch op := op.(type) {
+	case *language.Reg:
+		return op.Value
+
+	case *language.RegPair:
+		return op.Value
+
+	default:
+		return 0
+	}
+}
+
+func imm(op language.Value) uint64 {
+	switch op := op.(type) {
+	case *language.Int:
+		return op.Value
+
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,117 @@
+package simulator
+
+import (
+	"testing"
+
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/language"
+)
+
+/*
+An instruction executed from given registers and SREG, and the
+registers and SREG it must leave
+*/
+type flagCase struct {
+	Name     string
+	Mnemonic language.Mnemonic
+	Word     uint16
+	Before   map[uint64]byte
+	SREG     byte
+	After    map[uint64]byte
+	Flags    byte
+}
+
+/* Encodings of the instructions tested */
+func twoRegs(base uint16, d uint16, r uint16) uint16 {
+	return base | (r&0x10)<<5 | (d&0x1F)<<4 | r&0x0F
+}
+
+func pairImm(base uint16, d uint16, k uint16) uint16 {
+	return base | (k&0x30)<<2 | (d-24)/2<<4 | k&0x0F
+}
+
+func fractional(base uint16, d uint16, r uint16) uint16 {
+	return base | (d-16)<<4 | (r - 16)
+}
+
+var flagCases = []flagCase{
+	// add r16, r17
+	{"add half carry", language.ADD, twoRegs(0x0C00, 16, 17), map[uint64]byte{16: 0x0F, 17: 0x01}, 0, map[uint64]byte{16: 0x10}, FLAG_H},
+	{"add signed overflow", language.ADD, twoRegs(0x0C00, 16, 17), map[uint64]byte{16: 0x7F, 17: 0x01}, 0, map[uint64]byte{16: 0x80}, FLAG_H | FLAG_V | FLAG_N},
+	{"add carry to zero", language.ADD, twoRegs(0x0C00, 16, 17), map[uint64]byte{16: 0xFF, 17: 0x01}, 0, map[uint64]byte{16: 0x00}, FLAG_H | FLAG_C | FLAG_Z},
+	{"add negatives", language.ADD, twoRegs(0x0C00, 16, 17), map[uint64]byte{16: 0x80, 17: 0x80}, 0, map[uint64]byte{16: 0x00}, FLAG_C | FLAG_Z | FLAG_V | FLAG_S},
+	{"add clears flags", language.ADD, twoRegs(0x0C00, 16, 17), map[uint64]byte{16: 0x01, 17: 0x02}, 0x3F, map[uint64]byte{16: 0x03}, 0},
+
+	// sub r16, r17
+	{"sub half borrow", language.SUB, twoRegs(0x1800, 16, 17), map[uint64]byte{16: 0x10, 17: 0x01}, 0x3F, map[uint64]byte{16: 0x0F}, FLAG_H},
+	{"sub borrow", language.SUB, twoRegs(0x1800, 16, 17), map[uint64]byte{16: 0x00, 17: 0x01}, 0, map[uint64]byte{16: 0xFF}, FLAG_C | FLAG_H | FLAG_N | FLAG_S},
+	{"sub signed overflow", language.SUB, twoRegs(0x1800, 16, 17), map[uint64]byte{16: 0x80, 17: 0x01}, 0, map[uint64]byte{16: 0x7F}, FLAG_H | FLAG_V | FLAG_S},
+	{"sub to zero", language.SUB, twoRegs(0x1800, 16, 17), map[uint64]byte{16: 0x05, 17: 0x05}, 0, map[uint64]byte{16: 0x00}, FLAG_Z},
+
+	// adiw r25:r24, K
+	{"adiw into high byte", language.ADIW, pairImm(0x9600, 24, 1), map[uint64]byte{24: 0xFF, 25: 0x00}, 0, map[uint64]byte{24: 0x00, 25: 0x01}, 0},
+	{"adiw signed overflow", language.ADIW, pairImm(0x9600, 24, 1), map[uint64]byte{24: 0xFF, 25: 0x7F}, 0, map[uint64]byte{24: 0x00, 25: 0x80}, FLAG_V | FLAG_N},
+	{"adiw carry to zero", language.ADIW, pairImm(0x9600, 24, 1), map[uint64]byte{24: 0xFF, 25: 0xFF}, 0, map[uint64]byte{24: 0x00, 25: 0x00}, FLAG_C | FLAG_Z},
+	{"adiw negative", language.ADIW, pairImm(0x9600, 24, 63), map[uint64]byte{24: 0x00, 25: 0x80}, 0, map[uint64]byte{24: 0x3F, 25: 0x80}, FLAG_N | FLAG_S},
+
+	// sbiw r25:r24, K
+	{"sbiw from high byte", language.SBIW, pairImm(0x9700, 24, 1), map[uint64]byte{24: 0x00, 25: 0x01}, 0, map[uint64]byte{24: 0xFF, 25: 0x00}, 0},
+	{"sbiw signed overflow", language.SBIW, pairImm(0x9700, 24, 1), map[uint64]byte{24: 0x00, 25: 0x80}, 0, map[uint64]byte{24: 0xFF, 25: 0x7F}, FLAG_V | FLAG_S},
+	{"sbiw borrow", language.SBIW, pairImm(0x9700, 24, 1), map[uint64]byte{24: 0x00, 25: 0x00}, 0, map[uint64]byte{24: 0xFF, 25: 0xFF}, FLAG_C | FLAG_N | FLAG_S},
+	{"sbiw keeps H", language.SBIW, pairImm(0x9700, 24, 1), map[uint64]byte{24: 0x01, 25: 0x00}, 0x3F, map[uint64]byte{24: 0x00, 25: 0x00}, FLAG_H | FLAG_Z},
+
+	// mul r16, r17
+	{"mul small", language.MUL, twoRegs(0x9C00, 16, 17), map[uint64]byte{16: 3, 17: 4}, 0, map[uint64]byte{0: 12, 1: 0}, 0},
+	{"mul high bit", language.MUL, twoRegs(0x9C00, 16, 17), map[uint64]byte{16: 0xFF, 17: 0xFF}, 0, map[uint64]byte{0: 0x01, 1: 0xFE}, FLAG_C},
+	{"mul zero", language.MUL, twoRegs(0x9C00, 16, 17), map[uint64]byte{16: 0, 17: 0x55}, FLAG_C, map[uint64]byte{0: 0, 1: 0}, FLAG_Z},
+	{"mul keeps other flags", language.MUL, twoRegs(0x9C00, 16, 17), map[uint64]byte{16: 0x80, 17: 0x02}, FLAG_N | FLAG_V, map[uint64]byte{0: 0x00, 1: 0x01}, FLAG_N | FLAG_V},
+
+	// fmul r16, r17
+	{"fmul quarter", language.FMUL, fractional(0x0308, 16, 17), map[uint64]byte{16: 0x40, 17: 0x40}, 0, map[uint64]byte{0: 0x00, 1: 0x20}, 0},
+	{"fmul into sign bit", language.FMUL, fractional(0x0308, 16, 17), map[uint64]byte{16: 0x80, 17: 0x80}, 0, map[uint64]byte{0: 0x00, 1: 0x80}, 0},
+	{"fmul carry out", language.FMUL, fractional(0x0308, 16, 17), map[uint64]byte{16: 0xFF, 17: 0xFF}, 0, map[uint64]byte{0: 0x02, 1: 0xFC}, FLAG_C},
+	{"fmul zero", language.FMUL, fractional(0x0308, 16, 17), map[uint64]byte{16: 0x80, 17: 0x00}, FLAG_C, map[uint64]byte{0: 0, 1: 0}, FLAG_Z},
+}
+
+func TestFlags(t *testing.T) {
+	dev := device.DeviceMap[device.ATMEGA328P]
+
+	for _, c := range flagCases {
+		sim := NewSimulator(&dev)
+		sim.Flash[0] = c.Word
+
+		for n, val := range c.Before {
+			sim.SetReg(n, val)
+		}
+
+		sim.Data[SREG] = c.SREG
+		decoded, err := sim.Fetch(0)
+
+		if err != nil {
+			t.Errorf("%v: %v", c.Name, err)
+			continue
+		}
+
+		if decoded.Mnemonic != c.Mnemonic {
+			t.Errorf("%v: 0x%04X decodes as '%v', not '%v'", c.Name, c.Word, decoded.Mnemonic, c.Mnemonic)
+			continue
+		}
+
+		err = sim.Step()
+
+		if err != nil {
+			t.Errorf("%v: %v", c.Name, err)
+			continue
+		}
+
+		for n, val := range c.After {
+			if sim.Reg(n) != val {
+				t.Errorf("%v: r%v is 0x%02X, not 0x%02X", c.Name, n, sim.Reg(n), val)
+			}
+		}
+
+		if sim.Data[SREG] != c.Flags {
+			t.Errorf("%v: SREG is %08b, not %08b", c.Name, sim.Data[SREG], c.Flags)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package simulator
+
+import (
+	"io"
+	"sort"
+)
+
+/* Cycles taken to respond to an interrupt, pushing the PC and jumping to its vector */
+const INTERRUPT_CYCLES uint64 = 4
+
+/*
+A model of a peripheral of the device, driven through its I/O
+registers. Registers keep their values in the data space, and
+are passed through the peripheral as the program reads and
+writes them, so that it can give the value read or change the
+value kept. Every cycle the program runs is passed to Tick.
+*/
+type Peripheral interface {
+	Addresses() []uint64
+	Load(addr uint64, val byte) byte
+	Store(addr uint64, val byte) byte
+	Reset()
+	Tick(cycles uint64)
+}
+
+/*
+Bits of a register in the data space
+*/
+type Bit struct {
+	Address uint64
+	Mask    byte
+}
+
+/*
+An interrupt, taken when both its enable and flag bits are set
+along with the I flag. Interrupts with Clear have their flag
+cleared as they are taken, or by the program writing a one to
+it, as timers do.
+*/
+type Interrupt struct {
+	Clear  bool
+	Enable Bit
+	Flag   Bit
+	Name   string
+	Vector uint64
+}
+
+/*
+Add a peripheral, passing the registers it handles through it
+*/
+func (s *Simulator) Attach(p Peripheral) {
+	s.Peripherals = append(s.Peripherals, p)
+
+	for _, addr := range p.Addresses() {
+		s.Hooks[addr] = append(s.Hooks[addr], p)
+	}
+
+	p.Reset()
+}
+
+/*
+Add the peripherals of the device found in its I/O registers
+*/
+func (s *Simulator) attachDevice() {
+	for _, name := range PORT_NAMES {
+		if port, ok := NewPort(s, string(name)); ok {
+			s.Attach(port)
+		}
+	}
+
+	for _, config := range Timers {
+		if timer, ok := NewTimer(s, config); ok {
+			s.Attach(timer)
+		}
+	}
+
+	for _, config := range USARTs {
+		if usart, ok := NewUSART(s, config); ok {
+			s.Attach(usart)
+		}
+	}
+}
+
+/*
+Add an interrupt, given the name of its vector on the device.
+Returns false if the device has no such vector.
+*/
+func (s *Simulator) AddInterrupt(name string, enable Bit, flag Bit, clear bool) bool {
+	vector, ok := s.Vector(name)
+
+	if !ok {
+		return false
+	}
+
+	s.Interrupts = append(s.Interrupts, Interrupt{
+		Clear:  clear,
+		Enable: enable,
+		Flag:   flag,
+		Name:   name,
+		Vector: vector,
+	})
+
+	// Lower vectors have higher priority
+	sort.SliceStable(s.Interrupts, func(i, j int) bool {
+		return s.Interrupts[i].Vector < s.Interrupts[j].Vector
+	})
+
+	return true
+}
+
+/*
+Word address of an interrupt vector of the device, given its
+name
+*/
+func (s *Simulator) Vector(name string) (uint64, bool) {
+	for _, vec := range s.Device.Vectors {
+		if vec.Name == name {
+			return uint64(vec.Address), true
+		}
+	}
+
+	return 0, false
+}
+
+/*
+Data address of an I/O register of the device, given its name
+*/
+func (s *Simulator) Register(name string) (uint64, bool) {
+	for _, reg := range s.Device.IO {
+		if reg.Name == name {
+			return uint64(reg.Address), true
+		}
+	}
+
+	return 0, false
+}
+
+/*
+Data addresses of several I/O registers, if the device has all
+of them
+*/
+func (s *Simulator) Registers(names ...string) ([]uint64, bool) {
+	addrs := []uint64{}
+
+	for _, name := range names {
+		addr, ok := s.Register(name)
+
+		if !ok {
+			return nil, false
+		}
+
+		addrs = append(addrs, addr)
+	}
+
+	return addrs, true
+}
+
+/*
+Connect the USARTs of the device to a terminal, or any other
+reader and writer
+*/
+func (s *Simulator) Connect(in io.Reader, out io.Writer) {
+	for _, p := range s.Peripherals {
+		if usart, ok := p.(*USART); ok {
+			usart.Connect(in, out)
+		}
+	}
+}
+
+func (s *Simulator) SetBit(bit Bit, set bool) {
+	if set {
+		s.Data[bit.Address] |= bit.Mask
+	} else {
+		s.Data[bit.Address] &^= bit.Mask
+	}
+}
+
+func (s *Simulator) IsSet(bit Bit) bool {
+	return s.Data[bit.Address]&bit.Mask != 0
+}
+
+/*
+Take the interrupt of highest priority that is pending, if
+interrupts are enabled
+*/
+func (s *Simulator) interrupt() (bool, error) {
+	if !s.Flag(FLAG_I) {
+		return false, nil
+	}
+
+	for _, irq := range s.Interrupts {
+		if !s.IsSet(irq.Enable) || !s.IsSet(irq.Flag) {
+			continue
+		}
+
+		if irq.Clear {
+			s.SetBit(irq.Flag, false)
+		}
+
+		err := s.PushPC(s.PC)
+
+		if err != nil {
+			return false, err
+		}
+
+		s.SetFlag(FLAG_I, false)
+		s.PC = s.wrap(irq.Vector)
+		s.Sleeping = false
+		s.tick(INTERRUPT_CYCLES)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+/*
+Whether an interrupt could wake the device from sleep
+*/
+func (s *Simulator) canWake() bool {
+	if !s.Flag(FLAG_I) {
+		return false
+	}
+
+	for _, irq := range s.Interrupts {
+		if s.IsSet(irq.Enable) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+Let a number of cycles pass
+*/
+func (s *Simulator) tick(cycles uint64) {
+	s.Cycles += cycles
+
+	for _, p := range s.Peripherals {
+		p.Tick(cycles)
+	}
+}
@@ -0,0 +1,494 @@
+package simulator
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/language"
+	"github.com/silaspace/aria/object"
+)
+
+/* Registers every device has in the same place of the data space */
+const (
+	SPL  uint64 = 0x5D
+	SPH  uint64 = 0x5E
+	SREG uint64 = 0x5F
+)
+
+/* Bits of SREG */
+const (
+	FLAG_C byte = 1 << 0
+	FLAG_Z byte = 1 << 1
+	FLAG_N byte = 1 << 2
+	FLAG_V byte = 1 << 3
+	FLAG_S byte = 1 << 4
+	FLAG_H byte = 1 << 5
+	FLAG_T byte = 1 << 6
+	FLAG_I byte = 1 << 7
+)
+
+type Halt int
+
+/* Reasons the simulator stops running */
+const (
+	RUNNING Halt = 0
+	BREAK   Halt = 1
+	SLEEP   Halt = 2
+	LIMIT   Halt = 3
+)
+
+/*
+An instruction set simulator of a device. The data space holds
+the register file at 0x00, the I/O registers from 0x20, and
+SRAM from the device's RAMStart, as on the device itself. The
+PC is in words, like addresses in flash.
+*/
+type Simulator struct {
+	Cycles      uint64
+	Data        []byte
+	Decoded     []*language.Decoded
+	Decoder     *language.Decoder
+	Device      device.Device
+	EEPROM      []byte
+	Flash       []uint16
+	Halted      Halt
+	Hooks       map[uint64][]Peripheral /* Peripherals handling each I/O register */
+	Interrupts  []Interrupt             /* In order of priority */
+	PC          uint64
+	Peripherals []Peripheral
+	Sleeping    bool
+	deferred    bool /* Interrupts wait for one instruction after sei or reti */
+	taken       bool /* The instruction being executed branched or skipped */
+}
+
+/*
+Put a program in flash, EEPROM and SRAM, and reset the device
+to run it
+*/
+func (s *Simulator) LoadFile(program *object.File) error {
+	code := program.Image(object.Code, 0xFF)
+
+	if uint64(len(code)) > uint64(len(s.Flash))*2 {
+		return fmt.Errorf(
+			"program of %v bytes does not fit in the %v bytes of flash of the %v",
+			len(code),
+			len(s.Flash)*2,
+			s.Device.Name,
+		)
+	}
+
+	for i := 0; i+1 < len(code); i += 2 {
+		s.Flash[i/2] = uint16(code[i]) | uint16(code[i+1])<<8
+	}
+
+	if len(code)%2 == 1 {
+		s.Flash[len(code)/2] = uint16(code[len(code)-1]) | 0xFF00
+	}
+
+	eeprom := program.Image(object.EEPROM, 0xFF)
+
+	if len(eeprom) > len(s.EEPROM) {
+		return fmt.Errorf(
+			"EEPROM data of %v bytes does not fit in the %v bytes of the %v",
+			len(eeprom),
+			len(s.EEPROM),
+			s.Device.Name,
+		)
+	}
+
+	copy(s.EEPROM, eeprom)
+
+	s.Decoded = make([]*language.Decoded, len(s.Flash))
+	s.Reset()
+
+	// Initialised data in SRAM, as from a .data section of the GNU tools
+	data := program.Image(object.Data, 0)
+
+	for addr := uint64(s.Device.RAMStart); addr < uint64(len(data)); addr++ {
+		err := s.Store(addr, data[addr])
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+Clear the registers and SRAM, reset the peripherals, and start
+again from address 0 with the stack at the end of SRAM
+*/
+func (s *Simulator) Reset() {
+	for i := range s.Data {
+		s.Data[i] = 0
+	}
+
+	s.Cycles = 0
+	s.Halted = RUNNING
+	s.PC = 0
+	s.Sleeping = false
+	s.deferred = false
+	s.SetSP(uint64(len(s.Data)) - 1)
+
+	for _, p := range s.Peripherals {
+		p.Reset()
+	}
+}
+
+/*
+Run until the program stops at a break or sleep, or until it
+has run for the given number of cycles
+*/
+func (s *Simulator) Run(limit uint64) error {
+	s.Halted = RUNNING
+
+	for s.Halted == RUNNING {
+		if s.Cycles >= limit {
+			s.Halted = LIMIT
+			return nil
+		}
+
+		err := s.Step()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+Execute the instruction at the PC, after taking any interrupt
+that is pending. While asleep, a cycle passes instead.
+*/
+func (s *Simulator) Step() error {
+	if !s.deferred {
+		taken, err := s.interrupt()
+
+		if err != nil || taken {
+			return err
+		}
+	}
+
+	s.deferred = false
+
+	if s.Sleeping {
+		if !s.canWake() {
+			s.Sleeping = false
+			s.Halted = SLEEP
+			return nil
+		}
+
+		s.tick(1)
+		return nil
+	}
+
+	decoded, err := s.Fetch(s.PC)
+
+	if err != nil {
+		return err
+	}
+
+	operation, exists := Operations[decoded.Mnemonic]
+
+	if !exists {
+		return fmt.Errorf("'%v' at 0x%04X cannot be simulated", decoded.Mnemonic, s.PC)
+	}
+
+	pc := s.PC
+	next := s.wrap(pc + decoded.Size())
+	s.PC = next
+	s.taken = false
+	enabled := s.Flag(FLAG_I)
+
+	err = operation(s, decoded)
+
+	if err != nil {
+		s.PC = pc
+		return fmt.Errorf("%v, executing '%v' at 0x%04X", err, decoded.Mnemonic, pc)
+	}
+
+	// The instruction after one enabling interrupts runs before any is taken
+	s.deferred = !enabled && s.Flag(FLAG_I)
+	s.tick(s.cycles(decoded, next))
+	return nil
+}
+
+/*
+Cycles taken by an instruction just executed, given the address
+of the instruction after it
+*/
+func (s *Simulator) cycles(decoded *language.Decoded, next uint64) uint64 {
+	timing := decoded.Instruction.Cycles
+
+	switch {
+	case !s.taken:
+		return timing.Cycles
+
+	// Skipping a two word instruction takes a cycle more
+	case decoded.Instruction.IsSkip():
+		return timing.Taken + s.wrap(s.PC-next) - 1
+
+	default:
+		return timing.Taken
+	}
+}
+
+/*
+The instruction at an address in flash, decoded once and kept
+until a new program is loaded
+*/
+func (s *Simulator) Fetch(pc uint64) (*language.Decoded, error) {
+	if pc >= uint64(len(s.Flash)) {
+		return nil, fmt.Errorf("address 0x%04X is outside of flash", pc)
+	}
+
+	if s.Decoded[pc] != nil {
+		return s.Decoded[pc], nil
+	}
+
+	words := []uint16{s.Flash[pc], s.Flash[s.wrap(pc+1)]}
+	decoded, err := s.Decoder.Decode(words)
+
+	if err != nil {
+		return nil, fmt.Errorf("%v, at 0x%04X", err, pc)
+	}
+
+	s.Decoded[pc] = decoded
+	return decoded, nil
+}
+
+/*
+Read a byte of the data space, through any peripheral handling
+the register
+*/
+func (s *Simulator) Load(addr uint64) (byte, error) {
+	if addr >= uint64(len(s.Data)) {
+		return 0, fmt.Errorf("data address 0x%04X is outside of memory", addr)
+	}
+
+	val := s.Data[addr]
+
+	for _, p := range s.Hooks[addr] {
+		val = p.Load(addr, val)
+	}
+
+	return val, nil
+}
+
+/*
+Write a byte of the data space, through any peripheral handling
+the register. Writing a one to an interrupt flag cleared as the
+interrupt is taken clears it instead.
+*/
+func (s *Simulator) Store(addr uint64, val byte) error {
+	if addr >= uint64(len(s.Data)) {
+		return fmt.Errorf("data address 0x%04X is outside of memory", addr)
+	}
+
+	for _, irq := range s.Interrupts {
+		if irq.Clear && irq.Flag.Address == addr {
+			mask := irq.Flag.Mask
+			val = val&^mask | s.Data[addr]&mask&^val
+		}
+	}
+
+	for _, p := range s.Hooks[addr] {
+		val = p.Store(addr, val)
+	}
+
+	s.Data[addr] = val
+	return nil
+}
+
+/*
+Read a byte of flash, given its byte address
+*/
+func (s *Simulator) LoadFlash(addr uint64) byte {
+	word := s.Flash[s.wrap(addr/2)]
+
+	if addr%2 == 1 {
+		return byte(word >> 8)
+	}
+
+	return byte(word)
+}
+
+/*
+Write a byte of flash, given its byte address. Instructions
+decoded from the word, or from the long instruction before it,
+are decoded again when next executed.
+*/
+func (s *Simulator) StoreFlash(addr uint64, val byte) {
+	pc := s.wrap(addr / 2)
+
+	if addr%2 == 1 {
+		s.Flash[pc] = s.Flash[pc]&0x00FF | uint16(val)<<8
+	} else {
+		s.Flash[pc] = s.Flash[pc]&0xFF00 | uint16(val)
+	}
+
+	s.Decoded[pc] = nil
+	s.Decoded[s.wrap(pc+uint64(len(s.Flash))-1)] = nil
+}
+
+func (s *Simulator) Reg(n uint64) byte {
+	return s.Data[n]
+}
+
+func (s *Simulator) SetReg(n uint64, val byte) {
+	s.Data[n] = val
+}
+
+/*
+Value of a register pair, such as 26 for X
+*/
+func (s *Simulator) Pair(n uint64) uint64 {
+	return uint64(s.Data[n]) | uint64(s.Data[n+1])<<8
+}
+
+func (s *Simulator) SetPair(n uint64, val uint64) {
+	s.Data[n] = byte(val)
+	s.Data[n+1] = byte(val >> 8)
+}
+
+func (s *Simulator) SP() uint64 {
+	return uint64(s.Data[SPL]) | uint64(s.Data[SPH])<<8
+}
+
+func (s *Simulator) SetSP(val uint64) {
+	s.Data[SPL] = byte(val)
+	s.Data[SPH] = byte(val >> 8)
+}
+
+func (s *Simulator) Flag(flag byte) bool {
+	return s.Data[SREG]&flag != 0
+}
+
+func (s *Simulator) SetFlag(flag byte, set bool) {
+	if set {
+		s.Data[SREG] |= flag
+	} else {
+		s.Data[SREG] &^= flag
+	}
+}
+
+/*
+Push a byte on the stack, which grows down from the end of SRAM
+*/
+func (s *Simulator) Push(val byte) error {
+	sp := s.SP()
+	err := s.Store(sp, val)
+
+	if err != nil {
+		return fmt.Errorf("stack overflow, %v", err)
+	}
+
+	s.SetSP(sp - 1)
+	return nil
+}
+
+func (s *Simulator) Pop() (byte, error) {
+	sp := s.SP() + 1
+	val, err := s.Load(sp)
+
+	if err != nil {
+		return 0, fmt.Errorf("stack underflow, %v", err)
+	}
+
+	s.SetSP(sp)
+	return val, nil
+}
+
+/*
+Push a return address, low byte first so that it is stored
+high byte first in memory
+*/
+func (s *Simulator) PushPC(pc uint64) error {
+	err := s.Push(byte(pc))
+
+	if err != nil {
+		return err
+	}
+
+	return s.Push(byte(pc >> 8))
+}
+
+func (s *Simulator) PopPC() (uint64, error) {
+	high, err := s.Pop()
+
+	if err != nil {
+		return 0, err
+	}
+
+	low, err := s.Pop()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return s.wrap(uint64(high)<<8 | uint64(low)), nil
+}
+
+/*
+Addresses in flash wrap around, so that rjmp can reach the
+end of flash from its start
+*/
+func (s *Simulator) wrap(pc uint64) uint64 {
+	return pc % uint64(len(s.Flash))
+}
+
+/*
+Write the registers, SREG, SP and PC
+*/
+func (s *Simulator) Write(w io.Writer) error {
+	fmt.Fprintf(w, "Stopped %v after %v cycles\n\n", s.Halted, s.Cycles)
+
+	for row := uint64(0); row < 32; row += 8 {
+		fmt.Fprintf(w, "r%-3v", row)
+
+		for n := row; n < row+8; n++ {
+			fmt.Fprintf(w, " %02X", s.Reg(n))
+		}
+
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "\nX    %04X\nY    %04X\nZ    %04X\n", s.Pair(26), s.Pair(28), s.Pair(30))
+	fmt.Fprintf(w, "\nPC   %04X\nSP   %04X\nSREG %02X  %v\n", s.PC, s.SP(), s.Data[SREG], s.Flags())
+	return nil
+}
+
+/*
+Flags of SREG from I down to C, in capitals when set
+*/
+func (s *Simulator) Flags() string {
+	names := "ithsvnzc"
+	flags := []byte(names)
+
+	for i := range flags {
+		if s.Flag(FLAG_I >> i) {
+			flags[i] = names[i] - 'a' + 'A'
+		}
+	}
+
+	return string(flags)
+}
+
+func (h Halt) String() string {
+	switch h {
+	case RUNNING:
+		return "while running"
+	case BREAK:
+		return "at break"
+	case SLEEP:
+		return "at sleep"
+	case LIMIT:
+		return "at the cycle limit"
+	default:
+		return fmt.Sprintf("for reason %d", int(h))
+	}
+}
@@ -0,0 +1,321 @@
+package simulator
+
+/*
+A condition on the bits of a register, as for the waveform
+generation mode of a timer
+*/
+type Match struct {
+	Mask     byte
+	Register string
+	Value    byte
+}
+
+/*
+A condition on the bits of a register at an address
+*/
+type Condition struct {
+	Bit   Bit
+	Value byte
+}
+
+/*
+An interrupt of a timer, with its bit in the timer's mask and
+flag registers. Compare interrupts also name the register
+compared with the count.
+*/
+type TimerInterrupt struct {
+	Bit      byte
+	Register string
+	Vector   string
+}
+
+/*
+How a timer appears on a device. 16 bit registers are named
+without their L and H suffixes. A timer is simulated if the
+device has all of its registers and vectors.
+*/
+type TimerConfig struct {
+	Bits       uint
+	Clock      string /* Clock select in bits 2:0 */
+	Compare    []TimerInterrupt
+	Count      string
+	CTC        []Match /* Clear the count on compare match with the first compare register */
+	Flags      string
+	Mask       string
+	Name       string
+	Overflow   TimerInterrupt
+	Prescalers []uint64 /* Cycles per count for each clock select, 0 when stopped */
+}
+
+/* Clock selects of timers 0 and 1, stopped for an external clock */
+var prescalers = []uint64{0, 1, 8, 64, 256, 1024, 0, 0}
+
+/* Clock selects of timer 2, which may count asynchronously */
+var asyncPrescalers = []uint64{0, 1, 8, 32, 64, 128, 256, 1024}
+
+/*
+Timers of the supported devices, told apart by the names of
+their registers
+*/
+var Timers = []TimerConfig{
+	// ATmega328P and AT90USB
+	{
+		Bits:  8,
+		Clock: "TCCR0B",
+		Compare: []TimerInterrupt{
+			{1, "OCR0A", "TIMER0_COMPA"},
+			{2, "OCR0B", "TIMER0_COMPB"},
+		},
+		Count:      "TCNT0",
+		CTC:        []Match{{0x03, "TCCR0A", 0x02}, {0x08, "TCCR0B", 0x00}},
+		Flags:      "TIFR0",
+		Mask:       "TIMSK0",
+		Name:       "TIMER0",
+		Overflow:   TimerInterrupt{0, "", "TIMER0_OVF"},
+		Prescalers: prescalers,
+	},
+	{
+		Bits:  16,
+		Clock: "TCCR1B",
+		Compare: []TimerInterrupt{
+			{1, "OCR1A", "TIMER1_COMPA"},
+			{2, "OCR1B", "TIMER1_COMPB"},
+		},
+		Count:      "TCNT1",
+		CTC:        []Match{{0x03, "TCCR1A", 0x00}, {0x18, "TCCR1B", 0x08}},
+		Flags:      "TIFR1",
+		Mask:       "TIMSK1",
+		Name:       "TIMER1",
+		Overflow:   TimerInterrupt{0, "", "TIMER1_OVF"},
+		Prescalers: prescalers,
+	},
+	{
+		Bits:  8,
+		Clock: "TCCR2B",
+		Compare: []TimerInterrupt{
+			{1, "OCR2A", "TIMER2_COMPA"},
+			{2, "OCR2B", "TIMER2_COMPB"},
+		},
+		Count:      "TCNT2",
+		CTC:        []Match{{0x03, "TCCR2A", 0x02}, {0x08, "TCCR2B", 0x00}},
+		Flags:      "TIFR2",
+		Mask:       "TIMSK2",
+		Name:       "TIMER2",
+		Overflow:   TimerInterrupt{0, "", "TIMER2_OVF"},
+		Prescalers: asyncPrescalers,
+	},
+
+	// ATmega8, with one mask and flag register for every timer
+	{
+		Bits:       8,
+		Clock:      "TCCR0",
+		Count:      "TCNT0",
+		Flags:      "TIFR",
+		Mask:       "TIMSK",
+		Name:       "TIMER0",
+		Overflow:   TimerInterrupt{0, "", "TIMER0_OVF"},
+		Prescalers: prescalers,
+	},
+	{
+		Bits:  16,
+		Clock: "TCCR1B",
+		Compare: []TimerInterrupt{
+			{4, "OCR1A", "TIMER1_COMPA"},
+			{3, "OCR1B", "TIMER1_COMPB"},
+		},
+		Count:      "TCNT1",
+		CTC:        []Match{{0x03, "TCCR1A", 0x00}, {0x18, "TCCR1B", 0x08}},
+		Flags:      "TIFR",
+		Mask:       "TIMSK",
+		Name:       "TIMER1",
+		Overflow:   TimerInterrupt{2, "", "TIMER1_OVF"},
+		Prescalers: prescalers,
+	},
+	{
+		Bits:  8,
+		Clock: "TCCR2",
+		Compare: []TimerInterrupt{
+			{7, "OCR2", "TIMER2_COMP"},
+		},
+		Count:      "TCNT2",
+		CTC:        []Match{{0x48, "TCCR2", 0x08}},
+		Flags:      "TIFR",
+		Mask:       "TIMSK",
+		Name:       "TIMER2",
+		Overflow:   TimerInterrupt{6, "", "TIMER2_OVF"},
+		Prescalers: asyncPrescalers,
+	},
+
+	// ATtiny25/45/85
+	{
+		Bits:  8,
+		Clock: "TCCR0B",
+		Compare: []TimerInterrupt{
+			{4, "OCR0A", "TIMER0_COMPA"},
+			{3, "OCR0B", "TIMER0_COMPB"},
+		},
+		Count:      "TCNT0",
+		CTC:        []Match{{0x03, "TCCR0A", 0x02}, {0x08, "TCCR0B", 0x00}},
+		Flags:      "TIFR",
+		Mask:       "TIMSK",
+		Name:       "TIMER0",
+		Overflow:   TimerInterrupt{1, "", "TIMER0_OVF"},
+		Prescalers: prescalers,
+	},
+}
+
+/*
+A timer counting up from the CPU clock through its prescaler,
+in normal mode or clearing on compare match. PWM modes count
+as in normal mode, without driving their output pins. The
+count and compare registers are kept in the data space, with
+the high byte of 16 bit registers read and written through a
+temporary register as on the device.
+*/
+type Timer struct {
+	Clock      uint64   /* Clock select register */
+	Compare    []uint64 /* Compare registers */
+	Count      uint64
+	CTC        []Condition
+	Matched    []Bit /* Flags set on compare match */
+	Max        uint64
+	Name       string
+	Overflowed Bit    /* Flag set on overflow */
+	Prescaled  uint64 /* Cycles since the count last changed */
+	Prescalers []uint64
+	Sim        *Simulator
+	Temp       byte
+	Wide       bool /* 16 bit registers, with the high byte after the low */
+}
+
+func (t *Timer) Addresses() []uint64 {
+	if !t.Wide {
+		return nil
+	}
+
+	addrs := []uint64{}
+
+	for _, addr := range append([]uint64{t.Count}, t.Compare...) {
+		addrs = append(addrs, addr, addr+1)
+	}
+
+	return addrs
+}
+
+/*
+Reading the low byte of a 16 bit register latches its high byte
+*/
+func (t *Timer) Load(addr uint64, val byte) byte {
+	if t.isHigh(addr) {
+		return t.Temp
+	}
+
+	t.Temp = t.Sim.Data[addr+1]
+	return val
+}
+
+/*
+Writing the high byte of a 16 bit register only latches it, and
+writing the low byte writes both
+*/
+func (t *Timer) Store(addr uint64, val byte) byte {
+	if t.isHigh(addr) {
+		t.Temp = val
+		return t.Sim.Data[addr]
+	}
+
+	t.Sim.Data[addr+1] = t.Temp
+	return val
+}
+
+func (t *Timer) Reset() {
+	t.Prescaled = 0
+	t.Temp = 0
+}
+
+func (t *Timer) Tick(cycles uint64) {
+	prescale := t.Prescalers[t.Sim.Data[t.Clock]&0x07]
+
+	if prescale == 0 {
+		return
+	}
+
+	t.Prescaled += cycles
+
+	for t.Prescaled >= prescale {
+		t.Prescaled -= prescale
+		t.count()
+	}
+}
+
+/*
+Count once, overflowing at the top of the count, or clearing on
+a match with the first compare register in CTC mode
+*/
+func (t *Timer) count() {
+	n := t.read(t.Count)
+
+	switch {
+	case n == t.Max:
+		n = 0
+		t.Sim.SetBit(t.Overflowed, true)
+
+	case t.isCTC() && n == t.read(t.Compare[0]):
+		n = 0
+
+	default:
+		n++
+	}
+
+	t.write(t.Count, n)
+
+	for i, reg := range t.Compare {
+		if n == t.read(reg) {
+			t.Sim.SetBit(t.Matched[i], true)
+		}
+	}
+}
+
+func (t *Timer) isCTC() bool {
+	if len(t.CTC) == 0 || len(t.Compare) == 0 {
+		return false
+	}
+
+	for _, cond := range t.CTC {
+		if t.Sim.Data[cond.Bit.Address]&cond.Bit.Mask != cond.Value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (t *Timer) isHigh(addr uint64) bool {
+	if addr == t.Count+1 {
+		return true
+	}
+
+	for _, reg := range t.Compare {
+		if addr == reg+1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *Timer) read(addr uint64) uint64 {
+	if t.Wide {
+		return uint64(t.Sim.Data[addr]) | uint64(t.Sim.Data[addr+1])<<8
+	}
+
+	return uint64(t.Sim.Data[addr])
+}
+
+func (t *Timer) write(addr uint64, val uint64) {
+	t.Sim.Data[addr] = byte(val)
+
+	if t.Wide {
+		t.Sim.Data[addr+1] = byte(val >> 8)
+	}
+}
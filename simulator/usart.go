@@ -0,0 +1,183 @@
+package simulator
+
+import (
+	"bufio"
+	"io"
+)
+
+/* Bits of the status register, UCSRnA */
+const (
+	USART_UDRE byte = 1 << 5
+	USART_TXC  byte = 1 << 6
+	USART_RXC  byte = 1 << 7
+)
+
+/* Bits of the control register, UCSRnB */
+const (
+	USART_TXEN  byte = 1 << 3
+	USART_RXEN  byte = 1 << 4
+	USART_UDRIE byte = 1 << 5
+	USART_TXCIE byte = 1 << 6
+	USART_RXCIE byte = 1 << 7
+)
+
+/*
+How a USART appears on a device, by the names of its registers
+and vectors
+*/
+type USARTConfig struct {
+	Complete string /* Vector of transmit complete */
+	Control  string
+	Data     string
+	Empty    string /* Vector of data register empty */
+	Name     string
+	Receive  string /* Vector of receive complete */
+	Status   string
+}
+
+/*
+USARTs of the supported devices
+*/
+var USARTs = []USARTConfig{
+	// ATmega328P
+	{
+		Complete: "USART_TX",
+		Control:  "UCSR0B",
+		Data:     "UDR0",
+		Empty:    "USART_UDRE",
+		Name:     "USART0",
+		Receive:  "USART_RX",
+		Status:   "UCSR0A",
+	},
+	// ATmega8
+	{
+		Complete: "USART_TXC",
+		Control:  "UCSRB",
+		Data:     "UDR",
+		Empty:    "USART_UDRE",
+		Name:     "USART",
+		Receive:  "USART_RXC",
+		Status:   "UCSRA",
+	},
+	// AT90USB
+	{
+		Complete: "USART1_TX",
+		Control:  "UCSR1B",
+		Data:     "UDR1",
+		Empty:    "USART1_UDRE",
+		Name:     "USART1",
+		Receive:  "USART1_RX",
+		Status:   "UCSR1A",
+	},
+}
+
+/*
+A USART, sending bytes written to UDRn to its output and
+receiving bytes from its input. Bytes are sent as soon as they
+are written, whatever the baud rate, and received as soon as
+the last one has been read.
+*/
+type USART struct {
+	Control  uint64
+	Data     uint64
+	Name     string
+	Output   io.Writer
+	Received chan byte
+	Sim      *Simulator
+	Status   uint64
+	buffer   byte /* Last byte received */
+}
+
+/*
+Send bytes written to the output, and receive bytes read from
+the input as they arrive
+*/
+func (u *USART) Connect(in io.Reader, out io.Writer) {
+	u.Output = out
+
+	if in == nil {
+		u.Received = nil
+		return
+	}
+
+	u.Received = make(chan byte, 64)
+	go receive(bufio.NewReader(in), u.Received)
+}
+
+func receive(r *bufio.Reader, received chan byte) {
+	defer close(received)
+
+	for {
+		c, err := r.ReadByte()
+
+		if err != nil {
+			return
+		}
+
+		received <- c
+	}
+}
+
+func (u *USART) Addresses() []uint64 {
+	return []uint64{u.Data, u.Status}
+}
+
+/*
+Reading UDRn gives the byte received, and clears RXC
+*/
+func (u *USART) Load(addr uint64, val byte) byte {
+	if addr != u.Data {
+		return val
+	}
+
+	u.Sim.Data[u.Status] &^= USART_RXC
+	return u.buffer
+}
+
+/*
+Writing UDRn sends a byte if the transmitter is enabled. RXC
+and UDRE cannot be written.
+*/
+func (u *USART) Store(addr uint64, val byte) byte {
+	if addr == u.Status {
+		mask := USART_RXC | USART_UDRE
+		return val&^mask | u.Sim.Data[addr]&mask
+	}
+
+	if u.Sim.Data[u.Control]&USART_TXEN == 0 {
+		return val
+	}
+
+	if u.Output != nil {
+		u.Output.Write([]byte{val})
+	}
+
+	u.Sim.Data[u.Status] |= USART_TXC
+	return val
+}
+
+func (u *USART) Reset() {
+	u.Sim.Data[u.Status] = USART_UDRE
+	u.buffer = 0
+}
+
+func (u *USART) Tick(cycles uint64) {
+	status := u.Sim.Data[u.Status]
+
+	if u.Received == nil || status&USART_RXC != 0 || u.Sim.Data[u.Control]&USART_RXEN == 0 {
+		return
+	}
+
+	select {
+	case c, ok := <-u.Received:
+		if !ok {
+			u.Received = nil
+			return
+		}
+
+		u.buffer = c
+		u.Sim.Data[u.Status] |= USART_RXC
+
+	default:
+	}
+}
@@ -0,0 +1,212 @@
+package simulator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+/* Picoseconds in a second, the timescale of a trace */
+const PICOSECONDS uint64 = 1000000000000
+
+/*
+A signal of a trace, sampled after every instruction
+*/
+type Signal struct {
+	Bits   int
+	Code   string /* Identifier in the dump */
+	Name   string
+	Sample func() uint64
+	Scope  string
+	value  uint64
+}
+
+/*
+A trace of the program as a Value Change Dump, recording the
+PC, the flags of SREG, the pins of each port and chosen I/O
+registers whenever they change. Times are in picoseconds of a
+clock at FCPU Hz. Attached to the simulator, it samples its
+signals as cycles pass.
+*/
+type Trace struct {
+	FCPU    uint64
+	Output  io.Writer
+	Signals []*Signal
+	Sim     *Simulator
+	Writer  *bufio.Writer
+	last    uint64 /* Time of the last changes written */
+	started bool
+}
+
+func (t *Trace) Addresses() []uint64 {
+	return nil
+}
+
+func (t *Trace) Load(addr uint64, val byte) byte {
+	return val
+}
+
+func (t *Trace) Store(addr uint64, val byte) byte {
+	return val
+}
+
+func (t *Trace) Reset() {}
+
+func (t *Trace) Tick(cycles uint64) {
+	if !t.started {
+		return
+	}
+
+	changed := []*Signal{}
+
+	for _, sig := range t.Signals {
+		val := sig.Sample()
+
+		if val != sig.value {
+			sig.value = val
+			changed = append(changed, sig)
+		}
+	}
+
+	if len(changed) == 0 {
+		return
+	}
+
+	t.timestamp()
+
+	for _, sig := range changed {
+		t.writeValue(sig)
+	}
+}
+
+/*
+Add a signal, giving it the next identifier
+*/
+func (t *Trace) Add(scope string, name string, width int, sample func() uint64) {
+	t.Signals = append(t.Signals, &Signal{
+		Bits:   width,
+		Code:   code(len(t.Signals)),
+		Name:   name,
+		Sample: sample,
+		Scope:  scope,
+	})
+}
+
+/*
+Write the definitions of the signals and their values so far
+*/
+func (t *Trace) Start() {
+	fmt.Fprintf(t.Writer, "$version aria $end\n")
+	fmt.Fprintf(t.Writer, "$timescale 1 ps $end\n")
+	fmt.Fprintf(t.Writer, "$scope module %v $end\n", t.Sim.Device.Name)
+
+	scope := ""
+
+	for _, sig := range t.Signals {
+		if sig.Scope != scope {
+			if scope != "" {
+				fmt.Fprintf(t.Writer, "$upscope $end\n")
+			}
+
+			if sig.Scope != "" {
+				fmt.Fprintf(t.Writer, "$scope module %v $end\n", sig.Scope)
+			}
+
+			scope = sig.Scope
+		}
+
+		fmt.Fprintf(t.Writer, "$var wire %v %v %v $end\n", sig.Bits, sig.Code, sig.Name)
+	}
+
+	if scope != "" {
+		fmt.Fprintf(t.Writer, "$upscope $end\n")
+	}
+
+	fmt.Fprintf(t.Writer, "$upscope $end\n$enddefinitions $end\n")
+
+	t.last = t.time()
+	fmt.Fprintf(t.Writer, "#%v\n$dumpvars\n", t.last)
+
+	for _, sig := range t.Signals {
+		sig.value = sig.Sample()
+		t.writeValue(sig)
+	}
+
+	fmt.Fprintf(t.Writer, "$end\n")
+	t.started = true
+}
+
+/*
+Mark the time the program stopped, write out the trace, and
+close its output if it can be closed
+*/
+func (t *Trace) Close() error {
+	if t.started {
+		t.timestamp()
+	}
+
+	err := t.Writer.Flush()
+
+	if closer, ok := t.Output.(io.Closer); ok {
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+/*
+Write the time if it has moved on since the last changes
+*/
+func (t *Trace) timestamp() {
+	now := t.time()
+
+	if now != t.last {
+		fmt.Fprintf(t.Writer, "#%v\n", now)
+		t.last = now
+	}
+}
+
+/*
+Time in picoseconds of the cycles run so far
+*/
+func (t *Trace) time() uint64 {
+	hi, lo := bits.Mul64(t.Sim.Cycles, PICOSECONDS)
+
+	if hi >= t.FCPU {
+		return math.MaxUint64
+	}
+
+	ps, _ := bits.Div64(hi, lo, t.FCPU)
+	return ps
+}
+
+func (t *Trace) writeValue(sig *Signal) {
+	if sig.Bits == 1 {
+		fmt.Fprintf(t.Writer, "%v%v\n", sig.value&1, sig.Code)
+	} else {
+		fmt.Fprintf(t.Writer, "b%v %v\n", strconv.FormatUint(sig.value, 2), sig.Code)
+	}
+}
+
+/*
+Identifier of the nth signal, from the printable characters
+*/
+func code(n int) string {
+	id := []byte{}
+
+	for {
+		id = append(id, byte('!'+n%94))
+		n /= 94
+
+		if n == 0 {
+			return string(id)
+		}
+
+		n--
+	}
+}
@@ -0,0 +1,20 @@
+package tester
+
+import (
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/object"
+)
+
+/* Cycles a routine may run for before its test fails */
+const DEFAULT_LIMIT uint64 = 1000000
+
+func NewRunner(dev *device.Device, program *object.File, eval Evaluator) *Runner {
+	return &Runner{
+		ByteAddresses: false,
+		Device:        dev,
+		Eval:          eval,
+		File:          "",
+		Limit:         DEFAULT_LIMIT,
+		Program:       program,
+	}
+}
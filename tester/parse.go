@@ -0,0 +1,344 @@
+package tester
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/silaspace/aria/simulator"
+)
+
+/* Directives of a test, read from the source before it is assembled */
+const (
+	DIR_TEST   string = ".test"
+	DIR_GIVEN  string = ".given"
+	DIR_EXPECT string = ".expect"
+)
+
+type Kind int
+
+/* Kinds of state a test sets up or checks */
+const (
+	REGISTER Kind = 0 /* r0 to r31 */
+	PAIR     Kind = 1 /* Such as r25:r24, or X, Y and Z */
+	FLAG     Kind = 2 /* A flag of SREG, such as SREG.Z */
+	STATUS   Kind = 3 /* SREG */
+	STACK    Kind = 4 /* SP */
+	MEMORY   Kind = 5 /* Bytes of the data space from an address, such as [buf] */
+	CYCLES   Kind = 6 /* Cycles taken by the routine */
+)
+
+/*
+State a test sets up or checks. The address of memory is an
+expression, evaluated once the source has been assembled.
+*/
+type Target struct {
+	Address string
+	Flag    byte
+	Kind    Kind
+	Number  uint64 /* Register, or low register of a pair */
+	Text    string
+}
+
+/*
+A line setting up or checking state, with the expressions of
+its values
+*/
+type Check struct {
+	Line   uint64
+	Op     string
+	Target Target
+	Values []string
+}
+
+/*
+A test, calling a routine with the state set up by its .given
+lines and checking the state it returns with by its .expect
+lines
+*/
+type Test struct {
+	Expect  []Check
+	Given   []Check
+	Line    uint64
+	Name    string
+	Routine string
+}
+
+/* Comparisons of .expect, longest first so that <= is not read as < */
+var comparisons = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+var targetPattern = regexp.MustCompile(`^\s*(\[[^\]]*\]|[^\s=!<>]+)\s*(.*)$`)
+
+/*
+Read the tests of a source, giving the source with their lines
+left blank so that it can be assembled with its lines numbered
+as before
+*/
+func Parse(source string) ([]*Test, string, error) {
+	tests := []*Test{}
+	lines := strings.Split(source, "\n")
+
+	for i, line := range lines {
+		number := uint64(i + 1)
+		dir, rest := directive(line)
+
+		if dir == "" {
+			continue
+		}
+
+		lines[i] = ""
+
+		if dir == DIR_TEST {
+			test, err := parseTest(rest, number)
+
+			if err != nil {
+				return nil, "", fmt.Errorf("%v on line %v", err, number)
+			}
+
+			tests = append(tests, test)
+			continue
+		}
+
+		if len(tests) == 0 {
+			return nil, "", fmt.Errorf("%v before any %v on line %v", dir, DIR_TEST, number)
+		}
+
+		test := tests[len(tests)-1]
+		check, err := parseCheck(dir, rest, number)
+
+		if err != nil {
+			return nil, "", fmt.Errorf("%v on line %v", err, number)
+		}
+
+		if dir == DIR_GIVEN {
+			test.Given = append(test.Given, check)
+		} else {
+			test.Expect = append(test.Expect, check)
+		}
+	}
+
+	return tests, strings.Join(lines, "\n"), nil
+}
+
+/*
+The test directive starting a line, if any, and the rest of the
+line without its comment
+*/
+func directive(line string) (string, string) {
+	fields := strings.Fields(line)
+
+	if len(fields) == 0 {
+		return "", ""
+	}
+
+	dir := strings.ToLower(fields[0])
+
+	switch dir {
+	case DIR_TEST, DIR_GIVEN, DIR_EXPECT:
+		rest := strings.TrimSpace(line)[len(dir):]
+		rest, _, _ = strings.Cut(rest, ";")
+		return dir, strings.TrimSpace(rest)
+
+	default:
+		return "", ""
+	}
+}
+
+/*
+A test given as .test routine, optionally followed by a name
+*/
+func parseTest(text string, line uint64) (*Test, error) {
+	routine, name, _ := strings.Cut(text, " ")
+	name = strings.TrimSpace(name)
+
+	if routine == "" {
+		return nil, fmt.Errorf("expected the routine to test after %v", DIR_TEST)
+	}
+
+	if unquoted, err := strconv.Unquote(name); err == nil {
+		name = unquoted
+	}
+
+	if name == "" {
+		name = routine
+	}
+
+	return &Test{
+		Expect:  []Check{},
+		Given:   []Check{},
+		Line:    line,
+		Name:    name,
+		Routine: routine,
+	}, nil
+}
+
+/*
+A check given as target op values, where the op of .given is =
+and the op of .expect is a comparison
+*/
+func parseCheck(dir string, text string, line uint64) (Check, error) {
+	match := targetPattern.FindStringSubmatch(text)
+
+	if match == nil {
+		return Check{}, fmt.Errorf("expected a register, flag or memory after %v", dir)
+	}
+
+	target, err := parseTarget(match[1])
+
+	if err != nil {
+		return Check{}, err
+	}
+
+	op, values := "", strings.TrimSpace(match[2])
+
+	for _, cmp := range comparisons {
+		if strings.HasPrefix(values, cmp) {
+			op = cmp
+			break
+		}
+	}
+
+	switch {
+	case dir == DIR_GIVEN && target.Kind == CYCLES:
+		return Check{}, fmt.Errorf("cycles cannot be given")
+
+	case dir == DIR_GIVEN && strings.HasPrefix(values, "=") && op == "":
+		op = "="
+
+	case dir == DIR_GIVEN:
+		return Check{}, fmt.Errorf("expected %v = value", target.Text)
+
+	case op == "":
+		return Check{}, fmt.Errorf("expected a comparison after %v", target.Text)
+	}
+
+	exprs := split(values[len(op):])
+
+	if len(exprs) == 0 {
+		return Check{}, fmt.Errorf("expected a value after %v %v", target.Text, op)
+	}
+
+	if len(exprs) > 1 && target.Kind != MEMORY {
+		return Check{}, fmt.Errorf("only memory takes a list of values")
+	}
+
+	return Check{
+		Line:   line,
+		Op:     op,
+		Target: target,
+		Values: exprs,
+	}, nil
+}
+
+/*
+A register such as r24, a pair such as r25:r24 or X, a flag
+such as SREG.C, SREG, SP, memory such as [buf+1], or cycles
+*/
+func parseTarget(text string) (Target, error) {
+	name := strings.ToLower(text)
+	target := Target{Text: text}
+
+	switch {
+	case strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]"):
+		target.Kind = MEMORY
+		target.Address = strings.TrimSpace(text[1 : len(text)-1])
+
+		if target.Address == "" {
+			return target, fmt.Errorf("expected an address in %v", text)
+		}
+
+	case name == "cycles":
+		target.Kind = CYCLES
+
+	case name == "sreg":
+		target.Kind = STATUS
+
+	case name == "sp":
+		target.Kind = STACK
+
+	case name == "x" || name == "y" || name == "z":
+		target.Kind = PAIR
+		target.Number = 26 + uint64(name[0]-'x')*2
+
+	case strings.HasPrefix(name, "sreg."):
+		flags := "ithsvnzc"
+		i := strings.Index(flags, name[len("sreg."):])
+
+		if len(name) != len("sreg.")+1 || i < 0 {
+			return target, fmt.Errorf("'%v' is not a flag of SREG", text)
+		}
+
+		target.Kind = FLAG
+		target.Flag = simulator.FLAG_I >> i
+
+	case strings.Contains(name, ":"):
+		high, low, _ := strings.Cut(name, ":")
+		h, herr := register(high)
+		l, lerr := register(low)
+
+		if herr != nil || lerr != nil || h != l+1 || l%2 != 0 {
+			return target, fmt.Errorf("'%v' is not a register pair", text)
+		}
+
+		target.Kind = PAIR
+		target.Number = l
+
+	default:
+		n, err := register(name)
+
+		if err != nil {
+			return target, err
+		}
+
+		target.Kind = REGISTER
+		target.Number = n
+	}
+
+	return target, nil
+}
+
+func register(name string) (uint64, error) {
+	n, err := strconv.ParseUint(strings.TrimPrefix(name, "r"), 10, 64)
+
+	if !strings.HasPrefix(name, "r") || err != nil || n > 31 {
+		return 0, fmt.Errorf("'%v' is not a register", name)
+	}
+
+	return n, nil
+}
+
+/*
+Split values at commas outside of brackets and strings
+*/
+func split(text string) []string {
+	values := []string{}
+	depth, start := 0, 0
+	quoted := false
+
+	for i, c := range text {
+		switch {
+		case c == '"':
+			quoted = !quoted
+
+		case quoted:
+			continue
+
+		case c == '(':
+			depth++
+
+		case c == ')':
+			depth--
+
+		case c == ',' && depth == 0:
+			values = append(values, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+
+	if last := strings.TrimSpace(text[start:]); last != "" || len(values) > 0 {
+		values = append(values, last)
+	}
+
+	return values
+}
@@ -0,0 +1,312 @@
+package tester
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/silaspace/aria/device"
+	"github.com/silaspace/aria/object"
+	"github.com/silaspace/aria/simulator"
+)
+
+/*
+Evaluates an expression of a test with the symbols of the
+assembled source
+*/
+type Evaluator func(expr string) (uint64, error)
+
+/*
+Runs tests against an assembled program, each in a simulator
+of its own. Addresses of routines are in bytes rather than
+words when ByteAddresses is set, as in GNU syntax.
+*/
+type Runner struct {
+	ByteAddresses bool
+	Device        *device.Device
+	Eval          Evaluator
+	File          string
+	Limit         uint64 /* Cycles a routine may run for */
+	Program       *object.File
+}
+
+/*
+The outcome of a test
+*/
+type Result struct {
+	Cycles   uint64   `json:"cycles"`
+	Failures []string `json:"failures"`
+	File     string   `json:"file"`
+	Line     uint64   `json:"line"`
+	Name     string   `json:"name"`
+	Passed   bool     `json:"passed"`
+	Routine  string   `json:"routine"`
+}
+
+/*
+Call the routine of a test with the state it gives, and check
+the state it returns with
+*/
+func (r *Runner) Run(test *Test) Result {
+	result := Result{
+		Failures: []string{},
+		File:     r.File,
+		Line:     test.Line,
+		Name:     test.Name,
+		Routine:  test.Routine,
+	}
+
+	sim, err := r.call(test)
+
+	if err != nil {
+		result.Failures = append(result.Failures, err.Error())
+		return result
+	}
+
+	result.Cycles = sim.Cycles
+
+	for _, expect := range test.Expect {
+		err := r.check(sim, expect)
+
+		if err != nil {
+			result.Failures = append(result.Failures, fmt.Sprintf("line %v: %v", expect.Line, err))
+		}
+	}
+
+	result.Passed = len(result.Failures) == 0
+	return result
+}
+
+/*
+Call the routine with the state the test gives, stopping when
+it returns to the address left on the stack for it
+*/
+func (r *Runner) call(test *Test) (*simulator.Simulator, error) {
+	sim := simulator.NewSimulator(r.Device)
+	err := sim.LoadFile(r.Program)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, given := range test.Given {
+		err := r.set(sim, given)
+
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", given.Line, err)
+		}
+	}
+
+	addr, err := r.Eval(test.Routine)
+
+	if err != nil {
+		return nil, fmt.Errorf("routine '%v': %v", test.Routine, err)
+	}
+
+	if r.ByteAddresses {
+		addr /= 2
+	}
+
+	// Return to the last word of flash, which the routine is unlikely to reach
+	ret := uint64(len(sim.Flash)) - 1
+	sp := sim.SP()
+	err = sim.PushPC(ret)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sim.PC = addr
+
+	for sim.PC != ret || sim.SP() != sp {
+		if sim.Cycles >= r.Limit {
+			return nil, fmt.Errorf("did not return within %v cycles", r.Limit)
+		}
+
+		err := sim.Step()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if sim.Halted != simulator.RUNNING {
+			return nil, fmt.Errorf("stopped %v at 0x%04X", sim.Halted, sim.PC)
+		}
+	}
+
+	return sim, nil
+}
+
+/*
+Set up state before the routine is called
+*/
+func (r *Runner) set(sim *simulator.Simulator, given Check) error {
+	values, err := r.values(given)
+
+	if err != nil {
+		return err
+	}
+
+	target := given.Target
+
+	switch target.Kind {
+	case MEMORY:
+		addr, err := r.Eval(target.Address)
+
+		if err != nil {
+			return err
+		}
+
+		for i, val := range values {
+			if addr+uint64(i) >= uint64(len(sim.Data)) {
+				return fmt.Errorf("data address 0x%04X is outside of memory", addr+uint64(i))
+			}
+
+			sim.Data[addr+uint64(i)] = byte(val)
+		}
+
+	case REGISTER:
+		sim.SetReg(target.Number, byte(values[0]))
+
+	case PAIR:
+		sim.SetPair(target.Number, values[0])
+
+	case FLAG:
+		sim.SetFlag(target.Flag, values[0] != 0)
+
+	case STATUS:
+		sim.Data[simulator.SREG] = byte(values[0])
+
+	case STACK:
+		sim.SetSP(values[0])
+	}
+
+	return nil
+}
+
+/*
+Check state once the routine has returned
+*/
+func (r *Runner) check(sim *simulator.Simulator, expect Check) error {
+	values, err := r.values(expect)
+
+	if err != nil {
+		return err
+	}
+
+	target := expect.Target
+
+	if target.Kind == MEMORY {
+		addr, err := r.Eval(target.Address)
+
+		if err != nil {
+			return err
+		}
+
+		for i, want := range values {
+			at := addr + uint64(i)
+
+			if at >= uint64(len(sim.Data)) {
+				return fmt.Errorf("data address 0x%04X is outside of memory", at)
+			}
+
+			got := uint64(sim.Data[at])
+
+			if !compare(got, expect.Op, want) {
+				return fmt.Errorf("expected [0x%04X] %v 0x%02X, got 0x%02X", at, expect.Op, want, got)
+			}
+		}
+
+		return nil
+	}
+
+	got := uint64(0)
+
+	switch target.Kind {
+	case REGISTER:
+		got = uint64(sim.Reg(target.Number))
+
+	case PAIR:
+		got = sim.Pair(target.Number)
+
+	case FLAG:
+		if sim.Flag(target.Flag) {
+			got = 1
+		}
+
+	case STATUS:
+		got = uint64(sim.Data[simulator.SREG])
+
+	case STACK:
+		got = sim.SP()
+
+	case CYCLES:
+		got = sim.Cycles
+	}
+
+	if !compare(got, expect.Op, values[0]) {
+		return fmt.Errorf("expected %v %v %v, got %v", target.Text, expect.Op, expect.Values[0], format(target, got))
+	}
+
+	return nil
+}
+
+/*
+Values of a line, where a string gives each of its bytes
+*/
+func (r *Runner) values(check Check) ([]uint64, error) {
+	values := []uint64{}
+
+	for _, expr := range check.Values {
+		if text, err := strconv.Unquote(expr); err == nil {
+			for i := 0; i < len(text); i++ {
+				values = append(values, uint64(text[i]))
+			}
+
+			continue
+		}
+
+		val, err := r.Eval(expr)
+
+		if err != nil {
+			return nil, fmt.Errorf("%v in '%v'", err, expr)
+		}
+
+		values = append(values, val)
+	}
+
+	if len(values) == 0 {
+		return nil, fmt.Errorf("expected a value")
+	}
+
+	return values, nil
+}
+
+func compare(got uint64, op string, want uint64) bool {
+	switch op {
+	case "==":
+		return got == want
+	case "!=":
+		return got != want
+	case "<":
+		return got < want
+	case "<=":
+		return got <= want
+	case ">":
+		return got > want
+	case ">=":
+		return got >= want
+	default:
+		return false
+	}
+}
+
+func format(target Target, val uint64) string {
+	switch target.Kind {
+	case CYCLES, FLAG:
+		return fmt.Sprintf("%v", val)
+	case PAIR, STACK:
+		return fmt.Sprintf("0x%04X", val)
+	default:
+		return fmt.Sprintf("0x%02X", val)
+	}
+}
@@ -5,12 +5,12 @@ import (
 
 	"github.com/silaspace/aria/assembler"
 	"github.com/silaspace/aria/handler"
+	"github.com/silaspace/aria/preprocessor"
 )
 
 func main() {
 	reader := handler.NewWebReader()
 	writer := handler.NewWebWriter()
-	asm := assembler.NewAssembler(reader, writer)
 
 	js.Global().Set("write", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 		data := make([]byte, args[0].Get("length").Int())
@@ -21,7 +21,19 @@ func main() {
 
 	js.Global().Set("assemble", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 		writer.Reset()
-		err := asm.Run()
+		reader.Reset()
+
+		// Files cannot be included in the browser
+		pp := preprocessor.NewPreprocessor(nil)
+		err := pp.Process("", reader)
+
+		if err != nil {
+			return js.Global().Get("Error").New(err.Error())
+		}
+
+		asm := assembler.NewAssembler(pp, writer)
+		asm.Lines = pp
+		err = asm.Run()
 
 		if err != nil {
 			return js.Global().Get("Error").New(err.Error())